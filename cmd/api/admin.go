@@ -1,9 +1,16 @@
 package main
 
 import (
+	"errors"
 	"net/http"
+	"strings"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/jesusthecreator017/fswithgo/cmd/api/helpers"
+	"github.com/jesusthecreator017/fswithgo/cmd/api/middleware"
+	"github.com/jesusthecreator017/fswithgo/internal/auth"
+	"github.com/jesusthecreator017/fswithgo/internal/store"
 )
 
 func (app *application) adminStatsHandler(w http.ResponseWriter, req *http.Request) {
@@ -15,3 +22,287 @@ func (app *application) adminStatsHandler(w http.ResponseWriter, req *http.Reque
 
 	helpers.WriteJson(w, http.StatusOK, helpers.Envelope{"stats": stats})
 }
+
+func (app *application) adminListUsersHandler(w http.ResponseWriter, req *http.Request) {
+	qs := req.URL.Query()
+	errs := make(map[string]string)
+
+	page, err := helpers.ReadInt(qs, "page", 1)
+	if err != nil {
+		errs["page"] = err.Error()
+	} else if page < 1 || page > 10_000 {
+		errs["page"] = "must be between 1 and 10000"
+	}
+
+	pageSize, err := helpers.ReadInt(qs, "page_size", 20)
+	if err != nil {
+		errs["page_size"] = err.Error()
+	} else if pageSize < 1 || pageSize > 100 {
+		errs["page_size"] = "must be between 1 and 100"
+	}
+
+	if len(errs) > 0 {
+		helpers.ValidationErrorJson(w, errs)
+		return
+	}
+
+	users, meta, err := app.store.Admin.ListUsers(req.Context(), store.UserFilter{
+		Search:   strings.TrimSpace(qs.Get("q")),
+		Page:     page,
+		PageSize: pageSize,
+	})
+	if err != nil {
+		helpers.ErrorJson(w, http.StatusInternalServerError, "failed to list users")
+		return
+	}
+
+	helpers.WriteJson(w, http.StatusOK, helpers.Envelope{"users": users, "metadata": meta})
+}
+
+func (app *application) adminGetUserHandler(w http.ResponseWriter, req *http.Request) {
+	id, err := uuid.Parse(req.PathValue("id"))
+	if err != nil {
+		helpers.ErrorJson(w, http.StatusBadRequest, "id must be a valid uuid")
+		return
+	}
+
+	detail, err := app.store.Admin.GetUserDetail(req.Context(), id)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			helpers.ErrorJson(w, http.StatusNotFound, "user not found")
+			return
+		}
+		helpers.ErrorJson(w, http.StatusInternalServerError, "failed to get user")
+		return
+	}
+
+	helpers.WriteJson(w, http.StatusOK, helpers.Envelope{"user": detail})
+}
+
+func (app *application) adminUpdatePermissionsHandler(w http.ResponseWriter, req *http.Request) {
+	id, err := uuid.Parse(req.PathValue("id"))
+	if err != nil {
+		helpers.ErrorJson(w, http.StatusBadRequest, "id must be a valid uuid")
+		return
+	}
+
+	// Either set the raw permission bits or pick a named role
+	var input struct {
+		Permissions *int32 `json:"permissions"`
+		Role        string `json:"role"`
+	}
+
+	if err := helpers.ReadJson(req, &input); err != nil {
+		helpers.ErrorJson(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	errs := make(map[string]string)
+
+	var permissions int32
+	switch {
+	case input.Permissions != nil && input.Role != "":
+		errs["role"] = "provide either role or permissions, not both"
+	case input.Permissions != nil:
+		permissions = *input.Permissions
+		if permissions < 0 || permissions > int32(auth.PermAll) {
+			errs["permissions"] = "must be between 0 and 7"
+		}
+	case input.Role == "user":
+		permissions = int32(auth.PermDefault)
+	case input.Role == "admin":
+		permissions = int32(auth.PermAll)
+	default:
+		errs["role"] = "must be one of: user, admin"
+	}
+
+	if len(errs) > 0 {
+		helpers.ValidationErrorJson(w, errs)
+		return
+	}
+
+	user, err := app.store.Admin.UpdateUserPermissions(req.Context(), id, permissions)
+	if err != nil {
+		adminUserErrorJson(w, err, "failed to update permissions")
+		return
+	}
+
+	entry := newAuditEntry(req, store.AuditAdminPermissions, "user", user.ID.String())
+	entry.Metadata = map[string]any{"permissions": user.Permissions}
+	app.recordAudit(req, entry)
+
+	helpers.WriteJson(w, http.StatusOK, helpers.Envelope{"user": user})
+}
+
+func (app *application) adminSuspendUserHandler(w http.ResponseWriter, req *http.Request) {
+	id, err := uuid.Parse(req.PathValue("id"))
+	if err != nil {
+		helpers.ErrorJson(w, http.StatusBadRequest, "id must be a valid uuid")
+		return
+	}
+
+	user, err := app.store.Admin.SuspendUser(req.Context(), id)
+	if err != nil {
+		adminUserErrorJson(w, err, "failed to suspend user")
+		return
+	}
+
+	app.recordAudit(req, newAuditEntry(req, store.AuditAdminSuspend, "user", user.ID.String()))
+
+	helpers.WriteJson(w, http.StatusOK, helpers.Envelope{"user": user})
+}
+
+func (app *application) adminReactivateUserHandler(w http.ResponseWriter, req *http.Request) {
+	id, err := uuid.Parse(req.PathValue("id"))
+	if err != nil {
+		helpers.ErrorJson(w, http.StatusBadRequest, "id must be a valid uuid")
+		return
+	}
+
+	user, err := app.store.Admin.ReactivateUser(req.Context(), id)
+	if err != nil {
+		adminUserErrorJson(w, err, "failed to reactivate user")
+		return
+	}
+
+	app.recordAudit(req, newAuditEntry(req, store.AuditAdminReactivate, "user", user.ID.String()))
+
+	helpers.WriteJson(w, http.StatusOK, helpers.Envelope{"user": user})
+}
+
+func (app *application) adminResetPasswordHandler(w http.ResponseWriter, req *http.Request) {
+	id, err := uuid.Parse(req.PathValue("id"))
+	if err != nil {
+		helpers.ErrorJson(w, http.StatusBadRequest, "id must be a valid uuid")
+		return
+	}
+
+	user, err := app.store.Admin.RequirePasswordReset(req.Context(), id)
+	if err != nil {
+		adminUserErrorJson(w, err, "failed to require password reset")
+		return
+	}
+
+	app.recordAudit(req, newAuditEntry(req, store.AuditAdminResetPassword, "user", user.ID.String()))
+
+	helpers.WriteJson(w, http.StatusOK, helpers.Envelope{"user": user})
+}
+
+// adminDeleteUserHandler deletes a user. The issues query parameter is
+// required: "cascade" deletes their issues, "reassign" moves them to the
+// user given by reassign_to.
+func (app *application) adminDeleteUserHandler(w http.ResponseWriter, req *http.Request) {
+	id, err := uuid.Parse(req.PathValue("id"))
+	if err != nil {
+		helpers.ErrorJson(w, http.StatusBadRequest, "id must be a valid uuid")
+		return
+	}
+
+	qs := req.URL.Query()
+	errs := make(map[string]string)
+
+	opts := store.DeleteUserOptions{Issues: store.IssueDisposition(qs.Get("issues"))}
+	switch opts.Issues {
+	case store.CascadeIssues:
+		// Nothing else to validate
+	case store.ReassignIssues:
+		opts.ReassignTo, err = uuid.Parse(qs.Get("reassign_to"))
+		if err != nil {
+			errs["reassign_to"] = "must be a valid uuid"
+		} else if opts.ReassignTo == id {
+			errs["reassign_to"] = "must be a different user"
+		} else if _, err := app.store.Users.GetByID(req.Context(), opts.ReassignTo); err != nil {
+			errs["reassign_to"] = "user does not exist"
+		}
+	default:
+		errs["issues"] = "must be one of: reassign, cascade"
+	}
+
+	if len(errs) > 0 {
+		helpers.ValidationErrorJson(w, errs)
+		return
+	}
+
+	if err := app.store.Admin.DeleteUser(req.Context(), id, opts); err != nil {
+		adminUserErrorJson(w, err, "failed to delete user")
+		return
+	}
+
+	entry := newAuditEntry(req, store.AuditAdminDeleteUser, "user", id.String())
+	entry.Metadata = map[string]any{"issues": opts.Issues}
+	if opts.Issues == store.ReassignIssues {
+		entry.Metadata["reassign_to"] = opts.ReassignTo
+	}
+	app.recordAudit(req, entry)
+
+	helpers.WriteJson(w, http.StatusOK, helpers.Envelope{"message": "user deleted"})
+}
+
+// impersonationTTL keeps "view as user" tokens short-lived; they are not refreshable.
+const impersonationTTL = 15 * time.Minute
+
+// adminImpersonateHandler issues a token that authenticates as the target user
+// and carries the calling admin in its actor claim.
+func (app *application) adminImpersonateHandler(w http.ResponseWriter, req *http.Request) {
+	id, err := uuid.Parse(req.PathValue("id"))
+	if err != nil {
+		helpers.ErrorJson(w, http.StatusBadRequest, "id must be a valid uuid")
+		return
+	}
+
+	adminID := middleware.GetUserID(req)
+	if id == adminID {
+		helpers.ErrorJson(w, http.StatusBadRequest, "cannot impersonate yourself")
+		return
+	}
+
+	target, err := app.store.Users.GetByID(req.Context(), id)
+	if err != nil {
+		adminUserErrorJson(w, err, "failed to get user")
+		return
+	}
+
+	// Admins can't be impersonated, so an impersonation token never carries admin rights.
+	if auth.HasPermission(auth.Permission(target.Permissions), auth.PermAdmin) {
+		helpers.ErrorJson(w, http.StatusForbidden, "cannot impersonate an admin")
+		return
+	}
+
+	if target.IsSuspended() {
+		helpers.ErrorJson(w, http.StatusConflict, "cannot impersonate a suspended user")
+		return
+	}
+
+	expiresAt := time.Now().Add(impersonationTTL)
+	token, err := auth.GenerateImpersonationJWT(target.ID, target.Permissions, adminID, app.config.jwtSecret, impersonationTTL)
+	if err != nil {
+		helpers.ErrorJson(w, http.StatusInternalServerError, "failed to create jwt token")
+		return
+	}
+
+	entry := newAuditEntry(req, store.AuditImpersonationStart, "user", target.ID.String())
+	entry.Metadata = map[string]any{"expires_at": expiresAt}
+	if err := app.store.Audit.Record(req.Context(), entry); err != nil {
+		// Never hand out an impersonation token that isn't on the record.
+		helpers.ErrorJson(w, http.StatusInternalServerError, "failed to record impersonation")
+		return
+	}
+
+	helpers.WriteJson(w, http.StatusCreated, helpers.Envelope{
+		"user":       target,
+		"token":      token,
+		"expires_at": expiresAt,
+	})
+}
+
+// adminUserErrorJson maps store errors from the user-management endpoints to responses.
+func adminUserErrorJson(w http.ResponseWriter, err error, message string) {
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		helpers.ErrorJson(w, http.StatusNotFound, "user not found")
+	case errors.Is(err, store.ErrLastAdmin):
+		helpers.ErrorJson(w, http.StatusConflict, err.Error())
+	default:
+		helpers.ErrorJson(w, http.StatusInternalServerError, message)
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jesusthecreator017/fswithgo/cmd/api/helpers"
+	"github.com/jesusthecreator017/fswithgo/internal/store"
+)
+
+// maxAnalyticsBuckets caps how many rows one analytics request can produce.
+var maxAnalyticsBuckets = map[store.Granularity]int{
+	store.GranularityDay:   366,
+	store.GranularityWeek:  260,
+	store.GranularityMonth: 120,
+}
+
+// adminAnalyticsHandler returns trend series over [from, to). It defaults to
+// the last 30 days by day. With format=csv it returns the series as CSV, or
+// the open-issue aging buckets when table=aging.
+func (app *application) adminAnalyticsHandler(w http.ResponseWriter, req *http.Request) {
+	qs := req.URL.Query()
+	errs := make(map[string]string)
+
+	r := store.AnalyticsRange{Granularity: store.GranularityDay}
+	if g := qs.Get("granularity"); g != "" {
+		r.Granularity = store.Granularity(g)
+	}
+
+	maxBuckets, ok := maxAnalyticsBuckets[r.Granularity]
+	if !ok {
+		errs["granularity"] = "must be one of: day, week, month"
+	}
+
+	var err error
+	if r.To, err = readTime(qs.Get("to")); err != nil {
+		errs["to"] = err.Error()
+	} else if r.To.IsZero() {
+		r.To = time.Now().UTC()
+	}
+
+	if r.From, err = readTime(qs.Get("from")); err != nil {
+		errs["from"] = err.Error()
+	} else if r.From.IsZero() {
+		r.From = r.To.AddDate(0, 0, -30)
+	}
+
+	if len(errs) == 0 {
+		if !r.From.Before(r.To) {
+			errs["from"] = "must be before to"
+		} else if approxBuckets(r) > maxBuckets {
+			errs["from"] = fmt.Sprintf("range must not exceed %d %ss", maxBuckets, r.Granularity)
+		}
+	}
+
+	format := qs.Get("format")
+	if format != "" && format != "json" && format != "csv" {
+		errs["format"] = "must be one of: json, csv"
+	}
+
+	table := qs.Get("table")
+	if table != "" && table != "series" && table != "aging" {
+		errs["table"] = "must be one of: series, aging"
+	}
+
+	if len(errs) > 0 {
+		helpers.ValidationErrorJson(w, errs)
+		return
+	}
+
+	analytics, err := app.store.Admin.GetAnalytics(req.Context(), r)
+	if err != nil {
+		helpers.ErrorJson(w, http.StatusInternalServerError, "failed to get analytics")
+		return
+	}
+
+	if format == "csv" {
+		if table == "aging" {
+			helpers.WriteCSV(w, http.StatusOK, "open-issue-aging.csv", agingCSV(analytics))
+			return
+		}
+		helpers.WriteCSV(w, http.StatusOK, "analytics.csv", seriesCSV(analytics))
+		return
+	}
+
+	helpers.WriteJson(w, http.StatusOK, helpers.Envelope{"analytics": analytics})
+}
+
+// approxBuckets estimates how many buckets the range spans, rounding up.
+func approxBuckets(r store.AnalyticsRange) int {
+	bucket := 24 * time.Hour
+	switch r.Granularity {
+	case store.GranularityWeek:
+		bucket *= 7
+	case store.GranularityMonth:
+		bucket *= 28
+	}
+	return int(r.To.Sub(r.From)/bucket) + 1
+}
+
+func seriesCSV(a *store.Analytics) [][]string {
+	records := [][]string{{"bucket", "issues_created", "issues_closed", "signups", "median_cycle_hours"}}
+	for _, p := range a.Series {
+		median := ""
+		if p.MedianCycleHours != nil {
+			median = strconv.FormatFloat(*p.MedianCycleHours, 'f', 2, 64)
+		}
+		records = append(records, []string{
+			p.Bucket.UTC().Format(time.RFC3339),
+			strconv.FormatInt(p.IssuesCreated, 10),
+			strconv.FormatInt(p.IssuesClosed, 10),
+			strconv.FormatInt(p.Signups, 10),
+			median,
+		})
+	}
+	return records
+}
+
+func agingCSV(a *store.Analytics) [][]string {
+	records := [][]string{{"age", "open_issues"}}
+	for _, b := range a.OpenIssueAging {
+		records = append(records, []string{b.Label, strconv.FormatInt(b.Count, 10)})
+	}
+	return records
+}
@@ -1,24 +1,88 @@
 package main
 
 import (
-	"fmt"
+	"context"
+	"errors"
+	"log/slog"
 	"net/http"
+	"net/netip"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/jesusthecreator017/fswithgo/cmd/api/middleware"
+	"github.com/jesusthecreator017/fswithgo/internal/blob"
+	"github.com/jesusthecreator017/fswithgo/internal/collab"
+	"github.com/jesusthecreator017/fswithgo/internal/events"
+	"github.com/jesusthecreator017/fswithgo/internal/health"
+	"github.com/jesusthecreator017/fswithgo/internal/jobs"
+	"github.com/jesusthecreator017/fswithgo/internal/lifecycle"
+	"github.com/jesusthecreator017/fswithgo/internal/logging"
+	"github.com/jesusthecreator017/fswithgo/internal/ratelimit"
+	"github.com/jesusthecreator017/fswithgo/internal/schedule"
 	"github.com/jesusthecreator017/fswithgo/internal/store"
+	"github.com/jesusthecreator017/fswithgo/internal/telemetry"
 )
 
 type application struct {
-	config config
-	store  store.Storage
+	config   config
+	store    store.Storage
+	blobs    blob.Store
+	events   *events.Broker
+	collab   *collab.Hub
+	jobs     *jobs.Queue
+	schedule *schedule.Scheduler
+
+	rateLimiter ratelimit.Store
+	cors        *middleware.CORSPolicy
+
+	lifecycle *lifecycle.Lifecycle
+
+	live, ready   *health.Checker
+	metrics       *appMetrics
+	webhookClient *http.Client
 }
 
 type config struct {
-	addr       string
-	db         dbConfig
-	corsOrigin string
-	jwtSecret  string
+	addr        string
+	db          dbConfig
+	cors        middleware.CORSConfig
+	jwtSecret   string
+	audit       auditConfig
+	blob        blobConfig
+	attachments attachmentConfig
+	events      eventsConfig
+	webhooks    webhookConfig
+	jobs        jobs.Config
+	shutdown    shutdownConfig
+	health      healthConfig
+	metrics     metricsConfig
+	logging     logging.Config
+	telemetry   telemetry.Config
+	rateLimit   rateLimitConfig
+	proxy       proxyConfig
+}
+
+// blobConfig picks where attachment bytes are stored: "local" files under
+// dir, or "s3" for any S3-compatible bucket.
+type blobConfig struct {
+	backend string
+	dir     string
+	s3      blob.S3Config
+}
+
+// proxyConfig says which peers are proxies whose word on the client's
+// address is believed.
+type proxyConfig struct {
+	trusted  []netip.Prefix
+	protocol bool // whether they send a PROXY protocol header
+}
+
+// shutdownConfig controls how the server stops on SIGINT or SIGTERM.
+type shutdownConfig struct {
+	delay   time.Duration // time between failing readiness and stopping
+	timeout time.Duration // time everything gets to stop
 }
 
 // dbConfig holds database connection settings.
@@ -32,7 +96,12 @@ type dbConfig struct {
 func (app *application) mount() *http.ServeMux {
 	mux := http.NewServeMux()
 
-	mux.HandleFunc("GET /v1/health", app.healthCheckHandler)
+	mux.HandleFunc("GET /v1/health", app.readinessHandler)
+	mux.HandleFunc("GET /v1/health/live", app.livenessHandler)
+	mux.HandleFunc("GET /v1/health/ready", app.readinessHandler)
+	if app.config.metrics.addr == "" {
+		mux.Handle("GET /metrics", middleware.RequiredAuth(middleware.RequiredAdmin(app.metrics.registry.Handler())))
+	}
 	// Issue
 	mux.Handle("GET /v1/issues", middleware.RequiredAuth(http.HandlerFunc(app.listIssueHandler)))
 	mux.HandleFunc("GET /v1/issues/{id}", app.getIssueHandler)
@@ -41,28 +110,113 @@ func (app *application) mount() *http.ServeMux {
 	mux.Handle("POST /v1/issues", middleware.RequiredAuth(http.HandlerFunc(app.createIssueHandler)))
 	mux.Handle("DELETE /v1/issues/{id}", middleware.RequiredAuth(http.HandlerFunc(app.deleteIssueHandler)))
 	mux.Handle("PATCH /v1/issues/{id}/status", middleware.RequiredAuth(http.HandlerFunc(app.updateIssueStatusHandler)))
+	mux.Handle("POST /v1/issues/{id}/move", middleware.RequiredAuth(http.HandlerFunc(app.moveIssueHandler)))
+	mux.Handle("GET /v1/issues/{id}/children", middleware.RequiredAuth(http.HandlerFunc(app.listIssueChildrenHandler)))
+	mux.Handle("PATCH /v1/issues/{id}/parent", middleware.RequiredAuth(http.HandlerFunc(app.setIssueParentHandler)))
+	mux.Handle("PATCH /v1/issues/{id}/auto-complete", middleware.RequiredAuth(http.HandlerFunc(app.setIssueAutoCompleteHandler)))
+	mux.Handle("GET /v1/issues/{id}/links", middleware.RequiredAuth(http.HandlerFunc(app.listIssueLinksHandler)))
+	mux.Handle("POST /v1/issues/{id}/links", middleware.RequiredAuth(http.HandlerFunc(app.createIssueLinkHandler)))
+	mux.Handle("DELETE /v1/issues/{id}/links", middleware.RequiredAuth(http.HandlerFunc(app.deleteIssueLinkHandler)))
+
+	// Attachments
+	mux.Handle("GET /v1/issues/{id}/attachments", middleware.RequiredAuth(http.HandlerFunc(app.listAttachmentsHandler)))
+	mux.Handle("POST /v1/issues/{id}/attachments", middleware.RequiredAuth(http.HandlerFunc(app.uploadAttachmentHandler)))
+	mux.Handle("GET /v1/issues/{id}/attachments/{attachment_id}", middleware.RequiredAuth(http.HandlerFunc(app.downloadAttachmentHandler)))
+	mux.Handle("DELETE /v1/issues/{id}/attachments/{attachment_id}", middleware.RequiredAuth(http.HandlerFunc(app.deleteAttachmentHandler)))
+
+	// Real-time events
+	mux.Handle("GET /v1/events/stream", middleware.RequiredAuth(http.HandlerFunc(app.eventStreamHandler)))
+	mux.Handle("GET /v1/ws", middleware.RequiredAuth(http.HandlerFunc(app.collabHandler)))
+
+	// Webhooks (admin only)
+	mux.Handle("GET /v1/webhooks", middleware.RequiredAuth(middleware.RequiredAdmin(http.HandlerFunc(app.listWebhooksHandler))))
+	mux.Handle("POST /v1/webhooks", middleware.RequiredAuth(middleware.RequiredAdmin(http.HandlerFunc(app.createWebhookHandler))))
+	mux.Handle("GET /v1/webhooks/{id}", middleware.RequiredAuth(middleware.RequiredAdmin(http.HandlerFunc(app.getWebhookHandler))))
+	mux.Handle("PATCH /v1/webhooks/{id}", middleware.RequiredAuth(middleware.RequiredAdmin(http.HandlerFunc(app.updateWebhookHandler))))
+	mux.Handle("DELETE /v1/webhooks/{id}", middleware.RequiredAuth(middleware.RequiredAdmin(http.HandlerFunc(app.deleteWebhookHandler))))
+	mux.Handle("POST /v1/webhooks/{id}/ping", middleware.RequiredAuth(middleware.RequiredAdmin(http.HandlerFunc(app.pingWebhookHandler))))
+	mux.Handle("GET /v1/webhooks/{id}/deliveries", middleware.RequiredAuth(middleware.RequiredAdmin(http.HandlerFunc(app.listWebhookDeliveriesHandler))))
+	mux.Handle("POST /v1/webhooks/{id}/deliveries/{delivery_id}/redeliver", middleware.RequiredAuth(middleware.RequiredAdmin(http.HandlerFunc(app.redeliverWebhookHandler))))
+
+	// Projects and workflows
+	mux.Handle("GET /v1/workflow", middleware.RequiredAuth(http.HandlerFunc(app.defaultWorkflowHandler)))
+	mux.Handle("GET /v1/projects", middleware.RequiredAuth(http.HandlerFunc(app.listProjectsHandler)))
+	mux.Handle("POST /v1/projects", middleware.RequiredAuth(http.HandlerFunc(app.createProjectHandler)))
+	mux.Handle("GET /v1/projects/{id}", middleware.RequiredAuth(http.HandlerFunc(app.getProjectHandler)))
+	mux.Handle("GET /v1/projects/{id}/issues", middleware.RequiredAuth(http.HandlerFunc(app.listProjectIssuesHandler)))
+	mux.Handle("GET /v1/projects/{id}/workflow", middleware.RequiredAuth(http.HandlerFunc(app.getProjectWorkflowHandler)))
+	mux.Handle("POST /v1/projects/{id}/statuses", middleware.RequiredAuth(http.HandlerFunc(app.createWorkflowStatusHandler)))
+	mux.Handle("PATCH /v1/projects/{id}/statuses/{status_id}", middleware.RequiredAuth(http.HandlerFunc(app.updateWorkflowStatusHandler)))
+	mux.Handle("DELETE /v1/projects/{id}/statuses/{status_id}", middleware.RequiredAuth(http.HandlerFunc(app.deleteWorkflowStatusHandler)))
+	mux.Handle("PUT /v1/projects/{id}/transitions", middleware.RequiredAuth(http.HandlerFunc(app.setWorkflowTransitionsHandler)))
+
+	mux.Handle("GET /v1/wip-limits", middleware.RequiredAuth(http.HandlerFunc(app.boardWIPHandler)))
+
+	// Reports
+	mux.Handle("GET /v1/reports/cumulative-flow", middleware.RequiredAuth(http.HandlerFunc(app.cumulativeFlowHandler)))
+	mux.Handle("GET /v1/reports/burndown", middleware.RequiredAuth(http.HandlerFunc(app.burndownHandler)))
 
 	// Users
 	mux.HandleFunc("POST /v1/users/register", app.registerUserHandler)
 	mux.HandleFunc("POST /v1/users/login", app.loginUserHandler)
+	mux.Handle("PATCH /v1/users/me/password", middleware.RequiredAuthAllowReset(middleware.BlockImpersonation(http.HandlerFunc(app.changePasswordHandler))))
 
 	// Admin
 	mux.Handle("GET /v1/admin/stats", middleware.RequiredAuth(middleware.RequiredAdmin(http.HandlerFunc(app.adminStatsHandler))))
+	mux.Handle("GET /v1/admin/analytics", middleware.RequiredAuth(middleware.RequiredAdmin(http.HandlerFunc(app.adminAnalyticsHandler))))
+	mux.Handle("GET /v1/admin/users", middleware.RequiredAuth(middleware.RequiredAdmin(http.HandlerFunc(app.adminListUsersHandler))))
+	mux.Handle("GET /v1/admin/users/{id}", middleware.RequiredAuth(middleware.RequiredAdmin(http.HandlerFunc(app.adminGetUserHandler))))
+	mux.Handle("PATCH /v1/admin/users/{id}/permissions", middleware.RequiredAuth(middleware.RequiredAdmin(http.HandlerFunc(app.adminUpdatePermissionsHandler))))
+	mux.Handle("POST /v1/admin/users/{id}/suspend", middleware.RequiredAuth(middleware.RequiredAdmin(http.HandlerFunc(app.adminSuspendUserHandler))))
+	mux.Handle("POST /v1/admin/users/{id}/reactivate", middleware.RequiredAuth(middleware.RequiredAdmin(http.HandlerFunc(app.adminReactivateUserHandler))))
+	mux.Handle("POST /v1/admin/users/{id}/reset-password", middleware.RequiredAuth(middleware.RequiredAdmin(http.HandlerFunc(app.adminResetPasswordHandler))))
+	mux.Handle("DELETE /v1/admin/users/{id}", middleware.RequiredAuth(middleware.RequiredAdmin(http.HandlerFunc(app.adminDeleteUserHandler))))
+	mux.Handle("GET /v1/admin/wip-limits", middleware.RequiredAuth(middleware.RequiredAdmin(http.HandlerFunc(app.adminListWIPLimitsHandler))))
+	mux.Handle("PUT /v1/admin/wip-limits", middleware.RequiredAuth(middleware.RequiredAdmin(http.HandlerFunc(app.adminSetWIPLimitHandler))))
+	mux.Handle("DELETE /v1/admin/wip-limits/{id}", middleware.RequiredAuth(middleware.RequiredAdmin(http.HandlerFunc(app.adminDeleteWIPLimitHandler))))
+	mux.Handle("GET /v1/admin/audit", middleware.RequiredAuth(middleware.RequiredAdmin(http.HandlerFunc(app.adminAuditHandler))))
+	mux.Handle("GET /v1/admin/jobs", middleware.RequiredAuth(middleware.RequiredAdmin(http.HandlerFunc(app.adminListJobsHandler))))
+	mux.Handle("GET /v1/admin/jobs/{id}", middleware.RequiredAuth(middleware.RequiredAdmin(http.HandlerFunc(app.adminGetJobHandler))))
+	mux.Handle("POST /v1/admin/jobs/{id}/retry", middleware.RequiredAuth(middleware.RequiredAdmin(http.HandlerFunc(app.adminRetryJobHandler))))
+	mux.Handle("GET /v1/admin/schedule", middleware.RequiredAuth(middleware.RequiredAdmin(http.HandlerFunc(app.adminScheduleHandler))))
+	mux.Handle("GET /v1/admin/schedule/{task}/runs", middleware.RequiredAuth(middleware.RequiredAdmin(http.HandlerFunc(app.adminTaskRunsHandler))))
+	mux.Handle("POST /v1/admin/users/{id}/impersonate", middleware.RequiredAuth(middleware.RequiredAdmin(middleware.BlockImpersonation(http.HandlerFunc(app.adminImpersonateHandler)))))
 
 	return mux
 }
 
+// requestTimeout is the default deadline for a request's context.
+const requestTimeout = 25 * time.Second
+
+// routeTimeouts overrides requestTimeout by route pattern; zero means none.
+// File transfers get as long as their connection deadlines allow, and the
+// event stream and WebSocket stay open until the client leaves.
+func (app *application) routeTimeouts() map[string]time.Duration {
+	transfer := app.config.attachments.transferTimeout
+	return map[string]time.Duration{
+		"POST /v1/issues/{id}/attachments":                transfer,
+		"GET /v1/issues/{id}/attachments/{attachment_id}": transfer,
+		"GET /v1/events/stream":                           0,
+		"GET /v1/ws":                                      0,
+	}
+}
+
 func (app *application) run(mux *http.ServeMux) error {
 
 	// Global Middleware
 	stack := middleware.CreateStack(
-		middleware.CORS(app.config.corsOrigin),
-		middleware.Recoverer,
+		middleware.Route(mux),
+		middleware.Tracing,
+		middleware.Metrics(app.metrics.registry),
+		middleware.CORS(app.cors, mux),
 		middleware.RequestID,
-		middleware.RealIP,
+		middleware.RealIP(app.config.proxy.trusted),
 		middleware.Logging,
-		middleware.GlobalAuth(app.config.jwtSecret),
-		middleware.Timeout(time.Second*25),
+		middleware.Recoverer,
+		middleware.GlobalAuth(app.config.jwtSecret, app.store.Users),
+		middleware.RateLimit(app.rateLimiter, app.config.rateLimit.def, app.rateLimits()),
+		middleware.AuditImpersonation(app.store.Audit),
+		middleware.Timeout(middleware.RouteTimeouts(requestTimeout, app.routeTimeouts())),
 	)
 
 	srv := &http.Server{
@@ -72,7 +226,31 @@ func (app *application) run(mux *http.ServeMux) error {
 		ReadTimeout:  time.Second * 10,
 		IdleTimeout:  time.Minute,
 	}
+	// Added last, so the server stops before anything it depends on.
+	app.lifecycle.Append(app.serverHook("http", srv, app.config.proxy.protocol))
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := app.lifecycle.Start(ctx); err != nil {
+		return errors.Join(err, app.stop())
+	}
+	slog.Info("listening", "addr", app.config.addr)
+
+	var err error
+	select {
+	case <-ctx.Done():
+		slog.Info("shutting down")
+	case err = <-app.lifecycle.Failed():
+		slog.Error("shutting down", "error", err)
+	}
+	// A second signal kills the process.
+	stop()
+
+	// Fail readiness checks first so load balancers stop sending requests
+	// before the listener closes.
+	app.lifecycle.Drain()
+	time.Sleep(app.config.shutdown.delay)
 
-	fmt.Printf("Listening on port%s\n", app.config.addr)
-	return srv.ListenAndServe()
+	return errors.Join(err, app.stop())
 }
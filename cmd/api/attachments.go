@@ -0,0 +1,371 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jesusthecreator017/fswithgo/cmd/api/helpers"
+	"github.com/jesusthecreator017/fswithgo/cmd/api/middleware"
+	"github.com/jesusthecreator017/fswithgo/internal/blob"
+	"github.com/jesusthecreator017/fswithgo/internal/jobs"
+	"github.com/jesusthecreator017/fswithgo/internal/logging"
+	"github.com/jesusthecreator017/fswithgo/internal/store"
+)
+
+// attachmentConfig limits uploads. Content types are checked against what
+// the first bytes of the file look like, not what the client claims.
+type attachmentConfig struct {
+	maxBytes        int64
+	allowedTypes    []string
+	transferTimeout time.Duration // read/write deadline for one upload or download
+	sweepSchedule   string        // cron schedule for deleting orphaned blobs
+}
+
+// multipartOverhead is the room allowed on top of maxBytes for multipart
+// boundaries and part headers.
+const multipartOverhead = 64 << 10
+
+// attachmentSweepBatch is how many orphaned attachments the sweeper loads
+// per query.
+const attachmentSweepBatch = 100
+
+func (app *application) listAttachmentsHandler(w http.ResponseWriter, req *http.Request) {
+	issue, ok := app.readIssue(w, req)
+	if !ok {
+		return
+	}
+
+	attachments, err := app.store.Attachments.ListByIssueID(req.Context(), issue.ID)
+	if err != nil {
+		helpers.ErrorJson(w, http.StatusInternalServerError, "failed to get attachments")
+		return
+	}
+
+	helpers.WriteJson(w, http.StatusOK, helpers.Envelope{"attachments": attachments})
+}
+
+// uploadAttachmentHandler streams the "file" part of a multipart body to
+// blob storage, hashing it on the way. A client that sends
+// X-Checksum-SHA256 gets a 422 if the stored bytes don't match it.
+func (app *application) uploadAttachmentHandler(w http.ResponseWriter, req *http.Request) {
+	issue, ok := app.readIssue(w, req)
+	if !ok {
+		return
+	}
+
+	cfg := app.config.attachments
+	extendDeadlines(w, req, cfg.transferTimeout)
+
+	if req.ContentLength > cfg.maxBytes+multipartOverhead {
+		tooLargeJson(w, cfg.maxBytes)
+		return
+	}
+	req.Body = http.MaxBytesReader(w, req.Body, cfg.maxBytes+multipartOverhead)
+
+	mr, err := req.MultipartReader()
+	if err != nil {
+		helpers.ErrorJson(w, http.StatusBadRequest, "body must be multipart/form-data")
+		return
+	}
+
+	part, err := nextFilePart(mr)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			tooLargeJson(w, cfg.maxBytes)
+			return
+		}
+		helpers.ErrorJson(w, http.StatusBadRequest, `body must include a "file" part`)
+		return
+	}
+	defer part.Close()
+
+	filename := cleanFilename(part.FileName())
+	if filename == "" {
+		helpers.ValidationErrorJson(w, map[string]string{"file": "must have a file name"})
+		return
+	}
+
+	body := bufio.NewReaderSize(part, 512)
+	head, err := body.Peek(512)
+	if err != nil && !errors.Is(err, io.EOF) {
+		helpers.ErrorJson(w, http.StatusBadRequest, "failed to read file")
+		return
+	}
+
+	contentType := sniffContentType(head)
+	if !slices.Contains(cfg.allowedTypes, contentType) {
+		helpers.ErrorJson(w, http.StatusUnsupportedMediaType,
+			fmt.Sprintf("%s files are not allowed; allowed types: %s", contentType, strings.Join(cfg.allowedTypes, ", ")))
+		return
+	}
+
+	// Read one byte past the limit so an oversized file is detectable.
+	hash := sha256.New()
+	counter := &countingReader{r: io.LimitReader(body, cfg.maxBytes+1)}
+	key := fmt.Sprintf("issues/%d/%s", issue.ID, uuid.New())
+
+	err = app.blobs.Put(req.Context(), key, io.TeeReader(counter, hash), contentType)
+	if err == nil && counter.n > cfg.maxBytes {
+		err = &http.MaxBytesError{Limit: cfg.maxBytes}
+	}
+	if err != nil {
+		app.deleteBlob(req, key)
+
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			tooLargeJson(w, cfg.maxBytes)
+			return
+		}
+		logging.FromContext(req.Context()).Error("storing attachment", "key", key, "error", err)
+		helpers.ErrorJson(w, http.StatusInternalServerError, "failed to store file")
+		return
+	}
+
+	sum := hex.EncodeToString(hash.Sum(nil))
+	if want := req.Header.Get("X-Checksum-SHA256"); want != "" && !strings.EqualFold(want, sum) {
+		app.deleteBlob(req, key)
+		helpers.ValidationErrorJson(w, map[string]string{"file": "checksum does not match X-Checksum-SHA256"})
+		return
+	}
+
+	userID := middleware.GetUserID(req)
+	attachment := &store.Attachment{
+		IssueID:     &issue.ID,
+		UserID:      &userID,
+		Filename:    filename,
+		ContentType: contentType,
+		SizeBytes:   counter.n,
+		SHA256:      sum,
+		StorageKey:  key,
+	}
+
+	if err := app.store.Attachments.Create(req.Context(), attachment); err != nil {
+		app.deleteBlob(req, key)
+		if errors.Is(err, store.ErrNotFound) {
+			helpers.ErrorJson(w, http.StatusNotFound, "issue not found")
+			return
+		}
+		helpers.ErrorJson(w, http.StatusInternalServerError, "failed to save attachment")
+		return
+	}
+
+	entry := newAuditEntry(req, store.AuditIssueAttachmentUpload, "issue", strconv.FormatInt(issue.ID, 10))
+	entry.Metadata = map[string]any{"attachment_id": attachment.ID, "filename": filename, "size_bytes": attachment.SizeBytes}
+	app.recordAudit(req, entry)
+
+	helpers.WriteJson(w, http.StatusCreated, helpers.Envelope{"attachment": attachment})
+}
+
+// downloadAttachmentHandler serves an attachment's bytes. Range and
+// conditional requests are handled by http.ServeContent.
+func (app *application) downloadAttachmentHandler(w http.ResponseWriter, req *http.Request) {
+	attachment, ok := app.readAttachment(w, req)
+	if !ok {
+		return
+	}
+
+	extendDeadlines(w, req, app.config.attachments.transferTimeout)
+
+	w.Header().Set("Content-Type", attachment.ContentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Filename}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Cache-Control", "private")
+	w.Header().Set("ETag", `"`+attachment.SHA256+`"`)
+
+	content := blob.NewReadSeeker(req.Context(), app.blobs, attachment.StorageKey, attachment.SizeBytes)
+	defer content.Close()
+
+	http.ServeContent(w, req, "", attachment.CreatedAt, content)
+}
+
+func (app *application) deleteAttachmentHandler(w http.ResponseWriter, req *http.Request) {
+	attachment, ok := app.readAttachment(w, req)
+	if !ok {
+		return
+	}
+
+	issueID := *attachment.IssueID
+	detached, err := app.store.Attachments.Detach(req.Context(), issueID, attachment.ID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			helpers.ErrorJson(w, http.StatusNotFound, "attachment not found")
+			return
+		}
+		helpers.ErrorJson(w, http.StatusInternalServerError, "failed to delete attachment")
+		return
+	}
+
+	// The attachment is already gone from the issue. Its file is deleted in
+	// the background; if it can't even be queued, the sweeper gets it later.
+	_, err = app.jobs.Enqueue(context.WithoutCancel(req.Context()), purgeAttachmentArgs{
+		AttachmentID: detached.ID,
+		StorageKey:   detached.StorageKey,
+	}, jobs.EnqueueOptions{UniqueKey: strconv.FormatInt(detached.ID, 10)})
+	if err != nil {
+		logging.FromContext(req.Context()).Error("queueing attachment purge", "error", err)
+	}
+
+	entry := newAuditEntry(req, store.AuditIssueAttachmentDelete, "issue", strconv.FormatInt(issueID, 10))
+	entry.Metadata = map[string]any{"attachment_id": attachment.ID, "filename": attachment.Filename}
+	app.recordAudit(req, entry)
+
+	helpers.WriteJson(w, http.StatusOK, helpers.Envelope{"message": "attachment deleted"})
+}
+
+// readAttachment loads the attachment named by the {id} and
+// {attachment_id} path values, writing the error response and returning
+// false if it can't.
+func (app *application) readAttachment(w http.ResponseWriter, req *http.Request) (*store.Attachment, bool) {
+	issueID, err := strconv.ParseInt(req.PathValue("id"), 10, 64)
+	if err != nil {
+		helpers.ErrorJson(w, http.StatusBadRequest, "id must be a number")
+		return nil, false
+	}
+
+	id, err := strconv.ParseInt(req.PathValue("attachment_id"), 10, 64)
+	if err != nil {
+		helpers.ErrorJson(w, http.StatusBadRequest, "attachment_id must be a number")
+		return nil, false
+	}
+
+	attachment, err := app.store.Attachments.Get(req.Context(), issueID, id)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			helpers.ErrorJson(w, http.StatusNotFound, "attachment not found")
+			return nil, false
+		}
+		helpers.ErrorJson(w, http.StatusInternalServerError, "failed to get attachment")
+		return nil, false
+	}
+
+	return attachment, true
+}
+
+// sweepAttachmentsTask deletes the blobs of attachments whose issue is
+// gone.
+func (app *application) sweepAttachmentsTask(ctx context.Context) error {
+	swept, err := app.sweepAttachments(ctx)
+	if swept > 0 {
+		logging.FromContext(ctx).Info("swept attachments", "deleted", swept)
+	}
+	return err
+}
+
+// sweepAttachments purges orphaned attachments batch by batch. It stops at
+// the first failure so a blob store outage doesn't spin through the table.
+func (app *application) sweepAttachments(ctx context.Context) (int, error) {
+	swept := 0
+	for {
+		batch, err := app.store.Attachments.ListOrphaned(ctx, attachmentSweepBatch)
+		if err != nil {
+			return swept, err
+		}
+
+		for _, attachment := range batch {
+			if err := app.purgeAttachment(ctx, attachment); err != nil {
+				return swept, err
+			}
+			swept++
+		}
+
+		if len(batch) < attachmentSweepBatch {
+			return swept, nil
+		}
+	}
+}
+
+// purgeAttachment deletes an attachment's blob and then its row.
+func (app *application) purgeAttachment(ctx context.Context, attachment *store.Attachment) error {
+	if err := app.blobs.Delete(ctx, attachment.StorageKey); err != nil {
+		return fmt.Errorf("deleting blob %s: %w", attachment.StorageKey, err)
+	}
+	return app.store.Attachments.Delete(ctx, attachment.ID)
+}
+
+// deleteBlob cleans up after a failed upload.
+func (app *application) deleteBlob(req *http.Request, key string) {
+	if err := app.blobs.Delete(context.WithoutCancel(req.Context()), key); err != nil {
+		logging.FromContext(req.Context()).Error("cleaning up attachment", "key", key, "error", err)
+	}
+}
+
+// nextFilePart skips to the part named "file".
+func nextFilePart(mr *multipart.Reader) (*multipart.Part, error) {
+	for {
+		part, err := mr.NextPart()
+		if err != nil {
+			return nil, err
+		}
+		if part.FormName() == "file" {
+			return part, nil
+		}
+		part.Close()
+	}
+}
+
+// cleanFilename keeps the base name of what the client sent, since some
+// browsers include the whole path.
+func cleanFilename(name string) string {
+	name = strings.ReplaceAll(name, `\`, "/")
+	name = strings.TrimSpace(filepath.Base(name))
+	if name == "." || name == "/" {
+		return ""
+	}
+	if len(name) > 255 {
+		name = name[:255]
+	}
+	return name
+}
+
+// sniffContentType returns the media type of a file from its first bytes,
+// without parameters such as charset.
+func sniffContentType(head []byte) string {
+	mediaType, _, err := mime.ParseMediaType(http.DetectContentType(head))
+	if err != nil {
+		return "application/octet-stream"
+	}
+	return mediaType
+}
+
+// extendDeadlines gives a file transfer longer than the server-wide read
+// and write timeouts.
+func extendDeadlines(w http.ResponseWriter, req *http.Request, d time.Duration) {
+	rc := http.NewResponseController(w)
+	deadline := time.Now().Add(d)
+	if err := rc.SetReadDeadline(deadline); err != nil {
+		logging.FromContext(req.Context()).Warn("extending read deadline", "error", err)
+	}
+	if err := rc.SetWriteDeadline(deadline); err != nil {
+		logging.FromContext(req.Context()).Warn("extending write deadline", "error", err)
+	}
+}
+
+func tooLargeJson(w http.ResponseWriter, limit int64) {
+	helpers.ErrorJson(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("file must not be larger than %d bytes", limit))
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
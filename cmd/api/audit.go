@@ -0,0 +1,231 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jesusthecreator017/fswithgo/cmd/api/helpers"
+	"github.com/jesusthecreator017/fswithgo/cmd/api/middleware"
+	"github.com/jesusthecreator017/fswithgo/internal/logging"
+	"github.com/jesusthecreator017/fswithgo/internal/store"
+)
+
+// auditConfig controls how long audit entries stay in Postgres before the
+// retention job moves them to NDJSON files in archiveDir.
+type auditConfig struct {
+	retention  time.Duration // 0 disables the retention job
+	schedule   string        // cron schedule for the retention job
+	archiveDir string
+}
+
+// auditArchiveBatch is how many rows the retention job reads per query.
+const auditArchiveBatch = 1000
+
+// newAuditEntry starts an entry attributed to whoever made req, including the
+// admin behind it when the request uses an impersonation token.
+func newAuditEntry(req *http.Request, action, targetType, targetID string) *store.AuditEntry {
+	entry := &store.AuditEntry{
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetID,
+		IP:         middleware.GetRealIP(req),
+		RequestID:  middleware.GetRequestID(req),
+	}
+
+	if userID := middleware.GetUserID(req); userID != uuid.Nil {
+		entry.ActorID = &userID
+	}
+	if impersonatorID, ok := middleware.GetImpersonatorID(req); ok {
+		entry.ImpersonatorID = &impersonatorID
+	}
+
+	return entry
+}
+
+// recordAudit writes entry without failing the request if the audit log is
+// unavailable. The client may already have disconnected, so the request's
+// cancellation is ignored.
+func (app *application) recordAudit(req *http.Request, entry *store.AuditEntry) {
+	if err := app.store.Audit.Record(context.WithoutCancel(req.Context()), entry); err != nil {
+		logging.FromContext(req.Context()).Error("recording audit entry", "action", entry.Action, "error", err)
+	}
+}
+
+// adminAuditHandler lists audit entries newest first. Pass the returned
+// next_cursor back as cursor to fetch the following page.
+func (app *application) adminAuditHandler(w http.ResponseWriter, req *http.Request) {
+	qs := req.URL.Query()
+	errs := make(map[string]string)
+
+	filter := store.AuditFilter{
+		Action:     strings.TrimSpace(qs.Get("action")),
+		TargetType: strings.TrimSpace(qs.Get("target_type")),
+		TargetID:   strings.TrimSpace(qs.Get("target_id")),
+	}
+
+	if s := qs.Get("actor_id"); s != "" {
+		actorID, err := uuid.Parse(s)
+		if err != nil {
+			errs["actor_id"] = "must be a valid uuid"
+		} else {
+			filter.ActorID = &actorID
+		}
+	}
+
+	var err error
+	if filter.Since, err = readTime(qs.Get("since")); err != nil {
+		errs["since"] = err.Error()
+	}
+	if filter.Until, err = readTime(qs.Get("until")); err != nil {
+		errs["until"] = err.Error()
+	}
+
+	if filter.BeforeID, err = decodeAuditCursor(qs.Get("cursor")); err != nil {
+		errs["cursor"] = "is invalid"
+	}
+
+	filter.Limit, err = helpers.ReadInt(qs, "limit", 50)
+	if err != nil {
+		errs["limit"] = err.Error()
+	} else if filter.Limit < 1 || filter.Limit > 200 {
+		errs["limit"] = "must be between 1 and 200"
+	}
+
+	if len(errs) > 0 {
+		helpers.ValidationErrorJson(w, errs)
+		return
+	}
+
+	entries, err := app.store.Audit.List(req.Context(), filter)
+	if err != nil {
+		helpers.ErrorJson(w, http.StatusInternalServerError, "failed to list audit log")
+		return
+	}
+
+	// A full page means there may be more; an empty cursor means there aren't.
+	nextCursor := ""
+	if len(entries) == filter.Limit {
+		nextCursor = encodeAuditCursor(entries[len(entries)-1].ID)
+	}
+
+	helpers.WriteJson(w, http.StatusOK, helpers.Envelope{"entries": entries, "next_cursor": nextCursor})
+}
+
+// auditRetentionTask archives expired audit entries.
+func (app *application) auditRetentionTask(ctx context.Context) error {
+	archived, err := app.archiveAuditLog(ctx)
+	if archived > 0 {
+		logging.FromContext(ctx).Info("archived audit entries", "archived", archived)
+	}
+	return err
+}
+
+// archiveAuditLog writes every entry older than the retention window to a new
+// NDJSON file, then deletes exactly those rows. The file is synced before the
+// delete, so a crash can at worst archive the same rows twice, never lose them.
+func (app *application) archiveAuditLog(ctx context.Context) (int64, error) {
+	cutoff := time.Now().Add(-app.config.audit.retention)
+	dir := app.config.audit.archiveDir
+
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return 0, fmt.Errorf("creating archive dir: %w", err)
+	}
+
+	f, err := os.CreateTemp(dir, "audit-*.ndjson.tmp")
+	if err != nil {
+		return 0, fmt.Errorf("creating archive file: %w", err)
+	}
+	// Removing the temp file after a successful rename fails harmlessly.
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	enc := json.NewEncoder(f)
+	var firstID, lastID int64
+	for {
+		batch, err := app.store.Audit.ListBefore(ctx, cutoff, lastID, auditArchiveBatch)
+		if err != nil {
+			return 0, err
+		}
+
+		for _, entry := range batch {
+			if err := enc.Encode(entry); err != nil {
+				return 0, fmt.Errorf("writing archive: %w", err)
+			}
+		}
+
+		if len(batch) > 0 {
+			if firstID == 0 {
+				firstID = batch[0].ID
+			}
+			lastID = batch[len(batch)-1].ID
+		}
+		if len(batch) < auditArchiveBatch {
+			break
+		}
+	}
+
+	if lastID == 0 {
+		return 0, nil
+	}
+
+	if err := f.Sync(); err != nil {
+		return 0, fmt.Errorf("syncing archive: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return 0, fmt.Errorf("closing archive: %w", err)
+	}
+
+	name := fmt.Sprintf("audit-%s-%d-%d.ndjson", cutoff.UTC().Format("20060102T150405Z"), firstID, lastID)
+	if err := os.Rename(f.Name(), filepath.Join(dir, name)); err != nil {
+		return 0, fmt.Errorf("finalizing archive: %w", err)
+	}
+
+	return app.store.Audit.DeleteBefore(ctx, cutoff, lastID)
+}
+
+// readTime parses an optional query parameter given either as an RFC 3339
+// timestamp or as a YYYY-MM-DD date (midnight UTC).
+func readTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.DateOnly, s); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("must be an RFC 3339 timestamp or a YYYY-MM-DD date")
+}
+
+// Cursors are opaque to clients so the paging key can change later.
+func encodeAuditCursor(id int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(id, 10)))
+}
+
+func decodeAuditCursor(cursor string) (int64, error) {
+	if cursor == "" {
+		return 0, nil
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := strconv.ParseInt(string(raw), 10, 64)
+	if err != nil || id < 1 {
+		return 0, fmt.Errorf("invalid cursor")
+	}
+	return id, nil
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/gorilla/websocket"
+	"github.com/jesusthecreator017/fswithgo/cmd/api/helpers"
+	"github.com/jesusthecreator017/fswithgo/cmd/api/middleware"
+	"github.com/jesusthecreator017/fswithgo/internal/auth"
+	"github.com/jesusthecreator017/fswithgo/internal/collab"
+	"github.com/jesusthecreator017/fswithgo/internal/logging"
+	"github.com/jesusthecreator017/fswithgo/internal/store"
+)
+
+var (
+	errTopicNotFound  = errors.New("not found")
+	errTopicForbidden = errors.New("not allowed")
+	errTopicCheck     = errors.New("failed to check access")
+)
+
+// collabHandler upgrades to the WebSocket collaboration channel. Browsers
+// pass their token as the second subprotocol after "bearer".
+func (app *application) collabHandler(w http.ResponseWriter, req *http.Request) {
+	user, err := app.store.Users.GetByID(req.Context(), middleware.GetUserID(req))
+	if err != nil {
+		helpers.ErrorJson(w, http.StatusInternalServerError, "failed to load user")
+		return
+	}
+
+	upgrader := websocket.Upgrader{
+		Subprotocols: []string{middleware.WebSocketTokenProtocol},
+		CheckOrigin:  app.checkOrigin,
+	}
+
+	// Upgrade writes its own error response.
+	ws, err := upgrader.Upgrade(w, req, nil)
+	if err != nil {
+		logging.FromContext(req.Context()).Warn("websocket upgrade failed", "error", err)
+		return
+	}
+
+	app.collab.Serve(req.Context(), ws, collab.User{
+		ID:    user.ID,
+		Name:  user.Name,
+		Admin: auth.HasPermission(auth.Permission(user.Permissions), auth.PermAdmin),
+	})
+}
+
+// checkOrigin accepts WebSocket connections from the origins CORS allows,
+// as well as from clients that send no Origin at all.
+func (app *application) checkOrigin(req *http.Request) bool {
+	origin := req.Header.Get("Origin")
+	return origin == "" || app.cors.Allows(origin)
+}
+
+// authorizeTopic lets anyone follow a project or their own board, and
+// follow an issue they could see events for.
+func (app *application) authorizeTopic(ctx context.Context, user collab.User, topic collab.Topic) error {
+	switch topic.Kind {
+	case collab.TopicProject:
+		_, err := app.store.Projects.GetByID(ctx, topic.ID)
+		if errors.Is(err, store.ErrNotFound) {
+			return errTopicNotFound
+		}
+		if err != nil {
+			logging.FromContext(ctx).Error("getting project for collab topic", "project_id", topic.ID, "error", err)
+			return errTopicCheck
+		}
+
+	case collab.TopicIssue:
+		issue, err := app.store.Issues.GetByID(ctx, topic.ID)
+		if errors.Is(err, store.ErrNotFound) {
+			return errTopicNotFound
+		}
+		if err != nil {
+			logging.FromContext(ctx).Error("getting issue for collab topic", "issue_id", topic.ID, "error", err)
+			return errTopicCheck
+		}
+		if issue.ProjectID == nil && issue.UserID != user.ID && !user.Admin {
+			return errTopicForbidden
+		}
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jesusthecreator017/fswithgo/cmd/api/helpers"
+	"github.com/jesusthecreator017/fswithgo/cmd/api/middleware"
+	"github.com/jesusthecreator017/fswithgo/internal/auth"
+	"github.com/jesusthecreator017/fswithgo/internal/events"
+	"github.com/jesusthecreator017/fswithgo/internal/logging"
+	"github.com/jesusthecreator017/fswithgo/internal/store"
+)
+
+type eventsConfig struct {
+	replaySize int           // events kept for Last-Event-ID resume
+	heartbeat  time.Duration // idle time before a keep-alive comment
+}
+
+// eventStreamHandler streams issue events the user may see as Server-Sent
+// Events. A client reconnecting with Last-Event-ID gets what it missed; if
+// that is no longer buffered it gets a reset event and should reload.
+func (app *application) eventStreamHandler(w http.ResponseWriter, req *http.Request) {
+	var lastID int64
+	if s := req.Header.Get("Last-Event-ID"); s != "" {
+		id, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			helpers.ErrorJson(w, http.StatusBadRequest, "Last-Event-ID must be an event id")
+			return
+		}
+		lastID = id
+	}
+
+	userID := middleware.GetUserID(req)
+	admin := auth.HasPermission(auth.Permission(middleware.GetPermissions(req)), auth.PermAdmin)
+
+	sub, missed, ok := app.events.Subscribe(lastID)
+	defer app.events.Unsubscribe(sub)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	rc := http.NewResponseController(w)
+	heartbeat := app.config.events.heartbeat
+
+	// send writes one message and flushes it, giving the write its own
+	// deadline in place of the server's WriteTimeout.
+	send := func(format string, args ...any) bool {
+		rc.SetWriteDeadline(time.Now().Add(heartbeat * 2))
+		if _, err := fmt.Fprintf(w, format, args...); err != nil {
+			return false
+		}
+		return rc.Flush() == nil
+	}
+
+	if !send("retry: %d\n\n", heartbeat.Milliseconds()) {
+		return
+	}
+	if !ok && !send("event: reset\ndata: {}\n\n") {
+		return
+	}
+	for _, e := range missed {
+		if e.VisibleTo(userID, admin) && !sendEvent(send, e) {
+			return
+		}
+	}
+
+	ticker := time.NewTicker(heartbeat)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-req.Context().Done():
+			return
+		case <-app.lifecycle.Stopping():
+			// The client reconnects, with Last-Event-ID, to an instance
+			// that isn't stopping.
+			return
+		case e, open := <-sub.C:
+			if !open {
+				return
+			}
+			if e.VisibleTo(userID, admin) && !sendEvent(send, e) {
+				return
+			}
+		case <-ticker.C:
+			if !send(": ping\n\n") {
+				return
+			}
+		}
+	}
+}
+
+func sendEvent(send func(string, ...any) bool, e events.Event) bool {
+	data, err := json.Marshal(e)
+	if err != nil {
+		slog.Error("encoding event", "event_id", e.ID, "error", err)
+		return true
+	}
+	return send("id: %d\nevent: %s\ndata: %s\n\n", e.ID, e.Type, data)
+}
+
+// publishIssueEvent tells subscribers about a change to issue. Like the
+// audit log, a failure here doesn't fail the request.
+func (app *application) publishIssueEvent(req *http.Request, eventType events.Type, issue *store.Issue) {
+	e := events.Event{
+		Type:      eventType,
+		IssueID:   issue.ID,
+		ProjectID: issue.ProjectID,
+		OwnerID:   issue.UserID,
+	}
+	if userID := middleware.GetUserID(req); userID != uuid.Nil {
+		e.ActorID = &userID
+	}
+	if eventType != events.IssueDeleted {
+		data, err := json.Marshal(issue)
+		if err != nil {
+			logging.FromContext(req.Context()).Error("encoding issue for event", "issue_id", issue.ID, "error", err)
+			return
+		}
+		e.Data = data
+	}
+
+	ctx := context.WithoutCancel(req.Context())
+	if err := app.events.Publish(ctx, &e); err != nil {
+		logging.FromContext(ctx).Error("publishing event", "type", eventType, "issue_id", issue.ID, "error", err)
+	}
+
+	// Webhooks get the event even if live subscribers missed it, as long
+	// as it was numbered.
+	if e.ID != 0 {
+		app.enqueueWebhooks(ctx, &e)
+	}
+}
+
+// publishParentUpdate sends the current state of issue's parent, whose
+// progress (and possibly status) changes with its children.
+func (app *application) publishParentUpdate(req *http.Request, issue *store.Issue) {
+	if issue.ParentID == nil {
+		return
+	}
+	parent, err := app.store.Issues.GetByID(context.WithoutCancel(req.Context()), *issue.ParentID)
+	if err != nil {
+		logging.FromContext(req.Context()).Error("getting parent issue", "issue_id", issue.ID, "error", err)
+		return
+	}
+	app.publishIssueEvent(req, events.IssueUpdated, parent)
+}
@@ -1,7 +1,72 @@
 package main
 
-import "net/http"
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
 
-func (app *application) healthCheckHandler(w http.ResponseWriter, req *http.Request) {
-	w.Write([]byte("OK\n"))
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jesusthecreator017/fswithgo/cmd/api/helpers"
+	"github.com/jesusthecreator017/fswithgo/cmd/migrate/migrations"
+	"github.com/jesusthecreator017/fswithgo/internal/health"
+)
+
+type healthConfig struct {
+	cacheTTL time.Duration // how long a probe's results are reused
+}
+
+// Health check limits.
+const (
+	healthCheckTimeout = 2 * time.Second
+	slowPing           = 250 * time.Millisecond
+	poolWarnAt         = 0.9
+)
+
+// addHealthChecks sets up the probes. Liveness only looks inside the
+// process, so a database outage doesn't get every instance restarted;
+// readiness checks what serving requests needs.
+func (app *application) addHealthChecks(pool *pgxpool.Pool) error {
+	schemaVersion, err := migrations.Latest()
+	if err != nil {
+		return fmt.Errorf("reading migrations: %w", err)
+	}
+
+	app.live = health.NewChecker(app.config.health.cacheTTL, healthCheckTimeout)
+	// A worker that hasn't polled in this long is stuck, not busy.
+	if cfg := app.config.jobs; cfg.Workers > 0 {
+		app.live.Add("jobs", health.Stale(app.jobs.LastPoll, cfg.Timeout+cfg.PollInterval+time.Minute))
+	}
+
+	app.ready = health.NewChecker(app.config.health.cacheTTL, healthCheckTimeout)
+	app.ready.Add("lifecycle", func(context.Context) health.Result {
+		if !app.lifecycle.Ready() {
+			return health.Result{Status: health.Fail, Message: "starting or shutting down"}
+		}
+		return health.Result{Status: health.Pass}
+	})
+	app.ready.Add("database", health.Ping(pool, slowPing))
+	app.ready.Add("pool", health.PoolSaturation(pool, poolWarnAt))
+	app.ready.Add("migrations", health.SchemaVersion(pool, schemaVersion))
+	return nil
+}
+
+// livenessHandler fails if the process is stuck and should be restarted.
+func (app *application) livenessHandler(w http.ResponseWriter, req *http.Request) {
+	writeHealthReport(w, app.live.Run())
+}
+
+// readinessHandler fails while the instance can't serve requests: it is
+// starting, draining, or can't use the database.
+func (app *application) readinessHandler(w http.ResponseWriter, req *http.Request) {
+	writeHealthReport(w, app.ready.Run())
+}
+
+func writeHealthReport(w http.ResponseWriter, report *health.Report) {
+	status := http.StatusOK
+	if report.Status == health.Fail {
+		status = http.StatusServiceUnavailable
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	helpers.WriteJson(w, status, report)
 }
@@ -0,0 +1,20 @@
+package helpers
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+)
+
+// WriteCSV writes records as a CSV attachment. The first record should be the header row.
+func WriteCSV(w http.ResponseWriter, status int, filename string, records [][]string) error {
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	w.WriteHeader(status)
+
+	cw := csv.NewWriter(w)
+	if err := cw.WriteAll(records); err != nil {
+		return err
+	}
+	return cw.Error()
+}
@@ -0,0 +1,22 @@
+package helpers
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+)
+
+// ReadInt parses an integer query parameter, returning fallback when the key is absent.
+func ReadInt(qs url.Values, key string, fallback int) (int, error) {
+	s := qs.Get(key)
+	if s == "" {
+		return fallback, nil
+	}
+
+	i, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("must be an integer")
+	}
+
+	return i, nil
+}
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
 	"strings"
@@ -8,13 +10,18 @@ import (
 
 	"github.com/jesusthecreator017/fswithgo/cmd/api/helpers"
 	"github.com/jesusthecreator017/fswithgo/cmd/api/middleware"
+	"github.com/jesusthecreator017/fswithgo/internal/events"
 	"github.com/jesusthecreator017/fswithgo/internal/store"
 )
 
 func (app *application) createIssueHandler(w http.ResponseWriter, req *http.Request) {
 	var input struct {
-		Title       string `json:"title"`
-		Description string `json:"description"`
+		Title        string `json:"title"`
+		Description  string `json:"description"`
+		ProjectID    *int64 `json:"project_id"`
+		ParentID     *int64 `json:"parent_id"`
+		AutoComplete bool   `json:"auto_complete"`
+		Force        bool   `json:"force"`
 	}
 
 	if err := helpers.ReadJson(req, &input); err != nil {
@@ -31,26 +38,61 @@ func (app *application) createIssueHandler(w http.ResponseWriter, req *http.Requ
 		errs["title"] = "must not be more than 255 characters"
 	}
 
+	if input.ProjectID != nil {
+		_, err := app.store.Projects.GetByID(req.Context(), *input.ProjectID)
+		if errors.Is(err, store.ErrNotFound) {
+			errs["project_id"] = "project does not exist"
+		} else if err != nil {
+			helpers.ErrorJson(w, http.StatusInternalServerError, "failed to get project")
+			return
+		}
+	}
+
 	if len(errs) > 0 {
 		helpers.ValidationErrorJson(w, errs)
 		return
 	}
 
+	if !allowWIPOverride(w, req, input.Force) {
+		return
+	}
+
+	// New issues start in the first column of their workflow
+	workflow, err := app.store.Workflows.Get(req.Context(), input.ProjectID)
+	if err != nil || workflow.Initial() == nil {
+		helpers.ErrorJson(w, http.StatusInternalServerError, "failed to get workflow")
+		return
+	}
+
 	userID := middleware.GetUserID(req)
 
 	issue := &store.Issue{
-		Title:       input.Title,
-		UserID:      userID,
-		Description: input.Description,
-		CreatedAt:   time.Now(),
-		Status:      store.Incomplete,
+		Title:        input.Title,
+		UserID:       userID,
+		Description:  input.Description,
+		CreatedAt:    time.Now(),
+		Status:       workflow.Initial().Name,
+		ProjectID:    input.ProjectID,
+		ParentID:     input.ParentID,
+		AutoComplete: input.AutoComplete,
 	}
 
-	if err := app.store.Issues.Create(req.Context(), issue); err != nil {
+	if err := app.store.Issues.Create(req.Context(), issue, input.Force); err != nil {
+		if wipLimitErrorJson(w, err) || parentErrorJson(w, err) {
+			return
+		}
 		helpers.ErrorJson(w, http.StatusInternalServerError, "failed to create issue")
 		return
 	}
 
+	if input.Force {
+		app.recordWIPOverride(req, issue)
+	}
+	app.metrics.issuesCreated.Inc()
+
+	app.publishIssueEvent(req, events.IssueCreated, issue)
+	app.publishParentUpdate(req, issue)
+
 	helpers.WriteJson(w, http.StatusCreated, helpers.Envelope{"issue": issue})
 }
 
@@ -119,7 +161,9 @@ func (app *application) updateIssueStatusHandler(w http.ResponseWriter, req *htt
 
 	// Now that we have the issue we need to update it
 	var input struct {
-		Status store.StatusType `json:"status"`
+		Status         store.StatusType `json:"status"`
+		Force          bool             `json:"force"`
+		IgnoreBlockers bool             `json:"ignore_blockers"`
 	}
 
 	// Decode the input and handle any errors
@@ -130,11 +174,8 @@ func (app *application) updateIssueStatusHandler(w http.ResponseWriter, req *htt
 
 	errs := make(map[string]string)
 
-	switch input.Status {
-	case store.Incomplete, store.InProgress, store.Complete:
-		// Valid Do nothing: pass
-	default:
-		errs["status"] = "must be one of: Incomplete, In-Progress, Complete"
+	if strings.TrimSpace(string(input.Status)) == "" {
+		errs["status"] = "must not be blank"
 	}
 
 	if len(errs) > 0 {
@@ -142,16 +183,121 @@ func (app *application) updateIssueStatusHandler(w http.ResponseWriter, req *htt
 		return
 	}
 
-	// Update the status
-	updatedIssue, err := app.store.Issues.UpdateStatus(req.Context(), issue.ID, input.Status)
+	if !allowWIPOverride(w, req, input.Force) {
+		return
+	}
+
+	// Update the status; the store checks it against the issue's workflow,
+	// WIP limits and blockers
+	overrides := store.Overrides{WIPLimits: input.Force, Blockers: input.IgnoreBlockers}
+	updatedIssue, err := app.store.Issues.UpdateStatus(req.Context(), issue.ID, input.Status, overrides)
 	if err != nil {
-		helpers.ErrorJson(w, http.StatusInternalServerError, "failed to update issue status")
+		app.issueStatusErrorJson(w, req, issue, input.Status, err)
 		return
 	}
 
+	app.recordOverrides(req, updatedIssue, overrides)
+	app.publishIssueEvent(req, events.IssueUpdated, updatedIssue)
+	app.publishParentUpdate(req, updatedIssue)
+
 	helpers.WriteJson(w, http.StatusOK, helpers.Envelope{"issue": updatedIssue})
 }
 
+// moveIssueHandler drops an issue into a column between two cards. Status
+// defaults to the issue's current one; after_id and before_id name the cards
+// that should end up directly above and below it.
+func (app *application) moveIssueHandler(w http.ResponseWriter, req *http.Request) {
+	intID, err := strconv.ParseInt(req.PathValue("id"), 10, 64)
+	if err != nil {
+		helpers.ErrorJson(w, http.StatusBadRequest, "id must be a number")
+		return
+	}
+
+	issue, err := app.store.Issues.GetByID(req.Context(), intID)
+	if err != nil {
+		helpers.ErrorJson(w, http.StatusNotFound, "issue not found")
+		return
+	}
+
+	var input struct {
+		Status         *store.StatusType `json:"status"`
+		AfterID        *int64            `json:"after_id"`
+		BeforeID       *int64            `json:"before_id"`
+		Force          bool              `json:"force"`
+		IgnoreBlockers bool              `json:"ignore_blockers"`
+	}
+
+	if err := helpers.ReadJson(req, &input); err != nil {
+		helpers.ErrorJson(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	move := store.IssueMove{
+		Status:   issue.Status,
+		AfterID:  input.AfterID,
+		BeforeID: input.BeforeID,
+		Overrides: store.Overrides{
+			WIPLimits: input.Force,
+			Blockers:  input.IgnoreBlockers,
+		},
+	}
+	if input.Status != nil {
+		move.Status = *input.Status
+	}
+
+	errs := make(map[string]string)
+
+	if strings.TrimSpace(string(move.Status)) == "" {
+		errs["status"] = "must not be blank"
+	}
+	if move.AfterID != nil && move.BeforeID != nil && *move.AfterID == *move.BeforeID {
+		errs["before_id"] = "must differ from after_id"
+	}
+
+	if len(errs) > 0 {
+		helpers.ValidationErrorJson(w, errs)
+		return
+	}
+
+	if !allowWIPOverride(w, req, input.Force) {
+		return
+	}
+
+	movedIssue, err := app.store.Issues.Move(req.Context(), issue.ID, move)
+	if err != nil {
+		app.issueStatusErrorJson(w, req, issue, move.Status, err)
+		return
+	}
+
+	app.recordOverrides(req, movedIssue, move.Overrides)
+	app.publishIssueEvent(req, events.IssueUpdated, movedIssue)
+	app.publishParentUpdate(req, movedIssue)
+
+	helpers.WriteJson(w, http.StatusOK, helpers.Envelope{"issue": movedIssue})
+}
+
+// issueStatusErrorJson maps errors from changing an issue's status or
+// position to responses.
+func (app *application) issueStatusErrorJson(w http.ResponseWriter, req *http.Request, issue *store.Issue, status store.StatusType, err error) {
+	if wipLimitErrorJson(w, err) || blockedErrorJson(w, err) {
+		return
+	}
+
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		helpers.ErrorJson(w, http.StatusNotFound, "issue not found")
+	case errors.Is(err, store.ErrUnknownStatus):
+		app.unknownStatusJson(w, req, issue.ProjectID)
+	case errors.Is(err, store.ErrTransitionNotAllowed):
+		helpers.ErrorJson(w, http.StatusConflict,
+			fmt.Sprintf("cannot move issue from %s to %s", issue.Status, status))
+	case errors.Is(err, store.ErrInvalidNeighbor):
+		helpers.ValidationErrorJson(w, map[string]string{"after_id": err.Error(), "before_id": err.Error()})
+	default:
+		helpers.ErrorJson(w, http.StatusInternalServerError, "failed to update issue status")
+	}
+}
+
 func (app *application) deleteIssueHandler(w http.ResponseWriter, req *http.Request) {
 	// Handle the id path
 	id := req.PathValue("id")
@@ -167,12 +313,63 @@ func (app *application) deleteIssueHandler(w http.ResponseWriter, req *http.Requ
 		return
 	}
 
+	// An issue with sub-tasks needs ?children=cascade or ?children=orphan
+	children := store.ChildPolicy(req.URL.Query().Get("children"))
+	if children != "" && !children.Valid() {
+		helpers.ValidationErrorJson(w, map[string]string{"children": "must be cascade or orphan"})
+		return
+	}
+
+	// Keep the issue for the deleted event
+	issue, err := app.store.Issues.GetByID(req.Context(), int64(intID))
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			helpers.ErrorJson(w, http.StatusNotFound, "Issue not found")
+			return
+		}
+		helpers.ErrorJson(w, http.StatusInternalServerError, "failed to get issue")
+		return
+	}
+
 	// Delete the issue
-	err = app.store.Issues.Delete(req.Context(), int64(intID))
+	err = app.store.Issues.Delete(req.Context(), int64(intID), children)
 	if err != nil {
-		helpers.ErrorJson(w, http.StatusNotFound, "Issue not found")
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			helpers.ErrorJson(w, http.StatusNotFound, "Issue not found")
+		case errors.Is(err, store.ErrHasChildren):
+			helpers.ErrorJson(w, http.StatusConflict, "issue has sub-tasks; pass children=cascade or children=orphan")
+		default:
+			helpers.ErrorJson(w, http.StatusInternalServerError, "failed to delete issue")
+		}
 		return
 	}
 
+	entry := newAuditEntry(req, store.AuditIssueDelete, "issue", strconv.Itoa(intID))
+	if children != "" {
+		entry.Metadata = map[string]any{"children": children}
+	}
+	app.recordAudit(req, entry)
+
+	app.publishIssueEvent(req, events.IssueDeleted, issue)
+	app.publishParentUpdate(req, issue)
+
 	helpers.WriteJson(w, http.StatusOK, helpers.Envelope{"message": "issue deleted"})
 }
+
+// unknownStatusJson writes the validation error for a status that isn't in
+// the issue's workflow, listing the ones that are.
+func (app *application) unknownStatusJson(w http.ResponseWriter, req *http.Request, projectID *int64) {
+	workflow, err := app.store.Workflows.Get(req.Context(), projectID)
+	if err != nil {
+		helpers.ErrorJson(w, http.StatusInternalServerError, "failed to get workflow")
+		return
+	}
+
+	names := make([]string, len(workflow.Statuses))
+	for i, s := range workflow.Statuses {
+		names[i] = string(s.Name)
+	}
+
+	helpers.ValidationErrorJson(w, map[string]string{"status": "must be one of: " + strings.Join(names, ", ")})
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/jesusthecreator017/fswithgo/cmd/api/helpers"
+	"github.com/jesusthecreator017/fswithgo/internal/jobs"
+	"github.com/jesusthecreator017/fswithgo/internal/store"
+)
+
+// purgeAttachmentArgs deletes a detached attachment's blob, then its row.
+type purgeAttachmentArgs struct {
+	AttachmentID int64  `json:"attachment_id"`
+	StorageKey   string `json:"storage_key"`
+}
+
+func (purgeAttachmentArgs) Kind() string { return "attachment.purge" }
+
+// deliverWebhookArgs sends a queued webhook delivery.
+type deliverWebhookArgs struct {
+	DeliveryID int64 `json:"delivery_id"`
+}
+
+func (deliverWebhookArgs) Kind() string { return "webhook.deliver" }
+
+// registerJobs sets the handler for every kind of background job.
+func (app *application) registerJobs() {
+	jobs.Register(app.jobs, func(ctx context.Context, args purgeAttachmentArgs) error {
+		return app.purgeAttachment(ctx, &store.Attachment{ID: args.AttachmentID, StorageKey: args.StorageKey})
+	})
+	jobs.Register(app.jobs, app.deliverWebhook)
+}
+
+var jobStatuses = []string{jobs.StatusPending, jobs.StatusRunning, jobs.StatusSucceeded, jobs.StatusDead}
+
+// adminListJobsHandler lists background jobs, newest first, filtered by
+// status and kind.
+func (app *application) adminListJobsHandler(w http.ResponseWriter, req *http.Request) {
+	qs := req.URL.Query()
+	errs := make(map[string]string)
+
+	filter := jobs.Filter{
+		Status: strings.TrimSpace(qs.Get("status")),
+		Kind:   strings.TrimSpace(qs.Get("kind")),
+	}
+	if filter.Status != "" && !slices.Contains(jobStatuses, filter.Status) {
+		errs["status"] = "must be one of " + strings.Join(jobStatuses, ", ")
+	}
+
+	var err error
+	if filter.BeforeID, err = decodeAuditCursor(qs.Get("cursor")); err != nil {
+		errs["cursor"] = "is invalid"
+	}
+
+	filter.Limit, err = helpers.ReadInt(qs, "limit", 50)
+	if err != nil {
+		errs["limit"] = err.Error()
+	} else if filter.Limit < 1 || filter.Limit > 200 {
+		errs["limit"] = "must be between 1 and 200"
+	}
+
+	if len(errs) > 0 {
+		helpers.ValidationErrorJson(w, errs)
+		return
+	}
+
+	list, err := app.jobs.List(req.Context(), filter)
+	if err != nil {
+		helpers.ErrorJson(w, http.StatusInternalServerError, "failed to list jobs")
+		return
+	}
+
+	nextCursor := ""
+	if len(list) == filter.Limit {
+		nextCursor = encodeAuditCursor(list[len(list)-1].ID)
+	}
+
+	helpers.WriteJson(w, http.StatusOK, helpers.Envelope{"jobs": list, "next_cursor": nextCursor})
+}
+
+func (app *application) adminGetJobHandler(w http.ResponseWriter, req *http.Request) {
+	id, err := strconv.ParseInt(req.PathValue("id"), 10, 64)
+	if err != nil {
+		helpers.ErrorJson(w, http.StatusBadRequest, "id must be a number")
+		return
+	}
+
+	job, err := app.jobs.Get(req.Context(), id)
+	if err != nil {
+		if errors.Is(err, jobs.ErrNotFound) {
+			helpers.ErrorJson(w, http.StatusNotFound, "job not found")
+			return
+		}
+		helpers.ErrorJson(w, http.StatusInternalServerError, "failed to get job")
+		return
+	}
+
+	helpers.WriteJson(w, http.StatusOK, helpers.Envelope{"job": job})
+}
+
+// adminRetryJobHandler runs a dead or pending job now.
+func (app *application) adminRetryJobHandler(w http.ResponseWriter, req *http.Request) {
+	id, err := strconv.ParseInt(req.PathValue("id"), 10, 64)
+	if err != nil {
+		helpers.ErrorJson(w, http.StatusBadRequest, "id must be a number")
+		return
+	}
+
+	job, err := app.jobs.Retry(req.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, jobs.ErrNotFound):
+			helpers.ErrorJson(w, http.StatusNotFound, "job not found")
+		case errors.Is(err, jobs.ErrNotRetryable), errors.Is(err, jobs.ErrDuplicate):
+			helpers.ErrorJson(w, http.StatusConflict, err.Error())
+		default:
+			helpers.ErrorJson(w, http.StatusInternalServerError, "failed to retry job")
+		}
+		return
+	}
+
+	entry := newAuditEntry(req, store.AuditAdminJobRetry, "job", strconv.FormatInt(job.ID, 10))
+	entry.Metadata = map[string]any{"kind": job.Kind}
+	app.recordAudit(req, entry)
+
+	helpers.WriteJson(w, http.StatusOK, helpers.Envelope{"job": job})
+}
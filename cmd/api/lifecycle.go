@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/jesusthecreator017/fswithgo/internal/lifecycle"
+	"github.com/jesusthecreator017/fswithgo/internal/proxyproto"
+)
+
+// addLifecycleHooks adds the background subsystems to the lifecycle. They
+// start in this order and stop in reverse, after the HTTP server: first
+// the workers, then WebSockets, then the event listener they all rely on.
+func (app *application) addLifecycleHooks(shutdownTracing func(context.Context) error) {
+	// Flush spans last, once nothing is left to record them.
+	app.lifecycle.Append(lifecycle.Hook{Name: "telemetry", Stop: shutdownTracing})
+
+	// Hear issue events published by every instance, this one included.
+	app.lifecycle.Append(lifecycle.Go("events", app.events.Run))
+
+	// Relay those events, presence and typing to WebSocket clients. Shutdown
+	// doesn't track hijacked connections, so the hub closes them itself.
+	app.lifecycle.Append(lifecycle.Go("collab", app.collab.Run))
+	app.lifecycle.Append(lifecycle.Hook{Name: "websockets", Stop: app.collab.Shutdown})
+
+	// Run background jobs. Jobs that don't finish in time go back to the
+	// queue.
+	app.lifecycle.Append(lifecycle.Hook{
+		Name: "jobs",
+		Start: func(context.Context) error {
+			app.jobs.Start()
+			return nil
+		},
+		Stop: app.jobs.Shutdown,
+	})
+
+	// Run recurring maintenance: archiving the audit log, deleting the files
+	// of deleted issues' attachments, and pruning old jobs and deliveries.
+	app.lifecycle.Append(lifecycle.Hook{
+		Name: "schedule",
+		Start: func(context.Context) error {
+			app.schedule.Start()
+			return nil
+		},
+		Stop: app.schedule.Shutdown,
+	})
+
+	// Serve /metrics to Prometheus on a listener of its own, without auth.
+	if addr := app.config.metrics.addr; addr != "" {
+		mux := http.NewServeMux()
+		mux.Handle("GET /metrics", app.metrics.registry.Handler())
+		app.lifecycle.Append(app.serverHook("metrics", &http.Server{
+			Addr:         addr,
+			Handler:      mux,
+			ReadTimeout:  10 * time.Second,
+			WriteTimeout: 30 * time.Second,
+		}, false))
+	}
+}
+
+// serverHook listens when started, so a taken port fails startup, and
+// drains in-flight requests when stopped. Event streams end as soon as
+// stopping begins; clients reconnect to another instance. With
+// proxyProtocol, trusted proxies' connections may start with a PROXY
+// protocol header giving the client's address.
+func (app *application) serverHook(name string, srv *http.Server, proxyProtocol bool) lifecycle.Hook {
+	return lifecycle.Hook{
+		Name: name,
+		Start: func(context.Context) error {
+			ln, err := net.Listen("tcp", srv.Addr)
+			if err != nil {
+				return err
+			}
+			if proxyProtocol {
+				ln = proxyproto.NewListener(ln, app.config.proxy.trusted, proxyHeaderTimeout)
+			}
+			go func() {
+				if err := srv.Serve(ln); !errors.Is(err, http.ErrServerClosed) {
+					app.lifecycle.Fail(fmt.Errorf("%s server: %w", name, err))
+				}
+			}()
+			return nil
+		},
+		Stop: func(ctx context.Context) error {
+			if err := srv.Shutdown(ctx); err != nil {
+				// Cut off the requests still running.
+				srv.Close()
+				return err
+			}
+			return nil
+		},
+	}
+}
+
+// proxyHeaderTimeout is how long a proxy has to send its PROXY header.
+const proxyHeaderTimeout = 5 * time.Second
+
+// stop stops everything that started, within the shutdown timeout.
+func (app *application) stop() error {
+	ctx, cancel := context.WithTimeout(context.Background(), app.config.shutdown.timeout)
+	defer cancel()
+	return app.lifecycle.Stop(ctx)
+}
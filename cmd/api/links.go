@@ -0,0 +1,164 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/jesusthecreator017/fswithgo/cmd/api/helpers"
+	"github.com/jesusthecreator017/fswithgo/internal/store"
+)
+
+const linkTypes = "blocks, blocked_by, duplicates, duplicated_by, relates_to"
+
+func (app *application) listIssueLinksHandler(w http.ResponseWriter, req *http.Request) {
+	issue, ok := app.readIssue(w, req)
+	if !ok {
+		return
+	}
+
+	links, err := app.store.Links.List(req.Context(), issue.ID)
+	if err != nil {
+		helpers.ErrorJson(w, http.StatusInternalServerError, "failed to get issue links")
+		return
+	}
+
+	helpers.WriteJson(w, http.StatusOK, helpers.Envelope{"links": links})
+}
+
+// createIssueLinkHandler links the issue to issue_id; the inverse link is
+// added to the other issue.
+func (app *application) createIssueLinkHandler(w http.ResponseWriter, req *http.Request) {
+	issue, ok := app.readIssue(w, req)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Type    store.LinkType `json:"type"`
+		IssueID int64          `json:"issue_id"`
+	}
+
+	if err := helpers.ReadJson(req, &input); err != nil {
+		helpers.ErrorJson(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	errs := make(map[string]string)
+
+	if !input.Type.Valid() {
+		errs["type"] = "must be one of: " + linkTypes
+	}
+
+	switch {
+	case input.IssueID == 0:
+		errs["issue_id"] = "is required"
+	case input.IssueID == issue.ID:
+		errs["issue_id"] = "an issue cannot be linked to itself"
+	}
+
+	if len(errs) > 0 {
+		helpers.ValidationErrorJson(w, errs)
+		return
+	}
+
+	link, err := app.store.Links.Create(req.Context(), issue.ID, input.Type, input.IssueID)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			helpers.ValidationErrorJson(w, map[string]string{"issue_id": "issue does not exist"})
+		case errors.Is(err, store.ErrDuplicateLink):
+			helpers.ErrorJson(w, http.StatusConflict, err.Error())
+		case errors.Is(err, store.ErrLinkCycle):
+			helpers.ErrorJson(w, http.StatusConflict, err.Error())
+		default:
+			helpers.ErrorJson(w, http.StatusInternalServerError, "failed to link issues")
+		}
+		return
+	}
+
+	entry := newAuditEntry(req, store.AuditIssueLink, "issue", strconv.FormatInt(issue.ID, 10))
+	entry.Metadata = map[string]any{"type": input.Type, "issue_id": input.IssueID}
+	app.recordAudit(req, entry)
+
+	helpers.WriteJson(w, http.StatusCreated, helpers.Envelope{"link": link})
+}
+
+// deleteIssueLinkHandler removes the link named by ?type= and ?issue_id=,
+// along with its inverse.
+func (app *application) deleteIssueLinkHandler(w http.ResponseWriter, req *http.Request) {
+	issue, ok := app.readIssue(w, req)
+	if !ok {
+		return
+	}
+
+	query := req.URL.Query()
+	errs := make(map[string]string)
+
+	linkType := store.LinkType(query.Get("type"))
+	if !linkType.Valid() {
+		errs["type"] = "must be one of: " + linkTypes
+	}
+
+	targetID, err := strconv.ParseInt(query.Get("issue_id"), 10, 64)
+	if err != nil {
+		errs["issue_id"] = "must be an issue id"
+	}
+
+	if len(errs) > 0 {
+		helpers.ValidationErrorJson(w, errs)
+		return
+	}
+
+	if err := app.store.Links.Delete(req.Context(), issue.ID, linkType, targetID); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			helpers.ErrorJson(w, http.StatusNotFound, "link not found")
+			return
+		}
+		helpers.ErrorJson(w, http.StatusInternalServerError, "failed to unlink issues")
+		return
+	}
+
+	entry := newAuditEntry(req, store.AuditIssueUnlink, "issue", strconv.FormatInt(issue.ID, 10))
+	entry.Metadata = map[string]any{"type": linkType, "issue_id": targetID}
+	app.recordAudit(req, entry)
+
+	helpers.WriteJson(w, http.StatusOK, helpers.Envelope{"message": "link deleted"})
+}
+
+// readIssue loads the issue named by the {id} path value, writing the error
+// response and returning false if it can't.
+func (app *application) readIssue(w http.ResponseWriter, req *http.Request) (*store.Issue, bool) {
+	id, err := strconv.ParseInt(req.PathValue("id"), 10, 64)
+	if err != nil {
+		helpers.ErrorJson(w, http.StatusBadRequest, "id must be a number")
+		return nil, false
+	}
+
+	issue, err := app.store.Issues.GetByID(req.Context(), id)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			helpers.ErrorJson(w, http.StatusNotFound, "issue not found")
+			return nil, false
+		}
+		helpers.ErrorJson(w, http.StatusInternalServerError, "failed to get issue")
+		return nil, false
+	}
+
+	return issue, true
+}
+
+// blockedErrorJson writes a 409 listing the open blockers if err is a
+// *store.BlockedError, and reports whether it did.
+func blockedErrorJson(w http.ResponseWriter, err error) bool {
+	var blocked *store.BlockedError
+	if !errors.As(err, &blocked) {
+		return false
+	}
+
+	helpers.WriteJson(w, http.StatusConflict, helpers.Envelope{
+		"error":    blocked.Error(),
+		"blockers": blocked.Blockers,
+	})
+	return true
+}
@@ -1,13 +1,30 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"log"
+	"log/slog"
 	"os"
+	"strings"
 	"time"
 
+	"github.com/jesusthecreator017/fswithgo/cmd/api/middleware"
+	"github.com/jesusthecreator017/fswithgo/internal/blob"
+	"github.com/jesusthecreator017/fswithgo/internal/collab"
 	"github.com/jesusthecreator017/fswithgo/internal/db"
 	"github.com/jesusthecreator017/fswithgo/internal/env"
+	"github.com/jesusthecreator017/fswithgo/internal/events"
+	"github.com/jesusthecreator017/fswithgo/internal/jobs"
+	"github.com/jesusthecreator017/fswithgo/internal/lifecycle"
+	"github.com/jesusthecreator017/fswithgo/internal/logging"
+	"github.com/jesusthecreator017/fswithgo/internal/proxyproto"
+	"github.com/jesusthecreator017/fswithgo/internal/ratelimit"
+	"github.com/jesusthecreator017/fswithgo/internal/schedule"
 	"github.com/jesusthecreator017/fswithgo/internal/store"
+	"github.com/jesusthecreator017/fswithgo/internal/telemetry"
+	"github.com/jesusthecreator017/fswithgo/internal/webhooks"
 )
 
 func main() {
@@ -20,33 +37,184 @@ func main() {
 			maxConns:    env.GetInt("DB_MAX_CONNS", 25),
 			maxIdleTime: time.Duration(env.GetInt("DB_MAX_IDLE_MINS", 15)) * time.Minute,
 		},
-		corsOrigin: os.Getenv("CORS_ORIGIN"),
-		jwtSecret:  env.GetString("JWT_SECRET", ""),
+		cors: middleware.CORSConfig{
+			// CORS_ORIGIN, which took a single origin, still works.
+			Origins:          strings.Split(env.GetString("CORS_ORIGINS", os.Getenv("CORS_ORIGIN")), ","),
+			AllowCredentials: env.GetBool("CORS_ALLOW_CREDENTIALS", false),
+			MaxAge:           time.Duration(env.GetInt("CORS_MAX_AGE_SECS", 600)) * time.Second,
+		},
+		jwtSecret: env.GetString("JWT_SECRET", ""),
+		audit: auditConfig{
+			retention:  time.Duration(env.GetInt("AUDIT_RETENTION_DAYS", 90)) * 24 * time.Hour,
+			schedule:   env.GetString("AUDIT_RETENTION_SCHEDULE", "@hourly"),
+			archiveDir: env.GetString("AUDIT_ARCHIVE_DIR", "audit-archive"),
+		},
+		blob: blobConfig{
+			backend: env.GetString("BLOB_BACKEND", "local"),
+			dir:     env.GetString("BLOB_DIR", "attachments"),
+			s3: blob.S3Config{
+				Endpoint:  env.GetString("S3_ENDPOINT", "https://s3.amazonaws.com"),
+				Region:    env.GetString("S3_REGION", "us-east-1"),
+				Bucket:    env.GetString("S3_BUCKET", ""),
+				AccessKey: env.GetString("S3_ACCESS_KEY_ID", ""),
+				SecretKey: env.GetString("S3_SECRET_ACCESS_KEY", ""),
+				PathStyle: env.GetBool("S3_PATH_STYLE", false),
+			},
+		},
+		attachments: attachmentConfig{
+			maxBytes:        int64(env.GetInt("ATTACHMENT_MAX_MB", 25)) << 20,
+			allowedTypes:    strings.Split(env.GetString("ATTACHMENT_ALLOWED_TYPES", "image/png,image/jpeg,image/gif,image/webp,text/plain,application/pdf,application/zip,application/x-gzip"), ","),
+			transferTimeout: time.Duration(env.GetInt("ATTACHMENT_TRANSFER_TIMEOUT_MINS", 10)) * time.Minute,
+			sweepSchedule:   env.GetString("ATTACHMENT_SWEEP_SCHEDULE", "*/15 * * * *"),
+		},
+		events: eventsConfig{
+			replaySize: env.GetInt("EVENTS_REPLAY_SIZE", 1000),
+			heartbeat:  time.Duration(env.GetInt("EVENTS_HEARTBEAT_SECS", 15)) * time.Second,
+		},
+		jobs: jobs.Config{
+			Workers:      env.GetInt("JOB_WORKERS", 4),
+			PollInterval: time.Duration(env.GetInt("JOB_POLL_INTERVAL_SECS", 2)) * time.Second,
+			Timeout:      time.Duration(env.GetInt("JOB_TIMEOUT_MINS", 5)) * time.Minute,
+			Retention:    time.Duration(env.GetInt("JOB_RETENTION_DAYS", 7)) * 24 * time.Hour,
+		},
+		webhooks: webhookConfig{
+			maxAttempts:  env.GetInt("WEBHOOK_MAX_ATTEMPTS", 8),
+			disableAfter: env.GetInt("WEBHOOK_DISABLE_AFTER_FAILURES", 20),
+			timeout:      time.Duration(env.GetInt("WEBHOOK_TIMEOUT_SECS", 10)) * time.Second,
+			logRetention: time.Duration(env.GetInt("WEBHOOK_LOG_RETENTION_DAYS", 30)) * 24 * time.Hour,
+		},
+		shutdown: shutdownConfig{
+			delay:   time.Duration(env.GetInt("SHUTDOWN_DELAY_SECS", 5)) * time.Second,
+			timeout: time.Duration(env.GetInt("SHUTDOWN_TIMEOUT_SECS", 30)) * time.Second,
+		},
+		metrics: metricsConfig{
+			addr: env.GetString("METRICS_ADDR", ""),
+		},
+		health: healthConfig{
+			cacheTTL: time.Duration(env.GetInt("HEALTH_CACHE_SECS", 2)) * time.Second,
+		},
+		logging: logging.Config{
+			Level:  env.GetString("LOG_LEVEL", "info"),
+			Format: env.GetString("LOG_FORMAT", "json"),
+		},
+		rateLimit: rateLimitConfig{
+			backend: env.GetString("RATE_LIMIT_BACKEND", "memory"),
+		},
+		telemetry: telemetry.Config{
+			Exporter:    env.GetString("OTEL_TRACES_EXPORTER", "none"),
+			File:        env.GetString("OTEL_TRACES_FILE", "traces.json"),
+			ServiceName: "fswithgo-api",
+		},
 	}
 
 	// Initialize any environment variables
 
+	logger, err := logging.New(os.Stderr, cfg.logging)
+	if err != nil {
+		log.Fatalf("failed to set up logging: %v", err)
+	}
+	// The log package's output goes through it too.
+	slog.SetDefault(logger)
+
+	// Limits are written like "60/m"; see ratelimit.ParseLimit.
+	if cfg.rateLimit.def, err = ratelimit.ParseLimit(env.GetString("RATE_LIMIT_DEFAULT", "300/m")); err != nil {
+		fatal("bad RATE_LIMIT_DEFAULT", err)
+	}
+	if cfg.rateLimit.routes, err = ratelimit.ParsePolicies(env.GetString("RATE_LIMITS", "")); err != nil {
+		fatal("bad RATE_LIMITS", err)
+	}
+
+	if cfg.proxy.trusted, err = proxyproto.ParsePrefixes(env.GetString("TRUSTED_PROXIES", "")); err != nil {
+		fatal("bad TRUSTED_PROXIES", err)
+	}
+	cfg.proxy.protocol = env.GetBool("PROXY_PROTOCOL", false)
+	if cfg.proxy.protocol && len(cfg.proxy.trusted) == 0 {
+		fatal("bad PROXY_PROTOCOL", errors.New("only trusted proxies' headers are read, and TRUSTED_PROXIES is empty"))
+	}
+
+	// Set up tracing before anything that starts spans.
+	shutdownTracing, err := telemetry.Setup(context.Background(), cfg.telemetry)
+	if err != nil {
+		fatal("failed to set up tracing", err)
+	}
+
 	// Create the pgxpool connection pool.
 	// This is like *sql.DB but for pgx — it manages a pool of connections
 	// so you don't open/close a connection for every query.
 	// db.New() also pings the database to verify it's reachable.
 	pool, err := db.New(cfg.db.dsn, cfg.db.maxConns, cfg.db.maxIdleTime)
 	if err != nil {
-		log.Fatalf("failed to connect to database: %v", err)
+		fatal("failed to connect to database", err)
 	}
-	// pool.Close() returns all connections to PostgreSQL when the server shuts down.
-	defer pool.Close()
-	log.Println("database connection pool established")
+	slog.Info("database connection pool established")
 
 	// Pass the pool to NewStorage. Inside, it creates a sqlc Queries struct
 	// (dbsqlc.New(pool)) and wires it into each repository implementation.
 	store := store.NewStorage(pool)
 
+	blobs, err := newBlobStore(cfg.blob)
+	if err != nil {
+		fatal("failed to set up blob storage", err)
+	}
+
+	cors, err := middleware.NewCORSPolicy(cfg.cors)
+	if err != nil {
+		fatal("bad CORS_ORIGINS", err)
+	}
+
+	rateLimiter, err := newRateLimiter(cfg.rateLimit, pool)
+	if err != nil {
+		fatal("failed to set up rate limiting", err)
+	}
+
 	app := &application{
-		config: cfg,
-		store:  store,
+		config:        cfg,
+		store:         store,
+		blobs:         blobs,
+		events:        events.NewBroker(pool, cfg.events.replaySize),
+		jobs:          jobs.New(pool, cfg.jobs),
+		schedule:      schedule.New(pool),
+		rateLimiter:   rateLimiter,
+		cors:          cors,
+		lifecycle:     lifecycle.New(),
+		metrics:       newAppMetrics(pool),
+		webhookClient: webhooks.NewClient(cfg.webhooks.timeout),
+	}
+	app.collab = collab.NewHub(app.events, app.authorizeTopic)
+
+	app.registerJobs()
+	if err := app.registerTasks(); err != nil {
+		fatal("failed to schedule tasks", err)
+	}
+	app.addLifecycleHooks(shutdownTracing)
+	if err := app.addHealthChecks(pool); err != nil {
+		fatal("failed to set up health checks", err)
 	}
 
 	mux := app.mount()
-	log.Fatal(app.run(mux))
+	err = app.run(mux)
+
+	// Everything using the pool has stopped, so return its connections to
+	// PostgreSQL.
+	pool.Close()
+	if err != nil {
+		fatal("server stopped", err)
+	}
+}
+
+// fatal logs err and exits.
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
+}
+
+func newBlobStore(cfg blobConfig) (blob.Store, error) {
+	switch cfg.backend {
+	case "local":
+		return blob.NewLocal(cfg.dir)
+	case "s3":
+		return blob.NewS3(cfg.s3)
+	default:
+		return nil, fmt.Errorf("unknown BLOB_BACKEND %q", cfg.backend)
+	}
 }
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jesusthecreator017/fswithgo/internal/metrics"
+)
+
+// metricsConfig sets where /metrics is served. With an addr it gets a
+// listener of its own, to keep off the public network; without one it is
+// served on the API's listener to admins only.
+type metricsConfig struct {
+	addr string
+}
+
+// appMetrics holds the registry and the domain counters handlers update.
+// Request metrics are recorded by middleware.Metrics.
+type appMetrics struct {
+	registry      *metrics.Registry
+	issuesCreated *metrics.Counter
+	loginsFailed  *metrics.CounterVec
+}
+
+func newAppMetrics(pool *pgxpool.Pool) *appMetrics {
+	reg := metrics.NewRegistry()
+	metrics.RegisterRuntime(reg)
+	metrics.RegisterPool(reg, pool)
+
+	return &appMetrics{
+		registry:      reg,
+		issuesCreated: reg.NewCounter("issues_created_total", "Issues created.").With(),
+		loginsFailed:  reg.NewCounter("logins_failed_total", "Rejected logins, by reason.", "reason"),
+	}
+}
@@ -2,51 +2,123 @@ package middleware
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"strings"
 
 	"github.com/google/uuid"
 	"github.com/jesusthecreator017/fswithgo/cmd/api/helpers"
 	"github.com/jesusthecreator017/fswithgo/internal/auth"
+	"github.com/jesusthecreator017/fswithgo/internal/store"
 )
 
 const UserIDKey contextKey = "user_id"
 const PermissionsKey contextKey = "permissions"
+const PasswordResetKey contextKey = "password_reset_required"
+const ImpersonatorKey contextKey = "impersonator_id"
 
-func GlobalAuth(jwtSecret string) Middleware {
+// UserGetter loads the account behind a token so that suspensions and
+// permission changes take effect without waiting for the token to expire.
+type UserGetter interface {
+	GetByID(context.Context, uuid.UUID) (*store.User, error)
+}
+
+func GlobalAuth(jwtSecret string, users UserGetter) Middleware {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
-			// Get Auth header
-			authHeader := req.Header.Get("Authorization")
-			if authHeader == "" {
+			token, ok := bearerToken(req)
+			if !ok {
 				next.ServeHTTP(w, req)
 				return
 			}
 
-			prefix := "Bearer "
-			if ok := strings.HasPrefix(authHeader, prefix); !ok {
+			// Validate the token
+			id, claims, err := auth.ValidateToken(token, jwtSecret)
+			if err != nil {
 				next.ServeHTTP(w, req)
 				return
 			}
 
-			// Get and Validate the token
-			token := strings.TrimPrefix(authHeader, prefix)
-			id, permissions, err := auth.ValidateToken(token, jwtSecret)
+			// Load the account; a deleted user is treated like a bad token
+			user, err := users.GetByID(req.Context(), id)
 			if err != nil {
-				next.ServeHTTP(w, req)
+				if errors.Is(err, store.ErrNotFound) {
+					next.ServeHTTP(w, req)
+					return
+				}
+				helpers.ErrorJson(w, http.StatusInternalServerError, "failed to load user")
+				return
+			}
+
+			if user.IsSuspended() {
+				helpers.ErrorJson(w, http.StatusForbidden, "account suspended")
 				return
 			}
 
-			// Store the id and permissions in context
-			ctx := context.WithValue(req.Context(), UserIDKey, id)
-			ctx = context.WithValue(ctx, PermissionsKey, permissions)
+			// Store the id and permissions in context. Permissions come from the
+			// database rather than the token so admin changes apply immediately.
+			ctx := context.WithValue(withLogAttrs(req.Context(), "user_id", id), UserIDKey, id)
+			ctx = context.WithValue(ctx, PermissionsKey, user.Permissions)
+			ctx = context.WithValue(ctx, PasswordResetKey, user.PasswordResetRequired)
+
+			// Impersonation tokens stay valid only while the admin behind them
+			// is still an active admin, and only while the target isn't one:
+			// admins can't be impersonated, including those promoted since.
+			if actorID, ok := claims.ActorID(); ok {
+				if auth.HasPermission(auth.Permission(user.Permissions), auth.PermAdmin) {
+					helpers.ErrorJson(w, http.StatusUnauthorized, "impersonation no longer permitted")
+					return
+				}
+				actor, err := users.GetByID(req.Context(), actorID)
+				if err != nil || actor.IsSuspended() ||
+					!auth.HasPermission(auth.Permission(actor.Permissions), auth.PermAdmin) {
+					helpers.ErrorJson(w, http.StatusUnauthorized, "impersonation no longer permitted")
+					return
+				}
+				ctx = context.WithValue(withLogAttrs(ctx, "impersonator_id", actorID), ImpersonatorKey, actorID)
+			}
 
 			next.ServeHTTP(w, req.WithContext(ctx))
 		})
 	}
 }
 
+// WebSocketTokenProtocol is the subprotocol a browser WebSocket, which
+// can't set headers, names before its token:
+// new WebSocket(url, ["bearer", token]).
+const WebSocketTokenProtocol = "bearer"
+
+// bearerToken reads the JWT from the Authorization header or, on a
+// WebSocket upgrade, from Sec-WebSocket-Protocol.
+func bearerToken(req *http.Request) (string, bool) {
+	if authHeader := req.Header.Get("Authorization"); authHeader != "" {
+		return strings.CutPrefix(authHeader, "Bearer ")
+	}
+
+	if !strings.EqualFold(req.Header.Get("Upgrade"), "websocket") {
+		return "", false
+	}
+	protocols := strings.Split(req.Header.Get("Sec-WebSocket-Protocol"), ",")
+	if len(protocols) != 2 || strings.TrimSpace(protocols[0]) != WebSocketTokenProtocol {
+		return "", false
+	}
+	return strings.TrimSpace(protocols[1]), true
+}
+
 func RequiredAuth(next http.Handler) http.Handler {
+	return RequiredAuthAllowReset(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if PasswordResetRequired(req) {
+			helpers.ErrorJson(w, http.StatusForbidden, "password reset required")
+			return
+		}
+
+		next.ServeHTTP(w, req)
+	}))
+}
+
+// RequiredAuthAllowReset is RequiredAuth without the forced-reset check.
+// Only the change-password route should use it.
+func RequiredAuthAllowReset(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
 		// Get id from context
 		_, ok := req.Context().Value(UserIDKey).(uuid.UUID)
@@ -80,3 +152,8 @@ func GetPermissions(req *http.Request) int32 {
 	perms, _ := req.Context().Value(PermissionsKey).(int32)
 	return perms
 }
+
+func PasswordResetRequired(req *http.Request) bool {
+	required, _ := req.Context().Value(PasswordResetKey).(bool)
+	return required
+}
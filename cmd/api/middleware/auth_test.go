@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jesusthecreator017/fswithgo/internal/auth"
+	"github.com/jesusthecreator017/fswithgo/internal/store"
+)
+
+const testSecret = "test-secret"
+
+type fakeUsers map[uuid.UUID]*store.User
+
+func (f fakeUsers) GetByID(_ context.Context, id uuid.UUID) (*store.User, error) {
+	if u, ok := f[id]; ok {
+		return u, nil
+	}
+	return nil, store.ErrNotFound
+}
+
+func TestGlobalAuthImpersonation(t *testing.T) {
+	adminID, targetID := uuid.New(), uuid.New()
+	suspended := time.Now()
+
+	tests := []struct {
+		name       string
+		actor      store.User
+		target     store.User
+		wantStatus int
+	}{
+		{"active admin on user", store.User{Permissions: int32(auth.PermAll)}, store.User{Permissions: int32(auth.PermDefault)}, http.StatusOK},
+		{"target promoted to admin", store.User{Permissions: int32(auth.PermAll)}, store.User{Permissions: int32(auth.PermAll)}, http.StatusUnauthorized},
+		{"actor lost admin", store.User{Permissions: int32(auth.PermDefault)}, store.User{Permissions: int32(auth.PermDefault)}, http.StatusUnauthorized},
+		{"actor suspended", store.User{Permissions: int32(auth.PermAll), SuspendedAt: &suspended}, store.User{Permissions: int32(auth.PermDefault)}, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actor, target := tt.actor, tt.target
+			actor.ID, target.ID = adminID, targetID
+			users := fakeUsers{adminID: &actor, targetID: &target}
+
+			// The token carries the permissions the target had when it was
+			// issued; GlobalAuth must go by the database instead.
+			token, err := auth.GenerateImpersonationJWT(targetID, int32(auth.PermDefault), adminID, testSecret, time.Minute)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var gotImpersonator bool
+			h := GlobalAuth(testSecret, users)(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				_, gotImpersonator = req.Context().Value(ImpersonatorKey).(uuid.UUID)
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if tt.wantStatus == http.StatusOK && !gotImpersonator {
+				t.Fatal("impersonator missing from context")
+			}
+		})
+	}
+}
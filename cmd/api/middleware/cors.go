@@ -1,21 +1,173 @@
 package middleware
 
-import "net/http"
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
 
-func CORS(allowedOrigin string) Middleware {
+// Request headers browsers may send, and response headers scripts may
+// read, across origins.
+const (
+	corsAllowHeaders  = "Authorization, Content-Type, Last-Event-ID, X-Checksum-SHA256, X-Request-ID, traceparent, tracestate"
+	corsExposeHeaders = "Content-Disposition, ETag, X-Request-ID, RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset, RateLimit-Policy, Retry-After"
+)
+
+// corsMethods are the methods a preflight can be told a route allows.
+var corsMethods = []string{http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+
+// CORSConfig is what the CORS policy is built from.
+type CORSConfig struct {
+	// Origins are exact origins ("https://app.example.com"), origins
+	// with a wildcard subdomain ("https://*.example.com", which doesn't
+	// match example.com itself), or "*" for any origin.
+	Origins          []string
+	AllowCredentials bool
+	MaxAge           time.Duration // how long browsers may cache a preflight
+}
+
+// CORSPolicy decides which origins may call the API from a browser.
+type CORSPolicy struct {
+	any         bool
+	exact       map[string]bool
+	wildcards   []wildcardOrigin
+	credentials bool
+	maxAge      string
+}
+
+// wildcardOrigin matches any subdomain of suffix with the given scheme
+// and port.
+type wildcardOrigin struct {
+	scheme, suffix, port string
+}
+
+func NewCORSPolicy(cfg CORSConfig) (*CORSPolicy, error) {
+	p := &CORSPolicy{
+		exact:       make(map[string]bool),
+		credentials: cfg.AllowCredentials,
+		maxAge:      strconv.Itoa(int(cfg.MaxAge.Seconds())),
+	}
+	for _, origin := range cfg.Origins {
+		// Browsers never send a trailing slash, so an entry copied from
+		// the address bar would otherwise never match.
+		origin = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(origin)), "/")
+		switch {
+		case origin == "":
+		case origin == "*":
+			p.any = true
+		case strings.Contains(origin, "*"):
+			u, err := url.Parse(strings.Replace(origin, "*.", "wildcard.", 1))
+			if err != nil || !strings.HasPrefix(u.Host, "wildcard.") || strings.Contains(u.Host, "*") || !isOrigin(u) {
+				return nil, fmt.Errorf("CORS origin %q: a wildcard must be the first label, as in https://*.example.com", origin)
+			}
+			p.wildcards = append(p.wildcards, wildcardOrigin{
+				scheme: u.Scheme,
+				suffix: strings.TrimPrefix(u.Hostname(), "wildcard"),
+				port:   u.Port(),
+			})
+		default:
+			u, err := url.Parse(origin)
+			if origin != "null" && (err != nil || !isOrigin(u)) {
+				return nil, fmt.Errorf("CORS origin %q: want scheme://host[:port]", origin)
+			}
+			p.exact[origin] = true
+		}
+	}
+	if p.any && p.credentials {
+		// Any site could then act as a signed-in user.
+		return nil, errors.New("CORS: credentials can't be allowed from any origin")
+	}
+	return p, nil
+}
+
+// isOrigin reports whether u is only a scheme, host and port.
+func isOrigin(u *url.URL) bool {
+	return u.Scheme != "" && u.Host != "" && u.Path == "" && u.RawQuery == "" && u.Fragment == "" && u.User == nil
+}
+
+// Allows reports whether a browser page at origin may call the API.
+func (p *CORSPolicy) Allows(origin string) bool {
+	if origin == "" {
+		return false
+	}
+	if p.any {
+		return true
+	}
+	origin = strings.ToLower(origin)
+	if p.exact[origin] {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	for _, w := range p.wildcards {
+		// The suffix starts with a dot, so evil-example.com isn't a
+		// subdomain of example.com, and there must be a label before it.
+		host := u.Hostname()
+		if u.Scheme == w.scheme && u.Port() == w.port && len(host) > len(w.suffix) && strings.HasSuffix(host, w.suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// CORS answers preflights and marks responses to allowed origins as
+// readable by them. The origin is echoed rather than sent as "*", so
+// responses vary by Origin. A preflight is told the methods that mux
+// routes the path to, so browsers fail fast on a wrong one; that takes a
+// lookup per method, so only preflights do it.
+func CORS(policy *CORSPolicy, mux *http.ServeMux) Middleware {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
-			// Set Headers for CORS
-			w.Header().Set("Access-Control-Allow-Origin", allowedOrigin)
-			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PATCH, DELETE, OPTIONS")
-			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+			h := w.Header()
+			h.Add("Vary", "Origin")
+
+			origin := req.Header.Get("Origin")
+			preflight := req.Method == http.MethodOptions && req.Header.Get("Access-Control-Request-Method") != ""
+			if !policy.Allows(origin) {
+				if preflight {
+					w.WriteHeader(http.StatusNoContent)
+					return
+				}
+				next.ServeHTTP(w, req)
+				return
+			}
+
+			h.Set("Access-Control-Allow-Origin", origin)
+			if policy.credentials {
+				h.Set("Access-Control-Allow-Credentials", "true")
+			}
 
-			if req.Method == http.MethodOptions {
-				w.WriteHeader(http.StatusNoContent)
+			if !preflight {
+				h.Set("Access-Control-Expose-Headers", corsExposeHeaders)
+				next.ServeHTTP(w, req)
 				return
 			}
 
-			next.ServeHTTP(w, req)
+			h.Add("Vary", "Access-Control-Request-Method")
+			if methods := routeMethods(mux, req); len(methods) > 0 {
+				h.Set("Access-Control-Allow-Methods", strings.Join(methods, ", "))
+			}
+			h.Set("Access-Control-Allow-Headers", corsAllowHeaders)
+			h.Set("Access-Control-Max-Age", policy.maxAge)
+			w.WriteHeader(http.StatusNoContent)
 		})
 	}
 }
+
+// routeMethods returns the methods mux has a route for at req's path.
+func routeMethods(mux *http.ServeMux, req *http.Request) []string {
+	var methods []string
+	for _, method := range corsMethods {
+		probe := &http.Request{Method: method, URL: req.URL, Host: req.Host, Header: http.Header{}}
+		if _, pattern := mux.Handler(probe); pattern != "" {
+			methods = append(methods, method)
+		}
+	}
+	return methods
+}
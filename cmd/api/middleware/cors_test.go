@@ -0,0 +1,200 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestNewCORSPolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     CORSConfig
+		wantErr bool
+	}{
+		{"exact", CORSConfig{Origins: []string{"https://app.example.com"}}, false},
+		{"exact with port", CORSConfig{Origins: []string{"http://localhost:5173"}}, false},
+		{"trailing slash", CORSConfig{Origins: []string{"https://app.example.com/"}}, false},
+		{"wildcard", CORSConfig{Origins: []string{"https://*.example.com"}}, false},
+		{"null", CORSConfig{Origins: []string{"null"}}, false},
+		{"blank entries", CORSConfig{Origins: []string{"", " "}}, false},
+		{"any", CORSConfig{Origins: []string{"*"}}, false},
+		{"any with credentials", CORSConfig{Origins: []string{"*"}, AllowCredentials: true}, true},
+		{"any among others with credentials", CORSConfig{Origins: []string{"https://app.example.com", "*"}, AllowCredentials: true}, true},
+		{"exact with credentials", CORSConfig{Origins: []string{"https://app.example.com"}, AllowCredentials: true}, false},
+		{"no scheme", CORSConfig{Origins: []string{"app.example.com"}}, true},
+		{"path", CORSConfig{Origins: []string{"https://app.example.com/app"}}, true},
+		{"query", CORSConfig{Origins: []string{"https://app.example.com?x=1"}}, true},
+		{"user info", CORSConfig{Origins: []string{"https://me@app.example.com"}}, true},
+		{"wildcard without scheme", CORSConfig{Origins: []string{"*.example.com"}}, true},
+		{"wildcard not first", CORSConfig{Origins: []string{"https://app.*.example.com"}}, true},
+		{"wildcard inside a label", CORSConfig{Origins: []string{"https://*example.com"}}, true},
+		{"two wildcards", CORSConfig{Origins: []string{"https://*.*.example.com"}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewCORSPolicy(tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewCORSPolicy error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCORSPolicyAllows(t *testing.T) {
+	tests := []struct {
+		allowed string
+		origin  string
+		want    bool
+	}{
+		{"https://app.example.com", "https://app.example.com", true},
+		{"https://app.example.com", "https://APP.example.com", true},
+		{"https://app.example.com/", "https://app.example.com", true},
+		{" HTTPS://App.Example.com/ ", "https://app.example.com", true},
+		{"https://app.example.com", "http://app.example.com", false},
+		{"https://app.example.com", "https://app.example.com:8443", false},
+		{"https://app.example.com", "https://app.example.com.evil.com", false},
+		{"https://app.example.com", "", false},
+		{"http://localhost:5173", "http://localhost:5173", true},
+		{"http://localhost:5173", "http://localhost:5174", false},
+
+		{"https://*.example.com", "https://app.example.com", true},
+		{"https://*.example.com", "https://a.b.example.com", true},
+		{"https://*.example.com", "https://example.com", false},
+		{"https://*.example.com", "https://evil-example.com", false},
+		{"https://*.example.com", "https://example.com.evil.com", false},
+		{"https://*.example.com", "https://.example.com", false},
+		{"https://*.example.com", "http://app.example.com", false},
+		{"https://*.example.com", "https://app.example.com:8443", false},
+		{"https://*.example.com:8443", "https://app.example.com:8443", true},
+		{"https://*.example.com:8443", "https://app.example.com", false},
+
+		{"null", "null", true},
+		{"https://app.example.com", "null", false},
+		{"*", "https://anything.test", true},
+		{"*", "null", true},
+		{"*", "", false},
+	}
+	for _, tt := range tests {
+		p, err := NewCORSPolicy(CORSConfig{Origins: []string{tt.allowed}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := p.Allows(tt.origin); got != tt.want {
+			t.Errorf("allowing %q: Allows(%q) = %v, want %v", tt.allowed, tt.origin, got, tt.want)
+		}
+	}
+}
+
+func TestCORS(t *testing.T) {
+	policy, err := NewCORSPolicy(CORSConfig{
+		Origins:          []string{"https://app.example.com"},
+		AllowCredentials: true,
+		MaxAge:           10 * time.Minute,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mux := http.NewServeMux()
+	noop := func(http.ResponseWriter, *http.Request) {}
+	mux.HandleFunc("GET /v1/issues/{id}", noop)
+	mux.HandleFunc("PATCH /v1/issues/{id}", noop)
+	mux.HandleFunc("DELETE /v1/issues/{id}", noop)
+	mux.HandleFunc("POST /v1/issues", noop)
+
+	const (
+		allowed = "https://app.example.com"
+		other   = "https://evil-example.com"
+	)
+	tests := []struct {
+		name          string
+		method, path  string
+		origin        string
+		requestMethod string // Access-Control-Request-Method
+		wantServed    bool
+		wantOrigin    string
+		wantMethods   string
+		wantVary      []string
+	}{
+		{"allowed", http.MethodGet, "/v1/issues/7", allowed, "", true, allowed, "", []string{"Origin"}},
+		{"other origin", http.MethodGet, "/v1/issues/7", other, "", true, "", "", []string{"Origin"}},
+		{"no origin", http.MethodGet, "/v1/issues/7", "", "", true, "", "", []string{"Origin"}},
+		{"preflight", http.MethodOptions, "/v1/issues/7", allowed, http.MethodPatch, false, allowed, "GET, HEAD, PATCH, DELETE", []string{"Origin", "Access-Control-Request-Method"}},
+		{"preflight for one method", http.MethodOptions, "/v1/issues", allowed, http.MethodPost, false, allowed, "POST", []string{"Origin", "Access-Control-Request-Method"}},
+		{"preflight for no route", http.MethodOptions, "/missing", allowed, http.MethodGet, false, allowed, "", []string{"Origin", "Access-Control-Request-Method"}},
+		{"preflight from other origin", http.MethodOptions, "/v1/issues/7", other, http.MethodDelete, false, "", "", []string{"Origin"}},
+		{"OPTIONS that isn't a preflight", http.MethodOptions, "/v1/issues/7", allowed, "", true, allowed, "", []string{"Origin"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var served bool
+			h := CORS(policy, mux)(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+				served = true
+			}))
+
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			if tt.requestMethod != "" {
+				req.Header.Set("Access-Control-Request-Method", tt.requestMethod)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			got := rec.Header()
+
+			if served != tt.wantServed {
+				t.Fatalf("served = %v, want %v", served, tt.wantServed)
+			}
+			if !served && rec.Code != http.StatusNoContent {
+				t.Fatalf("preflight status = %d, want 204", rec.Code)
+			}
+			// Caches must key on Origin even for responses that don't
+			// allow it, or one origin's response is served to another.
+			if vary := got.Values("Vary"); !slices.Equal(vary, tt.wantVary) {
+				t.Fatalf("Vary = %q, want %q", vary, tt.wantVary)
+			}
+			if o := got.Get("Access-Control-Allow-Origin"); o != tt.wantOrigin {
+				t.Fatalf("Allow-Origin = %q, want %q", o, tt.wantOrigin)
+			}
+			if m := got.Get("Access-Control-Allow-Methods"); m != tt.wantMethods {
+				t.Fatalf("Allow-Methods = %q, want %q", m, tt.wantMethods)
+			}
+
+			if tt.wantOrigin == "" {
+				for _, name := range []string{"Access-Control-Allow-Credentials", "Access-Control-Expose-Headers", "Access-Control-Allow-Headers", "Access-Control-Max-Age"} {
+					if v := got.Get(name); v != "" {
+						t.Fatalf("%s = %q for an origin that isn't allowed", name, v)
+					}
+				}
+				return
+			}
+			if c := got.Get("Access-Control-Allow-Credentials"); c != "true" {
+				t.Fatalf("Allow-Credentials = %q, want true", c)
+			}
+			if served {
+				if got.Get("Access-Control-Expose-Headers") != corsExposeHeaders || got.Get("Access-Control-Max-Age") != "" {
+					t.Fatalf("headers = %v, want exposed headers and no preflight ones", got)
+				}
+			} else if got.Get("Access-Control-Allow-Headers") != corsAllowHeaders || got.Get("Access-Control-Max-Age") != "600" {
+				t.Fatalf("headers = %v, want allowed headers and a max age of 600", got)
+			}
+		})
+	}
+
+	// Without credentials allowed, browsers aren't told they may send them.
+	policy, err = NewCORSPolicy(CORSConfig{Origins: []string{"https://*.example.com"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(http.MethodGet, "/v1/issues/7", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	rec := httptest.NewRecorder()
+	CORS(policy, mux)(http.HandlerFunc(noop)).ServeHTTP(rec, req)
+	if o, c := rec.Header().Get("Access-Control-Allow-Origin"), rec.Header().Get("Access-Control-Allow-Credentials"); o != "https://app.example.com" || c != "" {
+		t.Fatalf("Allow-Origin %q, Allow-Credentials %q; want the origin echoed without credentials", o, c)
+	}
+}
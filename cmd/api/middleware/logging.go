@@ -1,14 +1,21 @@
 package middleware
 
 import (
-	"log"
+	"bufio"
+	"context"
+	"io"
+	"log/slog"
+	"net"
 	"net/http"
 	"time"
+
+	"github.com/jesusthecreator017/fswithgo/internal/logging"
 )
 
 type wrappedWriter struct {
 	http.ResponseWriter
 	statusCode int
+	bytes      int64
 }
 
 func (wr *wrappedWriter) WriteHeader(statusCode int) {
@@ -16,16 +23,96 @@ func (wr *wrappedWriter) WriteHeader(statusCode int) {
 	wr.statusCode = statusCode
 }
 
+func (wr *wrappedWriter) Write(b []byte) (int, error) {
+	n, err := wr.ResponseWriter.Write(b)
+	wr.bytes += int64(n)
+	return n, err
+}
+
+// ReadFrom keeps io.Copy on the underlying writer's fast path, which
+// Timeout's writer relies on, while still counting the bytes.
+func (wr *wrappedWriter) ReadFrom(r io.Reader) (int64, error) {
+	n, err := io.Copy(wr.ResponseWriter, r)
+	wr.bytes += n
+	return n, err
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (wr *wrappedWriter) Unwrap() http.ResponseWriter {
+	return wr.ResponseWriter
+}
+
+// Hijack lets WebSocket upgrades through the logger.
+func (wr *wrappedWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := http.NewResponseController(wr.ResponseWriter).Hijack()
+	if err == nil {
+		wr.statusCode = http.StatusSwitchingProtocols
+	}
+	return conn, rw, err
+}
+
+const accessLogKey contextKey = "access_log"
+
+// accessLog collects what later middleware learns about a request, like
+// who made it, for the line logged when it is done.
+type accessLog struct {
+	attrs []any
+}
+
+// Logging gives each request a logger carrying its id, client IP, method
+// and route, for handlers to get with logging.FromContext, and logs a line
+// when the request is done. It must run after Route, RequestID and RealIP.
 func Logging(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
 		start := time.Now()
 
+		logger := logging.FromContext(req.Context()).With(
+			"request_id", GetRequestID(req),
+			"ip", GetRealIP(req),
+			"method", req.Method,
+			"route", routeOf(req),
+		)
+		entry := &accessLog{}
+		ctx := logging.WithLogger(req.Context(), logger)
+		ctx = context.WithValue(ctx, accessLogKey, entry)
+
 		wrapped := &wrappedWriter{
 			ResponseWriter: w,
 			statusCode:     http.StatusOK,
 		}
+		next.ServeHTTP(wrapped, req.WithContext(ctx))
 
-		next.ServeHTTP(wrapped, req)
-		log.Println(wrapped.statusCode, req.Method, req.URL.Path, time.Since(start))
+		level := slog.LevelInfo
+		if wrapped.statusCode >= http.StatusInternalServerError {
+			level = slog.LevelError
+		}
+		attrs := append(entry.attrs,
+			"path", req.URL.Path,
+			"status", wrapped.statusCode,
+			"bytes", wrapped.bytes,
+			"duration_ms", float64(time.Since(start).Microseconds())/1000,
+		)
+		// Headers are only logged for debugging; credentials in them are
+		// redacted by the handler.
+		if logger.Enabled(ctx, slog.LevelDebug) {
+			attrs = append(attrs, headerGroup(req.Header))
+		}
+		logger.Log(ctx, level, "request", attrs...)
 	})
 }
+
+// withLogAttrs adds args to the request's logger and its access log line.
+func withLogAttrs(ctx context.Context, args ...any) context.Context {
+	if entry, ok := ctx.Value(accessLogKey).(*accessLog); ok {
+		entry.attrs = append(entry.attrs, args...)
+	}
+	return logging.With(ctx, args...)
+}
+
+func headerGroup(h http.Header) slog.Attr {
+	attrs := make([]any, 0, len(h))
+	for name, values := range h {
+		attrs = append(attrs, slog.Any(name, values))
+	}
+	return slog.Group("headers", attrs...)
+}
@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jesusthecreator017/fswithgo/internal/metrics"
+)
+
+// Metrics counts, times and tracks in-flight requests by method, route and
+// status. The route is the pattern the request matched, never the raw
+// path, so each issue id doesn't get its own series; requests no route
+// matches are counted as "unmatched".
+func Metrics(reg *metrics.Registry) func(http.Handler) http.Handler {
+	requests := reg.NewCounter("http_requests_total", "HTTP requests handled.", "method", "route", "status")
+	duration := reg.NewHistogram("http_request_duration_seconds", "Time to handle an HTTP request.",
+		metrics.DefBuckets, "method", "route", "status")
+	inFlight := reg.NewGauge("http_requests_in_flight", "HTTP requests being handled, including open event streams and WebSockets.",
+		"method", "route")
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			start := time.Now()
+			route := routeOf(req)
+
+			gauge := inFlight.With(req.Method, route)
+			gauge.Inc()
+			defer gauge.Dec()
+
+			wrapped := &wrappedWriter{
+				ResponseWriter: w,
+				statusCode:     http.StatusOK,
+			}
+			next.ServeHTTP(wrapped, req)
+
+			status := strconv.Itoa(wrapped.statusCode)
+			requests.With(req.Method, route, status).Inc()
+			duration.With(req.Method, route, status).Observe(time.Since(start).Seconds())
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jesusthecreator017/fswithgo/cmd/api/helpers"
+	"github.com/jesusthecreator017/fswithgo/internal/logging"
+	"github.com/jesusthecreator017/fswithgo/internal/ratelimit"
+)
+
+// RateLimit gives each client a token bucket per policy: the entry in
+// routes for the pattern the request matched, or def, which all other
+// routes share. A zero Limit turns limiting off. Clients are the signed-in
+// user, or the IP address for anonymous requests, so it must run after
+// Route, GlobalAuth and RealIP.
+//
+// Responses carry RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset
+// and RateLimit-Policy headers; a request over the limit gets a 429 with
+// Retry-After. If the store fails, requests are let through.
+func RateLimit(store ratelimit.Store, def ratelimit.Limit, routes map[string]ratelimit.Limit) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			pattern := GetRoute(req)
+			policy, limit := "*", def
+			if l, ok := routes[pattern]; ok {
+				policy, limit = pattern, l
+			}
+			if limit.Unlimited() {
+				next.ServeHTTP(w, req)
+				return
+			}
+
+			client := "ip:" + GetRealIP(req)
+			if userID := GetUserID(req); userID != uuid.Nil {
+				client = "user:" + userID.String()
+			}
+
+			res, err := store.Take(req.Context(), policy+"|"+client, limit)
+			if err != nil {
+				logging.FromContext(req.Context()).Error("checking rate limit", "error", err)
+				next.ServeHTTP(w, req)
+				return
+			}
+
+			h := w.Header()
+			h.Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+			h.Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+			h.Set("RateLimit-Reset", strconv.Itoa(seconds(res.Reset)))
+			h.Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", limit.Burst, seconds(limit.Per)))
+
+			if !res.Allowed {
+				retry := seconds(res.RetryAfter)
+				h.Set("Retry-After", strconv.Itoa(retry))
+				helpers.ErrorJson(w, http.StatusTooManyRequests, fmt.Sprintf("rate limit exceeded; retry after %ds", retry))
+				return
+			}
+
+			next.ServeHTTP(w, req)
+		})
+	}
+}
+
+// seconds rounds d up to whole seconds, so a client that waits that long
+// isn't turned away again.
+func seconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,155 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jesusthecreator017/fswithgo/internal/ratelimit"
+)
+
+// recordingStore lets everything through and remembers what it was asked.
+type recordingStore struct {
+	key   string
+	limit ratelimit.Limit
+	calls int
+	err   error
+}
+
+func (s *recordingStore) Take(_ context.Context, key string, l ratelimit.Limit) (ratelimit.Result, error) {
+	s.key, s.limit = key, l
+	s.calls++
+	return ratelimit.Result{Allowed: true, Limit: l.Burst, Remaining: l.Burst - 1}, s.err
+}
+
+func TestRateLimitPolicy(t *testing.T) {
+	var (
+		def      = ratelimit.Limit{Burst: 100, Per: time.Minute}
+		create   = ratelimit.Limit{Burst: 60, Per: time.Minute}
+		register = ratelimit.Limit{Burst: 5, Per: time.Hour}
+	)
+	routes := map[string]ratelimit.Limit{
+		"POST /v1/issues":         create,
+		"POST /v1/users/register": register,
+		"GET /v1/events/stream":   {},
+	}
+
+	mux := http.NewServeMux()
+	noop := func(http.ResponseWriter, *http.Request) {}
+	for _, pattern := range []string{
+		"GET /v1/issues",
+		"POST /v1/issues",
+		"GET /v1/issues/{id}",
+		"POST /v1/users/register",
+		"GET /v1/events/stream",
+	} {
+		mux.HandleFunc(pattern, noop)
+	}
+
+	userID := uuid.New()
+	tests := []struct {
+		name         string
+		method, path string
+		user         uuid.UUID
+		wantKey      string
+		wantLimit    ratelimit.Limit
+	}{
+		{"route policy", http.MethodPost, "/v1/issues", uuid.Nil, "POST /v1/issues|ip:192.0.2.1", create},
+		{"route policy by pattern", http.MethodPost, "/v1/users/register", uuid.Nil, "POST /v1/users/register|ip:192.0.2.1", register},
+		{"same path, other method", http.MethodGet, "/v1/issues", uuid.Nil, "*|ip:192.0.2.1", def},
+		{"path with a wildcard", http.MethodGet, "/v1/issues/7", uuid.Nil, "*|ip:192.0.2.1", def},
+		{"unmatched", http.MethodGet, "/missing", uuid.Nil, "*|ip:192.0.2.1", def},
+		{"signed in", http.MethodPost, "/v1/issues", userID, "POST /v1/issues|user:" + userID.String(), create},
+		{"turned off", http.MethodGet, "/v1/events/stream", uuid.Nil, "", ratelimit.Limit{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &recordingStore{}
+			h := RateLimit(store, def, routes)(http.HandlerFunc(noop))
+
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			req.RemoteAddr = "192.0.2.1:4711"
+			if tt.user != uuid.Nil {
+				req = req.WithContext(context.WithValue(req.Context(), UserIDKey, tt.user))
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, routed(mux, req))
+
+			if tt.wantKey == "" {
+				if store.calls != 0 || rec.Header().Get("RateLimit-Limit") != "" {
+					t.Fatalf("limited a route whose policy is off: key %q", store.key)
+				}
+				return
+			}
+			if store.key != tt.wantKey || store.limit != tt.wantLimit {
+				t.Fatalf("took from %q with %+v, want %q with %+v", store.key, store.limit, tt.wantKey, tt.wantLimit)
+			}
+		})
+	}
+
+	// With no default, only routes with a policy are limited.
+	store := &recordingStore{}
+	h := RateLimit(store, ratelimit.Limit{}, routes)(http.HandlerFunc(noop))
+	h.ServeHTTP(httptest.NewRecorder(), routed(mux, httptest.NewRequest(http.MethodGet, "/v1/issues", nil)))
+	if store.calls != 0 {
+		t.Fatalf("limited %q with the default off", store.key)
+	}
+	h.ServeHTTP(httptest.NewRecorder(), routed(mux, httptest.NewRequest(http.MethodPost, "/v1/issues", nil)))
+	if store.calls != 1 {
+		t.Fatal("route policy not applied with the default off")
+	}
+}
+
+func TestRateLimitResponses(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/issues", func(http.ResponseWriter, *http.Request) {})
+	limit := ratelimit.Limit{Burst: 2, Per: time.Minute}
+	h := RateLimit(ratelimit.NewMemory(), limit, nil)(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	serve := func() *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, routed(mux, httptest.NewRequest(http.MethodGet, "/v1/issues", nil)))
+		return rec
+	}
+
+	for remaining := 1; remaining >= 0; remaining-- {
+		rec := serve()
+		if rec.Code != http.StatusNoContent {
+			t.Fatalf("status = %d within the limit", rec.Code)
+		}
+		h := rec.Header()
+		if h.Get("RateLimit-Limit") != "2" || h.Get("RateLimit-Remaining") != strconv.Itoa(remaining) || h.Get("RateLimit-Policy") != "2;w=60" {
+			t.Fatalf("headers = %v, want %d remaining of 2 a minute", h, remaining)
+		}
+	}
+
+	rec := serve()
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("status = %d over the limit, want 429", rec.Code)
+	}
+	// A token comes back every 30 seconds.
+	if got := rec.Header().Get("Retry-After"); got != "30" {
+		t.Fatalf("Retry-After = %q, want 30 seconds", got)
+	}
+}
+
+func TestRateLimitStoreFailure(t *testing.T) {
+	store := &recordingStore{err: errors.New("connection refused")}
+	var served bool
+	h := RateLimit(store, ratelimit.Limit{Burst: 1, Per: time.Minute}, nil)(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		served = true
+	}))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if !served || rec.Header().Get("RateLimit-Limit") != "" {
+		t.Fatal("request not let through when the store failed")
+	}
+}
@@ -4,100 +4,119 @@ import (
 	"context"
 	"net"
 	"net/http"
+	"net/netip"
+	"slices"
 	"strings"
+
+	"github.com/jesusthecreator017/fswithgo/internal/proxyproto"
 )
 
 const RealIPKey contextKey = "real_ip"
 
-// Headers to check for IP in prio order
-var realIPHeaders = []string{
-	"CF-Connecting-IP", // CloudFlare
-	"X-Real-IP",        // Nginx
-	"X-Forwaded-For",   // Most other proxies/load balancers
+// RealIP works out the client's address. Forwarding headers are only
+// believed from a peer in trusted: the Forwarded header (RFC 7239) if
+// present, otherwise X-Forwarded-For. Their hops are walked from the
+// right, the most recently added, past every trusted proxy, and the first
+// address that isn't one is the client. Anything further left was sent by
+// the client and could be made up.
+//
+// The address is stored for GetRealIP and replaces RemoteAddr.
+func RealIP(trusted []netip.Prefix) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			ip := clientIP(req, trusted)
+
+			req = req.WithContext(context.WithValue(req.Context(), RealIPKey, ip))
+			req.RemoteAddr = ip
+			next.ServeHTTP(w, req)
+		})
+	}
 }
 
-func RealIP(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
-		if isTrustedProxy(req) {
-			if ip := extractIP(req); ip != "" {
-				// Clone the request and override the RemoteAddr
-				ctx := context.WithValue(req.Context(), RealIPKey, ip)
-				req.WithContext(ctx)
+// clientIP returns the client's address for req.
+func clientIP(req *http.Request, trusted []netip.Prefix) string {
+	peer, err := netip.ParseAddr(hostOnly(req.RemoteAddr))
+	if err != nil {
+		return hostOnly(req.RemoteAddr)
+	}
+	peer = peer.Unmap()
+	if !proxyproto.Contains(trusted, peer) {
+		return peer.String()
+	}
 
-				req = req.Clone(req.Context())
-				req.RemoteAddr = ip
+	var hops []string
+	if values := req.Header.Values("Forwarded"); len(values) > 0 {
+		hops = forwardedFor(values)
+	} else {
+		for _, v := range req.Header.Values("X-Forwarded-For") {
+			for hop := range strings.SplitSeq(v, ",") {
+				hops = append(hops, strings.TrimSpace(hop))
 			}
 		}
-		next.ServeHTTP(w, req)
-	})
-}
+	}
 
-func extractIP(req *http.Request) string {
-	for _, header := range realIPHeaders {
-		value := req.Header.Get(header)
-		if value == "" {
-			continue
+	// The nearest hop is the trusted peer itself, so a request whose
+	// proxies all turn out to be trusted comes from the last one.
+	client := peer
+	for _, hop := range slices.Backward(hops) {
+		addr, err := parseHop(hop)
+		if err != nil {
+			// "unknown", an obfuscated identifier or garbage: nothing
+			// further left can be tied to an address.
+			break
 		}
-
-		// X-Forwarded-For can contain a comma-separated list of IPs
-		// The first one is the original client IP
-		ip := strings.TrimSpace(strings.SplitN(value, ",", 2)[0])
-
-		if isValidIP(ip) {
-			return ip
+		client = addr
+		if !proxyproto.Contains(trusted, addr) {
+			break
 		}
 	}
-
-	return ""
+	return client.String()
 }
 
-// Figure out whether the IP is valid
-func isValidIP(ip string) bool {
-	// Parse the IP
-	parsed := net.ParseIP(ip)
-	if parsed == nil {
-		return false
-	}
-
-	// Reject private/loopback addresses if you only want public IPs
-	// Remove this block if you want to allow private IPs
-	privateRanges := []string{
-		"10.0.0.0/8",
-		"172.16.0.0/12",
-		"192.168.0.0/16",
-		"127.0.0.0/8",
-		"::1/128",
-	}
-
-	for _, cidr := range privateRanges {
-		_, network, err := net.ParseCIDR(cidr)
-		if err != nil {
-			continue
-		}
-		if network.Contains(parsed) {
-			return false
+// forwardedFor returns the for= parameter of each element of Forwarded
+// header values, in order. An element without one gets "" so the walk
+// stops there.
+func forwardedFor(values []string) []string {
+	var hops []string
+	for _, v := range values {
+		for element := range strings.SplitSeq(v, ",") {
+			hop := ""
+			for pair := range strings.SplitSeq(element, ";") {
+				key, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+				if ok && strings.EqualFold(key, "for") {
+					hop = strings.Trim(value, `"`)
+				}
+			}
+			hops = append(hops, hop)
 		}
 	}
-
-	return true
+	return hops
 }
 
-func isTrustedProxy(req *http.Request) bool {
-	host, _, err := net.SplitHostPort(req.RemoteAddr)
-	if err != nil {
-		return false
+// parseHop reads an address that may carry a port, as "192.0.2.1:4711",
+// or brackets, as "[2001:db8::1]:4711" in Forwarded.
+func parseHop(hop string) (netip.Addr, error) {
+	if ap, err := netip.ParseAddrPort(hop); err == nil {
+		return ap.Addr().Unmap(), nil
 	}
+	addr, err := netip.ParseAddr(strings.TrimSuffix(strings.TrimPrefix(hop, "["), "]"))
+	return addr.Unmap(), err
+}
 
-	trustedProxies := []string{
-		"10.00.00.1",
-		"192.168.1.1",
+// GetRealIP returns the client IP resolved by RealIP, falling back to
+// RemoteAddr for requests that did not pass through it.
+func GetRealIP(req *http.Request) string {
+	if ip, ok := req.Context().Value(RealIPKey).(string); ok {
+		return ip
 	}
+	return hostOnly(req.RemoteAddr)
+}
 
-	for _, proxy := range trustedProxies {
-		if host == proxy {
-			return true
-		}
+// hostOnly strips the port from an address. A bare IP is returned as-is.
+func hostOnly(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
 	}
-
-	return false
+	return host
 }
@@ -0,0 +1,87 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	trusted := []netip.Prefix{
+		netip.MustParsePrefix("10.0.0.0/8"),
+		netip.MustParsePrefix("fd00::/8"),
+	}
+
+	tests := []struct {
+		name      string
+		remote    string
+		forwarded []string
+		xff       []string
+		want      string
+	}{
+		{"no proxy", "203.0.113.7:4711", nil, nil, "203.0.113.7"},
+		{"untrusted peer's XFF ignored", "198.51.100.1:4711", nil, []string{"203.0.113.7"}, "198.51.100.1"},
+		{"untrusted peer's Forwarded ignored", "198.51.100.1:4711", []string{"for=203.0.113.7"}, nil, "198.51.100.1"},
+		{"untrusted peer claiming to be a proxy", "198.51.100.1:4711", nil, []string{"10.0.0.9"}, "198.51.100.1"},
+		{"trusted peer without headers", "10.0.0.1:4711", nil, nil, "10.0.0.1"},
+		{"one proxy", "10.0.0.1:4711", nil, []string{"203.0.113.7"}, "203.0.113.7"},
+		{"spoofed leftmost entry", "10.0.0.1:4711", nil, []string{"1.2.3.4, 203.0.113.7"}, "203.0.113.7"},
+		{"spoofed leftmost through two proxies", "10.0.0.1:4711", nil, []string{"1.2.3.4, 203.0.113.7, 10.0.0.2"}, "203.0.113.7"},
+		{"spoofed trusted address", "10.0.0.1:4711", nil, []string{"10.0.0.9, 203.0.113.7"}, "203.0.113.7"},
+		{"spoofed entry in its own header", "10.0.0.1:4711", nil, []string{"1.2.3.4", "203.0.113.7, 10.0.0.2"}, "203.0.113.7"},
+		{"only proxies", "10.0.0.1:4711", nil, []string{"10.0.0.3, 10.0.0.2"}, "10.0.0.3"},
+		{"garbage stops the walk", "10.0.0.1:4711", nil, []string{"203.0.113.7, garbage, 10.0.0.2"}, "10.0.0.2"},
+		{"hop with a port", "10.0.0.1:4711", nil, []string{"203.0.113.7:5555"}, "203.0.113.7"},
+		{"IPv6 hop", "[fd00::1]:4711", nil, []string{"2001:db8::17"}, "2001:db8::17"},
+		{"mapped IPv4 peer", "[::ffff:10.0.0.1]:4711", nil, []string{"203.0.113.7"}, "203.0.113.7"},
+		{"Forwarded over XFF", "10.0.0.1:4711", []string{"for=203.0.113.7"}, []string{"1.2.3.4"}, "203.0.113.7"},
+		{"Forwarded spoofed leftmost", "10.0.0.1:4711", []string{`for=1.2.3.4, for=203.0.113.7;proto=https`}, nil, "203.0.113.7"},
+		{"Forwarded quoted IPv6 with port", "10.0.0.1:4711", []string{`for="[2001:db8:cafe::17]:4711"`}, nil, "2001:db8:cafe::17"},
+		{"Forwarded parameter case", "10.0.0.1:4711", []string{"proto=https;For=203.0.113.7"}, nil, "203.0.113.7"},
+		{"Forwarded obfuscated", "10.0.0.1:4711", []string{"for=_hidden, for=10.0.0.2"}, nil, "10.0.0.2"},
+		{"Forwarded unknown", "10.0.0.1:4711", []string{"for=unknown"}, nil, "10.0.0.1"},
+		{"Forwarded element without for", "10.0.0.1:4711", []string{"for=1.2.3.4, proto=https"}, nil, "10.0.0.1"},
+		{"unparseable peer", "pipe", nil, []string{"203.0.113.7"}, "pipe"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remote
+			for _, v := range tt.forwarded {
+				req.Header.Add("Forwarded", v)
+			}
+			for _, v := range tt.xff {
+				req.Header.Add("X-Forwarded-For", v)
+			}
+			if got := clientIP(req, trusted); got != tt.want {
+				t.Fatalf("clientIP = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRealIP(t *testing.T) {
+	trusted := []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}
+	var got *http.Request
+	h := RealIP(trusted)(http.HandlerFunc(func(_ http.ResponseWriter, req *http.Request) {
+		got = req
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "10.0.0.1:4711"
+	req.Header.Set("X-Forwarded-For", "1.2.3.4, 203.0.113.7")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	if ip := GetRealIP(got); ip != "203.0.113.7" {
+		t.Fatalf("GetRealIP = %q, want the client", ip)
+	}
+	if got.RemoteAddr != "203.0.113.7" {
+		t.Fatalf("RemoteAddr = %q, want the client", got.RemoteAddr)
+	}
+
+	// Without RealIP, the peer.
+	if ip := GetRealIP(req); ip != "10.0.0.1" {
+		t.Fatalf("GetRealIP without RealIP = %q, want the peer", ip)
+	}
+}
@@ -1,19 +1,25 @@
 package middleware
 
 import (
-	"log"
 	"net/http"
 	"runtime/debug"
+
+	"github.com/jesusthecreator017/fswithgo/internal/logging"
 )
 
+// Recoverer turns a panic into a 500 and logs it with its stack. It runs
+// after Logging, so the panic is logged with the request's id and the
+// access log line shows the 500.
 func Recoverer(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
 		defer func() {
 			if err := recover(); err != nil {
-				log.Printf("Panic recovered: %s\n%s", err, debug.Stack())
+				if err == http.ErrAbortHandler {
+					panic(err)
+				}
+				logging.FromContext(req.Context()).Error("panic recovered", "panic", err, "stack", string(debug.Stack()))
 				http.Error(w, "Something went wrong", http.StatusInternalServerError)
 			}
-			log.Println("Middleware.Recoverer: Done")
 		}()
 		next.ServeHTTP(w, req)
 	})
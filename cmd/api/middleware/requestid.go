@@ -5,6 +5,8 @@ import (
 	"net/http"
 
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const RequestIDKey contextKey = "request_id"
@@ -16,12 +18,14 @@ func RequestID(next http.Handler) http.Handler {
 		id := req.Header.Get(RequestIDHeader)
 
 		if id == "" {
-			id = generateID()
+			id = generateID(req.Context())
 		}
+		// Let traces be found by the id in logs and error responses.
+		trace.SpanFromContext(req.Context()).SetAttributes(attribute.String("request.id", id))
 
 		// Store in the context for downstream handlers
 		ctx := context.WithValue(req.Context(), RequestIDKey, id)
-		req.WithContext(ctx)
+		req = req.WithContext(ctx)
 
 		// Echo it back into the request header
 		w.Header().Set(RequestIDHeader, id)
@@ -30,11 +34,16 @@ func RequestID(next http.Handler) http.Handler {
 	})
 }
 
-func generateID() string {
+// generateID uses the request's trace id when it is traced, so the two
+// match, and a random UUID otherwise.
+func generateID(ctx context.Context) string {
+	if sc := trace.SpanContextFromContext(ctx); sc.HasTraceID() {
+		return sc.TraceID().String()
+	}
 	return uuid.New().String()
 }
 
 func GetRequestID(req *http.Request) string {
-	id, _ := req.Context().Value(RequestIDHeader).(string)
+	id, _ := req.Context().Value(RequestIDKey).(string)
 	return id
 }
@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+const routeKey contextKey = "route"
+
+// Route looks up the pattern mux will send each request to, once, for the
+// middleware after it to read with GetRoute. It must come first.
+func Route(mux *http.ServeMux) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			_, pattern := mux.Handler(req)
+			ctx := context.WithValue(req.Context(), routeKey, pattern)
+			next.ServeHTTP(w, req.WithContext(ctx))
+		})
+	}
+}
+
+// GetRoute returns the pattern req matched, like "GET /v1/issues/{id}", or
+// "" if it matched none.
+func GetRoute(req *http.Request) string {
+	pattern, _ := req.Context().Value(routeKey).(string)
+	return pattern
+}
+
+// routeOf returns the path part of req's pattern, for labels and log
+// fields that carry the method separately.
+func routeOf(req *http.Request) string {
+	pattern := GetRoute(req)
+	if pattern == "" {
+		return "unmatched"
+	}
+	if _, path, ok := strings.Cut(pattern, " "); ok {
+		return path
+	}
+	return pattern
+}
@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// routed returns req as the middleware after Route see it.
+func routed(mux *http.ServeMux, req *http.Request) *http.Request {
+	var out *http.Request
+	Route(mux)(http.HandlerFunc(func(_ http.ResponseWriter, req *http.Request) {
+		out = req
+	})).ServeHTTP(httptest.NewRecorder(), req)
+	return out
+}
+
+func TestRoute(t *testing.T) {
+	mux := http.NewServeMux()
+	noop := func(http.ResponseWriter, *http.Request) {}
+	mux.HandleFunc("GET /v1/issues/{id}", noop)
+	mux.HandleFunc("/v1/admin/", noop)
+
+	tests := []struct {
+		method, path string
+		pattern      string
+		route        string
+	}{
+		{http.MethodGet, "/v1/issues/7", "GET /v1/issues/{id}", "/v1/issues/{id}"},
+		{http.MethodHead, "/v1/issues/7", "GET /v1/issues/{id}", "/v1/issues/{id}"},
+		{http.MethodGet, "/v1/admin/jobs", "/v1/admin/", "/v1/admin/"},
+		{http.MethodGet, "/missing", "", "unmatched"},
+	}
+	for _, tt := range tests {
+		req := routed(mux, httptest.NewRequest(tt.method, tt.path, nil))
+		if got := GetRoute(req); got != tt.pattern {
+			t.Errorf("%s %s: GetRoute = %q, want %q", tt.method, tt.path, got, tt.pattern)
+		}
+		if got := routeOf(req); got != tt.route {
+			t.Errorf("%s %s: routeOf = %q, want %q", tt.method, tt.path, got, tt.route)
+		}
+	}
+
+	// Without Route there is nothing to read.
+	if got := GetRoute(httptest.NewRequest(http.MethodGet, "/v1/issues/7", nil)); got != "" {
+		t.Errorf("GetRoute without Route = %q, want none", got)
+	}
+}
@@ -1,86 +1,136 @@
 package middleware
 
 import (
+	"bufio"
 	"context"
+	"errors"
+	"io"
+	"net"
 	"net/http"
 	"time"
 )
 
-func Timeout(duration time.Duration) func(http.Handler) http.Handler {
+var errTimedOut = errors.New("request timed out")
+
+// Timeout gives each request a context deadline of timeout(req); zero
+// means no deadline. The handler runs on the request's own goroutine and
+// writes straight through, so flushing, hijacking and large responses work
+// as usual.
+//
+// If the deadline passes before the handler has written anything, its
+// writes are discarded with http.ErrHandlerTimeout and a 504 is sent once
+// it returns. Handlers should watch req.Context(); one that doesn't will
+// hold the 504 until it finishes. Once a response has started, the
+// deadline only cancels the context.
+func Timeout(timeout func(*http.Request) time.Duration) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
-			ctx, cancel := context.WithTimeout(req.Context(), duration)
+			d := timeout(req)
+			if d <= 0 {
+				next.ServeHTTP(w, req)
+				return
+			}
+
+			ctx, cancel := context.WithTimeoutCause(req.Context(), d, errTimedOut)
 			defer cancel()
 
-			req = req.WithContext(ctx)
+			// Headers set before the handler runs, e.g. by CORS, are kept
+			// on the 504; anything the handler set is not.
+			saved := w.Header().Clone()
 
-			// Use the custom timeoutWriter Wrapper
-			tw := &timeoutWriter{
-				ResponseWriter: w,
-			}
+			tw := &timeoutWriter{ResponseWriter: w, ctx: ctx}
+			next.ServeHTTP(tw, req.WithContext(ctx))
 
-			// Make the channel to handle "done" status for the ResponseWritter
-			done := make(chan struct{})
-
-			// Use concurrency to check if there has been any updates to the response writter
-			go func() {
-				next.ServeHTTP(tw, req)
-				close(done)
-			}()
-
-			select {
-			case <-done:
-				// Handler finished in time
-				tw.flush(w)
-			case <-ctx.Done():
-				if !tw.written {
-					http.Error(w, "Request Timed Out", http.StatusGatewayTimeout)
+			if tw.timedOut || (!tw.started && context.Cause(ctx) == errTimedOut) {
+				header := w.Header()
+				clear(header)
+				for k, v := range saved {
+					header[k] = v
 				}
+				http.Error(w, "Request Timed Out", http.StatusGatewayTimeout)
 			}
 		})
 	}
 }
 
+// RouteTimeouts returns a Timeout function that uses the entry in routes,
+// keyed by pattern, for the route the request matched, or def if it has
+// none. A zero entry turns the timeout off for that route. It relies on
+// Route having run.
+func RouteTimeouts(def time.Duration, routes map[string]time.Duration) func(*http.Request) time.Duration {
+	return func(req *http.Request) time.Duration {
+		if d, ok := routes[GetRoute(req)]; ok {
+			return d
+		}
+		return def
+	}
+}
+
+// timeoutWriter passes writes through until the deadline. Past it, a
+// response that hasn't started is refused so the 504 can go out instead.
+// Like any ResponseWriter it is only used from the handler's goroutine.
 type timeoutWriter struct {
 	http.ResponseWriter
-	headers http.Header
-	body    []byte
-	status  int
-	written bool
+	ctx      context.Context
+	started  bool
+	timedOut bool
 }
 
-func (tw *timeoutWriter) Header() http.Header {
-	if tw.headers == nil {
-		tw.headers = make(http.Header)
+// start reports whether the handler may write, marking the response as
+// started if so.
+func (tw *timeoutWriter) start() bool {
+	if tw.timedOut {
+		return false
+	}
+	if !tw.started && context.Cause(tw.ctx) == errTimedOut {
+		tw.timedOut = true
+		return false
 	}
-	return tw.headers
+	tw.started = true
+	return true
 }
 
 func (tw *timeoutWriter) WriteHeader(status int) {
-	tw.status = status
-	tw.written = true
+	if tw.start() {
+		tw.ResponseWriter.WriteHeader(status)
+	}
 }
 
 func (tw *timeoutWriter) Write(b []byte) (int, error) {
-	tw.written = true
-	tw.body = append(tw.body, b...)
-	return len(b), nil
+	if !tw.start() {
+		return 0, http.ErrHandlerTimeout
+	}
+	return tw.ResponseWriter.Write(b)
 }
 
-// Flush writes the buffered response to the real ResponseWriter
-func (tw *timeoutWriter) flush(w http.ResponseWriter) {
-	// Copy the buffered headers
-	for k, v := range tw.headers {
-		for _, vv := range v {
-			w.Header().Add(k, vv)
-		}
+// ReadFrom keeps io.Copy on the underlying writer's fast path.
+func (tw *timeoutWriter) ReadFrom(r io.Reader) (int64, error) {
+	if !tw.start() {
+		return 0, http.ErrHandlerTimeout
 	}
+	return io.Copy(tw.ResponseWriter, r)
+}
 
-	if tw.status != 0 {
-		w.WriteHeader(tw.status)
+func (tw *timeoutWriter) FlushError() error {
+	if !tw.start() {
+		return http.ErrHandlerTimeout
 	}
+	return http.NewResponseController(tw.ResponseWriter).Flush()
+}
 
-	if len(tw.body) > 0 {
-		w.Write(tw.body)
+func (tw *timeoutWriter) Flush() {
+	tw.FlushError()
+}
+
+func (tw *timeoutWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if !tw.start() {
+		return nil, nil, http.ErrHandlerTimeout
 	}
+	return http.NewResponseController(tw.ResponseWriter).Hijack()
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer for
+// deadlines and anything else not handled here.
+func (tw *timeoutWriter) Unwrap() http.ResponseWriter {
+	return tw.ResponseWriter
 }
@@ -0,0 +1,233 @@
+package middleware
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func fixed(d time.Duration) func(*http.Request) time.Duration {
+	return func(*http.Request) time.Duration { return d }
+}
+
+func TestTimeoutBeforeWrite(t *testing.T) {
+	h := Timeout(fixed(20 * time.Millisecond))(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		<-req.Context().Done()
+	}))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if rec.Code != http.StatusGatewayTimeout {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusGatewayTimeout)
+	}
+}
+
+func TestTimeoutWriteAfterDeadline(t *testing.T) {
+	errs := make(chan error, 1)
+	h := Timeout(fixed(20 * time.Millisecond))(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		<-req.Context().Done()
+		w.Header().Set("X-Handler", "late")
+		w.WriteHeader(http.StatusOK)
+		_, err := w.Write([]byte("late"))
+		errs <- err
+	}))
+
+	// Run behind a real server so the race detector sees the connection's
+	// goroutines alongside the handler's.
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("X-Before", "kept")
+		h.ServeHTTP(w, req)
+	}))
+	defer srv.Close()
+
+	res, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(res.Body)
+	res.Body.Close()
+
+	if err := <-errs; !errors.Is(err, http.ErrHandlerTimeout) {
+		t.Fatalf("late write error = %v, want %v", err, http.ErrHandlerTimeout)
+	}
+	if res.StatusCode != http.StatusGatewayTimeout {
+		t.Fatalf("status = %d, want %d", res.StatusCode, http.StatusGatewayTimeout)
+	}
+	if strings.Contains(string(body), "late") {
+		t.Fatalf("body contains the late write: %q", body)
+	}
+	if got := res.Header.Get("X-Before"); got != "kept" {
+		t.Fatalf("X-Before = %q, want header set before the handler kept", got)
+	}
+	if got := res.Header.Get("X-Handler"); got != "" {
+		t.Fatalf("X-Handler = %q, want the handler's headers dropped", got)
+	}
+}
+
+func TestTimeoutStartedResponsePassesThrough(t *testing.T) {
+	proceed := make(chan struct{})
+	errs := make(chan error, 1)
+	h := Timeout(fixed(50 * time.Millisecond))(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		io.WriteString(w, "first\n")
+		if err := http.NewResponseController(w).Flush(); err != nil {
+			errs <- err
+			return
+		}
+
+		// The client has to see the first line before the deadline passes,
+		// which only happens if nothing is buffered.
+		<-proceed
+		<-req.Context().Done()
+
+		_, err := io.WriteString(w, "second\n")
+		errs <- err
+	}))
+
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	res, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want %d", res.StatusCode, http.StatusOK)
+	}
+
+	r := bufio.NewReader(res.Body)
+	line, err := r.ReadString('\n')
+	if err != nil || line != "first\n" {
+		t.Fatalf("first line = %q, %v", line, err)
+	}
+	close(proceed)
+
+	line, err = r.ReadString('\n')
+	if err != nil || line != "second\n" {
+		t.Fatalf("second line = %q, %v", line, err)
+	}
+	if err := <-errs; err != nil {
+		t.Fatalf("write after deadline on a started response: %v", err)
+	}
+}
+
+// recordingWriter notes which optional interfaces were reached.
+type recordingWriter struct {
+	*httptest.ResponseRecorder
+	flushed  bool
+	hijacked bool
+	readFrom bool
+}
+
+func (rw *recordingWriter) Flush() {
+	rw.flushed = true
+	rw.ResponseRecorder.Flush()
+}
+
+func (rw *recordingWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	rw.hijacked = true
+	return nil, nil, nil
+}
+
+func (rw *recordingWriter) ReadFrom(r io.Reader) (int64, error) {
+	rw.readFrom = true
+	return io.Copy(rw.ResponseRecorder, r)
+}
+
+func TestTimeoutWriterUnwrap(t *testing.T) {
+	h := Timeout(fixed(time.Second))(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		rc := http.NewResponseController(w)
+		// A LimitedReader has no WriteTo, so io.Copy has to use ReadFrom.
+		if _, err := io.Copy(w, io.LimitReader(strings.NewReader("body"), 4)); err != nil {
+			t.Errorf("copy: %v", err)
+		}
+		if err := rc.Flush(); err != nil {
+			t.Errorf("flush: %v", err)
+		}
+		if _, _, err := rc.Hijack(); err != nil {
+			t.Errorf("hijack: %v", err)
+		}
+	}))
+
+	// Sit behind the same wrapper Logging and Metrics use, which has no
+	// Flush of its own, so reaching it means going through Unwrap.
+	rw := &recordingWriter{ResponseRecorder: httptest.NewRecorder()}
+	wrapped := &wrappedWriter{ResponseWriter: rw, statusCode: http.StatusOK}
+	h.ServeHTTP(wrapped, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if !rw.flushed || !rw.hijacked || !rw.readFrom {
+		t.Fatalf("flushed = %v, hijacked = %v, readFrom = %v; want all reached", rw.flushed, rw.hijacked, rw.readFrom)
+	}
+	if got := rw.Body.String(); got != "body" {
+		t.Fatalf("body = %q, want %q", got, "body")
+	}
+	if wrapped.bytes != int64(len("body")) {
+		t.Fatalf("bytes = %d, want %d", wrapped.bytes, len("body"))
+	}
+}
+
+func TestRouteTimeouts(t *testing.T) {
+	const (
+		def      = 25 * time.Second
+		transfer = 10 * time.Minute
+	)
+
+	mux := http.NewServeMux()
+	noop := func(http.ResponseWriter, *http.Request) {}
+	for _, pattern := range []string{
+		"GET /v1/issues/{id}",
+		"POST /v1/issues/{id}/attachments",
+		"GET /v1/issues/{id}/attachments/{attachment_id}",
+		"GET /v1/events/stream",
+		"GET /v1/ws",
+	} {
+		mux.HandleFunc(pattern, noop)
+	}
+
+	timeout := RouteTimeouts(def, map[string]time.Duration{
+		"POST /v1/issues/{id}/attachments":                transfer,
+		"GET /v1/issues/{id}/attachments/{attachment_id}": transfer,
+		"GET /v1/events/stream":                           0,
+		"GET /v1/ws":                                      0,
+	})
+
+	tests := []struct {
+		method, path string
+		want         time.Duration
+	}{
+		{http.MethodGet, "/v1/issues/7", def},
+		{http.MethodPost, "/v1/issues/7/attachments", transfer},
+		{http.MethodGet, "/v1/issues/7/attachments/3", transfer},
+		{http.MethodHead, "/v1/issues/7/attachments/3", transfer},
+		{http.MethodGet, "/v1/events/stream", 0},
+		{http.MethodGet, "/v1/ws", 0},
+		{http.MethodPost, "/v1/ws", def},
+		{http.MethodGet, "/missing", def},
+	}
+	for _, tt := range tests {
+		req := routed(mux, httptest.NewRequest(tt.method, tt.path, nil))
+		if got := timeout(req); got != tt.want {
+			t.Errorf("%s %s: timeout = %v, want %v", tt.method, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestTimeoutZeroHasNoDeadline(t *testing.T) {
+	h := Timeout(fixed(0))(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if _, ok := req.Context().Deadline(); ok {
+			t.Error("request has a deadline with a zero timeout")
+		}
+		if _, ok := w.(*timeoutWriter); ok {
+			t.Error("writer wrapped with a zero timeout")
+		}
+	}))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+}
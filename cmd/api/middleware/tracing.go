@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	semconv "go.opentelemetry.io/otel/semconv/v1.39.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Tracing starts a span for each request, continuing the trace from an
+// inbound traceparent header. Spans are named after the route, like
+// metrics are labeled. Health probes and scrapes aren't traced. It must
+// run after Route.
+func Tracing(next http.Handler) http.Handler {
+	withRoute := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		trace.SpanFromContext(req.Context()).SetAttributes(semconv.HTTPRoute(routeOf(req)))
+		next.ServeHTTP(w, req)
+	})
+	return otelhttp.NewHandler(withRoute, "http.request",
+		otelhttp.WithSpanNameFormatter(func(_ string, req *http.Request) string {
+			return req.Method + " " + routeOf(req)
+		}),
+		otelhttp.WithFilter(func(req *http.Request) bool {
+			return !strings.HasPrefix(req.URL.Path, "/v1/health") && req.URL.Path != "/metrics"
+		}),
+	)
+}
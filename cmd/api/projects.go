@@ -0,0 +1,116 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/jesusthecreator017/fswithgo/cmd/api/helpers"
+	"github.com/jesusthecreator017/fswithgo/cmd/api/middleware"
+	"github.com/jesusthecreator017/fswithgo/internal/auth"
+	"github.com/jesusthecreator017/fswithgo/internal/store"
+)
+
+func (app *application) createProjectHandler(w http.ResponseWriter, req *http.Request) {
+	var input struct {
+		Name string `json:"name"`
+	}
+
+	if err := helpers.ReadJson(req, &input); err != nil {
+		helpers.ErrorJson(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	errs := make(map[string]string)
+
+	input.Name = strings.TrimSpace(input.Name)
+	if input.Name == "" {
+		errs["name"] = "must not be blank"
+	} else if len(input.Name) > 100 {
+		errs["name"] = "must not be more than 100 characters"
+	}
+
+	if len(errs) > 0 {
+		helpers.ValidationErrorJson(w, errs)
+		return
+	}
+
+	project := &store.Project{
+		Name:    input.Name,
+		OwnerID: middleware.GetUserID(req),
+	}
+
+	if err := app.store.Projects.Create(req.Context(), project); err != nil {
+		helpers.ErrorJson(w, http.StatusInternalServerError, "failed to create project")
+		return
+	}
+
+	helpers.WriteJson(w, http.StatusCreated, helpers.Envelope{"project": project})
+}
+
+func (app *application) listProjectsHandler(w http.ResponseWriter, req *http.Request) {
+	projects, err := app.store.Projects.List(req.Context())
+	if err != nil {
+		helpers.ErrorJson(w, http.StatusInternalServerError, "failed to list projects")
+		return
+	}
+
+	helpers.WriteJson(w, http.StatusOK, helpers.Envelope{"projects": projects})
+}
+
+func (app *application) getProjectHandler(w http.ResponseWriter, req *http.Request) {
+	project, ok := app.readProject(w, req)
+	if !ok {
+		return
+	}
+
+	helpers.WriteJson(w, http.StatusOK, helpers.Envelope{"project": project})
+}
+
+func (app *application) listProjectIssuesHandler(w http.ResponseWriter, req *http.Request) {
+	project, ok := app.readProject(w, req)
+	if !ok {
+		return
+	}
+
+	issues, err := app.store.Issues.ListByProjectID(req.Context(), project.ID)
+	if err != nil {
+		helpers.ErrorJson(w, http.StatusInternalServerError, "failed to get issues")
+		return
+	}
+
+	helpers.WriteJson(w, http.StatusOK, helpers.Envelope{"issues": issues})
+}
+
+// readProject loads the project named by the {id} path value. On failure it
+// writes the error response and returns ok=false. There is no membership
+// check: projects are shared boards, see store.Project.
+func (app *application) readProject(w http.ResponseWriter, req *http.Request) (*store.Project, bool) {
+	id, err := strconv.ParseInt(req.PathValue("id"), 10, 64)
+	if err != nil {
+		helpers.ErrorJson(w, http.StatusBadRequest, "id must be a number")
+		return nil, false
+	}
+
+	project, err := app.store.Projects.GetByID(req.Context(), id)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			helpers.ErrorJson(w, http.StatusNotFound, "project not found")
+			return nil, false
+		}
+		helpers.ErrorJson(w, http.StatusInternalServerError, "failed to get project")
+		return nil, false
+	}
+
+	return project, true
+}
+
+// canManageProject reports whether the caller may change the project's
+// workflow: its owner or an admin.
+func canManageProject(req *http.Request, project *store.Project) bool {
+	if project.OwnerID == middleware.GetUserID(req) {
+		return true
+	}
+	return auth.HasPermission(auth.Permission(middleware.GetPermissions(req)), auth.PermAdmin)
+}
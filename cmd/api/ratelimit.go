@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jesusthecreator017/fswithgo/internal/logging"
+	"github.com/jesusthecreator017/fswithgo/internal/ratelimit"
+)
+
+// rateLimitConfig picks where buckets are kept, "memory" or "postgres",
+// and the limits.
+type rateLimitConfig struct {
+	backend string
+	def     ratelimit.Limit            // for routes without a policy
+	routes  map[string]ratelimit.Limit // overrides by route pattern
+}
+
+const rateLimitPruneSchedule = "@hourly"
+
+func newRateLimiter(cfg rateLimitConfig, pool *pgxpool.Pool) (ratelimit.Store, error) {
+	switch cfg.backend {
+	case "memory":
+		return ratelimit.NewMemory(), nil
+	case "postgres":
+		return ratelimit.NewPostgres(pool), nil
+	default:
+		return nil, fmt.Errorf("unknown RATE_LIMIT_BACKEND %q", cfg.backend)
+	}
+}
+
+// rateLimits returns the limits by route pattern: tight ones where abuse
+// is cheap for the client and costly for us, none for probes and scrapes,
+// and then whatever RATE_LIMITS sets.
+func (app *application) rateLimits() map[string]ratelimit.Limit {
+	limits := map[string]ratelimit.Limit{
+		"POST /v1/users/register":          {Burst: 5, Per: time.Hour},
+		"POST /v1/users/login":             {Burst: 10, Per: time.Minute},
+		"PATCH /v1/users/me/password":      {Burst: 5, Per: time.Hour},
+		"POST /v1/issues":                  {Burst: 60, Per: time.Minute},
+		"POST /v1/issues/{id}/attachments": {Burst: 30, Per: time.Hour},
+		"GET /v1/health":                   {},
+		"GET /v1/health/live":              {},
+		"GET /v1/health/ready":             {},
+		"GET /metrics":                     {},
+	}
+	maps.Copy(limits, app.config.rateLimit.routes)
+	return limits
+}
+
+// pruneRateLimitsTask deletes buckets in Postgres that have refilled.
+func (app *application) pruneRateLimitsTask(buckets *ratelimit.Postgres) func(context.Context) error {
+	// A bucket refills within its window, so one untouched for the
+	// longest window is full.
+	idle := app.config.rateLimit.def.Per
+	for _, l := range app.rateLimits() {
+		idle = max(idle, l.Per)
+	}
+
+	return func(ctx context.Context) error {
+		pruned, err := buckets.Prune(ctx, idle)
+		if pruned > 0 {
+			logging.FromContext(ctx).Info("pruned rate limit buckets", "deleted", pruned)
+		}
+		return err
+	}
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/jesusthecreator017/fswithgo/cmd/api/helpers"
+	"github.com/jesusthecreator017/fswithgo/cmd/api/middleware"
+	"github.com/jesusthecreator017/fswithgo/internal/store"
+)
+
+// maxReportDays caps the range of a single report request.
+const maxReportDays = 366
+
+type statusSeries struct {
+	Status store.StatusType `json:"status"`
+	Counts []int64          `json:"counts"`
+}
+
+// cumulativeFlow is chart-ready: Series[i].Counts[j] is the number of issues
+// in Series[i].Status at the end of Dates[j].
+type cumulativeFlow struct {
+	From   string         `json:"from"`
+	To     string         `json:"to"`
+	Dates  []string       `json:"dates"`
+	Series []statusSeries `json:"series"`
+}
+
+// burndown lines up with Dates. Remaining is everything not in a done-category
+// status, Completed is everything that is, Scope
+// is every issue that existed that day, and Ideal falls linearly from the
+// first day's Remaining to zero.
+type burndown struct {
+	From      string    `json:"from"`
+	To        string    `json:"to"`
+	Dates     []string  `json:"dates"`
+	Remaining []int64   `json:"remaining"`
+	Completed []int64   `json:"completed"`
+	Scope     []int64   `json:"scope"`
+	Ideal     []float64 `json:"ideal"`
+}
+
+func (app *application) cumulativeFlowHandler(w http.ResponseWriter, req *http.Request) {
+	filter, workflow, ok := app.readReportFilter(w, req)
+	if !ok {
+		return
+	}
+
+	counts, err := app.store.Reports.DailyStatusCounts(req.Context(), filter)
+	if err != nil {
+		helpers.ErrorJson(w, http.StatusInternalServerError, "failed to build cumulative flow")
+		return
+	}
+
+	dates, byStatus, statuses := pivotDailyCounts(filter, workflow, counts)
+
+	flow := cumulativeFlow{
+		From:   dates[0],
+		To:     dates[len(dates)-1],
+		Dates:  dates,
+		Series: make([]statusSeries, len(statuses)),
+	}
+	for i, status := range statuses {
+		flow.Series[i] = statusSeries{Status: status, Counts: byStatus[status]}
+	}
+
+	helpers.WriteJson(w, http.StatusOK, helpers.Envelope{"cumulative_flow": flow})
+}
+
+func (app *application) burndownHandler(w http.ResponseWriter, req *http.Request) {
+	filter, workflow, ok := app.readReportFilter(w, req)
+	if !ok {
+		return
+	}
+
+	counts, err := app.store.Reports.DailyStatusCounts(req.Context(), filter)
+	if err != nil {
+		helpers.ErrorJson(w, http.StatusInternalServerError, "failed to build burndown")
+		return
+	}
+
+	dates, byStatus, statuses := pivotDailyCounts(filter, workflow, counts)
+
+	chart := burndown{
+		From:      dates[0],
+		To:        dates[len(dates)-1],
+		Dates:     dates,
+		Remaining: make([]int64, len(dates)),
+		Completed: make([]int64, len(dates)),
+		Scope:     make([]int64, len(dates)),
+		Ideal:     make([]float64, len(dates)),
+	}
+	for _, status := range statuses {
+		// Statuses no longer in the workflow have no category; count them
+		// as remaining.
+		ws := workflow.Status(status)
+		done := ws != nil && ws.Category == store.CategoryDone
+		for i, n := range byStatus[status] {
+			chart.Scope[i] += n
+			if done {
+				chart.Completed[i] += n
+			}
+		}
+	}
+	for i := range dates {
+		chart.Remaining[i] = chart.Scope[i] - chart.Completed[i]
+	}
+
+	last := float64(len(dates) - 1)
+	for i := range dates {
+		if last == 0 {
+			chart.Ideal[i] = float64(chart.Remaining[0])
+			continue
+		}
+		ideal := float64(chart.Remaining[0]) * (last - float64(i)) / last
+		chart.Ideal[i] = math.Round(ideal*100) / 100
+	}
+
+	helpers.WriteJson(w, http.StatusOK, helpers.Envelope{"burndown": chart})
+}
+
+// readReportFilter parses the from/to dates, defaulting to the last 14 days,
+// and the optional project_id, and loads the board's workflow. On failure it
+// writes the error response and returns ok=false.
+//
+// There is no label filter: issues have no labels in the schema, so there is
+// nothing to scope by. A label parameter is rejected rather than ignored, so
+// a client asking for one doesn't get the whole board back unannounced.
+func (app *application) readReportFilter(w http.ResponseWriter, req *http.Request) (store.ReportFilter, *store.Workflow, bool) {
+	qs := req.URL.Query()
+	errs := make(map[string]string)
+
+	filter := store.ReportFilter{UserID: middleware.GetUserID(req)}
+
+	if qs.Has("label") {
+		errs["label"] = "issues have no labels to filter by"
+	}
+
+	projectID, err := helpers.ReadInt(qs, "project_id", 0)
+	if err != nil {
+		errs["project_id"] = err.Error()
+	} else if projectID != 0 {
+		id := int64(projectID)
+		filter.ProjectID = &id
+	}
+
+	if filter.To, err = readTime(qs.Get("to")); err != nil {
+		errs["to"] = err.Error()
+	} else if filter.To.IsZero() {
+		filter.To = time.Now().UTC()
+	}
+
+	if filter.From, err = readTime(qs.Get("from")); err != nil {
+		errs["from"] = err.Error()
+	} else if filter.From.IsZero() {
+		filter.From = filter.To.AddDate(0, 0, -13)
+	}
+
+	filter.From = truncateDay(filter.From)
+	filter.To = truncateDay(filter.To)
+
+	if len(errs) == 0 {
+		if filter.To.Before(filter.From) {
+			errs["from"] = "must not be after to"
+		} else if days := int(filter.To.Sub(filter.From).Hours()/24) + 1; days > maxReportDays {
+			errs["from"] = "range must not exceed 366 days"
+		}
+	}
+
+	if len(errs) > 0 {
+		helpers.ValidationErrorJson(w, errs)
+		return store.ReportFilter{}, nil, false
+	}
+
+	if filter.ProjectID != nil {
+		if _, err := app.store.Projects.GetByID(req.Context(), *filter.ProjectID); err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				helpers.ErrorJson(w, http.StatusNotFound, "project not found")
+				return store.ReportFilter{}, nil, false
+			}
+			helpers.ErrorJson(w, http.StatusInternalServerError, "failed to get project")
+			return store.ReportFilter{}, nil, false
+		}
+	}
+
+	workflow, err := app.store.Workflows.Get(req.Context(), filter.ProjectID)
+	if err != nil {
+		helpers.ErrorJson(w, http.StatusInternalServerError, "failed to get workflow")
+		return store.ReportFilter{}, nil, false
+	}
+
+	return filter, workflow, true
+}
+
+// pivotDailyCounts turns sparse (day, status, count) rows into one dense
+// series per status. The workflow's statuses always appear, in board order;
+// any other status found in the history (one since deleted) is appended
+// after them.
+func pivotDailyCounts(filter store.ReportFilter, workflow *store.Workflow, counts []store.DailyStatusCount) ([]string, map[store.StatusType][]int64, []store.StatusType) {
+	var dates []string
+	index := make(map[string]int)
+	for d := filter.From; !d.After(filter.To); d = d.AddDate(0, 0, 1) {
+		key := d.Format(time.DateOnly)
+		index[key] = len(dates)
+		dates = append(dates, key)
+	}
+
+	statuses := make([]store.StatusType, len(workflow.Statuses))
+	for i, ws := range workflow.Statuses {
+		statuses[i] = ws.Name
+	}
+	byStatus := make(map[store.StatusType][]int64, len(statuses))
+	for _, status := range statuses {
+		byStatus[status] = make([]int64, len(dates))
+	}
+
+	for _, c := range counts {
+		i, ok := index[c.Day.Format(time.DateOnly)]
+		if !ok {
+			continue
+		}
+		if _, ok := byStatus[c.Status]; !ok {
+			statuses = append(statuses, c.Status)
+			byStatus[c.Status] = make([]int64, len(dates))
+		}
+		byStatus[c.Status][i] = c.Count
+	}
+
+	return dates, byStatus, statuses
+}
+
+func truncateDay(t time.Time) time.Time {
+	y, m, d := t.UTC().Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/jesusthecreator017/fswithgo/cmd/api/helpers"
+	"github.com/jesusthecreator017/fswithgo/internal/logging"
+	"github.com/jesusthecreator017/fswithgo/internal/ratelimit"
+	"github.com/jesusthecreator017/fswithgo/internal/schedule"
+)
+
+// Schedules for maintenance that isn't configurable.
+const (
+	jobPruneSchedule     = "@hourly"
+	webhookPruneSchedule = "@daily"
+)
+
+// registerTasks adds every recurring maintenance task to the scheduler.
+func (app *application) registerTasks() error {
+	err := errors.Join(
+		app.schedule.Add("attachments.sweep", app.config.attachments.sweepSchedule, app.sweepAttachmentsTask),
+		app.schedule.Add("jobs.prune", jobPruneSchedule, app.pruneJobsTask),
+		app.schedule.Add("webhooks.prune", webhookPruneSchedule, app.pruneWebhookDeliveriesTask),
+	)
+	if buckets, ok := app.rateLimiter.(*ratelimit.Postgres); ok {
+		err = errors.Join(err, app.schedule.Add("ratelimit.prune", rateLimitPruneSchedule, app.pruneRateLimitsTask(buckets)))
+	}
+	if app.config.audit.retention > 0 {
+		err = errors.Join(err, app.schedule.Add("audit.archive", app.config.audit.schedule, app.auditRetentionTask))
+	}
+	return err
+}
+
+func (app *application) pruneJobsTask(ctx context.Context) error {
+	pruned, err := app.jobs.Prune(ctx)
+	if pruned > 0 {
+		logging.FromContext(ctx).Info("pruned finished jobs", "deleted", pruned)
+	}
+	return err
+}
+
+// pruneWebhookDeliveriesTask trims the webhook delivery log.
+func (app *application) pruneWebhookDeliveriesTask(ctx context.Context) error {
+	cutoff := time.Now().Add(-app.config.webhooks.logRetention)
+	pruned, err := app.store.Webhooks.PruneDeliveries(ctx, cutoff)
+	if pruned > 0 {
+		logging.FromContext(ctx).Info("pruned webhook deliveries", "deleted", pruned)
+	}
+	return err
+}
+
+// adminScheduleHandler lists the scheduled tasks with their next run and
+// their last run, including its error if it failed.
+func (app *application) adminScheduleHandler(w http.ResponseWriter, req *http.Request) {
+	tasks, err := app.schedule.Status(req.Context())
+	if err != nil {
+		helpers.ErrorJson(w, http.StatusInternalServerError, "failed to get schedule")
+		return
+	}
+
+	helpers.WriteJson(w, http.StatusOK, helpers.Envelope{"tasks": tasks})
+}
+
+// adminTaskRunsHandler returns a task's run history, newest first.
+func (app *application) adminTaskRunsHandler(w http.ResponseWriter, req *http.Request) {
+	limit, err := helpers.ReadInt(req.URL.Query(), "limit", 50)
+	if err != nil || limit < 1 || limit > 200 {
+		helpers.ValidationErrorJson(w, map[string]string{"limit": "must be between 1 and 200"})
+		return
+	}
+
+	runs, err := app.schedule.Runs(req.Context(), req.PathValue("task"), limit)
+	if err != nil {
+		if errors.Is(err, schedule.ErrUnknownTask) {
+			helpers.ErrorJson(w, http.StatusNotFound, "task not found")
+			return
+		}
+		helpers.ErrorJson(w, http.StatusInternalServerError, "failed to list runs")
+		return
+	}
+
+	helpers.WriteJson(w, http.StatusOK, helpers.Envelope{"runs": runs})
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/jesusthecreator017/fswithgo/cmd/api/helpers"
+	"github.com/jesusthecreator017/fswithgo/internal/events"
+	"github.com/jesusthecreator017/fswithgo/internal/store"
+)
+
+// listIssueChildrenHandler returns an issue's direct sub-tasks.
+func (app *application) listIssueChildrenHandler(w http.ResponseWriter, req *http.Request) {
+	id, err := strconv.ParseInt(req.PathValue("id"), 10, 64)
+	if err != nil {
+		helpers.ErrorJson(w, http.StatusBadRequest, "id must be a number")
+		return
+	}
+
+	if _, err := app.store.Issues.GetByID(req.Context(), id); err != nil {
+		helpers.ErrorJson(w, http.StatusNotFound, "issue not found")
+		return
+	}
+
+	children, err := app.store.Issues.ListChildren(req.Context(), id)
+	if err != nil {
+		helpers.ErrorJson(w, http.StatusInternalServerError, "failed to get sub-tasks")
+		return
+	}
+
+	helpers.WriteJson(w, http.StatusOK, helpers.Envelope{"issues": children})
+}
+
+// setIssueParentHandler moves an issue under another one, or back to the
+// top level when parent_id is null.
+func (app *application) setIssueParentHandler(w http.ResponseWriter, req *http.Request) {
+	id, err := strconv.ParseInt(req.PathValue("id"), 10, 64)
+	if err != nil {
+		helpers.ErrorJson(w, http.StatusBadRequest, "id must be a number")
+		return
+	}
+
+	var input struct {
+		ParentID *int64 `json:"parent_id"`
+	}
+
+	if err := helpers.ReadJson(req, &input); err != nil {
+		helpers.ErrorJson(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	before, err := app.store.Issues.GetByID(req.Context(), id)
+	if err != nil {
+		helpers.ErrorJson(w, http.StatusNotFound, "issue not found")
+		return
+	}
+
+	issue, err := app.store.Issues.SetParent(req.Context(), id, input.ParentID)
+	if err != nil {
+		if parentErrorJson(w, err) {
+			return
+		}
+		if errors.Is(err, store.ErrNotFound) {
+			helpers.ErrorJson(w, http.StatusNotFound, "issue not found")
+			return
+		}
+		helpers.ErrorJson(w, http.StatusInternalServerError, "failed to set parent")
+		return
+	}
+
+	app.publishIssueEvent(req, events.IssueUpdated, issue)
+	// Both the old and the new parent's progress changed.
+	app.publishParentUpdate(req, before)
+	if before.ParentID == nil || issue.ParentID == nil || *before.ParentID != *issue.ParentID {
+		app.publishParentUpdate(req, issue)
+	}
+
+	helpers.WriteJson(w, http.StatusOK, helpers.Envelope{"issue": issue})
+}
+
+// setIssueAutoCompleteHandler turns on or off completing an issue once all
+// of its sub-tasks are done.
+func (app *application) setIssueAutoCompleteHandler(w http.ResponseWriter, req *http.Request) {
+	id, err := strconv.ParseInt(req.PathValue("id"), 10, 64)
+	if err != nil {
+		helpers.ErrorJson(w, http.StatusBadRequest, "id must be a number")
+		return
+	}
+
+	var input struct {
+		AutoComplete *bool `json:"auto_complete"`
+	}
+
+	if err := helpers.ReadJson(req, &input); err != nil {
+		helpers.ErrorJson(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if input.AutoComplete == nil {
+		helpers.ValidationErrorJson(w, map[string]string{"auto_complete": "is required"})
+		return
+	}
+
+	issue, err := app.store.Issues.SetAutoComplete(req.Context(), id, *input.AutoComplete)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			helpers.ErrorJson(w, http.StatusNotFound, "issue not found")
+			return
+		}
+		helpers.ErrorJson(w, http.StatusInternalServerError, "failed to update issue")
+		return
+	}
+
+	app.publishIssueEvent(req, events.IssueUpdated, issue)
+	app.publishParentUpdate(req, issue)
+
+	helpers.WriteJson(w, http.StatusOK, helpers.Envelope{"issue": issue})
+}
+
+// parentErrorJson writes a validation error on parent_id if err is one of
+// the hierarchy errors, and reports whether it did.
+func parentErrorJson(w http.ResponseWriter, err error) bool {
+	switch {
+	case errors.Is(err, store.ErrInvalidParent),
+		errors.Is(err, store.ErrParentCycle),
+		errors.Is(err, store.ErrParentTooDeep):
+		helpers.ValidationErrorJson(w, map[string]string{"parent_id": err.Error()})
+		return true
+	}
+	return false
+}
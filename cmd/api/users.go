@@ -7,8 +7,10 @@ import (
 	"strings"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jesusthecreator017/fswithgo/cmd/api/helpers"
+	"github.com/jesusthecreator017/fswithgo/cmd/api/middleware"
 	"github.com/jesusthecreator017/fswithgo/internal/auth"
 	"github.com/jesusthecreator017/fswithgo/internal/store"
 	"golang.org/x/crypto/bcrypt"
@@ -53,31 +55,8 @@ func (app *application) registerUserHandler(w http.ResponseWriter, req *http.Req
 
 	// Validate the password
 	input.Password = strings.TrimSpace(input.Password)
-	if input.Password == "" {
-		errs["password"] = "must enter a password"
-	} else {
-		var pwErrs []string
-		if len(input.Password) < 8 {
-			pwErrs = append(pwErrs, "at least 8 characters")
-		}
-		if len(input.Password) > 20 {
-			pwErrs = append(pwErrs, "20 characters or fewer")
-		}
-		if !regexp.MustCompile(`[A-Z]`).MatchString(input.Password) {
-			pwErrs = append(pwErrs, "one uppercase letter")
-		}
-		if !regexp.MustCompile(`[a-z]`).MatchString(input.Password) {
-			pwErrs = append(pwErrs, "one lowercase letter")
-		}
-		if !regexp.MustCompile(`[0-9]`).MatchString(input.Password) {
-			pwErrs = append(pwErrs, "one number")
-		}
-		if !regexp.MustCompile(`[!@#$%^&*]`).MatchString(input.Password) {
-			pwErrs = append(pwErrs, "one special character (!@#$%^&*)")
-		}
-		if len(pwErrs) > 0 {
-			errs["password"] = "must contain: " + strings.Join(pwErrs, ", ")
-		}
+	if msg := validatePassword(input.Password); msg != "" {
+		errs["password"] = msg
 	}
 
 	// Check the errors
@@ -112,6 +91,10 @@ func (app *application) registerUserHandler(w http.ResponseWriter, req *http.Req
 		return
 	}
 
+	entry := newAuditEntry(req, store.AuditUserRegister, "user", user.ID.String())
+	entry.ActorID = &user.ID
+	app.recordAudit(req, entry)
+
 	// Generate the JWT Token
 	token, err := auth.GenerateJWT(user.ID, user.Permissions, app.config.jwtSecret, time.Hour*24*7)
 	if err != nil {
@@ -153,16 +136,24 @@ func (app *application) loginUserHandler(w http.ResponseWriter, req *http.Reques
 
 	user, err := app.store.Users.GetByEmail(req.Context(), input.Email)
 	if err != nil {
+		app.recordLoginFailure(req, input.Email, nil, "unknown email")
 		helpers.ErrorJson(w, http.StatusUnauthorized, "invalid email or password")
 		return
 	}
 
 	// Compare password with bcrypt
 	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(input.Password)); err != nil {
+		app.recordLoginFailure(req, input.Email, &user.ID, "wrong password")
 		helpers.ErrorJson(w, http.StatusUnauthorized, "invalid email or password")
 		return
 	}
 
+	if user.IsSuspended() {
+		app.recordLoginFailure(req, input.Email, &user.ID, "account suspended")
+		helpers.ErrorJson(w, http.StatusForbidden, "account suspended")
+		return
+	}
+
 	// Generate the JWT Token
 	token, err := auth.GenerateJWT(user.ID, user.Permissions, app.config.jwtSecret, time.Hour*24*7)
 	if err != nil {
@@ -170,5 +161,111 @@ func (app *application) loginUserHandler(w http.ResponseWriter, req *http.Reques
 		return
 	}
 
+	entry := newAuditEntry(req, store.AuditUserLogin, "user", user.ID.String())
+	entry.ActorID = &user.ID
+	app.recordAudit(req, entry)
+
 	helpers.WriteJson(w, http.StatusOK, helpers.Envelope{"user": user, "token": token})
 }
+
+// recordLoginFailure audits a rejected login. userID is nil when the email
+// doesn't match an account.
+func (app *application) recordLoginFailure(req *http.Request, email string, userID *uuid.UUID, reason string) {
+	entry := newAuditEntry(req, store.AuditUserLoginFailed, "user", "")
+	if userID != nil {
+		entry.TargetID = userID.String()
+	}
+	entry.Metadata = map[string]any{"email": email, "reason": reason}
+	app.recordAudit(req, entry)
+
+	app.metrics.loginsFailed.With(reason).Inc()
+}
+
+func (app *application) changePasswordHandler(w http.ResponseWriter, req *http.Request) {
+	var input struct {
+		CurrentPassword string `json:"current_password"`
+		NewPassword     string `json:"new_password"`
+	}
+
+	if err := helpers.ReadJson(req, &input); err != nil {
+		helpers.ErrorJson(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	errs := make(map[string]string)
+
+	if input.CurrentPassword == "" {
+		errs["current_password"] = "must enter your current password"
+	}
+
+	input.NewPassword = strings.TrimSpace(input.NewPassword)
+	if msg := validatePassword(input.NewPassword); msg != "" {
+		errs["new_password"] = msg
+	} else if input.NewPassword == input.CurrentPassword {
+		errs["new_password"] = "must be different from the current password"
+	}
+
+	if len(errs) > 0 {
+		helpers.ValidationErrorJson(w, errs)
+		return
+	}
+
+	user, err := app.store.Users.GetByID(req.Context(), middleware.GetUserID(req))
+	if err != nil {
+		helpers.ErrorJson(w, http.StatusNotFound, "user not found")
+		return
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(input.CurrentPassword)); err != nil {
+		helpers.ErrorJson(w, http.StatusUnauthorized, "current password is incorrect")
+		return
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(input.NewPassword), bcrypt.DefaultCost)
+	if err != nil {
+		helpers.ErrorJson(w, http.StatusInternalServerError, "failed to hash password")
+		return
+	}
+
+	if err := app.store.Users.UpdatePassword(req.Context(), user.ID, string(hashedPassword)); err != nil {
+		helpers.ErrorJson(w, http.StatusInternalServerError, "failed to update password")
+		return
+	}
+
+	app.recordAudit(req, newAuditEntry(req, store.AuditUserPasswordChange, "user", user.ID.String()))
+
+	helpers.WriteJson(w, http.StatusOK, helpers.Envelope{"message": "password updated"})
+}
+
+// validatePassword returns a user-facing message describing what the password
+// is missing, or "" if it meets every rule.
+func validatePassword(password string) string {
+	if password == "" {
+		return "must enter a password"
+	}
+
+	var pwErrs []string
+	if len(password) < 8 {
+		pwErrs = append(pwErrs, "at least 8 characters")
+	}
+	if len(password) > 20 {
+		pwErrs = append(pwErrs, "20 characters or fewer")
+	}
+	if !regexp.MustCompile(`[A-Z]`).MatchString(password) {
+		pwErrs = append(pwErrs, "one uppercase letter")
+	}
+	if !regexp.MustCompile(`[a-z]`).MatchString(password) {
+		pwErrs = append(pwErrs, "one lowercase letter")
+	}
+	if !regexp.MustCompile(`[0-9]`).MatchString(password) {
+		pwErrs = append(pwErrs, "one number")
+	}
+	if !regexp.MustCompile(`[!@#$%^&*]`).MatchString(password) {
+		pwErrs = append(pwErrs, "one special character (!@#$%^&*)")
+	}
+	if len(pwErrs) > 0 {
+		return "must contain: " + strings.Join(pwErrs, ", ")
+	}
+
+	return ""
+}
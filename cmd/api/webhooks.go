@@ -0,0 +1,451 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jesusthecreator017/fswithgo/cmd/api/helpers"
+	"github.com/jesusthecreator017/fswithgo/cmd/api/middleware"
+	"github.com/jesusthecreator017/fswithgo/internal/events"
+	"github.com/jesusthecreator017/fswithgo/internal/jobs"
+	"github.com/jesusthecreator017/fswithgo/internal/logging"
+	"github.com/jesusthecreator017/fswithgo/internal/store"
+	"github.com/jesusthecreator017/fswithgo/internal/telemetry"
+	"github.com/jesusthecreator017/fswithgo/internal/webhooks"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type webhookConfig struct {
+	maxAttempts  int           // attempts before a delivery is marked failed
+	disableAfter int           // failed attempts in a row that disable a webhook
+	timeout      time.Duration // per attempt
+	logRetention time.Duration // how long finished deliveries are kept
+}
+
+// Retries wait webhookBackoffBase, doubling after each failure, up to
+// webhookBackoffMax.
+const (
+	webhookBackoffBase = 30 * time.Second
+	webhookBackoffMax  = time.Hour
+)
+
+// webhookEventTypes are the events a webhook can subscribe to.
+var webhookEventTypes = []events.Type{events.IssueCreated, events.IssueUpdated, events.IssueDeleted}
+
+const webhookPingEvent = "ping"
+
+func (app *application) listWebhooksHandler(w http.ResponseWriter, req *http.Request) {
+	hooks, err := app.store.Webhooks.List(req.Context())
+	if err != nil {
+		helpers.ErrorJson(w, http.StatusInternalServerError, "failed to list webhooks")
+		return
+	}
+
+	for _, hook := range hooks {
+		hook.Secret = ""
+	}
+	helpers.WriteJson(w, http.StatusOK, helpers.Envelope{"webhooks": hooks})
+}
+
+// createWebhookHandler subscribes a URL to events. The response holds the
+// signing secret, which isn't shown again.
+func (app *application) createWebhookHandler(w http.ResponseWriter, req *http.Request) {
+	var input struct {
+		URL        string   `json:"url"`
+		EventTypes []string `json:"event_types"`
+	}
+
+	if err := helpers.ReadJson(req, &input); err != nil {
+		helpers.ErrorJson(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	errs := make(map[string]string)
+	input.URL = strings.TrimSpace(input.URL)
+	if msg := validateWebhookURL(input.URL); msg != "" {
+		errs["url"] = msg
+	}
+	eventTypes, msg := validateWebhookEventTypes(input.EventTypes)
+	if msg != "" {
+		errs["event_types"] = msg
+	}
+	if len(errs) > 0 {
+		helpers.ValidationErrorJson(w, errs)
+		return
+	}
+
+	userID := middleware.GetUserID(req)
+	hook := &store.Webhook{
+		UserID:     &userID,
+		URL:        input.URL,
+		Secret:     webhooks.NewSecret(),
+		EventTypes: eventTypes,
+	}
+	if err := app.store.Webhooks.Create(req.Context(), hook); err != nil {
+		helpers.ErrorJson(w, http.StatusInternalServerError, "failed to create webhook")
+		return
+	}
+
+	entry := newAuditEntry(req, store.AuditAdminWebhookCreate, "webhook", strconv.FormatInt(hook.ID, 10))
+	entry.Metadata = map[string]any{"url": hook.URL, "event_types": hook.EventTypes}
+	app.recordAudit(req, entry)
+
+	helpers.WriteJson(w, http.StatusCreated, helpers.Envelope{"webhook": hook})
+}
+
+func (app *application) getWebhookHandler(w http.ResponseWriter, req *http.Request) {
+	id, err := strconv.ParseInt(req.PathValue("id"), 10, 64)
+	if err != nil {
+		helpers.ErrorJson(w, http.StatusBadRequest, "id must be a number")
+		return
+	}
+
+	hook, err := app.store.Webhooks.Get(req.Context(), id)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			helpers.ErrorJson(w, http.StatusNotFound, "webhook not found")
+			return
+		}
+		helpers.ErrorJson(w, http.StatusInternalServerError, "failed to get webhook")
+		return
+	}
+
+	hook.Secret = ""
+	helpers.WriteJson(w, http.StatusOK, helpers.Envelope{"webhook": hook})
+}
+
+// updateWebhookHandler changes a webhook's URL, events or active flag, and
+// can rotate its secret. Re-activating a disabled webhook resets its
+// failure count and resumes its pending deliveries.
+func (app *application) updateWebhookHandler(w http.ResponseWriter, req *http.Request) {
+	id, err := strconv.ParseInt(req.PathValue("id"), 10, 64)
+	if err != nil {
+		helpers.ErrorJson(w, http.StatusBadRequest, "id must be a number")
+		return
+	}
+
+	var input struct {
+		URL          *string  `json:"url"`
+		EventTypes   []string `json:"event_types"`
+		Active       *bool    `json:"active"`
+		RotateSecret bool     `json:"rotate_secret"`
+	}
+
+	if err := helpers.ReadJson(req, &input); err != nil {
+		helpers.ErrorJson(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	errs := make(map[string]string)
+	update := store.WebhookUpdate{Active: input.Active}
+	if input.URL != nil {
+		u := strings.TrimSpace(*input.URL)
+		if msg := validateWebhookURL(u); msg != "" {
+			errs["url"] = msg
+		}
+		update.URL = &u
+	}
+	if input.EventTypes != nil {
+		eventTypes, msg := validateWebhookEventTypes(input.EventTypes)
+		if msg != "" {
+			errs["event_types"] = msg
+		}
+		update.EventTypes = eventTypes
+	}
+	if len(errs) > 0 {
+		helpers.ValidationErrorJson(w, errs)
+		return
+	}
+	if input.RotateSecret {
+		secret := webhooks.NewSecret()
+		update.Secret = &secret
+	}
+
+	hook, err := app.store.Webhooks.Update(req.Context(), id, update, app.queueWebhookDelivery)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			helpers.ErrorJson(w, http.StatusNotFound, "webhook not found")
+			return
+		}
+		helpers.ErrorJson(w, http.StatusInternalServerError, "failed to update webhook")
+		return
+	}
+
+	entry := newAuditEntry(req, store.AuditAdminWebhookUpdate, "webhook", strconv.FormatInt(hook.ID, 10))
+	entry.Metadata = map[string]any{
+		"url":           hook.URL,
+		"event_types":   hook.EventTypes,
+		"active":        hook.Active,
+		"rotate_secret": input.RotateSecret,
+	}
+	app.recordAudit(req, entry)
+
+	if !input.RotateSecret {
+		hook.Secret = ""
+	}
+	helpers.WriteJson(w, http.StatusOK, helpers.Envelope{"webhook": hook})
+}
+
+func (app *application) deleteWebhookHandler(w http.ResponseWriter, req *http.Request) {
+	id, err := strconv.ParseInt(req.PathValue("id"), 10, 64)
+	if err != nil {
+		helpers.ErrorJson(w, http.StatusBadRequest, "id must be a number")
+		return
+	}
+
+	if err := app.store.Webhooks.Delete(req.Context(), id); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			helpers.ErrorJson(w, http.StatusNotFound, "webhook not found")
+			return
+		}
+		helpers.ErrorJson(w, http.StatusInternalServerError, "failed to delete webhook")
+		return
+	}
+
+	app.recordAudit(req, newAuditEntry(req, store.AuditAdminWebhookDelete, "webhook", strconv.FormatInt(id, 10)))
+
+	helpers.WriteJson(w, http.StatusOK, helpers.Envelope{"message": "webhook deleted"})
+}
+
+// pingWebhookHandler queues a ping event, so a receiver can be checked
+// without waiting for an issue to change.
+func (app *application) pingWebhookHandler(w http.ResponseWriter, req *http.Request) {
+	id, err := strconv.ParseInt(req.PathValue("id"), 10, 64)
+	if err != nil {
+		helpers.ErrorJson(w, http.StatusBadRequest, "id must be a number")
+		return
+	}
+
+	payload, err := json.Marshal(map[string]any{
+		"type":       webhookPingEvent,
+		"webhook_id": id,
+		"created_at": time.Now(),
+	})
+	if err != nil {
+		helpers.ErrorJson(w, http.StatusInternalServerError, "failed to encode ping")
+		return
+	}
+
+	delivery, err := app.store.Webhooks.Ping(req.Context(), id, payload, telemetry.TraceParent(req.Context()), app.queueWebhookDelivery)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			helpers.ErrorJson(w, http.StatusNotFound, "webhook not found")
+			return
+		}
+		helpers.ErrorJson(w, http.StatusInternalServerError, "failed to queue ping")
+		return
+	}
+
+	helpers.WriteJson(w, http.StatusAccepted, helpers.Envelope{"delivery": delivery})
+}
+
+// listWebhookDeliveriesHandler returns a webhook's delivery log, newest
+// first, with the receiver's last response to each.
+func (app *application) listWebhookDeliveriesHandler(w http.ResponseWriter, req *http.Request) {
+	id, err := strconv.ParseInt(req.PathValue("id"), 10, 64)
+	if err != nil {
+		helpers.ErrorJson(w, http.StatusBadRequest, "id must be a number")
+		return
+	}
+
+	limit, err := helpers.ReadInt(req.URL.Query(), "limit", 50)
+	if err != nil || limit < 1 || limit > 200 {
+		helpers.ValidationErrorJson(w, map[string]string{"limit": "must be between 1 and 200"})
+		return
+	}
+
+	if _, err := app.store.Webhooks.Get(req.Context(), id); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			helpers.ErrorJson(w, http.StatusNotFound, "webhook not found")
+			return
+		}
+		helpers.ErrorJson(w, http.StatusInternalServerError, "failed to get webhook")
+		return
+	}
+
+	deliveries, err := app.store.Webhooks.ListDeliveries(req.Context(), id, limit)
+	if err != nil {
+		helpers.ErrorJson(w, http.StatusInternalServerError, "failed to list deliveries")
+		return
+	}
+
+	helpers.WriteJson(w, http.StatusOK, helpers.Envelope{"deliveries": deliveries})
+}
+
+// redeliverWebhookHandler queues a new delivery of an earlier delivery's
+// event. The original stays in the log as it was.
+func (app *application) redeliverWebhookHandler(w http.ResponseWriter, req *http.Request) {
+	id, err := strconv.ParseInt(req.PathValue("id"), 10, 64)
+	if err != nil {
+		helpers.ErrorJson(w, http.StatusBadRequest, "id must be a number")
+		return
+	}
+	deliveryID, err := strconv.ParseInt(req.PathValue("delivery_id"), 10, 64)
+	if err != nil {
+		helpers.ErrorJson(w, http.StatusBadRequest, "delivery_id must be a number")
+		return
+	}
+
+	delivery, err := app.store.Webhooks.Redeliver(req.Context(), id, deliveryID, telemetry.TraceParent(req.Context()), app.queueWebhookDelivery)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			helpers.ErrorJson(w, http.StatusNotFound, "delivery not found")
+			return
+		}
+		helpers.ErrorJson(w, http.StatusInternalServerError, "failed to redeliver")
+		return
+	}
+
+	entry := newAuditEntry(req, store.AuditAdminWebhookRedeliver, "webhook", strconv.FormatInt(id, 10))
+	entry.Metadata = map[string]any{"delivery_id": deliveryID, "new_delivery_id": delivery.ID}
+	app.recordAudit(req, entry)
+
+	helpers.WriteJson(w, http.StatusAccepted, helpers.Envelope{"delivery": delivery})
+}
+
+func validateWebhookURL(raw string) string {
+	u, err := url.Parse(raw)
+	if raw == "" || err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "must be an http or https URL"
+	}
+	return ""
+}
+
+// validateWebhookEventTypes checks that types is a non-empty list of known
+// event types and returns it without duplicates.
+func validateWebhookEventTypes(types []string) ([]string, string) {
+	if len(types) == 0 {
+		return nil, "must list at least one event type"
+	}
+
+	var valid []string
+	for _, t := range types {
+		if !slices.Contains(webhookEventTypes, events.Type(t)) {
+			return nil, fmt.Sprintf("%q is not an event type", t)
+		}
+		if !slices.Contains(valid, t) {
+			valid = append(valid, t)
+		}
+	}
+	return valid, ""
+}
+
+// enqueueWebhooks queues e for the webhooks subscribed to it. The payload
+// is the event as the stream sends it, always with its data. Deliveries
+// continue the trace of the request that caused the event.
+func (app *application) enqueueWebhooks(ctx context.Context, e *events.Event) {
+	payload, err := json.Marshal(e)
+	if err != nil {
+		logging.FromContext(ctx).Error("encoding event for webhooks", "event_id", e.ID, "error", err)
+		return
+	}
+	if _, err := app.store.Webhooks.Enqueue(ctx, e.ID, string(e.Type), payload, telemetry.TraceParent(ctx), app.queueWebhookDelivery); err != nil {
+		logging.FromContext(ctx).Error("queueing webhook deliveries", "event_id", e.ID, "error", err)
+	}
+}
+
+// queueWebhookDelivery queues the job that sends a delivery, in the
+// transaction that created it. The delivery's id is the job's unique key,
+// so it is never queued twice at once.
+func (app *application) queueWebhookDelivery(ctx context.Context, tx pgx.Tx, deliveryID int64) error {
+	_, err := app.jobs.EnqueueTx(ctx, tx, deliverWebhookArgs{DeliveryID: deliveryID}, jobs.EnqueueOptions{
+		MaxAttempts: app.config.webhooks.maxAttempts,
+		UniqueKey:   strconv.FormatInt(deliveryID, 10),
+	})
+	return err
+}
+
+// deliverWebhook makes one attempt at sending a delivery. A failed attempt
+// is retried by the job queue on the webhook backoff; after the last one
+// the delivery is marked failed and the job is left dead, and retrying the
+// job sends the delivery again.
+func (app *application) deliverWebhook(ctx context.Context, args deliverWebhookArgs) error {
+	cfg := app.config.webhooks
+
+	d, err := app.store.Webhooks.GetDeliveryToSend(ctx, args.DeliveryID)
+	if errors.Is(err, store.ErrNotFound) {
+		// Already sent, or deleted with its webhook.
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !d.Active {
+		// Turning the webhook back on queues the delivery again.
+		logging.FromContext(ctx).Info("webhook disabled; holding delivery", "webhook_id", d.WebhookID, "delivery_id", d.ID)
+		return nil
+	}
+
+	// Each attempt is a span in the trace that queued the delivery. The
+	// HTTP call itself gets a client span from the otelhttp transport, and
+	// the receiver a traceparent header to continue it.
+	ctx, span := telemetry.Tracer().Start(telemetry.WithTraceParent(ctx, d.TraceParent), "webhook.deliver",
+		trace.WithAttributes(
+			attribute.Int64("webhook.id", d.WebhookID),
+			attribute.Int64("webhook.delivery_id", d.ID),
+			attribute.String("webhook.event_type", d.EventType),
+			attribute.Int("webhook.attempt", d.Attempts+1),
+		),
+	)
+	defer span.End()
+
+	res := webhooks.Send(ctx, app.webhookClient, webhooks.Delivery{
+		ID:        d.ID,
+		EventType: d.EventType,
+		URL:       d.URL,
+		Secret:    d.Secret,
+		Payload:   d.Payload,
+	})
+
+	attempt := store.WebhookAttempt{
+		DeliveryID:     d.ID,
+		WebhookID:      d.WebhookID,
+		Succeeded:      res.OK(),
+		ResponseStatus: res.Status,
+		ResponseBody:   res.Body,
+	}
+	switch {
+	case res.Err != nil:
+		attempt.Error = res.Err.Error()
+	case !res.OK():
+		attempt.Error = fmt.Sprintf("receiver responded %d", res.Status)
+	}
+	if attempt.Error != "" {
+		span.SetStatus(codes.Error, attempt.Error)
+	}
+
+	n, maxAttempts := jobs.Attempt(ctx)
+	var retryIn time.Duration
+	if !attempt.Succeeded && n < maxAttempts {
+		retryIn = webhooks.Backoff(n, webhookBackoffBase, webhookBackoffMax)
+		attempt.RetryAt = time.Now().Add(retryIn)
+	}
+
+	disabled, err := app.store.Webhooks.RecordAttempt(ctx, attempt, cfg.disableAfter)
+	if err != nil {
+		return err
+	}
+	if disabled {
+		logging.FromContext(ctx).Warn("webhook disabled after repeated failures", "webhook_id", d.WebhookID, "failures", cfg.disableAfter)
+	}
+
+	switch {
+	case attempt.Succeeded:
+		return nil
+	case retryIn > 0:
+		return jobs.RetryAfter(errors.New(attempt.Error), retryIn)
+	default:
+		return errors.New(attempt.Error)
+	}
+}
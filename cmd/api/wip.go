@@ -0,0 +1,196 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jesusthecreator017/fswithgo/cmd/api/helpers"
+	"github.com/jesusthecreator017/fswithgo/cmd/api/middleware"
+	"github.com/jesusthecreator017/fswithgo/internal/auth"
+	"github.com/jesusthecreator017/fswithgo/internal/store"
+)
+
+// boardWIPHandler returns the limits that apply to the caller on a board
+// (project_id, or their personal board) with current counts.
+func (app *application) boardWIPHandler(w http.ResponseWriter, req *http.Request) {
+	projectID, err := helpers.ReadInt(req.URL.Query(), "project_id", 0)
+	if err != nil {
+		helpers.ValidationErrorJson(w, map[string]string{"project_id": err.Error()})
+		return
+	}
+
+	var board *int64
+	if projectID != 0 {
+		id := int64(projectID)
+		board = &id
+	}
+
+	usage, err := app.store.WIPLimits.Usage(req.Context(), board, middleware.GetUserID(req))
+	if err != nil {
+		helpers.ErrorJson(w, http.StatusInternalServerError, "failed to get wip limits")
+		return
+	}
+
+	helpers.WriteJson(w, http.StatusOK, helpers.Envelope{"wip_limits": usage})
+}
+
+func (app *application) adminListWIPLimitsHandler(w http.ResponseWriter, req *http.Request) {
+	limits, err := app.store.WIPLimits.List(req.Context())
+	if err != nil {
+		helpers.ErrorJson(w, http.StatusInternalServerError, "failed to list wip limits")
+		return
+	}
+
+	helpers.WriteJson(w, http.StatusOK, helpers.Envelope{"wip_limits": limits})
+}
+
+// adminSetWIPLimitHandler creates or replaces the limit for a status in a
+// scope. Leaving out project_id and user_id sets the default for every board.
+func (app *application) adminSetWIPLimitHandler(w http.ResponseWriter, req *http.Request) {
+	var input struct {
+		ProjectID *int64           `json:"project_id"`
+		UserID    *uuid.UUID       `json:"user_id"`
+		Status    store.StatusType `json:"status"`
+		MaxIssues *int32           `json:"max_issues"`
+	}
+
+	if err := helpers.ReadJson(req, &input); err != nil {
+		helpers.ErrorJson(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	errs := make(map[string]string)
+
+	input.Status = store.StatusType(strings.TrimSpace(string(input.Status)))
+	if input.Status == "" {
+		errs["status"] = "must not be blank"
+	}
+
+	if input.MaxIssues == nil {
+		errs["max_issues"] = "is required"
+	} else if *input.MaxIssues < 0 {
+		errs["max_issues"] = "must not be negative"
+	}
+
+	if input.ProjectID != nil {
+		_, err := app.store.Projects.GetByID(req.Context(), *input.ProjectID)
+		if errors.Is(err, store.ErrNotFound) {
+			errs["project_id"] = "project does not exist"
+		} else if err != nil {
+			helpers.ErrorJson(w, http.StatusInternalServerError, "failed to get project")
+			return
+		} else if input.Status != "" {
+			workflow, err := app.store.Workflows.Get(req.Context(), input.ProjectID)
+			if err != nil {
+				helpers.ErrorJson(w, http.StatusInternalServerError, "failed to get workflow")
+				return
+			}
+			if workflow.Status(input.Status) == nil {
+				errs["status"] = "is not in the project's workflow"
+			}
+		}
+	}
+
+	if input.UserID != nil {
+		_, err := app.store.Users.GetByID(req.Context(), *input.UserID)
+		if errors.Is(err, store.ErrNotFound) {
+			errs["user_id"] = "user does not exist"
+		} else if err != nil {
+			helpers.ErrorJson(w, http.StatusInternalServerError, "failed to get user")
+			return
+		}
+	}
+
+	if len(errs) > 0 {
+		helpers.ValidationErrorJson(w, errs)
+		return
+	}
+
+	limit := &store.WIPLimit{
+		ProjectID: input.ProjectID,
+		UserID:    input.UserID,
+		Status:    input.Status,
+		MaxIssues: *input.MaxIssues,
+	}
+
+	if err := app.store.WIPLimits.Set(req.Context(), limit); err != nil {
+		helpers.ErrorJson(w, http.StatusInternalServerError, "failed to set wip limit")
+		return
+	}
+
+	entry := newAuditEntry(req, store.AuditAdminWIPLimitSet, "wip_limit", strconv.FormatInt(limit.ID, 10))
+	entry.Metadata = map[string]any{"status": limit.Status, "max_issues": limit.MaxIssues}
+	app.recordAudit(req, entry)
+
+	helpers.WriteJson(w, http.StatusOK, helpers.Envelope{"wip_limit": limit})
+}
+
+func (app *application) adminDeleteWIPLimitHandler(w http.ResponseWriter, req *http.Request) {
+	id, err := strconv.ParseInt(req.PathValue("id"), 10, 64)
+	if err != nil {
+		helpers.ErrorJson(w, http.StatusBadRequest, "id must be a number")
+		return
+	}
+
+	if err := app.store.WIPLimits.Delete(req.Context(), id); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			helpers.ErrorJson(w, http.StatusNotFound, "wip limit not found")
+			return
+		}
+		helpers.ErrorJson(w, http.StatusInternalServerError, "failed to delete wip limit")
+		return
+	}
+
+	app.recordAudit(req, newAuditEntry(req, store.AuditAdminWIPLimitDelete, "wip_limit", strconv.FormatInt(id, 10)))
+
+	helpers.WriteJson(w, http.StatusOK, helpers.Envelope{"message": "wip limit deleted"})
+}
+
+// allowWIPOverride rejects force=true from non-admins. It writes the error
+// response and returns false if the request must stop.
+func allowWIPOverride(w http.ResponseWriter, req *http.Request, force bool) bool {
+	if force && !auth.HasPermission(auth.Permission(middleware.GetPermissions(req)), auth.PermAdmin) {
+		helpers.ErrorJson(w, http.StatusForbidden, "only admins can override WIP limits")
+		return false
+	}
+	return true
+}
+
+// wipLimitErrorJson writes a 409 naming the limit if err is a
+// *store.WIPLimitError, and reports whether it did.
+func wipLimitErrorJson(w http.ResponseWriter, err error) bool {
+	var wipErr *store.WIPLimitError
+	if !errors.As(err, &wipErr) {
+		return false
+	}
+
+	helpers.WriteJson(w, http.StatusConflict, helpers.Envelope{
+		"error": wipErr.Error(),
+		"wip_limit": store.WIPUsage{
+			WIPLimit: wipErr.Limit,
+			Current:  wipErr.Current,
+		},
+	})
+	return true
+}
+
+func (app *application) recordWIPOverride(req *http.Request, issue *store.Issue) {
+	entry := newAuditEntry(req, store.AuditIssueWIPOverride, "issue", strconv.FormatInt(issue.ID, 10))
+	entry.Metadata = map[string]any{"status": issue.Status}
+	app.recordAudit(req, entry)
+}
+
+// recordOverrides audits each check a status change asked to skip.
+func (app *application) recordOverrides(req *http.Request, issue *store.Issue, overrides store.Overrides) {
+	if overrides.WIPLimits {
+		app.recordWIPOverride(req, issue)
+	}
+	if overrides.Blockers {
+		entry := newAuditEntry(req, store.AuditIssueBlockerOverride, "issue", strconv.FormatInt(issue.ID, 10))
+		entry.Metadata = map[string]any{"status": issue.Status}
+		app.recordAudit(req, entry)
+	}
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/jesusthecreator017/fswithgo/cmd/api/helpers"
+	"github.com/jesusthecreator017/fswithgo/internal/store"
+)
+
+// defaultWorkflowHandler returns the workflow used by issues outside a project.
+func (app *application) defaultWorkflowHandler(w http.ResponseWriter, req *http.Request) {
+	workflow, err := app.store.Workflows.Get(req.Context(), nil)
+	if err != nil {
+		helpers.ErrorJson(w, http.StatusInternalServerError, "failed to get workflow")
+		return
+	}
+
+	helpers.WriteJson(w, http.StatusOK, helpers.Envelope{"workflow": workflow})
+}
+
+func (app *application) getProjectWorkflowHandler(w http.ResponseWriter, req *http.Request) {
+	project, ok := app.readProject(w, req)
+	if !ok {
+		return
+	}
+
+	workflow, err := app.store.Workflows.Get(req.Context(), &project.ID)
+	if err != nil {
+		helpers.ErrorJson(w, http.StatusInternalServerError, "failed to get workflow")
+		return
+	}
+
+	helpers.WriteJson(w, http.StatusOK, helpers.Envelope{"workflow": workflow})
+}
+
+func (app *application) createWorkflowStatusHandler(w http.ResponseWriter, req *http.Request) {
+	project, ok := app.readManagedProject(w, req)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Name     string               `json:"name"`
+		Category store.StatusCategory `json:"category"`
+		Position int32                `json:"position"`
+	}
+
+	if err := helpers.ReadJson(req, &input); err != nil {
+		helpers.ErrorJson(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	errs := make(map[string]string)
+
+	input.Name = strings.TrimSpace(input.Name)
+	if input.Name == "" {
+		errs["name"] = "must not be blank"
+	} else if len(input.Name) > 50 {
+		errs["name"] = "must not be more than 50 characters"
+	}
+
+	if !input.Category.Valid() {
+		errs["category"] = "must be one of: todo, in_progress, done"
+	}
+
+	if len(errs) > 0 {
+		helpers.ValidationErrorJson(w, errs)
+		return
+	}
+
+	status := &store.WorkflowStatus{
+		ProjectID: &project.ID,
+		Name:      store.StatusType(input.Name),
+		Category:  input.Category,
+		Position:  input.Position,
+	}
+
+	if err := app.store.Workflows.CreateStatus(req.Context(), status); err != nil {
+		if errors.Is(err, store.ErrDuplicateStatus) {
+			helpers.ErrorJson(w, http.StatusConflict, "status already exists")
+			return
+		}
+		helpers.ErrorJson(w, http.StatusInternalServerError, "failed to create status")
+		return
+	}
+
+	helpers.WriteJson(w, http.StatusCreated, helpers.Envelope{"status": status})
+}
+
+// updateWorkflowStatusHandler changes a status's category or position.
+// Renaming isn't supported: add a new status, move the issues, then delete
+// the old one.
+func (app *application) updateWorkflowStatusHandler(w http.ResponseWriter, req *http.Request) {
+	project, ok := app.readManagedProject(w, req)
+	if !ok {
+		return
+	}
+
+	statusID, err := strconv.ParseInt(req.PathValue("status_id"), 10, 64)
+	if err != nil {
+		helpers.ErrorJson(w, http.StatusBadRequest, "status_id must be a number")
+		return
+	}
+
+	workflow, err := app.store.Workflows.Get(req.Context(), &project.ID)
+	if err != nil {
+		helpers.ErrorJson(w, http.StatusInternalServerError, "failed to get workflow")
+		return
+	}
+
+	var status *store.WorkflowStatus
+	for _, s := range workflow.Statuses {
+		if s.ID == statusID {
+			status = s
+		}
+	}
+	if status == nil {
+		helpers.ErrorJson(w, http.StatusNotFound, "status not found")
+		return
+	}
+
+	var input struct {
+		Category *store.StatusCategory `json:"category"`
+		Position *int32                `json:"position"`
+	}
+
+	if err := helpers.ReadJson(req, &input); err != nil {
+		helpers.ErrorJson(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	errs := make(map[string]string)
+
+	if input.Category != nil {
+		if !input.Category.Valid() {
+			errs["category"] = "must be one of: todo, in_progress, done"
+		} else {
+			status.Category = *input.Category
+		}
+	}
+	if input.Position != nil {
+		status.Position = *input.Position
+	}
+
+	if len(errs) > 0 {
+		helpers.ValidationErrorJson(w, errs)
+		return
+	}
+
+	if err := app.store.Workflows.UpdateStatus(req.Context(), status); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			helpers.ErrorJson(w, http.StatusNotFound, "status not found")
+			return
+		}
+		helpers.ErrorJson(w, http.StatusInternalServerError, "failed to update status")
+		return
+	}
+
+	helpers.WriteJson(w, http.StatusOK, helpers.Envelope{"status": status})
+}
+
+func (app *application) deleteWorkflowStatusHandler(w http.ResponseWriter, req *http.Request) {
+	project, ok := app.readManagedProject(w, req)
+	if !ok {
+		return
+	}
+
+	statusID, err := strconv.ParseInt(req.PathValue("status_id"), 10, 64)
+	if err != nil {
+		helpers.ErrorJson(w, http.StatusBadRequest, "status_id must be a number")
+		return
+	}
+
+	err = app.store.Workflows.DeleteStatus(req.Context(), project.ID, statusID)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			helpers.ErrorJson(w, http.StatusNotFound, "status not found")
+		case errors.Is(err, store.ErrStatusInUse), errors.Is(err, store.ErrLastStatus):
+			helpers.ErrorJson(w, http.StatusConflict, err.Error())
+		default:
+			helpers.ErrorJson(w, http.StatusInternalServerError, "failed to delete status")
+		}
+		return
+	}
+
+	helpers.WriteJson(w, http.StatusOK, helpers.Envelope{"message": "status deleted"})
+}
+
+// setWorkflowTransitionsHandler replaces the project's transition graph.
+// Any move not listed is rejected afterwards.
+func (app *application) setWorkflowTransitionsHandler(w http.ResponseWriter, req *http.Request) {
+	project, ok := app.readManagedProject(w, req)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Transitions []store.WorkflowTransition `json:"transitions"`
+	}
+
+	if err := helpers.ReadJson(req, &input); err != nil {
+		helpers.ErrorJson(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err := app.store.Workflows.SetTransitions(req.Context(), project.ID, input.Transitions)
+	if err != nil {
+		if errors.Is(err, store.ErrUnknownStatus) {
+			helpers.ValidationErrorJson(w, map[string]string{"transitions": "must only name statuses in the project's workflow"})
+			return
+		}
+		helpers.ErrorJson(w, http.StatusInternalServerError, "failed to update transitions")
+		return
+	}
+
+	workflow, err := app.store.Workflows.Get(req.Context(), &project.ID)
+	if err != nil {
+		helpers.ErrorJson(w, http.StatusInternalServerError, "failed to get workflow")
+		return
+	}
+
+	helpers.WriteJson(w, http.StatusOK, helpers.Envelope{"workflow": workflow})
+}
+
+// readManagedProject is readProject plus the owner-or-admin check.
+func (app *application) readManagedProject(w http.ResponseWriter, req *http.Request) (*store.Project, bool) {
+	project, ok := app.readProject(w, req)
+	if !ok {
+		return nil, false
+	}
+
+	if !canManageProject(req, project) {
+		helpers.ErrorJson(w, http.StatusForbidden, "only the project owner or an admin can change its workflow")
+		return nil, false
+	}
+
+	return project, true
+}
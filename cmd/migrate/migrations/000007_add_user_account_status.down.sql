@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_users_active_admins;
+
+ALTER TABLE users
+    DROP COLUMN IF EXISTS password_reset_required,
+    DROP COLUMN IF EXISTS suspended_at;
@@ -0,0 +1,15 @@
+-- 000007_add_user_account_status.up.sql
+--
+-- Account state managed by admins.
+-- suspended_at is NULL for active accounts; GlobalAuth rejects tokens for
+-- users where it is set. password_reset_required forces the user to change
+-- their password before any other protected route will accept them.
+
+ALTER TABLE users
+    ADD COLUMN suspended_at TIMESTAMPTZ,
+    ADD COLUMN password_reset_required BOOLEAN NOT NULL DEFAULT false;
+
+-- Speeds up the "last admin" guardrail, which counts active admins.
+CREATE INDEX IF NOT EXISTS idx_users_active_admins
+    ON users (id)
+    WHERE permissions & 4 = 4 AND suspended_at IS NULL;
@@ -0,0 +1,25 @@
+-- 000008_create_audit_log.up.sql
+--
+-- Append-only record of security-relevant actions.
+-- actor_id is the identity the request ran as. When an admin is
+-- impersonating a user, actor_id is that user and impersonator_id is the
+-- admin, so every write made "as" someone else stays attributable.
+-- There are no foreign keys: entries must outlive the users they mention.
+
+CREATE TABLE IF NOT EXISTS audit_log (
+    id              BIGSERIAL PRIMARY KEY,
+    occurred_at     TIMESTAMPTZ NOT NULL DEFAULT now(),
+    actor_id        UUID,
+    impersonator_id UUID,
+    action          TEXT NOT NULL,
+    target_type     TEXT NOT NULL DEFAULT '',
+    target_id       TEXT NOT NULL DEFAULT '',
+    ip              TEXT NOT NULL DEFAULT '',
+    request_id      TEXT NOT NULL DEFAULT '',
+    metadata        JSONB NOT NULL DEFAULT '{}'
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_occurred_at ON audit_log (occurred_at);
+CREATE INDEX IF NOT EXISTS idx_audit_log_impersonator
+    ON audit_log (impersonator_id)
+    WHERE impersonator_id IS NOT NULL;
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_audit_log_target;
+DROP INDEX IF EXISTS idx_audit_log_action;
+DROP INDEX IF EXISTS idx_audit_log_actor;
+
+DROP TRIGGER IF EXISTS audit_log_no_update ON audit_log;
+DROP FUNCTION IF EXISTS audit_log_reject_update();
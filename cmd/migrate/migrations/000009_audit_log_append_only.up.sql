@@ -0,0 +1,19 @@
+-- 000009_audit_log_append_only.up.sql
+--
+-- Make audit_log append-only: rows may be inserted, and the retention job
+-- may delete rows once they have been archived, but nothing may rewrite history.
+
+CREATE OR REPLACE FUNCTION audit_log_reject_update() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_log_no_update
+    BEFORE UPDATE ON audit_log
+    FOR EACH ROW EXECUTE FUNCTION audit_log_reject_update();
+
+-- Indexes for the admin query API filters.
+CREATE INDEX IF NOT EXISTS idx_audit_log_actor ON audit_log (actor_id, id);
+CREATE INDEX IF NOT EXISTS idx_audit_log_action ON audit_log (action, id);
+CREATE INDEX IF NOT EXISTS idx_audit_log_target ON audit_log (target_type, target_id, id);
@@ -0,0 +1,7 @@
+DROP TRIGGER IF EXISTS issues_status_history ON issues;
+DROP FUNCTION IF EXISTS record_issue_status_event();
+
+DROP INDEX IF EXISTS idx_users_created_at;
+DROP INDEX IF EXISTS idx_issues_created_at;
+
+DROP TABLE IF EXISTS issue_status_events;
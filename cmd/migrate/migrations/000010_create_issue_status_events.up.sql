@@ -0,0 +1,48 @@
+-- 000010_create_issue_status_events.up.sql
+--
+-- Status history for analytics. A trigger on issues writes one row when an
+-- issue is created and one every time its status changes, so every write
+-- path is captured without the application having to remember to log it.
+-- from_status is NULL for the creation event.
+
+CREATE TABLE IF NOT EXISTS issue_status_events (
+    id          BIGSERIAL PRIMARY KEY,
+    issue_id    BIGINT NOT NULL REFERENCES issues(id) ON DELETE CASCADE,
+    from_status status_type,
+    to_status   status_type NOT NULL,
+    changed_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_issue_status_events_to_status
+    ON issue_status_events (to_status, changed_at);
+CREATE INDEX IF NOT EXISTS idx_issue_status_events_issue
+    ON issue_status_events (issue_id, changed_at);
+
+-- Range scans for the created/signup series.
+CREATE INDEX IF NOT EXISTS idx_issues_created_at ON issues (created_at);
+CREATE INDEX IF NOT EXISTS idx_users_created_at ON users (created_at);
+
+CREATE OR REPLACE FUNCTION record_issue_status_event() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        INSERT INTO issue_status_events (issue_id, from_status, to_status, changed_at)
+        VALUES (NEW.id, NULL, NEW.status, NEW.created_at);
+    ELSIF NEW.status IS DISTINCT FROM OLD.status THEN
+        INSERT INTO issue_status_events (issue_id, from_status, to_status, changed_at)
+        VALUES (NEW.id, OLD.status, NEW.status, now());
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER issues_status_history
+    AFTER INSERT OR UPDATE OF status ON issues
+    FOR EACH ROW EXECUTE FUNCTION record_issue_status_event();
+
+-- Backfill what we can infer for existing issues: they were created as
+-- Incomplete, and anything that has moved on did so at updated_at.
+INSERT INTO issue_status_events (issue_id, from_status, to_status, changed_at)
+SELECT id, NULL, 'Incomplete', created_at FROM issues;
+
+INSERT INTO issue_status_events (issue_id, from_status, to_status, changed_at)
+SELECT id, 'Incomplete', status, updated_at FROM issues WHERE status <> 'Incomplete';
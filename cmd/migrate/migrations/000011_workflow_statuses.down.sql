@@ -0,0 +1,25 @@
+-- Going back to the enum only works while every issue uses one of its
+-- three labels; custom statuses have to be moved off first.
+CREATE TYPE status_type AS ENUM ('Incomplete', 'In-Progress', 'Complete');
+
+DROP TRIGGER IF EXISTS issues_status_history ON issues;
+
+ALTER TABLE issue_status_events
+    ALTER COLUMN from_status TYPE status_type USING from_status::status_type,
+    ALTER COLUMN to_status TYPE status_type USING to_status::status_type;
+
+ALTER TABLE issues ALTER COLUMN status DROP DEFAULT;
+ALTER TABLE issues ALTER COLUMN status TYPE status_type USING status::status_type;
+ALTER TABLE issues ALTER COLUMN status SET DEFAULT 'Incomplete';
+
+CREATE TRIGGER issues_status_history
+    AFTER INSERT OR UPDATE OF status ON issues
+    FOR EACH ROW EXECUTE FUNCTION record_issue_status_event();
+
+DROP TABLE IF EXISTS workflow_transitions;
+DROP TABLE IF EXISTS workflow_statuses;
+
+DROP INDEX IF EXISTS idx_issues_project;
+ALTER TABLE issues DROP COLUMN IF EXISTS project_id;
+
+DROP TABLE IF EXISTS projects;
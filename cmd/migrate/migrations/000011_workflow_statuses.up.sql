@@ -0,0 +1,73 @@
+-- 000011_workflow_statuses.up.sql
+--
+-- Moves issue statuses off the status_type enum and into data, so each
+-- project can define its own board columns and which moves between them
+-- are allowed.
+--
+-- Statuses with project_id NULL are the default workflow. It is used by
+-- issues that don't belong to a project and is copied into every new
+-- project. It is seeded with the three statuses the enum had, with every
+-- transition allowed, so existing issues and clients behave as before.
+
+CREATE TABLE IF NOT EXISTS projects (
+    id         BIGSERIAL PRIMARY KEY,
+    name       TEXT NOT NULL,
+    owner_id   UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+ALTER TABLE issues
+    ADD COLUMN project_id BIGINT REFERENCES projects(id) ON DELETE CASCADE;
+
+CREATE INDEX IF NOT EXISTS idx_issues_project ON issues (project_id);
+
+-- category groups statuses for reporting: 'done' statuses count as closed.
+CREATE TABLE IF NOT EXISTS workflow_statuses (
+    id         BIGSERIAL PRIMARY KEY,
+    project_id BIGINT REFERENCES projects(id) ON DELETE CASCADE,
+    name       TEXT NOT NULL,
+    category   TEXT NOT NULL CHECK (category IN ('todo', 'in_progress', 'done')),
+    position   INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE NULLS NOT DISTINCT (project_id, name)
+);
+
+-- A move from one status to another is allowed only if a row exists here.
+-- Both ends always belong to the same workflow (enforced by the API).
+CREATE TABLE IF NOT EXISTS workflow_transitions (
+    from_status_id BIGINT NOT NULL REFERENCES workflow_statuses(id) ON DELETE CASCADE,
+    to_status_id   BIGINT NOT NULL REFERENCES workflow_statuses(id) ON DELETE CASCADE,
+    PRIMARY KEY (from_status_id, to_status_id),
+    CHECK (from_status_id <> to_status_id)
+);
+
+INSERT INTO workflow_statuses (project_id, name, category, position) VALUES
+    (NULL, 'Incomplete',  'todo',        0),
+    (NULL, 'In-Progress', 'in_progress', 1),
+    (NULL, 'Complete',    'done',        2);
+
+INSERT INTO workflow_transitions (from_status_id, to_status_id)
+SELECT f.id, t.id
+FROM workflow_statuses f
+JOIN workflow_statuses t ON t.project_id IS NULL AND t.id <> f.id
+WHERE f.project_id IS NULL;
+
+-- Convert the enum columns to TEXT in place; enum labels cast to their
+-- names, so no rows change. The history trigger depends on issues.status
+-- and has to be dropped around the type change.
+DROP TRIGGER IF EXISTS issues_status_history ON issues;
+
+ALTER TABLE issues ALTER COLUMN status DROP DEFAULT;
+ALTER TABLE issues ALTER COLUMN status TYPE TEXT USING status::text;
+ALTER TABLE issues ALTER COLUMN status SET DEFAULT 'Incomplete';
+
+ALTER TABLE issue_status_events
+    ALTER COLUMN from_status TYPE TEXT USING from_status::text,
+    ALTER COLUMN to_status TYPE TEXT USING to_status::text;
+
+CREATE TRIGGER issues_status_history
+    AFTER INSERT OR UPDATE OF status ON issues
+    FOR EACH ROW EXECUTE FUNCTION record_issue_status_event();
+
+DROP TYPE IF EXISTS status_type;
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_issues_user_rank;
+DROP INDEX IF EXISTS idx_issues_project_rank;
+
+ALTER TABLE issues DROP COLUMN IF EXISTS rank;
@@ -0,0 +1,29 @@
+-- 000012_add_issue_rank.up.sql
+--
+-- Manual card order within a board column. rank is a fractional sort key
+-- (see internal/rank) compared byte-wise, hence COLLATE "C". A board is a
+-- project, or a user's issues outside any project.
+
+ALTER TABLE issues ADD COLUMN rank TEXT COLLATE "C";
+
+-- Keep today's order (newest first) in every column. The keys are decimal
+-- row numbers with an 'i' appended, which are valid rank keys and leave
+-- room on both sides.
+UPDATE issues i
+SET rank = r.rank
+FROM (
+    SELECT id,
+           to_char(row_number() OVER (
+               PARTITION BY project_id, CASE WHEN project_id IS NULL THEN user_id END, status
+               ORDER BY created_at DESC, id DESC
+           ), 'FM00000000') || 'i' AS rank
+    FROM issues
+) r
+WHERE r.id = i.id;
+
+ALTER TABLE issues ALTER COLUMN rank SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_issues_project_rank
+    ON issues (project_id, status, rank) WHERE project_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_issues_user_rank
+    ON issues (user_id, status, rank) WHERE project_id IS NULL;
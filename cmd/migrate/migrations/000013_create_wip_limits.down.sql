@@ -0,0 +1 @@
+DROP TABLE IF EXISTS wip_limits;
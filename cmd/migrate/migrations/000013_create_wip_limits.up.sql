@@ -0,0 +1,24 @@
+-- 000013_create_wip_limits.up.sql
+--
+-- Work-in-progress caps on a status column.
+--
+--   project_id  user_id   applies to
+--   ----------  --------  ------------------------------------------------
+--   NULL        NULL      every board's column (default; a project limit
+--                         on the same status replaces it for that project)
+--   set         NULL      the project's column
+--   NULL        set       the user's issues in that status on any board
+--   set         set       the user's issues in that status in the project
+
+CREATE TABLE IF NOT EXISTS wip_limits (
+    id         BIGSERIAL PRIMARY KEY,
+    project_id BIGINT REFERENCES projects(id) ON DELETE CASCADE,
+    user_id    UUID REFERENCES users(id) ON DELETE CASCADE,
+    status     TEXT NOT NULL,
+    max_issues INTEGER NOT NULL CHECK (max_issues >= 0),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE NULLS NOT DISTINCT (project_id, user_id, status)
+);
+
+CREATE INDEX IF NOT EXISTS idx_wip_limits_status ON wip_limits (status);
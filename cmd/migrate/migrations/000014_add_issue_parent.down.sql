@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_issues_parent;
+
+ALTER TABLE issues
+    DROP CONSTRAINT IF EXISTS chk_issues_parent_not_self,
+    DROP COLUMN IF EXISTS auto_complete,
+    DROP COLUMN IF EXISTS parent_id;
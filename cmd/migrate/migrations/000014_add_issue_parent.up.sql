@@ -0,0 +1,16 @@
+-- 000014_add_issue_parent.up.sql
+--
+-- Sub-tasks. Cycles and the depth limit are enforced by the API, which
+-- serializes hierarchy changes with an advisory lock. Deleting a parent
+-- through the API either deletes its subtree or orphans the children;
+-- ON DELETE SET NULL is the orphan case for any other delete path.
+--
+-- auto_complete moves the issue to its workflow's first done status once
+-- every child is in a done status.
+
+ALTER TABLE issues
+    ADD COLUMN parent_id BIGINT REFERENCES issues(id) ON DELETE SET NULL,
+    ADD COLUMN auto_complete BOOLEAN NOT NULL DEFAULT false,
+    ADD CONSTRAINT chk_issues_parent_not_self CHECK (parent_id <> id);
+
+CREATE INDEX IF NOT EXISTS idx_issues_parent ON issues (parent_id);
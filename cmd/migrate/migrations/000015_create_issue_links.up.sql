@@ -0,0 +1,20 @@
+-- 000015_create_issue_links.up.sql
+--
+-- Typed links between issues. Every link is stored from both ends: "A
+-- blocks B" is the row (A, B, blocks) plus its inverse (B, A, blocked_by),
+-- so listing an issue's links is a scan on source_id. The API writes and
+-- deletes the two rows together and keeps "blocks" acyclic.
+
+CREATE TABLE IF NOT EXISTS issue_links (
+    id         BIGSERIAL PRIMARY KEY,
+    source_id  BIGINT NOT NULL REFERENCES issues(id) ON DELETE CASCADE,
+    target_id  BIGINT NOT NULL REFERENCES issues(id) ON DELETE CASCADE,
+    link_type  TEXT NOT NULL CHECK (link_type IN (
+        'blocks', 'blocked_by', 'duplicates', 'duplicated_by', 'relates_to'
+    )),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (source_id, target_id, link_type),
+    CHECK (source_id <> target_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_issue_links_target ON issue_links (target_id);
@@ -0,0 +1,23 @@
+-- 000016_create_attachments.up.sql
+--
+-- Files attached to issues. The bytes live in blob storage under
+-- storage_key; this table holds what the API needs to list and serve them.
+--
+-- Deleting an issue (or its owner) sets issue_id to NULL rather than
+-- cascading, so the attachment sweeper can still find the row and remove
+-- its blob before deleting it.
+
+CREATE TABLE IF NOT EXISTS attachments (
+    id           BIGSERIAL PRIMARY KEY,
+    issue_id     BIGINT REFERENCES issues(id) ON DELETE SET NULL,
+    user_id      UUID REFERENCES users(id) ON DELETE SET NULL,
+    filename     TEXT NOT NULL,
+    content_type TEXT NOT NULL,
+    size_bytes   BIGINT NOT NULL CHECK (size_bytes >= 0),
+    sha256       TEXT NOT NULL,
+    storage_key  TEXT NOT NULL UNIQUE,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_attachments_issue ON attachments (issue_id);
+CREATE INDEX IF NOT EXISTS idx_attachments_orphaned ON attachments (id) WHERE issue_id IS NULL;
@@ -0,0 +1 @@
+DROP SEQUENCE IF EXISTS event_id_seq;
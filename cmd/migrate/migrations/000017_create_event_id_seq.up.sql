@@ -0,0 +1,7 @@
+-- 000017_create_event_id_seq.up.sql
+--
+-- Ids for real-time events. Every API instance draws from the same
+-- sequence, so a client can resume its stream with Last-Event-ID on
+-- whichever instance it reconnects to.
+
+CREATE SEQUENCE IF NOT EXISTS event_id_seq;
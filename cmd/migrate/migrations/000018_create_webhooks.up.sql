@@ -0,0 +1,45 @@
+-- 000018_create_webhooks.up.sql
+--
+-- Outbound webhooks. Every issue event is copied into webhook_deliveries
+-- once per subscribed webhook; that table is both the queue the delivery
+-- worker claims rows from and the log admins read. A failed attempt pushes
+-- next_attempt_at back; after the last attempt the row is marked failed.
+--
+-- consecutive_failures counts failed attempts since the webhook's last
+-- success. Past a limit the webhook is disabled, and its pending
+-- deliveries wait until it is turned back on.
+
+CREATE TABLE IF NOT EXISTS webhooks (
+    id                   BIGSERIAL PRIMARY KEY,
+    user_id              UUID REFERENCES users(id) ON DELETE SET NULL,
+    url                  TEXT NOT NULL,
+    secret               TEXT NOT NULL,
+    event_types          TEXT[] NOT NULL,
+    active               BOOLEAN NOT NULL DEFAULT true,
+    consecutive_failures INTEGER NOT NULL DEFAULT 0,
+    disabled_at          TIMESTAMPTZ,
+    created_at           TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at           TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id              BIGSERIAL PRIMARY KEY,
+    webhook_id      BIGINT NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE,
+    event_id        BIGINT NOT NULL,
+    event_type      TEXT NOT NULL,
+    payload         JSONB NOT NULL,
+    status          TEXT NOT NULL DEFAULT 'pending'
+                    CHECK (status IN ('pending', 'succeeded', 'failed')),
+    attempts        INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    response_status INTEGER,
+    response_body   TEXT,
+    error           TEXT,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT now(),
+    completed_at    TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due
+    ON webhook_deliveries (next_attempt_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_webhook
+    ON webhook_deliveries (webhook_id, id DESC);
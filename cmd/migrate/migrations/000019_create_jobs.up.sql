@@ -0,0 +1,37 @@
+-- 000019_create_jobs.up.sql
+--
+-- Background jobs (internal/jobs). Workers claim due pending jobs with
+-- FOR UPDATE SKIP LOCKED and hold them until locked_until; a running job
+-- whose lock has lapsed belonged to a worker that died, and is claimed
+-- again. A job that fails max_attempts times, or fails permanently, is
+-- left dead for an admin to inspect and retry.
+--
+-- unique_key stops a second copy of a job from being queued while the
+-- first is still pending or running.
+
+CREATE TABLE IF NOT EXISTS jobs (
+    id           BIGSERIAL PRIMARY KEY,
+    kind         TEXT NOT NULL,
+    args         JSONB NOT NULL DEFAULT '{}',
+    status       TEXT NOT NULL DEFAULT 'pending'
+                 CHECK (status IN ('pending', 'running', 'succeeded', 'dead')),
+    attempts     INTEGER NOT NULL DEFAULT 0,
+    max_attempts INTEGER NOT NULL DEFAULT 5,
+    run_at       TIMESTAMPTZ NOT NULL DEFAULT now(),
+    unique_key   TEXT,
+    last_error   TEXT,
+    locked_until TIMESTAMPTZ,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+    finished_at  TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_jobs_due
+    ON jobs (run_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_jobs_locked
+    ON jobs (locked_until) WHERE status = 'running';
+CREATE INDEX IF NOT EXISTS idx_jobs_status
+    ON jobs (status, id DESC);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_jobs_unique_key
+    ON jobs (kind, unique_key)
+    WHERE unique_key IS NOT NULL AND status IN ('pending', 'running');
@@ -0,0 +1,23 @@
+-- 000020_create_scheduled_task_runs.up.sql
+--
+-- History of scheduled maintenance tasks (internal/schedule). Replicas
+-- take a Postgres advisory lock per task, so one runs it at a time, and
+-- record each run here; the unique (task, scheduled_for) pair stops a
+-- replica whose clock runs late from repeating a run another one has
+-- already done.
+
+CREATE TABLE IF NOT EXISTS scheduled_task_runs (
+    id            BIGSERIAL PRIMARY KEY,
+    task          TEXT NOT NULL,
+    scheduled_for TIMESTAMPTZ NOT NULL,
+    host          TEXT NOT NULL,
+    status        TEXT NOT NULL DEFAULT 'running'
+                  CHECK (status IN ('running', 'succeeded', 'failed')),
+    error         TEXT,
+    started_at    TIMESTAMPTZ NOT NULL DEFAULT now(),
+    finished_at   TIMESTAMPTZ,
+    UNIQUE (task, scheduled_for)
+);
+
+CREATE INDEX IF NOT EXISTS idx_scheduled_task_runs_task
+    ON scheduled_task_runs (task, id DESC);
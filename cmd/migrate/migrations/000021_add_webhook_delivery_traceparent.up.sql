@@ -0,0 +1,7 @@
+-- 000021_add_webhook_delivery_traceparent.up.sql
+--
+-- Keeps the W3C traceparent of the request that queued a delivery, so the
+-- delivery continues that trace when it is sent.
+
+ALTER TABLE webhook_deliveries
+    ADD COLUMN traceparent TEXT;
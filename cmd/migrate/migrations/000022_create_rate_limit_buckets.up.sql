@@ -0,0 +1,16 @@
+-- 000022_create_rate_limit_buckets.up.sql
+--
+-- Token buckets for the Postgres rate limit backend (internal/ratelimit),
+-- one per client and policy, so limits hold across replicas. A bucket
+-- that hasn't been touched for its policy's window is full again and is
+-- pruned. Losing the table in a crash only resets the limits, so it isn't
+-- written to the WAL.
+
+CREATE UNLOGGED TABLE IF NOT EXISTS rate_limit_buckets (
+    key        TEXT PRIMARY KEY,
+    tokens     DOUBLE PRECISION NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_rate_limit_buckets_updated_at
+    ON rate_limit_buckets (updated_at);
@@ -0,0 +1,5 @@
+DELETE FROM jobs
+WHERE kind = 'webhook.deliver' AND status IN ('pending', 'running');
+
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due
+    ON webhook_deliveries (next_attempt_at) WHERE status = 'pending';
@@ -0,0 +1,15 @@
+-- 000023_deliver_webhooks_as_jobs.up.sql
+--
+-- Webhook deliveries are now sent by webhook.deliver jobs rather than a
+-- worker of their own, so webhook_deliveries is only the log and no longer
+-- needs its queue index. Deliveries still pending get a job each, run when
+-- they were next due, with the attempts they have left.
+
+DROP INDEX IF EXISTS idx_webhook_deliveries_due;
+
+INSERT INTO jobs (kind, args, max_attempts, run_at, unique_key)
+SELECT 'webhook.deliver', jsonb_build_object('delivery_id', id),
+       GREATEST(8 - attempts, 1), next_attempt_at, id::text
+FROM webhook_deliveries
+WHERE status = 'pending'
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,33 @@
+// Package migrations embeds the schema migrations, so the API can tell
+// which schema version it was built for.
+package migrations
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"strconv"
+	"strings"
+)
+
+//go:embed *.sql
+var files embed.FS
+
+// Latest returns the version of the newest migration.
+func Latest() (uint, error) {
+	names, err := fs.Glob(files, "*.up.sql")
+	if err != nil {
+		return 0, err
+	}
+
+	var latest uint
+	for _, name := range names {
+		prefix, _, _ := strings.Cut(name, "_")
+		v, err := strconv.ParseUint(prefix, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("migration %s: bad version", name)
+		}
+		latest = max(latest, uint(v))
+	}
+	return latest, nil
+}
@@ -11,16 +11,53 @@ import (
 type CustomClaims struct {
 	jwt.RegisteredClaims
 	Permissions int32 `json:"permissions"`
+	// Actor is only set on impersonation tokens. It names the admin acting
+	// as the token's subject (RFC 8693 "act" claim).
+	Actor *ActorClaim `json:"act,omitempty"`
+}
+
+type ActorClaim struct {
+	Subject string `json:"sub"`
+}
+
+// ActorID returns the impersonating admin's id, or ok=false for a normal token.
+func (c *CustomClaims) ActorID() (id uuid.UUID, ok bool) {
+	if c.Actor == nil {
+		return uuid.UUID{}, false
+	}
+
+	id, err := uuid.Parse(c.Actor.Subject)
+	if err != nil {
+		return uuid.UUID{}, false
+	}
+	return id, true
 }
 
 func GenerateJWT(userID uuid.UUID, permissions int32, secret string, expiry time.Duration) (string, error) {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, CustomClaims{
+	return signClaims(CustomClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   userID.String(),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiry)),
+		},
+		Permissions: permissions,
+	}, secret)
+}
+
+// GenerateImpersonationJWT issues a token that authenticates as userID while
+// recording actorID as the admin behind it.
+func GenerateImpersonationJWT(userID uuid.UUID, permissions int32, actorID uuid.UUID, secret string, expiry time.Duration) (string, error) {
+	return signClaims(CustomClaims{
 		RegisteredClaims: jwt.RegisteredClaims{
 			Subject:   userID.String(),
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiry)),
 		},
 		Permissions: permissions,
-	})
+		Actor:       &ActorClaim{Subject: actorID.String()},
+	}, secret)
+}
+
+func signClaims(claims CustomClaims, secret string) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 
 	tokenString, err := token.SignedString([]byte(secret))
 	if err != nil {
@@ -30,7 +67,7 @@ func GenerateJWT(userID uuid.UUID, permissions int32, secret string, expiry time
 	return tokenString, nil
 }
 
-func ValidateToken(tokenString string, secret string) (uuid.UUID, int32, error) {
+func ValidateToken(tokenString string, secret string) (uuid.UUID, *CustomClaims, error) {
 	claims := &CustomClaims{}
 	token, err := jwt.ParseWithClaims(tokenString, claims, func(t *jwt.Token) (any, error) {
 		if t.Method != jwt.SigningMethodHS256 {
@@ -40,18 +77,24 @@ func ValidateToken(tokenString string, secret string) (uuid.UUID, int32, error)
 	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
 
 	if err != nil || !token.Valid {
-		return uuid.UUID{}, 0, fmt.Errorf("error parsing token")
+		return uuid.UUID{}, nil, fmt.Errorf("error parsing token")
 	}
 
 	subject, err := token.Claims.GetSubject()
 	if err != nil {
-		return uuid.UUID{}, 0, fmt.Errorf("error getting subject from token")
+		return uuid.UUID{}, nil, fmt.Errorf("error getting subject from token")
 	}
 
 	uuidFromSubject, err := uuid.Parse(subject)
 	if err != nil {
-		return uuid.UUID{}, 0, fmt.Errorf("error parsing into uuid")
+		return uuid.UUID{}, nil, fmt.Errorf("error parsing into uuid")
+	}
+
+	if claims.Actor != nil {
+		if _, ok := claims.ActorID(); !ok {
+			return uuid.UUID{}, nil, fmt.Errorf("error parsing actor into uuid")
+		}
 	}
 
-	return uuidFromSubject, claims.Permissions, nil
+	return uuidFromSubject, claims, nil
 }
@@ -0,0 +1,95 @@
+// Package blob stores file contents, such as issue attachments, outside
+// Postgres. Store has a local filesystem and an S3-compatible backend.
+package blob
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+var ErrNotFound = errors.New("blob not found")
+
+// Store keeps opaque blobs under slash-separated keys.
+type Store interface {
+	// Put streams r to key until EOF, replacing any blob already there. The
+	// size doesn't need to be known up front.
+	Put(ctx context.Context, key string, r io.Reader, contentType string) error
+
+	// Get returns length bytes of the blob starting at offset, or everything
+	// from offset on when length is negative.
+	Get(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error)
+
+	// Delete removes a blob. Deleting one that doesn't exist is not an error.
+	Delete(ctx context.Context, key string) error
+}
+
+// readSeeker reads a blob of known size through ranged Gets, so it can be
+// handed to http.ServeContent. Seeking is free; the next Read opens the
+// blob at the new offset.
+type readSeeker struct {
+	ctx    context.Context
+	store  Store
+	key    string
+	size   int64
+	offset int64
+	body   io.ReadCloser
+}
+
+// NewReadSeeker returns an io.ReadSeekCloser over the blob at key, which
+// must be size bytes long.
+func NewReadSeeker(ctx context.Context, store Store, key string, size int64) io.ReadSeekCloser {
+	return &readSeeker{ctx: ctx, store: store, key: key, size: size}
+}
+
+func (r *readSeeker) Read(p []byte) (int, error) {
+	if r.offset >= r.size {
+		return 0, io.EOF
+	}
+
+	if r.body == nil {
+		body, err := r.store.Get(r.ctx, r.key, r.offset, -1)
+		if err != nil {
+			return 0, err
+		}
+		r.body = body
+	}
+
+	n, err := r.body.Read(p)
+	r.offset += int64(n)
+	return n, err
+}
+
+func (r *readSeeker) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += r.offset
+	case io.SeekEnd:
+		offset += r.size
+	default:
+		return 0, errors.New("blob: invalid whence")
+	}
+	if offset < 0 {
+		return 0, errors.New("blob: negative position")
+	}
+
+	if offset != r.offset {
+		r.closeBody()
+		r.offset = offset
+	}
+	return offset, nil
+}
+
+func (r *readSeeker) Close() error {
+	return r.closeBody()
+}
+
+func (r *readSeeker) closeBody() error {
+	if r.body == nil {
+		return nil
+	}
+	err := r.body.Close()
+	r.body = nil
+	return err
+}
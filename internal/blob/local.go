@@ -0,0 +1,109 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// Local stores blobs as files under a root directory.
+type Local struct {
+	root string
+}
+
+func NewLocal(root string) (*Local, error) {
+	if err := os.MkdirAll(root, 0o750); err != nil {
+		return nil, fmt.Errorf("creating blob dir: %w", err)
+	}
+	return &Local{root: root}, nil
+}
+
+// Put writes to a temp file next to the target and renames it into place,
+// so readers never see a partial blob.
+func (l *Local) Put(ctx context.Context, key string, r io.Reader, contentType string) error {
+	name, err := l.path(key)
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(name)
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return fmt.Errorf("creating blob dir: %w", err)
+	}
+
+	f, err := os.CreateTemp(dir, ".upload-*")
+	if err != nil {
+		return fmt.Errorf("creating blob: %w", err)
+	}
+	// Removing the temp file after a successful rename fails harmlessly.
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	if _, err := io.Copy(f, r); err != nil {
+		return fmt.Errorf("writing blob: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		return fmt.Errorf("syncing blob: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("closing blob: %w", err)
+	}
+
+	if err := os.Rename(f.Name(), name); err != nil {
+		return fmt.Errorf("storing blob: %w", err)
+	}
+	return nil
+}
+
+func (l *Local) Get(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error) {
+	name, err := l.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(name)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("opening blob: %w", err)
+	}
+
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("seeking blob: %w", err)
+	}
+
+	if length < 0 {
+		return f, nil
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{io.LimitReader(f, length), f}, nil
+}
+
+func (l *Local) Delete(ctx context.Context, key string) error {
+	name, err := l.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(name); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("deleting blob: %w", err)
+	}
+	return nil
+}
+
+// path maps key to a file under root, refusing keys that would escape it.
+func (l *Local) path(key string) (string, error) {
+	clean := path.Clean("/" + key)
+	if clean == "/" || clean != "/"+key {
+		return "", fmt.Errorf("blob: invalid key %q", key)
+	}
+	return filepath.Join(l.root, filepath.FromSlash(clean)), nil
+}
@@ -0,0 +1,325 @@
+package blob
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// S3Config points S3 at a bucket on AWS or any S3-compatible server such
+// as MinIO.
+type S3Config struct {
+	Endpoint  string // e.g. https://s3.us-east-1.amazonaws.com or http://localhost:9000
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	PathStyle bool // bucket in the path instead of the host name, as MinIO expects
+}
+
+// s3PartSize is how much of an upload is held in memory at once. Blobs
+// smaller than this go up in a single PUT; larger ones as a multipart
+// upload of parts this size (S3's minimum is 5 MiB).
+const s3PartSize = 8 << 20
+
+// S3 stores blobs as objects in one bucket. Requests are signed with AWS
+// Signature Version 4.
+type S3 struct {
+	cfg      S3Config
+	endpoint *url.URL
+	client   *http.Client
+}
+
+func NewS3(cfg S3Config) (*S3, error) {
+	endpoint, err := url.Parse(cfg.Endpoint)
+	if err != nil || endpoint.Scheme == "" || endpoint.Host == "" {
+		return nil, fmt.Errorf("s3: invalid endpoint %q", cfg.Endpoint)
+	}
+	if cfg.Bucket == "" || cfg.Region == "" {
+		return nil, errors.New("s3: bucket and region are required")
+	}
+
+	return &S3{cfg: cfg, endpoint: endpoint, client: &http.Client{}}, nil
+}
+
+func (s *S3) Put(ctx context.Context, key string, r io.Reader, contentType string) error {
+	buf := make([]byte, s3PartSize)
+	n, err := io.ReadFull(r, buf)
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return s.putObject(ctx, key, buf[:n], contentType)
+	}
+	if err != nil {
+		return fmt.Errorf("s3: reading upload: %w", err)
+	}
+
+	return s.putMultipart(ctx, key, buf, r, contentType)
+}
+
+func (s *S3) putObject(ctx context.Context, key string, body []byte, contentType string) error {
+	header := http.Header{"Content-Type": {contentType}}
+	resp, err := s.do(ctx, http.MethodPut, key, nil, header, body)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// putMultipart uploads first and then the rest of r as numbered parts,
+// aborting the upload if any part fails so S3 doesn't keep the pieces.
+func (s *S3) putMultipart(ctx context.Context, key string, first []byte, r io.Reader, contentType string) error {
+	header := http.Header{"Content-Type": {contentType}}
+	resp, err := s.do(ctx, http.MethodPost, key, url.Values{"uploads": {""}}, header, nil)
+	if err != nil {
+		return err
+	}
+
+	var initiated struct {
+		UploadID string `xml:"UploadId"`
+	}
+	err = xml.NewDecoder(resp.Body).Decode(&initiated)
+	resp.Body.Close()
+	if err != nil || initiated.UploadID == "" {
+		return fmt.Errorf("s3: starting multipart upload: %v", err)
+	}
+
+	if err := s.uploadParts(ctx, key, initiated.UploadID, first, r); err != nil {
+		// Abort with a fresh context: ctx may be why the upload failed.
+		abortCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 30*time.Second)
+		defer cancel()
+		if resp, abortErr := s.do(abortCtx, http.MethodDelete, key, url.Values{"uploadId": {initiated.UploadID}}, nil, nil); abortErr == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+	return nil
+}
+
+type s3CompletedPart struct {
+	PartNumber int    `xml:"PartNumber"`
+	ETag       string `xml:"ETag"`
+}
+
+func (s *S3) uploadParts(ctx context.Context, key, uploadID string, buf []byte, r io.Reader) error {
+	var parts []s3CompletedPart
+
+	for number := 1; ; number++ {
+		query := url.Values{
+			"partNumber": {strconv.Itoa(number)},
+			"uploadId":   {uploadID},
+		}
+		resp, err := s.do(ctx, http.MethodPut, key, query, nil, buf)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		parts = append(parts, s3CompletedPart{PartNumber: number, ETag: resp.Header.Get("ETag")})
+
+		n, err := io.ReadFull(r, buf[:cap(buf)])
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+			return fmt.Errorf("s3: reading upload: %w", err)
+		}
+		buf = buf[:n]
+	}
+
+	body, err := xml.Marshal(struct {
+		XMLName xml.Name          `xml:"CompleteMultipartUpload"`
+		Parts   []s3CompletedPart `xml:"Part"`
+	}{Parts: parts})
+	if err != nil {
+		return fmt.Errorf("s3: encoding parts: %w", err)
+	}
+
+	resp, err := s.do(ctx, http.MethodPost, key, url.Values{"uploadId": {uploadID}}, nil, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// CompleteMultipartUpload can report a failure in a 200 response.
+	var result struct {
+		XMLName xml.Name
+		Message string `xml:"Message"`
+	}
+	if err := xml.NewDecoder(resp.Body).Decode(&result); err == nil && result.XMLName.Local == "Error" {
+		return fmt.Errorf("s3: completing multipart upload: %s", result.Message)
+	}
+	return nil
+}
+
+func (s *S3) Get(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error) {
+	var header http.Header
+	switch {
+	case length >= 0:
+		if length == 0 {
+			return io.NopCloser(strings.NewReader("")), nil
+		}
+		header = http.Header{"Range": {fmt.Sprintf("bytes=%d-%d", offset, offset+length-1)}}
+	case offset > 0:
+		header = http.Header{"Range": {fmt.Sprintf("bytes=%d-", offset)}}
+	}
+
+	resp, err := s.do(ctx, http.MethodGet, key, nil, header, nil)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+func (s *S3) Delete(ctx context.Context, key string) error {
+	resp, err := s.do(ctx, http.MethodDelete, key, nil, nil, nil)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// do sends a signed request for key and returns the response if it is a
+// 2xx. A 404 is ErrNotFound; anything else is an error carrying S3's message.
+func (s *S3) do(ctx context.Context, method, key string, query url.Values, header http.Header, body []byte) (*http.Response, error) {
+	u := *s.endpoint
+	objectPath := "/" + key
+	if s.cfg.PathStyle {
+		objectPath = "/" + s.cfg.Bucket + objectPath
+	} else {
+		u.Host = s.cfg.Bucket + "." + u.Host
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/") + objectPath
+	u.RawPath = awsEscape(u.Path, false)
+	u.RawQuery = canonicalQuery(query)
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("s3: building request: %w", err)
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	req.ContentLength = int64(len(body))
+	s.sign(req, u.RawPath, body, time.Now())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("s3: %s %s: %w", method, key, err)
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+
+	var s3Err struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+	xml.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&s3Err)
+	return nil, fmt.Errorf("s3: %s %s: %s %s %s", method, key, resp.Status, s3Err.Code, s3Err.Message)
+}
+
+// sign adds AWS Signature Version 4 headers to req. escapedPath is the
+// request path exactly as sent.
+func (s *S3) sign(req *http.Request, escapedPath string, body []byte, now time.Time) {
+	payloadHash := sha256Hex(body)
+	amzDate := now.UTC().Format("20060102T150405Z")
+	day := amzDate[:8]
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	const signedHeaders = "host;x-amz-content-sha256;x-amz-date"
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		escapedPath,
+		req.URL.RawQuery,
+		"host:" + req.URL.Host,
+		"x-amz-content-sha256:" + payloadHash,
+		"x-amz-date:" + amzDate,
+		"",
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := day + "/" + s.cfg.Region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+
+	key := hmacSHA256([]byte("AWS4"+s.cfg.SecretKey), day)
+	key = hmacSHA256(key, s.cfg.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.cfg.AccessKey, scope, signedHeaders, signature,
+	))
+}
+
+// canonicalQuery encodes query the way SigV4 expects: keys sorted, every
+// key and value escaped, and "=" present even for empty values.
+func canonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var pairs []string
+	for _, k := range keys {
+		for _, v := range query[k] {
+			pairs = append(pairs, awsEscape(k, true)+"="+awsEscape(v, true))
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+// awsEscape percent-encodes everything except RFC 3986 unreserved
+// characters, and '/' unless encodeSlash is set.
+func awsEscape(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func sha256Hex(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,209 @@
+package collab
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	"github.com/jesusthecreator017/fswithgo/internal/events"
+)
+
+const (
+	writeWait  = 10 * time.Second
+	pongWait   = 60 * time.Second
+	pingPeriod = pongWait * 9 / 10
+
+	// maxMessageSize bounds what a client may send; client messages are
+	// small JSON commands.
+	maxMessageSize = 4 << 10
+
+	// sendBuffer is how many messages may queue for a client. A client
+	// that falls further behind misses typing signals and, if it still
+	// can't keep up, is disconnected.
+	sendBuffer = 64
+
+	// A client may send messageRate messages a second, in bursts of up to
+	// messageBurst.
+	messageRate  = 5
+	messageBurst = 20
+)
+
+type conn struct {
+	hub  *Hub
+	ws   *websocket.Conn
+	key  string
+	user User
+
+	topics map[Topic]struct{} // guarded by hub.mu
+
+	send      chan []byte
+	closed    chan struct{}
+	closeOnce sync.Once
+	closeCode int
+	closeText string
+}
+
+func newConn(h *Hub, ws *websocket.Conn, user User) *conn {
+	return &conn{
+		hub:    h,
+		ws:     ws,
+		key:    uuid.NewString(),
+		user:   user,
+		topics: make(map[Topic]struct{}),
+		send:   make(chan []byte, sendBuffer),
+		closed: make(chan struct{}),
+	}
+}
+
+// run reads from the client until the connection ends, with writes on a
+// second goroutine. It returns once both are done.
+func (c *conn) run(ctx context.Context) {
+	written := make(chan struct{})
+	go func() {
+		defer close(written)
+		c.writeLoop()
+	}()
+
+	c.readLoop(ctx)
+	c.close(websocket.CloseNormalClosure, "")
+	<-written
+	c.ws.Close()
+}
+
+func (c *conn) readLoop(ctx context.Context) {
+	c.ws.SetReadLimit(maxMessageSize)
+	c.ws.SetReadDeadline(time.Now().Add(pongWait))
+	c.ws.SetPongHandler(func(string) error {
+		return c.ws.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
+	limit := limiter{tokens: messageBurst, last: time.Now()}
+
+	for {
+		_, data, err := c.ws.ReadMessage()
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway, websocket.CloseNoStatusReceived) {
+				slog.Warn("reading from websocket", "user_id", c.user.ID, "error", err)
+			}
+			return
+		}
+
+		if !limit.allow(time.Now()) {
+			c.sendError("rate limit exceeded; message dropped")
+			continue
+		}
+
+		var msg clientMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			c.sendError("message must be a JSON object")
+			continue
+		}
+		c.hub.handle(ctx, c, msg)
+	}
+}
+
+// writeLoop sends queued messages and pings until the connection is
+// closed, then sends the close frame.
+func (c *conn) writeLoop() {
+	ping := time.NewTicker(pingPeriod)
+	defer ping.Stop()
+
+	for {
+		select {
+		case data := <-c.send:
+			c.ws.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.ws.WriteMessage(websocket.TextMessage, data); err != nil {
+				c.abort()
+				return
+			}
+		case <-ping.C:
+			if err := c.ws.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeWait)); err != nil {
+				c.abort()
+				return
+			}
+		case <-c.closed:
+			msg := websocket.FormatCloseMessage(c.closeCode, c.closeText)
+			c.ws.WriteControl(websocket.CloseMessage, msg, time.Now().Add(writeWait))
+			// Give the client a moment to answer before the read fails.
+			c.ws.SetReadDeadline(time.Now().Add(time.Second))
+			return
+		}
+	}
+}
+
+// close asks the write loop to end the connection with code. Only the
+// first call counts.
+func (c *conn) close(code int, text string) {
+	c.closeOnce.Do(func() {
+		c.closeCode = code
+		c.closeText = text
+		close(c.closed)
+	})
+}
+
+// abort ends a connection that can no longer be written to.
+func (c *conn) abort() {
+	c.close(websocket.CloseAbnormalClosure, "")
+	c.ws.Close()
+}
+
+// queue hands data to the write loop without blocking. A full buffer
+// drops droppable messages and disconnects the client otherwise, since it
+// would have missed something it can't do without.
+func (c *conn) queue(data []byte, droppable bool) {
+	select {
+	case c.send <- data:
+	default:
+		if !droppable {
+			c.close(websocket.CloseTryAgainLater, "too slow; reconnect and reload")
+		}
+	}
+}
+
+func (c *conn) sendJSON(msg serverMessage, droppable bool) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		slog.Error("encoding collab message", "type", msg.Type, "error", err)
+		return
+	}
+	c.queue(data, droppable)
+}
+
+func (c *conn) sendError(text string) {
+	c.sendJSON(serverMessage{Type: "error", Error: text}, true)
+}
+
+// wants reports whether c subscribes to a topic e belongs to. The caller
+// must hold hub.mu.
+func (c *conn) wants(e events.Event) bool {
+	if _, ok := c.topics[Topic{Kind: TopicIssue, ID: e.IssueID}]; ok {
+		return true
+	}
+	if e.ProjectID != nil {
+		_, ok := c.topics[Topic{Kind: TopicProject, ID: *e.ProjectID}]
+		return ok
+	}
+	_, ok := c.topics[Topic{Kind: TopicBoard}]
+	return ok && e.OwnerID == c.user.ID
+}
+
+// limiter is a token bucket refilled at messageRate per second. It is only
+// used from the read loop.
+type limiter struct {
+	tokens float64
+	last   time.Time
+}
+
+func (l *limiter) allow(now time.Time) bool {
+	l.tokens = min(messageBurst, l.tokens+now.Sub(l.last).Seconds()*messageRate)
+	l.last = now
+	if l.tokens < 1 {
+		return false
+	}
+	l.tokens--
+	return true
+}
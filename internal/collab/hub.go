@@ -0,0 +1,375 @@
+// Package collab runs the WebSocket collaboration channel: clients
+// subscribe to projects and issues, receive their live events, and see who
+// else is viewing or typing on an issue. Presence and typing travel between
+// API instances as broker signals, so every replica sees the same viewers.
+package collab
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/jesusthecreator017/fswithgo/internal/events"
+)
+
+const (
+	// presenceRefresh is how often each instance re-announces its viewers;
+	// a viewer not heard from for presenceTTL is dropped, which clears the
+	// viewers of an instance that died without saying goodbye.
+	presenceRefresh = 20 * time.Second
+	presenceTTL     = 3 * presenceRefresh
+
+	// maxTopics caps the subscriptions one connection may hold.
+	maxTopics = 50
+
+	authorizeTimeout = 5 * time.Second
+)
+
+// Authorizer decides whether user may subscribe to topic.
+type Authorizer func(ctx context.Context, user User, topic Topic) error
+
+type Hub struct {
+	broker    *events.Broker
+	authorize Authorizer
+
+	mu      sync.Mutex
+	conns   map[*conn]struct{}
+	viewers map[int64]map[string]viewer // issue id → viewer key → viewer
+	closing bool
+	wg      sync.WaitGroup
+}
+
+type viewer struct {
+	user User
+	seen time.Time
+}
+
+func NewHub(broker *events.Broker, authorize Authorizer) *Hub {
+	h := &Hub{
+		broker:    broker,
+		authorize: authorize,
+		conns:     make(map[*conn]struct{}),
+		viewers:   make(map[int64]map[string]viewer),
+	}
+	broker.OnSignal(h.handleSignal)
+	return h
+}
+
+// Run forwards broker events to subscribed connections and keeps presence
+// fresh until ctx is cancelled.
+func (h *Hub) Run(ctx context.Context) {
+	refresh := time.NewTicker(presenceRefresh)
+	defer refresh.Stop()
+
+	for {
+		sub, _, _ := h.broker.Subscribe(0)
+
+	forward:
+		for {
+			select {
+			case <-ctx.Done():
+				h.broker.Unsubscribe(sub)
+				return
+			case e, open := <-sub.C:
+				if !open {
+					break forward
+				}
+				h.dispatch(e)
+			case <-refresh.C:
+				h.refreshPresence(ctx)
+			}
+		}
+
+		// The hub fell behind or the broker lost events; clients must reload.
+		h.broadcast(serverMessage{Type: "reset"})
+	}
+}
+
+// Serve runs a connection for user until either side closes it. ctx
+// should be the upgraded request's context.
+func (h *Hub) Serve(ctx context.Context, ws *websocket.Conn, user User) {
+	c := newConn(h, ws, user)
+
+	h.mu.Lock()
+	if h.closing {
+		h.mu.Unlock()
+		ws.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down"),
+			time.Now().Add(writeWait))
+		ws.Close()
+		return
+	}
+	h.conns[c] = struct{}{}
+	h.wg.Add(1)
+	h.mu.Unlock()
+
+	defer h.wg.Done()
+	defer h.remove(c)
+
+	c.run(ctx)
+}
+
+// Shutdown closes every connection with 1001 Going Away and waits for them
+// to finish, or for ctx to expire. New connections are refused.
+func (h *Hub) Shutdown(ctx context.Context) error {
+	h.mu.Lock()
+	h.closing = true
+	for c := range h.conns {
+		c.close(websocket.CloseGoingAway, "server shutting down")
+	}
+	h.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		h.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// handle acts on one message from c.
+func (h *Hub) handle(ctx context.Context, c *conn, msg clientMessage) {
+	switch msg.Type {
+	case "subscribe":
+		topic, err := ParseTopic(msg.Topic)
+		if err != nil {
+			c.sendError(err.Error())
+			return
+		}
+
+		authCtx, cancel := context.WithTimeout(ctx, authorizeTimeout)
+		err = h.authorize(authCtx, c.user, topic)
+		cancel()
+		if err != nil {
+			c.sendError(fmt.Sprintf("cannot subscribe to %s: %v", topic, err))
+			return
+		}
+
+		h.mu.Lock()
+		_, already := c.topics[topic]
+		full := !already && len(c.topics) >= maxTopics
+		if !already && !full {
+			c.topics[topic] = struct{}{}
+		}
+		h.mu.Unlock()
+
+		if full {
+			c.sendError(fmt.Sprintf("at most %d subscriptions per connection", maxTopics))
+			return
+		}
+
+		c.sendJSON(serverMessage{Type: "subscribed", Topic: topic.String()}, false)
+		if topic.Kind == TopicIssue && !already {
+			h.signal(ctx, signal{Type: "join", Key: c.key, IssueID: topic.ID, User: c.user})
+		}
+
+	case "unsubscribe":
+		topic, err := ParseTopic(msg.Topic)
+		if err != nil {
+			c.sendError(err.Error())
+			return
+		}
+
+		h.mu.Lock()
+		_, had := c.topics[topic]
+		delete(c.topics, topic)
+		h.mu.Unlock()
+
+		c.sendJSON(serverMessage{Type: "unsubscribed", Topic: topic.String()}, false)
+		if topic.Kind == TopicIssue && had {
+			h.signal(ctx, signal{Type: "leave", Key: c.key, IssueID: topic.ID, User: c.user})
+		}
+
+	case "typing":
+		h.mu.Lock()
+		_, viewing := c.topics[Topic{Kind: TopicIssue, ID: msg.IssueID}]
+		h.mu.Unlock()
+
+		if !viewing {
+			c.sendError("subscribe to the issue before sending typing signals")
+			return
+		}
+		h.signal(ctx, signal{Type: "typing", Key: c.key, IssueID: msg.IssueID, User: c.user})
+
+	default:
+		c.sendError("type must be subscribe, unsubscribe or typing")
+	}
+}
+
+// remove forgets c and announces that it stopped viewing its issues.
+func (h *Hub) remove(c *conn) {
+	h.mu.Lock()
+	delete(h.conns, c)
+	var issues []int64
+	for t := range c.topics {
+		if t.Kind == TopicIssue {
+			issues = append(issues, t.ID)
+		}
+	}
+	h.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), writeWait)
+	defer cancel()
+	for _, id := range issues {
+		h.signal(ctx, signal{Type: "leave", Key: c.key, IssueID: id, User: c.user})
+	}
+}
+
+// dispatch sends e to the connections subscribed to it that may see it.
+func (h *Hub) dispatch(e events.Event) {
+	data, err := json.Marshal(serverMessage{Type: "event", Event: &e})
+	if err != nil {
+		slog.Error("encoding event for collab", "event_id", e.ID, "error", err)
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for c := range h.conns {
+		if e.VisibleTo(c.user.ID, c.user.Admin) && c.wants(e) {
+			c.queue(data, false)
+		}
+	}
+}
+
+func (h *Hub) broadcast(msg serverMessage) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for c := range h.conns {
+		c.sendJSON(msg, false)
+	}
+}
+
+func (h *Hub) signal(ctx context.Context, s signal) {
+	payload, err := json.Marshal(s)
+	if err == nil {
+		err = h.broker.Signal(ctx, payload)
+	}
+	if err != nil {
+		slog.Error("sending collab signal", "type", s.Type, "error", err)
+	}
+}
+
+// handleSignal applies a presence or typing signal from any instance,
+// this one included.
+func (h *Hub) handleSignal(payload []byte) {
+	var s signal
+	if err := json.Unmarshal(payload, &s); err != nil {
+		slog.Warn("bad collab signal", "error", err)
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	switch s.Type {
+	case "join":
+		viewers := h.viewers[s.IssueID]
+		if viewers == nil {
+			viewers = make(map[string]viewer)
+			h.viewers[s.IssueID] = viewers
+		}
+		_, known := viewers[s.Key]
+		viewers[s.Key] = viewer{user: s.User, seen: time.Now()}
+		if !known {
+			h.sendPresence(s.IssueID)
+		}
+
+	case "leave":
+		if _, known := h.viewers[s.IssueID][s.Key]; known {
+			delete(h.viewers[s.IssueID], s.Key)
+			if len(h.viewers[s.IssueID]) == 0 {
+				delete(h.viewers, s.IssueID)
+			}
+			h.sendPresence(s.IssueID)
+		}
+
+	case "typing":
+		topic := Topic{Kind: TopicIssue, ID: s.IssueID}
+		user := s.User
+		data, err := json.Marshal(serverMessage{Type: "typing", IssueID: s.IssueID, User: &user})
+		if err != nil {
+			return
+		}
+		for c := range h.conns {
+			if _, ok := c.topics[topic]; ok && c.key != s.Key {
+				c.queue(data, true)
+			}
+		}
+	}
+}
+
+// refreshPresence re-announces this instance's viewers and drops viewers
+// that no instance has announced lately.
+func (h *Hub) refreshPresence(ctx context.Context) {
+	var joins []signal
+
+	h.mu.Lock()
+	for c := range h.conns {
+		for t := range c.topics {
+			if t.Kind == TopicIssue {
+				joins = append(joins, signal{Type: "join", Key: c.key, IssueID: t.ID, User: c.user})
+			}
+		}
+	}
+
+	cutoff := time.Now().Add(-presenceTTL)
+	for issueID, viewers := range h.viewers {
+		changed := false
+		for key, v := range viewers {
+			if v.seen.Before(cutoff) {
+				delete(viewers, key)
+				changed = true
+			}
+		}
+		if len(viewers) == 0 {
+			delete(h.viewers, issueID)
+		}
+		if changed {
+			h.sendPresence(issueID)
+		}
+	}
+	h.mu.Unlock()
+
+	for _, s := range joins {
+		h.signal(ctx, s)
+	}
+}
+
+// sendPresence tells the issue's local subscribers who is viewing it, one
+// entry per user. The caller must hold h.mu.
+func (h *Hub) sendPresence(issueID int64) {
+	users := make([]User, 0, len(h.viewers[issueID]))
+	for _, v := range h.viewers[issueID] {
+		if !slices.ContainsFunc(users, func(u User) bool { return u.ID == v.user.ID }) {
+			users = append(users, v.user)
+		}
+	}
+	slices.SortFunc(users, func(a, b User) int { return strings.Compare(a.Name, b.Name) })
+
+	data, err := json.Marshal(serverMessage{Type: "presence", IssueID: issueID, Viewers: users})
+	if err != nil {
+		return
+	}
+
+	topic := Topic{Kind: TopicIssue, ID: issueID}
+	for c := range h.conns {
+		if _, ok := c.topics[topic]; ok {
+			c.queue(data, false)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package collab
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jesusthecreator017/fswithgo/internal/events"
+)
+
+// Topic is something a client can subscribe to: a project's board
+// ("project:12"), a single issue ("issue:34"), or the user's personal
+// board ("board").
+type Topic struct {
+	Kind string
+	ID   int64
+}
+
+const (
+	TopicProject = "project"
+	TopicIssue   = "issue"
+	TopicBoard   = "board"
+)
+
+func ParseTopic(s string) (Topic, error) {
+	if s == TopicBoard {
+		return Topic{Kind: TopicBoard}, nil
+	}
+
+	kind, rawID, ok := strings.Cut(s, ":")
+	id, err := strconv.ParseInt(rawID, 10, 64)
+	if !ok || err != nil || id <= 0 || (kind != TopicProject && kind != TopicIssue) {
+		return Topic{}, fmt.Errorf("topic must be board, project:<id> or issue:<id>")
+	}
+	return Topic{Kind: kind, ID: id}, nil
+}
+
+func (t Topic) String() string {
+	if t.Kind == TopicBoard {
+		return TopicBoard
+	}
+	return t.Kind + ":" + strconv.FormatInt(t.ID, 10)
+}
+
+// User is the account behind a connection.
+type User struct {
+	ID    uuid.UUID `json:"id"`
+	Name  string    `json:"name"`
+	Admin bool      `json:"-"`
+}
+
+// clientMessage is anything a client sends.
+//
+//	{"type": "subscribe", "topic": "issue:34"}
+//	{"type": "unsubscribe", "topic": "issue:34"}
+//	{"type": "typing", "issue_id": 34}
+type clientMessage struct {
+	Type    string `json:"type"`
+	Topic   string `json:"topic,omitempty"`
+	IssueID int64  `json:"issue_id,omitempty"`
+}
+
+// serverMessage is anything the server sends: subscribed, unsubscribed,
+// event, reset, presence, typing or error.
+type serverMessage struct {
+	Type    string        `json:"type"`
+	Topic   string        `json:"topic,omitempty"`
+	Event   *events.Event `json:"event,omitempty"`
+	IssueID int64         `json:"issue_id,omitempty"`
+	Viewers []User        `json:"viewers,omitzero"`
+	User    *User         `json:"user,omitempty"`
+	Error   string        `json:"error,omitempty"`
+}
+
+// signal is what instances tell each other about presence and typing.
+// Key identifies the connection, across all instances.
+type signal struct {
+	Type    string `json:"type"` // "join", "leave" or "typing"
+	Key     string `json:"key"`
+	IssueID int64  `json:"issue_id"`
+	User    User   `json:"user"`
+}
@@ -34,6 +34,9 @@ func New(dsn string, maxConns int, maxIdleTime time.Duration) (*pgxpool.Pool, er
 	// for this long, freeing resources on both Go and PostgreSQL sides.
 	config.MaxConnIdleTime = maxIdleTime
 
+	// Trace queries that are part of a traced request or task.
+	config.ConnConfig.Tracer = queryTracer{}
+
 	pool, err := pgxpool.NewWithConfig(context.Background(), config)
 	if err != nil {
 		return nil, fmt.Errorf("creating connection pool: %w", err)
@@ -0,0 +1,254 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jesusthecreator017/fswithgo/internal/store/dbsqlc"
+)
+
+// channel is the Postgres NOTIFY channel events travel on; signalChannel
+// carries signals.
+const (
+	channel       = "issue_events"
+	signalChannel = "issue_signals"
+)
+
+// maxPayload keeps a notification under Postgres' 8000-byte limit. Larger
+// events are sent without their data, and clients fetch the issue instead.
+const maxPayload = 7900
+
+// subscriberBuffer is how many events a subscriber may fall behind by
+// before it is dropped.
+const subscriberBuffer = 64
+
+// Broker publishes events through Postgres and hands the ones it hears
+// back to its subscribers. It keeps the most recent events in a replay
+// buffer so a reconnecting client can pick up where it left off.
+type Broker struct {
+	pool    *pgxpool.Pool
+	queries *dbsqlc.Queries
+
+	mu      sync.Mutex
+	replay  []Event // oldest first, at most replaySize
+	size    int
+	subs    map[*Subscription]struct{}
+	signals []func([]byte)
+}
+
+func NewBroker(pool *pgxpool.Pool, replaySize int) *Broker {
+	return &Broker{
+		pool:    pool,
+		queries: dbsqlc.New(pool),
+		size:    replaySize,
+		subs:    make(map[*Subscription]struct{}),
+	}
+}
+
+// Subscription receives events as they arrive. C is closed if the
+// subscriber falls too far behind or the broker loses events while
+// reconnecting to Postgres; either way the client should resume or reload.
+type Subscription struct {
+	C  <-chan Event
+	ch chan Event
+}
+
+// Subscribe starts a subscription. If lastID is non-zero it also returns
+// the buffered events that arrived after lastID; ok is false if lastID is
+// no longer in the buffer, so some events can't be replayed.
+func (b *Broker) Subscribe(lastID int64) (sub *Subscription, missed []Event, ok bool) {
+	ch := make(chan Event, subscriberBuffer)
+	sub = &Subscription{C: ch, ch: ch}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.subs[sub] = struct{}{}
+
+	if lastID == 0 {
+		return sub, nil, true
+	}
+	// Every instance hears notifications in the same order, so resume by
+	// position rather than by comparing ids.
+	for i := len(b.replay) - 1; i >= 0; i-- {
+		if b.replay[i].ID == lastID {
+			return sub, append([]Event(nil), b.replay[i+1:]...), true
+		}
+	}
+	return sub, nil, false
+}
+
+func (b *Broker) Unsubscribe(sub *Subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.subs[sub]; ok {
+		delete(b.subs, sub)
+		close(sub.ch)
+	}
+}
+
+// Publish gives e the next event id and sends it to every instance. The
+// event reaches subscribers, including this instance's, once Postgres
+// delivers the notification.
+func (b *Broker) Publish(ctx context.Context, e *Event) error {
+	id, err := b.queries.NextEventID(ctx)
+	if err != nil {
+		return fmt.Errorf("getting event id: %w", err)
+	}
+	e.ID = id
+	if e.CreatedAt.IsZero() {
+		e.CreatedAt = time.Now()
+	}
+
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("encoding event: %w", err)
+	}
+	if len(payload) > maxPayload {
+		trimmed := *e
+		trimmed.Data = nil
+		if payload, err = json.Marshal(trimmed); err != nil {
+			return fmt.Errorf("encoding event: %w", err)
+		}
+	}
+
+	if err := b.queries.Notify(ctx, dbsqlc.NotifyParams{Channel: channel, Payload: string(payload)}); err != nil {
+		return fmt.Errorf("notifying: %w", err)
+	}
+	return nil
+}
+
+// Signal sends an ephemeral message, such as a presence update, to every
+// instance. Signals aren't numbered, buffered or replayed.
+func (b *Broker) Signal(ctx context.Context, payload []byte) error {
+	if len(payload) > maxPayload {
+		return fmt.Errorf("signal is %d bytes, over the %d-byte limit", len(payload), maxPayload)
+	}
+	if err := b.queries.Notify(ctx, dbsqlc.NotifyParams{Channel: signalChannel, Payload: string(payload)}); err != nil {
+		return fmt.Errorf("notifying: %w", err)
+	}
+	return nil
+}
+
+// OnSignal registers f to be called with every signal's payload. f runs on
+// the listener goroutine, so it must not block.
+func (b *Broker) OnSignal(f func([]byte)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.signals = append(b.signals, f)
+}
+
+// Run listens for events until ctx is cancelled, reconnecting with backoff
+// if the connection drops. It holds one pool connection while listening.
+func (b *Broker) Run(ctx context.Context) {
+	backoff := time.Second
+	for {
+		listening, err := b.listen(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		slog.Error("event listener stopped", "error", err)
+
+		// Anything published while we were away is gone, so subscribers
+		// can't trust the replay buffer.
+		if listening {
+			b.reset()
+			backoff = time.Second
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, 30*time.Second)
+	}
+}
+
+// listen runs one LISTEN session. listening reports whether it got as far
+// as receiving notifications.
+func (b *Broker) listen(ctx context.Context) (listening bool, err error) {
+	conn, err := b.pool.Acquire(ctx)
+	if err != nil {
+		return false, fmt.Errorf("acquiring connection: %w", err)
+	}
+	// A connection that has run LISTEN can't go back to the pool. LISTEN
+	// takes a channel name, not a parameter, so it can't be a sqlc query.
+	pgConn := conn.Hijack()
+	defer pgConn.Close(context.Background())
+
+	if _, err := pgConn.Exec(ctx, "LISTEN "+channel+"; LISTEN "+signalChannel); err != nil {
+		return false, fmt.Errorf("listening: %w", err)
+	}
+
+	for {
+		n, err := pgConn.WaitForNotification(ctx)
+		if err != nil {
+			return true, err
+		}
+
+		if n.Channel == signalChannel {
+			b.signal([]byte(n.Payload))
+			continue
+		}
+
+		var e Event
+		if err := json.Unmarshal([]byte(n.Payload), &e); err != nil {
+			slog.Warn("bad event payload", "error", err)
+			continue
+		}
+		b.deliver(e)
+	}
+}
+
+func (b *Broker) deliver(e Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.size > 0 {
+		if len(b.replay) == b.size {
+			copy(b.replay, b.replay[1:])
+			b.replay = b.replay[:b.size-1]
+		}
+		b.replay = append(b.replay, e)
+	}
+
+	for sub := range b.subs {
+		select {
+		case sub.ch <- e:
+		default:
+			// Too slow; it can resume from the replay buffer.
+			delete(b.subs, sub)
+			close(sub.ch)
+		}
+	}
+}
+
+func (b *Broker) signal(payload []byte) {
+	b.mu.Lock()
+	handlers := b.signals
+	b.mu.Unlock()
+
+	for _, f := range handlers {
+		f(payload)
+	}
+}
+
+// reset empties the replay buffer and ends every subscription.
+func (b *Broker) reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.replay = nil
+	for sub := range b.subs {
+		delete(b.subs, sub)
+		close(sub.ch)
+	}
+}
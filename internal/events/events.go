@@ -0,0 +1,36 @@
+// Package events fans issue changes out to live subscribers on every API
+// instance. Events are published with Postgres NOTIFY, so an instance
+// sees the changes made through all the others, in the same order.
+package events
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Type string
+
+const (
+	IssueCreated Type = "issue.created"
+	IssueUpdated Type = "issue.updated"
+	IssueDeleted Type = "issue.deleted"
+)
+
+type Event struct {
+	ID        int64           `json:"id"`
+	Type      Type            `json:"type"`
+	IssueID   int64           `json:"issue_id"`
+	ProjectID *int64          `json:"project_id"`
+	OwnerID   uuid.UUID       `json:"owner_id"`
+	ActorID   *uuid.UUID      `json:"actor_id,omitempty"`
+	Data      json.RawMessage `json:"data,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// VisibleTo reports whether a user may see the event: project issues are
+// visible to everyone, personal-board issues only to their owner and admins.
+func (e *Event) VisibleTo(userID uuid.UUID, admin bool) bool {
+	return e.ProjectID != nil || e.OwnerID == userID || admin
+}
@@ -0,0 +1,134 @@
+// Package health runs the checks behind the health probes. Results are
+// cached briefly, so probes from many load balancers can't add load to the
+// database.
+package health
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Status is a check's outcome. A warning is reported but doesn't fail the
+// probe.
+type Status string
+
+const (
+	Pass Status = "pass"
+	Warn Status = "warn"
+	Fail Status = "fail"
+)
+
+// Result is the outcome of one check. Checker fills in Duration.
+type Result struct {
+	Status   Status         `json:"status"`
+	Duration float64        `json:"duration_ms"`
+	Message  string         `json:"message,omitempty"`
+	Details  map[string]any `json:"details,omitempty"`
+}
+
+// Report holds every check's result. Its status is the worst of them.
+type Report struct {
+	Status    Status            `json:"status"`
+	CheckedAt time.Time         `json:"checked_at"`
+	Checks    map[string]Result `json:"checks"`
+}
+
+// Check runs one check. It should return when ctx expires.
+type Check func(ctx context.Context) Result
+
+type namedCheck struct {
+	name  string
+	check Check
+}
+
+// Checker runs a set of checks and caches the report for ttl.
+type Checker struct {
+	ttl     time.Duration
+	timeout time.Duration
+	checks  []namedCheck
+
+	mu     sync.Mutex
+	report *Report
+}
+
+// NewChecker returns a Checker whose checks get timeout to finish and whose
+// report is reused for ttl.
+func NewChecker(ttl, timeout time.Duration) *Checker {
+	return &Checker{ttl: ttl, timeout: timeout}
+}
+
+// Add registers a check. Add every check before calling Run.
+func (c *Checker) Add(name string, check Check) {
+	c.checks = append(c.checks, namedCheck{name, check})
+}
+
+// Run returns the cached report, or runs every check at once if it has
+// expired. Callers arriving during a run wait for it rather than starting
+// their own. The report must not be modified.
+func (c *Checker) Run() *Report {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.report != nil && time.Since(c.report.CheckedAt) < c.ttl {
+		return c.report
+	}
+
+	// Not the caller's context: a probe that hangs up shouldn't cut short
+	// a run other probes are waiting on.
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	results := make([]Result, len(c.checks))
+	var wg sync.WaitGroup
+	for i, nc := range c.checks {
+		wg.Go(func() {
+			start := time.Now()
+			r := nc.check(ctx)
+			r.Duration = float64(time.Since(start).Microseconds()) / 1000
+			results[i] = r
+		})
+	}
+	wg.Wait()
+
+	report := &Report{
+		Status:    Pass,
+		CheckedAt: time.Now(),
+		Checks:    make(map[string]Result, len(c.checks)),
+	}
+	for i, nc := range c.checks {
+		report.Checks[nc.name] = results[i]
+		report.Status = worse(report.Status, results[i].Status)
+	}
+	c.report = report
+	return report
+}
+
+var severity = map[Status]int{Pass: 0, Warn: 1, Fail: 2}
+
+// worse returns whichever of a and b is worse.
+func worse(a, b Status) Status {
+	if severity[b] > severity[a] {
+		return b
+	}
+	return a
+}
+
+// Stale fails if last, the time a loop last came around, is more than
+// maxAge ago.
+func Stale(last func() time.Time, maxAge time.Duration) Check {
+	return func(context.Context) Result {
+		t := last()
+		if t.IsZero() {
+			return Result{Status: Fail, Message: "not running"}
+		}
+
+		age := time.Since(t)
+		r := Result{Status: Pass, Details: map[string]any{"last_beat": t.UTC(), "max_age_secs": maxAge.Seconds()}}
+		if age > maxAge {
+			r.Status = Fail
+			r.Message = "stuck for " + age.Round(time.Second).String()
+		}
+		return r
+	}
+}
@@ -0,0 +1,91 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jesusthecreator017/fswithgo/internal/store/dbsqlc"
+)
+
+// Ping fails if the database doesn't answer and warns if it answers slower
+// than slow.
+func Ping(pool *pgxpool.Pool, slow time.Duration) Check {
+	return func(ctx context.Context) Result {
+		start := time.Now()
+		if err := pool.Ping(ctx); err != nil {
+			return Result{Status: Fail, Message: err.Error()}
+		}
+
+		if latency := time.Since(start); latency > slow {
+			return Result{Status: Warn, Message: fmt.Sprintf("slower than %s", slow)}
+		}
+		return Result{Status: Pass}
+	}
+}
+
+// PoolSaturation warns when at least warnAt (0 to 1) of the pool's
+// connections are in use. A full pool slows requests down rather than
+// failing them, so it never fails.
+func PoolSaturation(pool *pgxpool.Pool, warnAt float64) Check {
+	return func(context.Context) Result {
+		stat := pool.Stat()
+		saturation := float64(stat.AcquiredConns()) / float64(stat.MaxConns())
+
+		r := Result{
+			Status: Pass,
+			Details: map[string]any{
+				"acquired":   stat.AcquiredConns(),
+				"idle":       stat.IdleConns(),
+				"total":      stat.TotalConns(),
+				"max":        stat.MaxConns(),
+				"saturation": saturation,
+				// Acquires that found no idle connection, since startup.
+				"empty_acquires": stat.EmptyAcquireCount(),
+			},
+		}
+		if saturation >= warnAt {
+			r.Status = Warn
+			r.Message = fmt.Sprintf("%.0f%% of connections in use", saturation*100)
+		}
+		return r
+	}
+}
+
+// SchemaVersion compares the version golang-migrate recorded with want,
+// the newest migration this build knows about. An older or half-applied
+// schema fails; a newer one only warns, since migrations are meant to stay
+// compatible with the previous release during a rollout.
+func SchemaVersion(pool *pgxpool.Pool, want uint) Check {
+	queries := dbsqlc.New(pool)
+	return func(ctx context.Context) Result {
+		row, err := queries.GetSchemaVersion(ctx)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return Result{Status: Fail, Message: "no migrations applied"}
+		}
+		if err != nil {
+			return Result{Status: Fail, Message: err.Error()}
+		}
+
+		version, dirty := row.Version, row.Dirty
+		r := Result{
+			Status:  Pass,
+			Details: map[string]any{"version": version, "want": want, "dirty": dirty},
+		}
+		switch {
+		case dirty:
+			r.Status = Fail
+			r.Message = fmt.Sprintf("migration %d failed partway", version)
+		case version < int64(want):
+			r.Status = Fail
+			r.Message = fmt.Sprintf("schema is at %d, want %d", version, want)
+		case version > int64(want):
+			r.Status = Warn
+			r.Message = fmt.Sprintf("schema is at %d, newer than %d", version, want)
+		}
+		return r
+	}
+}
@@ -0,0 +1,259 @@
+// Package jobs runs background work from a Postgres-backed queue. Jobs
+// survive restarts, are retried with backoff when they fail, and end up
+// dead, for an admin to look at, when they keep failing. Any number of API
+// instances can work the same queue; each job runs on one of them at a time.
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jesusthecreator017/fswithgo/internal/store/dbsqlc"
+)
+
+// Job statuses. A failed job goes back to pending until it runs out of
+// attempts.
+const (
+	StatusPending   = "pending"
+	StatusRunning   = "running"
+	StatusSucceeded = "succeeded"
+	StatusDead      = "dead"
+)
+
+var (
+	ErrNotFound     = errors.New("job not found")
+	ErrNotRetryable = errors.New("only dead or pending jobs can be retried")
+	ErrDuplicate    = errors.New("a job with the same unique key is already queued")
+)
+
+// defaultMaxAttempts applies when a job is enqueued without MaxAttempts.
+const defaultMaxAttempts = 5
+
+type Job struct {
+	ID          int64           `json:"id"`
+	Kind        string          `json:"kind"`
+	Args        json.RawMessage `json:"args"`
+	Status      string          `json:"status"`
+	Attempts    int             `json:"attempts"`
+	MaxAttempts int             `json:"max_attempts"`
+	RunAt       time.Time       `json:"run_at"`
+	UniqueKey   *string         `json:"unique_key"`
+	LastError   *string         `json:"last_error"`
+	LockedUntil *time.Time      `json:"locked_until"`
+	CreatedAt   time.Time       `json:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+	FinishedAt  *time.Time      `json:"finished_at"`
+}
+
+// Args are a job's arguments, stored as JSON. Kind names the job type and
+// picks its handler; it must not depend on the receiver's fields.
+type Args interface {
+	Kind() string
+}
+
+// EnqueueOptions are optional; the zero value runs the job now, with up to
+// five attempts.
+type EnqueueOptions struct {
+	RunAt       time.Time
+	MaxAttempts int
+	// UniqueKey, if set, keeps a job of the same kind and key from being
+	// queued while this one is pending or running.
+	UniqueKey string
+}
+
+// permanentError marks a failure that retrying won't fix.
+type permanentError struct{ err error }
+
+func (e permanentError) Error() string { return e.err.Error() }
+func (e permanentError) Unwrap() error { return e.err }
+
+// Permanent wraps err so the job goes straight to dead instead of being
+// retried.
+func Permanent(err error) error {
+	return permanentError{err}
+}
+
+// RetryAfter wraps err so the job's next attempt comes after d instead of
+// the queue's usual backoff. It still counts as a failed attempt.
+func RetryAfter(err error, d time.Duration) error {
+	return retryError{err: err, after: d}
+}
+
+type retryError struct {
+	err   error
+	after time.Duration
+}
+
+func (e retryError) Error() string { return e.err.Error() }
+func (e retryError) Unwrap() error { return e.err }
+
+type contextKey string
+
+const attemptKey contextKey = "attempt"
+
+type attempt struct{ n, max int }
+
+// Attempt returns which attempt the running job is on, counting from one,
+// and how many it has. Both are zero outside a job.
+func Attempt(ctx context.Context) (n, maxAttempts int) {
+	a, _ := ctx.Value(attemptKey).(attempt)
+	return a.n, a.max
+}
+
+// Enqueue queues a job. If a job of the same kind with the same unique key
+// is already pending or running, Enqueue returns that job instead.
+func (q *Queue) Enqueue(ctx context.Context, args Args, opts EnqueueOptions) (*Job, error) {
+	job, err := enqueue(ctx, q.queries, args, opts)
+	if err != nil {
+		return nil, err
+	}
+	q.wakeWorker()
+	return job, nil
+}
+
+// EnqueueTx is Enqueue as part of tx, so the job is only queued if tx
+// commits. Workers find it at their next poll.
+func (q *Queue) EnqueueTx(ctx context.Context, tx pgx.Tx, args Args, opts EnqueueOptions) (*Job, error) {
+	return enqueue(ctx, q.queries.WithTx(tx), args, opts)
+}
+
+func enqueue(ctx context.Context, queries *dbsqlc.Queries, args Args, opts EnqueueOptions) (*Job, error) {
+	data, err := json.Marshal(args)
+	if err != nil {
+		return nil, fmt.Errorf("encoding %s args: %w", args.Kind(), err)
+	}
+
+	runAt := opts.RunAt
+	if runAt.IsZero() {
+		runAt = time.Now()
+	}
+	maxAttempts := opts.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = defaultMaxAttempts
+	}
+	var uniqueKey pgtype.Text
+	if opts.UniqueKey != "" {
+		uniqueKey = pgtype.Text{String: opts.UniqueKey, Valid: true}
+	}
+
+	// The existing job can finish between the insert and the lookup, so
+	// try once more.
+	for range 2 {
+		row, err := queries.EnqueueJob(ctx, dbsqlc.EnqueueJobParams{
+			Kind:        args.Kind(),
+			Args:        data,
+			MaxAttempts: int32(maxAttempts),
+			RunAt:       pgtype.Timestamptz{Time: runAt, Valid: true},
+			UniqueKey:   uniqueKey,
+		})
+		if err == nil {
+			return jobFromRow(row), nil
+		}
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("enqueueing %s: %w", args.Kind(), err)
+		}
+
+		row, err = queries.GetQueuedJob(ctx, dbsqlc.GetQueuedJobParams{Kind: args.Kind(), UniqueKey: uniqueKey})
+		if err == nil {
+			return jobFromRow(row), nil
+		}
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("finding queued %s: %w", args.Kind(), err)
+		}
+	}
+	return nil, fmt.Errorf("enqueueing %s: %w", args.Kind(), ErrDuplicate)
+}
+
+// Filter narrows List. Zero values mean "no filter"; BeforeID is the
+// pagination cursor.
+type Filter struct {
+	Status   string
+	Kind     string
+	BeforeID int64
+	Limit    int
+}
+
+// List returns jobs, newest first.
+func (q *Queue) List(ctx context.Context, f Filter) ([]*Job, error) {
+	rows, err := q.queries.ListJobs(ctx, dbsqlc.ListJobsParams{
+		Status:   f.Status,
+		Kind:     f.Kind,
+		BeforeID: f.BeforeID,
+		MaxJobs:  int32(f.Limit),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("listing jobs: %w", err)
+	}
+
+	jobs := make([]*Job, len(rows))
+	for i, row := range rows {
+		jobs[i] = jobFromRow(row)
+	}
+	return jobs, nil
+}
+
+func (q *Queue) Get(ctx context.Context, id int64) (*Job, error) {
+	row, err := q.queries.GetJob(ctx, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("getting job: %w", err)
+	}
+	return jobFromRow(row), nil
+}
+
+// Retry runs a dead or pending job now. A dead job gets a fresh set of
+// attempts; its last error is kept until it runs again.
+func (q *Queue) Retry(ctx context.Context, id int64) (*Job, error) {
+	row, err := q.queries.RetryJob(ctx, id)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return nil, ErrDuplicate
+		}
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("retrying job: %w", err)
+		}
+		if _, err := q.Get(ctx, id); err != nil {
+			return nil, err
+		}
+		return nil, ErrNotRetryable
+	}
+
+	q.wakeWorker()
+	return jobFromRow(row), nil
+}
+
+func jobFromRow(row dbsqlc.Job) *Job {
+	j := &Job{
+		ID:          row.ID,
+		Kind:        row.Kind,
+		Args:        row.Args,
+		Status:      row.Status,
+		Attempts:    int(row.Attempts),
+		MaxAttempts: int(row.MaxAttempts),
+		RunAt:       row.RunAt.Time,
+		CreatedAt:   row.CreatedAt.Time,
+		UpdatedAt:   row.UpdatedAt.Time,
+	}
+	if row.UniqueKey.Valid {
+		j.UniqueKey = &row.UniqueKey.String
+	}
+	if row.LastError.Valid {
+		j.LastError = &row.LastError.String
+	}
+	if row.LockedUntil.Valid {
+		j.LockedUntil = &row.LockedUntil.Time
+	}
+	if row.FinishedAt.Valid {
+		j.FinishedAt = &row.FinishedAt.Time
+	}
+	return j
+}
@@ -0,0 +1,132 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestDecide(t *testing.T) {
+	boom := errors.New("boom")
+	tests := []struct {
+		name     string
+		attempts int
+		err      error
+		shutdown bool
+		want     outcome
+	}{
+		{"success", 1, nil, false, outcome{status: StatusSucceeded}},
+		{"success on last attempt", 5, nil, false, outcome{status: StatusSucceeded}},
+		{"interrupted by shutdown", 1, context.Canceled, true, outcome{status: StatusPending, refund: true}},
+		{"shutdown on last attempt", 5, context.Canceled, true, outcome{status: StatusPending, refund: true}},
+		{"permanent", 1, Permanent(boom), false, outcome{status: StatusDead}},
+		{"wrapped permanent", 1, errors.Join(errors.New("sending"), Permanent(boom)), false, outcome{status: StatusDead}},
+		{"last attempt", 5, boom, false, outcome{status: StatusDead}},
+		{"last attempt with retry delay", 5, RetryAfter(boom, time.Minute), false, outcome{status: StatusDead}},
+		{"retry delay", 2, RetryAfter(boom, time.Minute), false, outcome{status: StatusPending, retryIn: time.Minute}},
+		{"retry now", 2, RetryAfter(boom, 0), false, outcome{status: StatusPending}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job := &Job{Attempts: tt.attempts, MaxAttempts: 5}
+			if got := decide(job, tt.err, tt.shutdown); got != tt.want {
+				t.Fatalf("decide = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	// Other failures wait out the backoff.
+	got := decide(&Job{Attempts: 3, MaxAttempts: 5}, boom, false)
+	if got.status != StatusPending || got.refund {
+		t.Fatalf("decide = %+v, want a pending retry", got)
+	}
+	if lo, hi := 36*time.Second, 44*time.Second; got.retryIn < lo || got.retryIn > hi {
+		t.Fatalf("retry in %v, want between %v and %v", got.retryIn, lo, hi)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{1, 10 * time.Second},
+		{2, 20 * time.Second},
+		{3, 40 * time.Second},
+		{6, 320 * time.Second},
+		{9, 2560 * time.Second},
+		{10, time.Hour},
+		{100, time.Hour},
+	}
+	for _, tt := range tests {
+		lo := time.Duration(float64(tt.want) * 0.9)
+		hi := time.Duration(float64(tt.want) * 1.1)
+		seen := make(map[time.Duration]bool)
+		for range 200 {
+			d := backoff(tt.failures)
+			if d < lo || d > hi {
+				t.Fatalf("backoff(%d) = %v, want between %v and %v", tt.failures, d, lo, hi)
+			}
+			seen[d] = true
+		}
+		// Jitter spreads retries out rather than returning one value.
+		if len(seen) < 2 {
+			t.Errorf("backoff(%d) has no jitter", tt.failures)
+		}
+	}
+}
+
+type testArgs struct {
+	N int `json:"n"`
+}
+
+func (testArgs) Kind() string { return "test.job" }
+
+func TestRegister(t *testing.T) {
+	q := &Queue{handlers: make(map[string]handler)}
+	var got testArgs
+	Register(q, func(ctx context.Context, args testArgs) error {
+		got = args
+		return nil
+	})
+
+	h := q.handlers["test.job"]
+	if h == nil {
+		t.Fatal("handler not registered under its kind")
+	}
+	if err := h(context.Background(), json.RawMessage(`{"n":7}`)); err != nil || got.N != 7 {
+		t.Fatalf("handler got %+v, err %v; want n 7", got, err)
+	}
+
+	// Args that don't decode will never decode, so the job shouldn't be
+	// retried.
+	err := h(context.Background(), json.RawMessage(`{"n":"seven"}`))
+	if !errors.As(err, new(permanentError)) {
+		t.Fatalf("bad args error = %v, want a permanent error", err)
+	}
+}
+
+func TestCallRecoversPanic(t *testing.T) {
+	q := &Queue{handlers: make(map[string]handler)}
+	Register(q, func(ctx context.Context, args testArgs) error {
+		panic("handler bug")
+	})
+
+	err := q.call(context.Background(), &Job{Kind: "test.job", Args: json.RawMessage(`{}`)})
+	if err == nil || err.Error() != "panic: handler bug" {
+		t.Fatalf("call error = %v, want the panic", err)
+	}
+}
+
+func TestAttempt(t *testing.T) {
+	if n, maxAttempts := Attempt(context.Background()); n != 0 || maxAttempts != 0 {
+		t.Fatalf("Attempt outside a job = %d, %d; want 0, 0", n, maxAttempts)
+	}
+
+	ctx := context.WithValue(context.Background(), attemptKey, attempt{n: 2, max: 8})
+	if n, maxAttempts := Attempt(ctx); n != 2 || maxAttempts != 8 {
+		t.Fatalf("Attempt = %d, %d; want 2, 8", n, maxAttempts)
+	}
+}
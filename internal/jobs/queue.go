@@ -0,0 +1,293 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jesusthecreator017/fswithgo/internal/logging"
+	"github.com/jesusthecreator017/fswithgo/internal/store/dbsqlc"
+)
+
+type Config struct {
+	Workers      int           // jobs run at once
+	PollInterval time.Duration // how often idle workers look for due jobs
+	// Timeout is how long a job may run. Its lock lasts a little longer,
+	// after which a job whose worker died is claimed again.
+	Timeout time.Duration
+	// Retention is how long Prune keeps succeeded jobs; dead jobs are kept
+	// until they are retried.
+	Retention time.Duration
+}
+
+// Retries wait backoffBase, doubling after each failure, up to backoffMax,
+// with some jitter so failed jobs don't all come back at once.
+const (
+	backoffBase = 10 * time.Second
+	backoffMax  = time.Hour
+)
+
+// lockGrace covers the time between a job's timeout and its worker
+// recording the result.
+const lockGrace = 30 * time.Second
+
+// errShutdown cancels the jobs still running when Shutdown gives up
+// waiting for them.
+var errShutdown = errors.New("worker shutting down")
+
+type handler func(ctx context.Context, args json.RawMessage) error
+
+// Queue enqueues jobs and, once started, runs the kinds registered on it.
+type Queue struct {
+	queries  *dbsqlc.Queries
+	cfg      Config
+	handlers map[string]handler
+
+	wake     chan struct{}
+	stopping chan struct{}
+	stopOnce sync.Once
+	cancel   context.CancelCauseFunc
+	wg       sync.WaitGroup
+	polled   atomic.Int64 // unix nanoseconds
+}
+
+func New(pool *pgxpool.Pool, cfg Config) *Queue {
+	return &Queue{
+		queries:  dbsqlc.New(pool),
+		cfg:      cfg,
+		handlers: make(map[string]handler),
+		wake:     make(chan struct{}, 1),
+		stopping: make(chan struct{}),
+	}
+}
+
+// Register sets the handler for jobs whose args are T. Register every kind
+// before calling Start.
+func Register[T Args](q *Queue, handle func(ctx context.Context, args T) error) {
+	var zero T
+	q.handlers[zero.Kind()] = func(ctx context.Context, data json.RawMessage) error {
+		var args T
+		if err := json.Unmarshal(data, &args); err != nil {
+			return Permanent(fmt.Errorf("decoding args: %w", err))
+		}
+		return handle(ctx, args)
+	}
+}
+
+// Start launches the workers. They only claim jobs of registered kinds.
+func (q *Queue) Start() {
+	ctx, cancel := context.WithCancelCause(context.Background())
+	q.cancel = cancel
+
+	for range q.cfg.Workers {
+		q.wg.Go(func() { q.work(ctx) })
+	}
+}
+
+// Shutdown stops claiming jobs and waits for running ones to finish. If ctx
+// expires first, the running jobs are cancelled and go back to the queue.
+func (q *Queue) Shutdown(ctx context.Context) error {
+	q.stopOnce.Do(func() { close(q.stopping) })
+
+	done := make(chan struct{})
+	go func() {
+		q.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		if q.cancel != nil {
+			q.cancel(errShutdown)
+		}
+		return ctx.Err()
+	}
+}
+
+func (q *Queue) work(ctx context.Context) {
+	for {
+		select {
+		case <-q.stopping:
+			return
+		default:
+		}
+
+		q.polled.Store(time.Now().UnixNano())
+		job, err := q.claim(ctx)
+		if err != nil && ctx.Err() == nil {
+			slog.Error("claiming job", "error", err)
+		}
+		if job != nil {
+			q.run(ctx, job)
+			continue
+		}
+
+		select {
+		case <-q.stopping:
+			return
+		case <-q.wake:
+		case <-time.After(q.cfg.PollInterval):
+		}
+	}
+}
+
+// LastPoll returns when a worker last looked for a job, or the zero time
+// if none has. Workers look at least every PollInterval unless they are
+// all busy.
+func (q *Queue) LastPoll() time.Time {
+	if n := q.polled.Load(); n != 0 {
+		return time.Unix(0, n)
+	}
+	return time.Time{}
+}
+
+// wakeWorker has an idle worker look for jobs now rather than at its next
+// poll.
+func (q *Queue) wakeWorker() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// claim locks the next due job, or returns nil if there is none.
+func (q *Queue) claim(ctx context.Context) (*Job, error) {
+	kinds := make([]string, 0, len(q.handlers))
+	for kind := range q.handlers {
+		kinds = append(kinds, kind)
+	}
+
+	row, err := q.queries.ClaimJob(ctx, dbsqlc.ClaimJobParams{
+		LockSecs: (q.cfg.Timeout + lockGrace).Seconds(),
+		Kinds:    kinds,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return jobFromRow(row), nil
+}
+
+// run calls the job's handler and records the result.
+func (q *Queue) run(ctx context.Context, job *Job) {
+	var err error
+	if job.Attempts > job.MaxAttempts {
+		// Only a job whose worker kept dying gets here.
+		err = Permanent(errors.New("ran out of attempts without finishing"))
+	} else {
+		// Handlers log with the job's id and kind.
+		jobCtx := logging.With(ctx, "job_id", job.ID, "job_kind", job.Kind)
+		jobCtx = context.WithValue(jobCtx, attemptKey, attempt{n: job.Attempts, max: job.MaxAttempts})
+		jobCtx, cancel := context.WithTimeout(jobCtx, q.cfg.Timeout)
+		err = q.call(jobCtx, job)
+		cancel()
+	}
+
+	// Record the result even if the job was cancelled by Shutdown.
+	finishCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+	defer cancel()
+
+	if err := q.finish(finishCtx, job, err, errors.Is(context.Cause(ctx), errShutdown)); err != nil {
+		slog.Error("recording job result", "job_id", job.ID, "job_kind", job.Kind, "error", err)
+	}
+}
+
+// call runs the handler, turning a panic into an error.
+func (q *Queue) call(ctx context.Context, job *Job) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return q.handlers[job.Kind](ctx, job.Args)
+}
+
+// outcome is what becomes of a job after a run.
+type outcome struct {
+	status string
+	// retryIn is how long a pending job waits before its next attempt.
+	retryIn time.Duration
+	// refund puts the attempt back, for a run that was interrupted rather
+	// than failed.
+	refund bool
+}
+
+// decide works out a job's outcome from how its run went.
+func decide(job *Job, jobErr error, shutdown bool) outcome {
+	var retry retryError
+	switch {
+	case jobErr == nil:
+		return outcome{status: StatusSucceeded}
+	case shutdown:
+		return outcome{status: StatusPending, refund: true}
+	case job.Attempts >= job.MaxAttempts || errors.As(jobErr, new(permanentError)):
+		return outcome{status: StatusDead}
+	case errors.As(jobErr, &retry):
+		return outcome{status: StatusPending, retryIn: retry.after}
+	default:
+		return outcome{status: StatusPending, retryIn: backoff(job.Attempts)}
+	}
+}
+
+// finish records how a run went. The queries only touch the job if it
+// still has this run's attempt count, so they are a no-op if the job's lock
+// lapsed and another worker has since claimed it.
+func (q *Queue) finish(ctx context.Context, job *Job, jobErr error, shutdown bool) error {
+	out := decide(job, jobErr, shutdown)
+	attempts := int32(job.Attempts)
+
+	switch {
+	case out.status == StatusSucceeded:
+		return q.queries.CompleteJob(ctx, dbsqlc.CompleteJobParams{ID: job.ID, Attempts: attempts})
+
+	case out.refund:
+		return q.queries.ReleaseJob(ctx, dbsqlc.ReleaseJobParams{ID: job.ID, Attempts: attempts})
+
+	case out.status == StatusDead:
+		slog.Warn("job is dead", "job_id", job.ID, "job_kind", job.Kind, "error", jobErr)
+		return q.queries.KillJob(ctx, dbsqlc.KillJobParams{
+			LastError: pgtype.Text{String: jobErr.Error(), Valid: true},
+			ID:        job.ID,
+			Attempts:  attempts,
+		})
+
+	default:
+		return q.queries.RescheduleJob(ctx, dbsqlc.RescheduleJobParams{
+			LastError: pgtype.Text{String: jobErr.Error(), Valid: true},
+			RunAt:     pgtype.Timestamptz{Time: time.Now().Add(out.retryIn), Valid: true},
+			ID:        job.ID,
+			Attempts:  attempts,
+		})
+	}
+}
+
+// backoff is how long to wait after the given number of failed attempts.
+func backoff(failures int) time.Duration {
+	d := min(float64(backoffBase)*math.Pow(2, float64(failures-1)), float64(backoffMax))
+	// Up to 10% either way.
+	return time.Duration(d * (0.9 + 0.2*rand.Float64()))
+}
+
+// Prune deletes succeeded jobs older than the retention period and
+// returns how many it deleted.
+func (q *Queue) Prune(ctx context.Context) (int64, error) {
+	n, err := q.queries.PruneJobs(ctx, q.cfg.Retention.Seconds())
+	if err != nil {
+		return 0, fmt.Errorf("pruning jobs: %w", err)
+	}
+	return n, nil
+}
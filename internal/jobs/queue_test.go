@@ -0,0 +1,187 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// testQueue returns a queue on the migrated database in TEST_DATABASE_URL,
+// with testArgs registered to run handle. The tests skip without one.
+func testQueue(t *testing.T, handle func(context.Context, testArgs) error) *Queue {
+	t.Helper()
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+
+	ctx := context.Background()
+	pool, err := pgxpool.New(ctx, dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	deleteJobs := func() {
+		if _, err := pool.Exec(ctx, "DELETE FROM jobs WHERE kind = $1", testArgs{}.Kind()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	deleteJobs()
+	t.Cleanup(func() {
+		deleteJobs()
+		pool.Close()
+	})
+
+	q := New(pool, Config{Workers: 1, PollInterval: time.Second, Timeout: time.Minute})
+	Register(q, handle)
+	return q
+}
+
+func TestClaimSkipsLockedJobs(t *testing.T) {
+	q := testQueue(t, func(context.Context, testArgs) error { return nil })
+	ctx := context.Background()
+
+	const n = 20
+	for i := range n {
+		if _, err := q.Enqueue(ctx, testArgs{N: i}, EnqueueOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Workers claiming side by side each get different jobs, and between
+	// them get every one.
+	var (
+		mu      sync.Mutex
+		claimed = make(map[int64]int)
+		wg      sync.WaitGroup
+	)
+	for range 8 {
+		wg.Go(func() {
+			for {
+				job, err := q.claim(ctx)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if job == nil {
+					return
+				}
+				mu.Lock()
+				claimed[job.ID]++
+				mu.Unlock()
+			}
+		})
+	}
+	wg.Wait()
+
+	if len(claimed) != n {
+		t.Fatalf("claimed %d jobs, want %d", len(claimed), n)
+	}
+	for id, times := range claimed {
+		if times != 1 {
+			t.Errorf("job %d claimed %d times", id, times)
+		}
+	}
+}
+
+func TestEnqueueUniqueKey(t *testing.T) {
+	q := testQueue(t, func(context.Context, testArgs) error { return nil })
+	ctx := context.Background()
+	opts := EnqueueOptions{UniqueKey: "same"}
+
+	first, err := q.Enqueue(ctx, testArgs{N: 1}, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := q.Enqueue(ctx, testArgs{N: 2}, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.ID != first.ID {
+		t.Fatalf("second enqueue made job %d, want the queued job %d", second.ID, first.ID)
+	}
+
+	// Still deduplicated while running.
+	job, err := q.claim(ctx)
+	if err != nil || job == nil || job.ID != first.ID {
+		t.Fatalf("claim = %v, %v; want job %d", job, err, first.ID)
+	}
+	if again, err := q.Enqueue(ctx, testArgs{N: 3}, opts); err != nil || again.ID != first.ID {
+		t.Fatalf("enqueue while running = %v, %v; want job %d", again, err, first.ID)
+	}
+
+	// Once it has finished the key is free again.
+	q.run(ctx, job)
+	third, err := q.Enqueue(ctx, testArgs{N: 4}, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if third.ID == first.ID {
+		t.Fatal("enqueue after the job finished returned the finished job")
+	}
+}
+
+func TestJobDies(t *testing.T) {
+	q := testQueue(t, func(ctx context.Context, args testArgs) error {
+		if args.N < 0 {
+			return Permanent(errors.New("never going to work"))
+		}
+		// Retry straight away so the test can claim it again.
+		return RetryAfter(errors.New("boom"), 0)
+	})
+	ctx := context.Background()
+
+	queued, err := q.Enqueue(ctx, testArgs{N: 1}, EnqueueOptions{MaxAttempts: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for attempt := 1; attempt <= 2; attempt++ {
+		job, err := q.claim(ctx)
+		if err != nil || job == nil || job.ID != queued.ID {
+			t.Fatalf("attempt %d: claim = %v, %v; want job %d", attempt, job, err, queued.ID)
+		}
+		if job.Attempts != attempt {
+			t.Fatalf("attempt %d: job has %d attempts", attempt, job.Attempts)
+		}
+		q.run(ctx, job)
+	}
+
+	dead, err := q.Get(ctx, queued.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dead.Status != StatusDead || dead.LastError == nil || *dead.LastError != "boom" || dead.FinishedAt == nil {
+		t.Fatalf("job after its last attempt = %+v, want dead with its error", dead)
+	}
+	if job, err := q.claim(ctx); err != nil || job != nil {
+		t.Fatalf("claimed a dead job: %v, %v", job, err)
+	}
+
+	// Retrying gives it a fresh set of attempts.
+	retried, err := q.Retry(ctx, queued.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if retried.Status != StatusPending || retried.Attempts != 0 {
+		t.Fatalf("retried job = %+v, want pending with no attempts", retried)
+	}
+
+	// A permanent failure dies on its first attempt.
+	permanent, err := q.Enqueue(ctx, testArgs{N: -1}, EnqueueOptions{RunAt: time.Now().Add(-time.Minute)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	job, err := q.claim(ctx)
+	if err != nil || job == nil || job.ID != permanent.ID {
+		t.Fatalf("claim = %v, %v; want job %d", job, err, permanent.ID)
+	}
+	q.run(ctx, job)
+	if got, err := q.Get(ctx, permanent.ID); err != nil || got.Status != StatusDead || got.Attempts != 1 {
+		t.Fatalf("permanently failed job = %+v, %v; want dead after one attempt", got, err)
+	}
+}
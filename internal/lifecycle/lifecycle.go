@@ -0,0 +1,149 @@
+// Package lifecycle starts an application's subsystems in the order they
+// were added and stops them in reverse, so each one can rely on the ones
+// added before it for as long as it runs.
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// Hook is a subsystem's part in the lifecycle. Either function may be nil.
+// Stop is only called if Start succeeded, and should give up when its
+// context expires.
+type Hook struct {
+	Name  string
+	Start func(ctx context.Context) error
+	Stop  func(ctx context.Context) error
+}
+
+type Lifecycle struct {
+	mu      sync.Mutex
+	hooks   []Hook
+	started int
+
+	ready    atomic.Bool
+	stopping chan struct{}
+	stopOnce sync.Once
+	failed   chan error
+}
+
+func New() *Lifecycle {
+	return &Lifecycle{
+		stopping: make(chan struct{}),
+		failed:   make(chan error, 1),
+	}
+}
+
+// Append adds a hook. Add every hook before calling Start.
+func (l *Lifecycle) Append(h Hook) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.hooks = append(l.hooks, h)
+}
+
+// Start runs the start hooks in order and marks the application ready. If
+// one fails, Start returns its error; Stop then stops the hooks that had
+// started.
+func (l *Lifecycle) Start(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, h := range l.hooks[l.started:] {
+		if h.Start != nil {
+			if err := h.Start(ctx); err != nil {
+				return fmt.Errorf("starting %s: %w", h.Name, err)
+			}
+		}
+		l.started++
+	}
+	l.ready.Store(true)
+	return nil
+}
+
+// Ready reports whether the application has started and isn't draining
+// or stopping.
+func (l *Lifecycle) Ready() bool {
+	return l.ready.Load()
+}
+
+// Drain marks the application not ready, so load balancers stop sending it
+// traffic, while everything keeps running.
+func (l *Lifecycle) Drain() {
+	l.ready.Store(false)
+}
+
+// Stopping is closed when Stop begins. Long-lived work such as event
+// streams should end when it is.
+func (l *Lifecycle) Stopping() <-chan struct{} {
+	return l.stopping
+}
+
+// Fail reports that a subsystem died and the application should stop. Only
+// the first failure is kept.
+func (l *Lifecycle) Fail(err error) {
+	select {
+	case l.failed <- err:
+	default:
+	}
+}
+
+// Failed delivers the error passed to Fail.
+func (l *Lifecycle) Failed() <-chan error {
+	return l.failed
+}
+
+// Stop runs the stop hooks of the started subsystems in reverse order,
+// sharing ctx between them, and returns their errors. A hook whose turn
+// comes after ctx expires still runs, so it can cut its work short.
+func (l *Lifecycle) Stop(ctx context.Context) error {
+	l.ready.Store(false)
+	l.stopOnce.Do(func() { close(l.stopping) })
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var errs []error
+	for ; l.started > 0; l.started-- {
+		h := l.hooks[l.started-1]
+		if h.Stop == nil {
+			continue
+		}
+		if err := h.Stop(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("stopping %s: %w", h.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Go returns a hook that runs fn in its own goroutine from Start until
+// Stop cancels fn's context. Stop waits for fn to return.
+func Go(name string, fn func(ctx context.Context)) Hook {
+	var cancel context.CancelFunc
+	done := make(chan struct{})
+
+	return Hook{
+		Name: name,
+		Start: func(context.Context) error {
+			var ctx context.Context
+			ctx, cancel = context.WithCancel(context.Background())
+			go func() {
+				defer close(done)
+				fn(ctx)
+			}()
+			return nil
+		},
+		Stop: func(ctx context.Context) error {
+			cancel()
+			select {
+			case <-done:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		},
+	}
+}
@@ -0,0 +1,81 @@
+// Package logging builds the application's slog logger and carries a
+// request- or job-scoped logger in a context.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+// Config picks how much is logged and how.
+type Config struct {
+	Level  string // "debug", "info", "warn" or "error"
+	Format string // "json" or "text"
+}
+
+// New returns a logger writing to w. Attributes that look like
+// credentials are redacted.
+func New(w io.Writer, cfg Config) (*slog.Logger, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(cfg.Level)); err != nil {
+		return nil, fmt.Errorf("unknown log level %q", cfg.Level)
+	}
+	opts := &slog.HandlerOptions{Level: level, ReplaceAttr: redact}
+
+	switch cfg.Format {
+	case "json":
+		return slog.New(slog.NewJSONHandler(w, opts)), nil
+	case "text":
+		return slog.New(slog.NewTextHandler(w, opts)), nil
+	default:
+		return nil, fmt.Errorf("unknown log format %q", cfg.Format)
+	}
+}
+
+// Redacted replaces the value of a sensitive attribute.
+const Redacted = "[REDACTED]"
+
+// sensitiveKeys are matched, case-insensitively, anywhere in an
+// attribute's key, so "new_password" and the Authorization header in a
+// group of headers are both caught.
+var sensitiveKeys = []string{"authorization", "cookie", "password", "secret", "token", "signature", "sec-websocket-protocol"}
+
+func redact(_ []string, a slog.Attr) slog.Attr {
+	if a.Value.Kind() == slog.KindGroup {
+		return a
+	}
+	key := strings.ToLower(a.Key)
+	for _, s := range sensitiveKeys {
+		if strings.Contains(key, s) {
+			return slog.String(a.Key, Redacted)
+		}
+	}
+	// Catch a bearer token under a key no one thought of.
+	if a.Value.Kind() == slog.KindString && strings.HasPrefix(a.Value.String(), "Bearer ") {
+		return slog.String(a.Key, Redacted)
+	}
+	return a
+}
+
+type ctxKey struct{}
+
+// WithLogger returns ctx carrying l.
+func WithLogger(ctx context.Context, l *slog.Logger) context.Context {
+	return context.WithValue(ctx, ctxKey{}, l)
+}
+
+// FromContext returns the logger in ctx, or the default logger.
+func FromContext(ctx context.Context) *slog.Logger {
+	if l, ok := ctx.Value(ctxKey{}).(*slog.Logger); ok {
+		return l
+	}
+	return slog.Default()
+}
+
+// With returns ctx carrying its logger with args added.
+func With(ctx context.Context, args ...any) context.Context {
+	return WithLogger(ctx, FromContext(ctx).With(args...))
+}
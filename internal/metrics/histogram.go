@@ -0,0 +1,84 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"slices"
+	"sync"
+)
+
+// DefBuckets suit request latencies in seconds.
+var DefBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Histogram counts observations into buckets.
+type Histogram struct {
+	buckets []float64 // upper bounds, ascending
+
+	mu     sync.Mutex
+	counts []uint64 // per bucket, not cumulative
+	sum    float64
+	count  uint64
+}
+
+func (h *Histogram) Observe(v float64) {
+	// The first bucket whose bound is at least v; past the last one, v
+	// only counts toward +Inf.
+	i, _ := slices.BinarySearch(h.buckets, v)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if i < len(h.counts) {
+		h.counts[i]++
+	}
+	h.sum += v
+	h.count++
+}
+
+type HistogramVec struct {
+	*vec[Histogram]
+	buckets []float64
+}
+
+// NewHistogram creates a histogram with the given bucket upper bounds,
+// which must be ascending, and label names.
+func (r *Registry) NewHistogram(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	buckets = slices.Clone(buckets)
+	h := &HistogramVec{
+		vec: newVec(desc{name, help, "histogram", labels}, func() *Histogram {
+			return &Histogram{buckets: buckets, counts: make([]uint64, len(buckets))}
+		}),
+		buckets: buckets,
+	}
+	r.add(h)
+	return h
+}
+
+// With returns the histogram for the label values, in the order the label
+// names were given.
+func (h *HistogramVec) With(values ...string) *Histogram {
+	return h.with(values)
+}
+
+func (h *HistogramVec) write(w *bufio.Writer) {
+	h.writeHeader(w)
+	h.each(func(labels string, s *Histogram) {
+		s.mu.Lock()
+		counts, sum, count := slices.Clone(s.counts), s.sum, s.count
+		s.mu.Unlock()
+
+		// Bucket series carry le after the other labels.
+		prefix := "{"
+		if labels != "" {
+			prefix = labels[:len(labels)-1] + ","
+		}
+
+		var cumulative uint64
+		for i, bound := range h.buckets {
+			cumulative += counts[i]
+			fmt.Fprintf(w, "%s_bucket%sle=\"%s\"} %d\n", h.name, prefix, formatValue(bound), cumulative)
+		}
+		fmt.Fprintf(w, "%s_bucket%sle=\"+Inf\"} %d\n", h.name, prefix, count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.name, labels, formatValue(sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.name, labels, count)
+	})
+}
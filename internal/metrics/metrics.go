@@ -0,0 +1,285 @@
+// Package metrics keeps counters, gauges and histograms and writes them in
+// the Prometheus text format, without pulling in the Prometheus client.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Registry holds metrics and writes them in the order they were created.
+type Registry struct {
+	mu      sync.Mutex
+	metrics []metric
+}
+
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+type metric interface {
+	write(w *bufio.Writer)
+}
+
+func (r *Registry) add(m metric) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.metrics = append(r.metrics, m)
+}
+
+// Write writes every metric in the Prometheus text format.
+func (r *Registry) Write(w io.Writer) error {
+	r.mu.Lock()
+	metrics := slices.Clone(r.metrics)
+	r.mu.Unlock()
+
+	bw := bufio.NewWriter(w)
+	for _, m := range metrics {
+		m.write(bw)
+	}
+	return bw.Flush()
+}
+
+// Handler serves the registry to Prometheus.
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		r.Write(w)
+	})
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+)
+
+// desc is what every metric has: a name, help text and label names.
+type desc struct {
+	name   string
+	help   string
+	typ    string
+	labels []string
+}
+
+func (d *desc) writeHeader(w *bufio.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", d.name, helpEscaper.Replace(d.help), d.name, d.typ)
+}
+
+// vec holds one series per combination of label values.
+type vec[T any] struct {
+	desc
+	mu     sync.RWMutex
+	series map[string]*T
+	values map[string][]string
+	create func() *T
+}
+
+func newVec[T any](d desc, create func() *T) *vec[T] {
+	return &vec[T]{desc: d, series: make(map[string]*T), values: make(map[string][]string), create: create}
+}
+
+// with returns the series for the label values, creating it if needed.
+func (v *vec[T]) with(values []string) *T {
+	if len(values) != len(v.labels) {
+		panic(fmt.Sprintf("metrics: %s takes %d label values, got %d", v.name, len(v.labels), len(values)))
+	}
+	key := strings.Join(values, "\xff")
+
+	v.mu.RLock()
+	s, ok := v.series[key]
+	v.mu.RUnlock()
+	if ok {
+		return s
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if s, ok := v.series[key]; ok {
+		return s
+	}
+	s = v.create()
+	v.series[key] = s
+	v.values[key] = slices.Clone(values)
+	return s
+}
+
+// each calls fn for every series, ordered by label values so the output
+// is stable.
+func (v *vec[T]) each(fn func(labels string, s *T)) {
+	v.mu.RLock()
+	keys := make([]string, 0, len(v.series))
+	for k := range v.series {
+		keys = append(keys, k)
+	}
+	v.mu.RUnlock()
+	slices.Sort(keys)
+
+	for _, k := range keys {
+		v.mu.RLock()
+		s, values := v.series[k], v.values[k]
+		v.mu.RUnlock()
+		fn(formatLabels(v.labels, values), s)
+	}
+}
+
+// formatLabels renders {name="value",...}, or nothing if there are none.
+func formatLabels(names, values []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, name := range names {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(name)
+		b.WriteString(`="`)
+		b.WriteString(labelEscaper.Replace(values[i]))
+		b.WriteByte('"')
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+func formatValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	default:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+}
+
+// value is a float64 that can be changed atomically.
+type value struct {
+	bits atomic.Uint64
+}
+
+func (v *value) add(delta float64) {
+	for {
+		old := v.bits.Load()
+		if v.bits.CompareAndSwap(old, math.Float64bits(math.Float64frombits(old)+delta)) {
+			return
+		}
+	}
+}
+
+func (v *value) load() float64 {
+	return math.Float64frombits(v.bits.Load())
+}
+
+// Counter only goes up.
+type Counter struct {
+	v value
+}
+
+func (c *Counter) Inc() {
+	c.v.add(1)
+}
+
+// Add adds delta, which must not be negative.
+func (c *Counter) Add(delta float64) {
+	c.v.add(delta)
+}
+
+type CounterVec struct {
+	*vec[Counter]
+}
+
+// NewCounter creates a counter with the given label names. Counter names
+// should end in _total.
+func (r *Registry) NewCounter(name, help string, labels ...string) *CounterVec {
+	c := &CounterVec{newVec(desc{name, help, "counter", labels}, func() *Counter { return new(Counter) })}
+	r.add(c)
+	return c
+}
+
+// With returns the counter for the label values, in the order the label
+// names were given.
+func (c *CounterVec) With(values ...string) *Counter {
+	return c.with(values)
+}
+
+func (c *CounterVec) write(w *bufio.Writer) {
+	c.writeHeader(w)
+	c.each(func(labels string, s *Counter) {
+		fmt.Fprintf(w, "%s%s %s\n", c.name, labels, formatValue(s.v.load()))
+	})
+}
+
+// Gauge goes up and down.
+type Gauge struct {
+	v value
+}
+
+func (g *Gauge) Set(v float64) {
+	g.v.bits.Store(math.Float64bits(v))
+}
+
+func (g *Gauge) Add(delta float64) {
+	g.v.add(delta)
+}
+
+func (g *Gauge) Inc() {
+	g.v.add(1)
+}
+
+func (g *Gauge) Dec() {
+	g.v.add(-1)
+}
+
+type GaugeVec struct {
+	*vec[Gauge]
+}
+
+func (r *Registry) NewGauge(name, help string, labels ...string) *GaugeVec {
+	g := &GaugeVec{newVec(desc{name, help, "gauge", labels}, func() *Gauge { return new(Gauge) })}
+	r.add(g)
+	return g
+}
+
+// With returns the gauge for the label values, in the order the label
+// names were given.
+func (g *GaugeVec) With(values ...string) *Gauge {
+	return g.with(values)
+}
+
+func (g *GaugeVec) write(w *bufio.Writer) {
+	g.writeHeader(w)
+	g.each(func(labels string, s *Gauge) {
+		fmt.Fprintf(w, "%s%s %s\n", g.name, labels, formatValue(s.v.load()))
+	})
+}
+
+// funcMetric reads its value when the registry is written.
+type funcMetric struct {
+	desc
+	fn func() float64
+}
+
+// GaugeFunc adds a gauge whose value fn returns at scrape time.
+func (r *Registry) GaugeFunc(name, help string, fn func() float64) {
+	r.add(&funcMetric{desc{name: name, help: help, typ: "gauge"}, fn})
+}
+
+// CounterFunc adds a counter whose value fn returns at scrape time, for
+// counts kept elsewhere.
+func (r *Registry) CounterFunc(name, help string, fn func() float64) {
+	r.add(&funcMetric{desc{name: name, help: help, typ: "counter"}, fn})
+}
+
+func (f *funcMetric) write(w *bufio.Writer) {
+	f.writeHeader(w)
+	fmt.Fprintf(w, "%s %s\n", f.name, formatValue(f.fn()))
+}
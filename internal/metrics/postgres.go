@@ -0,0 +1,31 @@
+package metrics
+
+import "github.com/jackc/pgx/v5/pgxpool"
+
+// RegisterPool adds the connection pool's statistics.
+func RegisterPool(r *Registry, pool *pgxpool.Pool) {
+	r.GaugeFunc("db_pool_acquired_conns", "Connections currently in use.", func() float64 {
+		return float64(pool.Stat().AcquiredConns())
+	})
+	r.GaugeFunc("db_pool_idle_conns", "Connections currently idle.", func() float64 {
+		return float64(pool.Stat().IdleConns())
+	})
+	r.GaugeFunc("db_pool_total_conns", "Connections open, including ones being opened.", func() float64 {
+		return float64(pool.Stat().TotalConns())
+	})
+	r.GaugeFunc("db_pool_max_conns", "Most connections the pool will open.", func() float64 {
+		return float64(pool.Stat().MaxConns())
+	})
+	r.CounterFunc("db_pool_acquires_total", "Connections acquired from the pool.", func() float64 {
+		return float64(pool.Stat().AcquireCount())
+	})
+	r.CounterFunc("db_pool_empty_acquires_total", "Acquires that had to wait because no connection was idle.", func() float64 {
+		return float64(pool.Stat().EmptyAcquireCount())
+	})
+	r.CounterFunc("db_pool_empty_acquire_wait_seconds_total", "Time spent waiting in acquires that found no idle connection.", func() float64 {
+		return pool.Stat().EmptyAcquireWaitTime().Seconds()
+	})
+	r.CounterFunc("db_pool_canceled_acquires_total", "Acquires cancelled before getting a connection.", func() float64 {
+		return float64(pool.Stat().CanceledAcquireCount())
+	})
+}
@@ -0,0 +1,53 @@
+package metrics
+
+import (
+	"runtime"
+	"runtime/metrics"
+	"time"
+)
+
+// RegisterRuntime adds Go runtime and process metrics under the names the
+// Prometheus client uses, so existing dashboards work.
+func RegisterRuntime(r *Registry) {
+	info := r.NewGauge("go_info", "Information about the Go environment.", "version")
+	info.With(runtime.Version()).Set(1)
+
+	start := float64(time.Now().Unix())
+	r.GaugeFunc("process_start_time_seconds", "Start time of the process since unix epoch in seconds.", func() float64 {
+		return start
+	})
+
+	r.GaugeFunc("go_goroutines", "Number of goroutines that currently exist.", func() float64 {
+		return float64(runtime.NumGoroutine())
+	})
+	r.GaugeFunc("go_gomaxprocs", "The value of GOMAXPROCS.", func() float64 {
+		return float64(runtime.GOMAXPROCS(0))
+	})
+	r.GaugeFunc("go_memstats_heap_alloc_bytes", "Number of heap bytes allocated and still in use.",
+		runtimeSample("/memory/classes/heap/objects:bytes"))
+	r.GaugeFunc("go_memstats_sys_bytes", "Number of bytes obtained from system.",
+		runtimeSample("/memory/classes/total:bytes"))
+	r.GaugeFunc("go_gc_heap_goal_bytes", "Heap size target for the end of the GC cycle.",
+		runtimeSample("/gc/heap/goal:bytes"))
+	r.CounterFunc("go_gc_cycles_total", "Count of all completed GC cycles.",
+		runtimeSample("/gc/cycles/total:gc-cycles"))
+	r.CounterFunc("go_memstats_alloc_bytes_total", "Total number of bytes allocated, even if freed.",
+		runtimeSample("/gc/heap/allocs:bytes"))
+}
+
+// runtimeSample reads one runtime/metrics value. Unlike
+// runtime.ReadMemStats, it doesn't stop the world.
+func runtimeSample(name string) func() float64 {
+	return func() float64 {
+		s := []metrics.Sample{{Name: name}}
+		metrics.Read(s)
+		switch s[0].Value.Kind() {
+		case metrics.KindUint64:
+			return float64(s[0].Value.Uint64())
+		case metrics.KindFloat64:
+			return s[0].Value.Float64()
+		default:
+			return 0
+		}
+	}
+}
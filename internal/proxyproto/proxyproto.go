@@ -0,0 +1,233 @@
+// Package proxyproto reads the PROXY protocol header (v1 text or v2
+// binary) that load balancers such as HAProxy and AWS NLB put in front of
+// a TCP connection, so the server sees the client's address rather than
+// the load balancer's.
+package proxyproto
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	v1Prefix    = []byte("PROXY ")
+	v2Signature = []byte("\r\n\r\n\x00\r\nQUIT\n")
+)
+
+// v1MaxLen is the longest a v1 header may be, CRLF included.
+const v1MaxLen = 107
+
+// Listener reads a PROXY header from connections whose peer is in
+// Trusted. The header is optional, so health checks that connect directly
+// still work; connections from anyone else are left alone, so their
+// headers aren't believed.
+type Listener struct {
+	net.Listener
+	Trusted []netip.Prefix
+	// Timeout bounds how long reading the header may take.
+	Timeout time.Duration
+}
+
+func NewListener(ln net.Listener, trusted []netip.Prefix, timeout time.Duration) *Listener {
+	return &Listener{Listener: ln, Trusted: trusted, Timeout: timeout}
+}
+
+// Accept returns the next connection. Its header is read on the first
+// Read or RemoteAddr call, in the connection's goroutine rather than the
+// accept loop, so a slow client can't hold up others.
+func (l *Listener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	if !Contains(l.Trusted, addrOf(conn.RemoteAddr())) {
+		return conn, nil
+	}
+	return &Conn{Conn: conn, r: bufio.NewReader(conn), timeout: l.Timeout}, nil
+}
+
+// Conn is a connection that may start with a PROXY header.
+type Conn struct {
+	net.Conn
+	r       *bufio.Reader
+	timeout time.Duration
+
+	once   sync.Once
+	remote net.Addr // from the header, if it had one
+	err    error
+}
+
+func (c *Conn) Read(b []byte) (int, error) {
+	c.once.Do(c.readHeader)
+	if c.err != nil {
+		return 0, c.err
+	}
+	return c.r.Read(b)
+}
+
+// RemoteAddr returns the client's address from the header, or the peer's
+// if there was none.
+func (c *Conn) RemoteAddr() net.Addr {
+	c.once.Do(c.readHeader)
+	if c.remote != nil {
+		return c.remote
+	}
+	return c.Conn.RemoteAddr()
+}
+
+func (c *Conn) readHeader() {
+	if c.timeout > 0 {
+		c.Conn.SetReadDeadline(time.Now().Add(c.timeout))
+		defer c.Conn.SetReadDeadline(time.Time{})
+	}
+
+	c.remote, c.err = readHeader(c.r)
+	if c.err != nil {
+		c.err = fmt.Errorf("proxyproto: %w", c.err)
+		c.Conn.Close()
+	}
+}
+
+// readHeader consumes a header from r if there is one and returns the
+// source address it gives. It returns a nil address for no header, a v1
+// UNKNOWN header or a v2 LOCAL one.
+func readHeader(r *bufio.Reader) (net.Addr, error) {
+	// Peek for the shorter signature first: a connection that sends less
+	// than 12 bytes and waits can't be a v2 header.
+	if b, err := r.Peek(len(v1Prefix)); err != nil || !bytes.Equal(b, v1Prefix) {
+		if b, err := r.Peek(len(v2Signature)); err == nil && bytes.Equal(b, v2Signature) {
+			return readV2(r)
+		}
+		// Not a header, or the client hung up; let the server find out.
+		return nil, nil
+	}
+	return readV1(r)
+}
+
+// readV1 reads "PROXY TCP4 <src> <dst> <src port> <dst port>\r\n".
+func readV1(r *bufio.Reader) (net.Addr, error) {
+	var line []byte
+	for !bytes.HasSuffix(line, []byte("\r\n")) {
+		if len(line) >= v1MaxLen {
+			return nil, errors.New("v1 header too long")
+		}
+		b, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		line = append(line, b)
+	}
+
+	fields := strings.Fields(string(line))
+	if len(fields) >= 2 && fields[1] == "UNKNOWN" {
+		return nil, nil
+	}
+	if len(fields) != 6 || (fields[1] != "TCP4" && fields[1] != "TCP6") {
+		return nil, fmt.Errorf("bad v1 header %q", line)
+	}
+	addr, err := netip.ParseAddr(fields[2])
+	if err != nil {
+		return nil, fmt.Errorf("bad v1 source address: %w", err)
+	}
+	port, err := strconv.ParseUint(fields[4], 10, 16)
+	if err != nil {
+		return nil, fmt.Errorf("bad v1 source port: %w", err)
+	}
+	return net.TCPAddrFromAddrPort(netip.AddrPortFrom(addr, uint16(port))), nil
+}
+
+// readV2 reads the binary header: the signature, version and command,
+// address family, length, then the addresses and any TLVs, which are
+// skipped.
+func readV2(r *bufio.Reader) (net.Addr, error) {
+	var head [16]byte
+	if _, err := io.ReadFull(r, head[:]); err != nil {
+		return nil, err
+	}
+	verCmd, family := head[12], head[13]
+	length := int(binary.BigEndian.Uint16(head[14:]))
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, err
+	}
+
+	if verCmd>>4 != 2 {
+		return nil, fmt.Errorf("unsupported v2 version %d", verCmd>>4)
+	}
+	switch verCmd & 0xf {
+	case 0: // LOCAL: the load balancer's own connection, e.g. a health check
+		return nil, nil
+	case 1: // PROXY
+	default:
+		return nil, fmt.Errorf("unknown v2 command %d", verCmd&0xf)
+	}
+
+	var addrLen int
+	switch family >> 4 {
+	case 1: // IPv4
+		addrLen = 4
+	case 2: // IPv6
+		addrLen = 16
+	default: // unix sockets and unspecified have no IP to give
+		return nil, nil
+	}
+	if len(body) < 2*addrLen+4 {
+		return nil, errors.New("v2 header too short for its addresses")
+	}
+	addr, _ := netip.AddrFromSlice(body[:addrLen])
+	port := binary.BigEndian.Uint16(body[2*addrLen:])
+	return net.TCPAddrFromAddrPort(netip.AddrPortFrom(addr, port)), nil
+}
+
+// Contains reports whether addr is in any of prefixes.
+func Contains(prefixes []netip.Prefix, addr netip.Addr) bool {
+	addr = addr.Unmap()
+	for _, p := range prefixes {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+func addrOf(a net.Addr) netip.Addr {
+	if tcp, ok := a.(*net.TCPAddr); ok {
+		return tcp.AddrPort().Addr()
+	}
+	ap, _ := netip.ParseAddrPort(a.String())
+	return ap.Addr()
+}
+
+// ParsePrefixes reads a comma-separated list of CIDRs and addresses; an
+// address stands for itself alone.
+func ParsePrefixes(s string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	for entry := range strings.SplitSeq(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if p, err := netip.ParsePrefix(entry); err == nil {
+			prefixes = append(prefixes, p.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(entry)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not an address or CIDR", entry)
+		}
+		addr = addr.Unmap()
+		prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	return prefixes, nil
+}
@@ -0,0 +1,241 @@
+package proxyproto
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+)
+
+// v2 builds a binary header with the given version and command, address
+// family and body.
+func v2(verCmd, family byte, body []byte) string {
+	head := append([]byte{}, v2Signature...)
+	head = append(head, verCmd, family)
+	head = binary.BigEndian.AppendUint16(head, uint16(len(body)))
+	return string(append(head, body...))
+}
+
+// v2Addrs is the body of a header from src to dst.
+func v2Addrs(src, dst netip.AddrPort) []byte {
+	body := append(src.Addr().AsSlice(), dst.Addr().AsSlice()...)
+	body = binary.BigEndian.AppendUint16(body, src.Port())
+	return binary.BigEndian.AppendUint16(body, dst.Port())
+}
+
+func TestReadHeader(t *testing.T) {
+	src4 := netip.MustParseAddrPort("203.0.113.7:4711")
+	dst4 := netip.MustParseAddrPort("10.0.0.1:443")
+	src6 := netip.MustParseAddrPort("[2001:db8::17]:4711")
+	dst6 := netip.MustParseAddrPort("[fd00::1]:443")
+	const rest = "GET / HTTP/1.1\r\n"
+
+	tests := []struct {
+		name  string
+		input string
+		want  string // the source address; "" for none
+		left  string // what the server reads after
+	}{
+		{"no header", rest, "", rest},
+		{"short request", "GET", "", "GET"},
+		{"empty", "", "", ""},
+		{"v1 TCP4", "PROXY TCP4 203.0.113.7 10.0.0.1 4711 443\r\n" + rest, "203.0.113.7:4711", rest},
+		{"v1 TCP6", "PROXY TCP6 2001:db8::17 fd00::1 4711 443\r\n" + rest, "[2001:db8::17]:4711", rest},
+		{"v1 UNKNOWN", "PROXY UNKNOWN\r\n" + rest, "", rest},
+		{"v1 UNKNOWN with addresses", "PROXY UNKNOWN 203.0.113.7 10.0.0.1 4711 443\r\n" + rest, "", rest},
+		{"v2 IPv4", v2(0x21, 0x11, v2Addrs(src4, dst4)) + rest, "203.0.113.7:4711", rest},
+		{"v2 IPv6", v2(0x21, 0x21, v2Addrs(src6, dst6)) + rest, "[2001:db8::17]:4711", rest},
+		{"v2 with TLVs", v2(0x21, 0x11, append(v2Addrs(src4, dst4), 0x04, 0x00, 0x01, 0xff)) + rest, "203.0.113.7:4711", rest},
+		{"v2 LOCAL", v2(0x20, 0x00, nil) + rest, "", rest},
+		{"v2 unix socket", v2(0x21, 0x31, make([]byte, 216)) + rest, "", rest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := bufio.NewReader(strings.NewReader(tt.input))
+			addr, err := readHeader(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := ""
+			if addr != nil {
+				got = addr.String()
+			}
+			if got != tt.want {
+				t.Fatalf("source = %q, want %q", got, tt.want)
+			}
+
+			if left, _ := io.ReadAll(r); string(left) != tt.left {
+				t.Fatalf("left %q, want %q", left, tt.left)
+			}
+		})
+	}
+}
+
+func TestReadHeaderMalformed(t *testing.T) {
+	src4 := netip.MustParseAddrPort("203.0.113.7:4711")
+	dst4 := netip.MustParseAddrPort("10.0.0.1:443")
+	full := v2(0x21, 0x11, v2Addrs(src4, dst4))
+
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"v1 without CRLF", "PROXY TCP4 203.0.113.7 10.0.0.1 4711 443\n"},
+		{"v1 truncated", "PROXY TCP4 203.0.113.7 10.0"},
+		{"v1 too long", "PROXY TCP4 " + strings.Repeat("1", 200) + "\r\n"},
+		{"v1 too few fields", "PROXY TCP4 203.0.113.7 10.0.0.1 4711\r\n"},
+		{"v1 too many fields", "PROXY TCP4 203.0.113.7 10.0.0.1 4711 443 x\r\n"},
+		{"v1 unknown protocol", "PROXY UDP4 203.0.113.7 10.0.0.1 4711 443\r\n"},
+		{"v1 bad address", "PROXY TCP4 203.0.113.999 10.0.0.1 4711 443\r\n"},
+		{"v1 bad port", "PROXY TCP4 203.0.113.7 10.0.0.1 70000 443\r\n"},
+		{"v1 no protocol", "PROXY \r\n"},
+		{"v2 truncated head", full[:14]},
+		{"v2 truncated body", full[:len(full)-3]},
+		{"v2 wrong version", v2(0x11, 0x11, v2Addrs(src4, dst4))},
+		{"v2 unknown command", v2(0x22, 0x11, v2Addrs(src4, dst4))},
+		{"v2 IPv4 too short", v2(0x21, 0x11, make([]byte, 11))},
+		{"v2 IPv6 too short", v2(0x21, 0x21, make([]byte, 35))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := bufio.NewReader(strings.NewReader(tt.input))
+			if addr, err := readHeader(r); err == nil {
+				t.Fatalf("readHeader = %v, want an error", addr)
+			}
+		})
+	}
+}
+
+// dial connects to ln from loopback, sends data and returns the server's
+// side of the connection.
+func dial(t *testing.T, ln net.Listener, data string) (server, client net.Conn) {
+	t.Helper()
+	client, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Close() })
+	if data != "" {
+		if _, err := io.WriteString(client, data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	server, err = ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { server.Close() })
+	return server, client
+}
+
+func listen(t *testing.T, trusted string, timeout time.Duration) net.Listener {
+	t.Helper()
+	inner, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { inner.Close() })
+	return NewListener(inner, []netip.Prefix{netip.MustParsePrefix(trusted)}, timeout)
+}
+
+func TestListenerTrustedPeer(t *testing.T) {
+	ln := listen(t, "127.0.0.0/8", time.Second)
+	conn, client := dial(t, ln, "PROXY TCP4 203.0.113.7 10.0.0.1 4711 443\r\nhello")
+
+	if got := conn.RemoteAddr().String(); got != "203.0.113.7:4711" {
+		t.Fatalf("RemoteAddr = %q, want the header's source", got)
+	}
+	buf := make([]byte, 5)
+	if _, err := io.ReadFull(conn, buf); err != nil || string(buf) != "hello" {
+		t.Fatalf("read %q, %v; want the data after the header", buf, err)
+	}
+
+	// A header is optional, for health checks that connect directly.
+	conn, _ = dial(t, ln, "GET /v1/health HTTP/1.1\r\n")
+	if got := addrOf(conn.RemoteAddr()); got != addrOf(client.LocalAddr()) {
+		t.Fatalf("RemoteAddr without a header = %v, want the peer", got)
+	}
+}
+
+func TestListenerUntrustedPeer(t *testing.T) {
+	// A client that isn't the load balancer can't choose its address.
+	const header = "PROXY TCP4 203.0.113.7 10.0.0.1 4711 443\r\n"
+	ln := listen(t, "192.0.2.0/24", time.Second)
+	conn, client := dial(t, ln, header)
+
+	if got := addrOf(conn.RemoteAddr()); got != addrOf(client.LocalAddr()) {
+		t.Fatalf("RemoteAddr = %v, want the peer", got)
+	}
+	// Nor is the header taken out of the stream, so the server sees it as
+	// a malformed request.
+	buf := make([]byte, len(header))
+	if _, err := io.ReadFull(conn, buf); err != nil || string(buf) != header {
+		t.Fatalf("read %q, %v; want the header untouched", buf, err)
+	}
+}
+
+func TestListenerMalformedHeader(t *testing.T) {
+	ln := listen(t, "127.0.0.0/8", time.Second)
+	conn, _ := dial(t, ln, "PROXY TCP4 not-an-address 10.0.0.1 4711 443\r\nhello")
+
+	if _, err := conn.Read(make([]byte, 5)); err == nil {
+		t.Fatal("read after a malformed header succeeded")
+	}
+}
+
+func TestListenerHeaderTimeout(t *testing.T) {
+	const timeout = 100 * time.Millisecond
+	ln := listen(t, "127.0.0.0/8", timeout)
+
+	// A client that starts a header and stalls is cut off.
+	conn, _ := dial(t, ln, "PROXY TCP4 203.0.113.7")
+	start := time.Now()
+	_, err := conn.Read(make([]byte, 1))
+	if err == nil {
+		t.Fatal("read from a stalled header succeeded")
+	}
+	if elapsed := time.Since(start); elapsed > 10*timeout {
+		t.Fatalf("stalled header held the read for %v, want about %v", elapsed, timeout)
+	}
+
+	// One that sends nothing at all is left for the server's own timeouts,
+	// but doesn't hold up RemoteAddr past the header timeout either.
+	conn, client := dial(t, ln, "")
+	start = time.Now()
+	if got := addrOf(conn.RemoteAddr()); got != addrOf(client.LocalAddr()) {
+		t.Fatalf("RemoteAddr = %v, want the peer", got)
+	}
+	if elapsed := time.Since(start); elapsed > 10*timeout {
+		t.Fatalf("silent client held RemoteAddr for %v, want about %v", elapsed, timeout)
+	}
+}
+
+func TestParsePrefixes(t *testing.T) {
+	got, err := ParsePrefixes(" 10.0.0.0/8, 192.0.2.77/24 ,203.0.113.7, ::ffff:198.51.100.1, fd00::/8,")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []netip.Prefix{
+		netip.MustParsePrefix("10.0.0.0/8"),
+		netip.MustParsePrefix("192.0.2.0/24"),
+		netip.MustParsePrefix("203.0.113.7/32"),
+		netip.MustParsePrefix("198.51.100.1/32"),
+		netip.MustParsePrefix("fd00::/8"),
+	}
+	if len(got) != len(want) {
+		t.Fatalf("ParsePrefixes = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("ParsePrefixes = %v, want %v", got, want)
+		}
+	}
+
+	if _, err := ParsePrefixes("10.0.0.0/8, nonsense"); err == nil {
+		t.Fatal("ParsePrefixes accepted nonsense")
+	}
+}
@@ -0,0 +1,123 @@
+// Package rank generates sort keys for manually ordered lists.
+//
+// A key is a base-36 fraction between 0 and 1 written without the leading
+// "0." and without trailing zeros, so "i" is 0.5 and "0i" is 0.0138.
+// Keys compare correctly as plain byte strings (COLLATE "C" in Postgres),
+// and there is always room for another key between any two of them, so
+// moving one item only rewrites that item's key.
+package rank
+
+import (
+	"errors"
+	"strings"
+)
+
+const alphabet = "0123456789abcdefghijklmnopqrstuvwxyz"
+
+const base = len(alphabet)
+
+// MaxLen is the longest key callers should store. Repeated inserts into the
+// same gap make keys grow by about one character per five inserts; once a
+// key would pass MaxLen the list should be respread with Spread.
+const MaxLen = 16
+
+var (
+	ErrInvalidKey   = errors.New("rank: invalid key")
+	ErrInvalidRange = errors.New("rank: lower key must sort before upper key")
+)
+
+// Between returns a key that sorts after a and before b. An empty a means
+// the start of the list and an empty b means the end.
+func Between(a, b string) (string, error) {
+	if !valid(a) || !valid(b) {
+		return "", ErrInvalidKey
+	}
+	if b != "" && a >= b {
+		return "", ErrInvalidRange
+	}
+	return midpoint(a, b), nil
+}
+
+// Spread returns n keys in ascending order, evenly spaced and all of the
+// same short length, for rewriting a list whose keys have grown too long.
+func Spread(n int) []string {
+	if n <= 0 {
+		return nil
+	}
+
+	// Leave at least one digit of room between neighbours.
+	width, space := 1, base
+	for space/(n+1) < base && width < MaxLen-1 {
+		width++
+		space *= base
+	}
+	step := space / (n + 1)
+
+	keys := make([]string, n)
+	digits := make([]byte, width)
+	for i := range keys {
+		v := step * (i + 1)
+		for d := width - 1; d >= 0; d-- {
+			digits[d] = alphabet[v%base]
+			v /= base
+		}
+		keys[i] = strings.TrimRight(string(digits), "0")
+	}
+	return keys
+}
+
+func midpoint(a, b string) string {
+	// Copy any prefix the two keys share; a is padded with zeros.
+	if b != "" {
+		n := 0
+		for n < len(b) && digitAt(a, n) == strings.IndexByte(alphabet, b[n]) {
+			n++
+		}
+		if n > 0 {
+			return b[:n] + midpoint(tail(a, n), b[n:])
+		}
+	}
+
+	lo := digitAt(a, 0)
+	hi := base
+	if b != "" {
+		hi = strings.IndexByte(alphabet, b[0])
+	}
+
+	if hi-lo > 1 {
+		return string(alphabet[(lo+hi)/2])
+	}
+
+	// Adjacent first digits. b's first digit on its own already sorts
+	// between the two if b has more digits after it.
+	if len(b) > 1 {
+		return b[:1]
+	}
+	return string(alphabet[lo]) + midpoint(tail(a, 1), "")
+}
+
+func digitAt(key string, i int) int {
+	if i >= len(key) {
+		return 0
+	}
+	return strings.IndexByte(alphabet, key[i])
+}
+
+func tail(key string, n int) string {
+	if n >= len(key) {
+		return ""
+	}
+	return key[n:]
+}
+
+func valid(key string) bool {
+	if strings.HasSuffix(key, "0") {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		if strings.IndexByte(alphabet, key[i]) < 0 {
+			return false
+		}
+	}
+	return true
+}
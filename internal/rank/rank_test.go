@@ -0,0 +1,125 @@
+package rank
+
+import (
+	"errors"
+	"math/rand/v2"
+	"testing"
+)
+
+func TestBetween(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want string
+	}{
+		{"empty list", "", "", "i"},
+		{"before first", "", "i", "9"},
+		{"after last", "i", "", "r"},
+		{"wide gap", "a", "k", "f"},
+		{"adjacent digits", "a", "b", "ai"},
+		{"adjacent, upper longer", "a", "b5", "b"},
+		{"adjacent at the top", "z", "", "zi"},
+		{"adjacent at the bottom", "", "1", "0i"},
+		{"lower is a prefix of upper", "a", "a1", "a0i"},
+		{"upper padded with zeros", "a", "a01", "a00i"},
+		{"shared deep prefix", "abc1", "abc2", "abc1i"},
+		{"shared deep prefix, lower shorter", "abc", "abc01", "abc00i"},
+		{"lower longer than upper", "azzz", "b", "azzzi"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Between(tt.a, tt.b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("Between(%q, %q) = %q, want %q", tt.a, tt.b, got, tt.want)
+			}
+			checkBetween(t, tt.a, tt.b, got)
+		})
+	}
+}
+
+func TestBetweenErrors(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want error
+	}{
+		{"A", "", ErrInvalidKey},
+		{"", "a-", ErrInvalidKey},
+		{"a0", "", ErrInvalidKey},
+		{"b", "a", ErrInvalidRange},
+		{"a", "a", ErrInvalidRange},
+	}
+	for _, tt := range tests {
+		if _, err := Between(tt.a, tt.b); !errors.Is(err, tt.want) {
+			t.Errorf("Between(%q, %q) error = %v, want %v", tt.a, tt.b, err, tt.want)
+		}
+	}
+}
+
+// TestBetweenRepeated keeps inserting into one gap, from either side, as
+// dragging cards into the same slot does.
+func TestBetweenRepeated(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 2))
+	a, b := "", ""
+	for i := 0; i < 500; i++ {
+		k, err := Between(a, b)
+		if err != nil {
+			t.Fatalf("step %d: Between(%q, %q): %v", i, a, b, err)
+		}
+		checkBetween(t, a, b, k)
+		if rng.IntN(2) == 0 {
+			a = k
+		} else {
+			b = k
+		}
+	}
+}
+
+func TestSpread(t *testing.T) {
+	for _, n := range []int{1, 2, 35, 36, 37, 1000, 50000} {
+		keys := Spread(n)
+		if len(keys) != n {
+			t.Fatalf("Spread(%d) returned %d keys", n, len(keys))
+		}
+		for i, k := range keys {
+			if k == "" || !valid(k) || len(k) > MaxLen {
+				t.Fatalf("Spread(%d)[%d] = %q is not a usable key", n, i, k)
+			}
+			if i > 0 && keys[i-1] >= k {
+				t.Fatalf("Spread(%d) not strictly ordered at %d: %q >= %q", n, i, keys[i-1], k)
+			}
+		}
+
+		// There must be room left around and between the new keys.
+		for i := 0; i <= n; i++ {
+			var a, b string
+			if i > 0 {
+				a = keys[i-1]
+			}
+			if i < n {
+				b = keys[i]
+			}
+			k, err := Between(a, b)
+			if err != nil {
+				t.Fatalf("Spread(%d): Between(%q, %q): %v", n, a, b, err)
+			}
+			checkBetween(t, a, b, k)
+		}
+	}
+
+	if keys := Spread(0); keys != nil {
+		t.Fatalf("Spread(0) = %v, want nil", keys)
+	}
+}
+
+func checkBetween(t *testing.T, a, b, k string) {
+	t.Helper()
+	if !valid(k) || k == "" {
+		t.Fatalf("Between(%q, %q) = %q is not a valid key", a, b, k)
+	}
+	if k <= a || (b != "" && k >= b) {
+		t.Fatalf("Between(%q, %q) = %q does not sort between them", a, b, k)
+	}
+}
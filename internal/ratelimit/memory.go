@@ -0,0 +1,65 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often Memory drops buckets that have refilled.
+const sweepInterval = time.Minute
+
+// Memory keeps buckets in this process. Each replica counts on its own.
+type Memory struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	swept   time.Time
+}
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	full    time.Time // when the bucket will have refilled
+}
+
+func NewMemory() *Memory {
+	return &Memory{buckets: make(map[string]*bucket), swept: time.Now()}
+}
+
+func (m *Memory) Take(_ context.Context, key string, l Limit) (Result, error) {
+	return m.take(key, l, time.Now()), nil
+}
+
+func (m *Memory) take(key string, l Limit, now time.Time) Result {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sweep(now)
+
+	b, ok := m.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(l.Burst), updated: now}
+		m.buckets[key] = b
+	}
+
+	tokens := l.refill(b.tokens, now.Sub(b.updated))
+	allowed := tokens >= 1
+	if allowed {
+		tokens--
+	}
+	b.tokens, b.updated = tokens, now
+	b.full = now.Add(l.after(float64(l.Burst) - tokens))
+	return l.result(tokens, allowed)
+}
+
+// sweep drops full buckets, which are no different from missing ones.
+func (m *Memory) sweep(now time.Time) {
+	if now.Sub(m.swept) < sweepInterval {
+		return
+	}
+	m.swept = now
+	for key, b := range m.buckets {
+		if !b.full.After(now) {
+			delete(m.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMemoryTake(t *testing.T) {
+	// Three at once, then one a second.
+	l := Limit{Burst: 3, Per: 3 * time.Second}
+	t0 := time.Now()
+	at := func(d time.Duration) time.Time { return t0.Add(d) }
+
+	tests := []struct {
+		name string
+		at   time.Time
+		want Result
+	}{
+		{"new bucket is full", at(0), Result{Allowed: true, Limit: 3, Remaining: 2, Reset: time.Second}},
+		{"burst", at(0), Result{Allowed: true, Limit: 3, Remaining: 1, Reset: 2 * time.Second}},
+		{"last of the burst", at(0), Result{Allowed: true, Limit: 3, Remaining: 0, Reset: 3 * time.Second}},
+		{"empty", at(0), Result{Allowed: false, Limit: 3, Remaining: 0, Reset: 3 * time.Second, RetryAfter: time.Second}},
+		{"half refilled", at(500 * time.Millisecond), Result{Allowed: false, Limit: 3, Remaining: 0, Reset: 2500 * time.Millisecond, RetryAfter: 500 * time.Millisecond}},
+		// The denied take above spent nothing.
+		{"refilled a token", at(time.Second), Result{Allowed: true, Limit: 3, Remaining: 0, Reset: 3 * time.Second}},
+		{"part of a token left over", at(2500 * time.Millisecond), Result{Allowed: true, Limit: 3, Remaining: 0, Reset: 2500 * time.Millisecond}},
+		{"left over part tops up", at(3 * time.Second), Result{Allowed: true, Limit: 3, Remaining: 0, Reset: 3 * time.Second}},
+		// Idle time refills up to the burst and no further.
+		{"idle", at(time.Hour), Result{Allowed: true, Limit: 3, Remaining: 2, Reset: time.Second}},
+	}
+
+	m := NewMemory()
+	m.swept = t0.Add(time.Hour)
+	for _, tt := range tests {
+		if got := m.take("k", l, tt.at); got != tt.want {
+			t.Fatalf("%s: take = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestMemoryTakeKeysAreSeparate(t *testing.T) {
+	l := Limit{Burst: 1, Per: time.Minute}
+	now := time.Now()
+	m := NewMemory()
+
+	if !m.take("a", l, now).Allowed {
+		t.Fatal("first take from a denied")
+	}
+	if m.take("a", l, now).Allowed {
+		t.Fatal("second take from a allowed")
+	}
+	if !m.take("b", l, now).Allowed {
+		t.Fatal("a's bucket limited b")
+	}
+}
+
+func TestMemorySweep(t *testing.T) {
+	l := Limit{Burst: 2, Per: time.Hour}
+	t0 := time.Now()
+	m := NewMemory()
+	m.swept = t0
+
+	m.take("full", Limit{Burst: 2, Per: time.Second}, t0)
+	m.take("draining", l, t0)
+
+	// The sweep runs once an interval; "full" has refilled by then and
+	// "draining" hasn't.
+	m.take("other", l, t0.Add(sweepInterval-time.Second))
+	if len(m.buckets) != 3 {
+		t.Fatalf("%d buckets before the sweep, want 3", len(m.buckets))
+	}
+	m.take("other", l, t0.Add(sweepInterval))
+	if _, ok := m.buckets["full"]; ok {
+		t.Fatal("full bucket not swept")
+	}
+	if _, ok := m.buckets["draining"]; !ok {
+		t.Fatal("bucket that hasn't refilled was swept")
+	}
+}
@@ -0,0 +1,57 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jesusthecreator017/fswithgo/internal/store/dbsqlc"
+)
+
+// Postgres keeps buckets in the rate_limit_buckets table, shared by every
+// replica. Each Take is one statement, so concurrent requests for the same
+// bucket can't both spend its last token.
+type Postgres struct {
+	queries *dbsqlc.Queries
+}
+
+func NewPostgres(pool *pgxpool.Pool) *Postgres {
+	return &Postgres{queries: dbsqlc.New(pool)}
+}
+
+func (p *Postgres) Take(ctx context.Context, key string, l Limit) (Result, error) {
+	tokens, err := p.queries.TakeRateLimitToken(ctx, dbsqlc.TakeRateLimitTokenParams{
+		Key:   key,
+		Burst: float64(l.Burst),
+		Rate:  l.rate(),
+	})
+	if err == nil {
+		return l.result(tokens, true), nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return Result{}, err
+	}
+
+	// Denied; read the bucket to tell the client when to come back.
+	tokens, err = p.queries.GetRateLimitTokens(ctx, dbsqlc.GetRateLimitTokensParams{
+		Burst: float64(l.Burst),
+		Rate:  l.rate(),
+		Key:   key,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		// Pruned in between, so it is full.
+		tokens = float64(l.Burst)
+	} else if err != nil {
+		return Result{}, err
+	}
+	return l.result(tokens, false), nil
+}
+
+// Prune deletes buckets untouched for idle, returning how many it
+// deleted. With idle at least the longest policy window, every bucket it
+// deletes has refilled.
+func (p *Postgres) Prune(ctx context.Context, idle time.Duration) (int64, error) {
+	return p.queries.PruneRateLimitBuckets(ctx, idle.Seconds())
+}
@@ -0,0 +1,124 @@
+// Package ratelimit limits how often a client may do something with token
+// buckets, kept in memory for one instance or in Postgres so a limit holds
+// across replicas.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Limit lets Burst requests through at once and refills the bucket at
+// Burst per Per. The zero Limit means no limit.
+type Limit struct {
+	Burst int
+	Per   time.Duration
+}
+
+// Unlimited reports whether l lets everything through.
+func (l Limit) Unlimited() bool {
+	return l.Burst <= 0 || l.Per <= 0
+}
+
+// rate is how many tokens are added per second.
+func (l Limit) rate() float64 {
+	return float64(l.Burst) / l.Per.Seconds()
+}
+
+// refill returns how many tokens a bucket holding tokens has after
+// elapsed.
+func (l Limit) refill(tokens float64, elapsed time.Duration) float64 {
+	return min(float64(l.Burst), tokens+elapsed.Seconds()*l.rate())
+}
+
+// Result is the outcome of taking a token.
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int           // whole tokens left
+	Reset      time.Duration // until the bucket is full again
+	RetryAfter time.Duration // until a token is available; zero if allowed
+}
+
+// result describes a bucket left holding tokens.
+func (l Limit) result(tokens float64, allowed bool) Result {
+	r := Result{
+		Allowed:   allowed,
+		Limit:     l.Burst,
+		Remaining: int(math.Floor(tokens)),
+		Reset:     l.after(float64(l.Burst) - tokens),
+	}
+	if !allowed {
+		r.RetryAfter = l.after(1 - tokens)
+	}
+	return r
+}
+
+// after returns how long refilling tokens takes.
+func (l Limit) after(tokens float64) time.Duration {
+	return time.Duration(max(tokens, 0) / l.rate() * float64(time.Second))
+}
+
+// Store keeps buckets. Take takes a token from key's bucket, filling it
+// for l if it is new.
+type Store interface {
+	Take(ctx context.Context, key string, l Limit) (Result, error)
+}
+
+var units = map[string]time.Duration{
+	"s": time.Second,
+	"m": time.Minute,
+	"h": time.Hour,
+	"d": 24 * time.Hour,
+}
+
+// ParseLimit reads a limit written "30/m": thirty requests a minute, all
+// of which may come at once. The window is s, m, h, d or a duration such
+// as 10m. "off" means no limit.
+func ParseLimit(s string) (Limit, error) {
+	s = strings.TrimSpace(s)
+	if s == "off" {
+		return Limit{}, nil
+	}
+	count, window, ok := strings.Cut(s, "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("rate limit %q: want <requests>/<window>", s)
+	}
+	burst, err := strconv.Atoi(count)
+	if err != nil || burst < 1 {
+		return Limit{}, fmt.Errorf("rate limit %q: requests must be a positive number", s)
+	}
+	per, ok := units[window]
+	if !ok {
+		per, err = time.ParseDuration(window)
+		if err != nil || per <= 0 {
+			return Limit{}, fmt.Errorf("rate limit %q: bad window %q", s, window)
+		}
+	}
+	return Limit{Burst: burst, Per: per}, nil
+}
+
+// ParsePolicies reads limits by route pattern, separated by semicolons:
+// "POST /v1/issues=60/m; POST /v1/users/register=5/h".
+func ParsePolicies(s string) (map[string]Limit, error) {
+	policies := make(map[string]Limit)
+	for entry := range strings.SplitSeq(s, ";") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		pattern, limit, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("rate limit policy %q: want <pattern>=<limit>", entry)
+		}
+		l, err := ParseLimit(limit)
+		if err != nil {
+			return nil, err
+		}
+		policies[strings.TrimSpace(pattern)] = l
+	}
+	return policies, nil
+}
@@ -0,0 +1,150 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func bits(vals ...int) uint64 {
+	var b uint64
+	for _, v := range vals {
+		b |= 1 << v
+	}
+	return b
+}
+
+func span(lo, hi int) uint64 {
+	var b uint64
+	for v := lo; v <= hi; v++ {
+		b |= 1 << v
+	}
+	return b
+}
+
+func TestParseCron(t *testing.T) {
+	tests := []struct {
+		spec string
+		want Cron
+	}{
+		{"* * * * *", Cron{span(0, 59), span(0, 23), span(1, 31), span(1, 12), span(0, 7), true, true}},
+		{"*/15 * * * *", Cron{bits(0, 15, 30, 45), span(0, 23), span(1, 31), span(1, 12), span(0, 7), true, true}},
+		{"0-30/10 9-17 * * *", Cron{bits(0, 10, 20, 30), span(9, 17), span(1, 31), span(1, 12), span(0, 7), true, true}},
+		{"5/20 0 * * *", Cron{bits(5, 25, 45), bits(0), span(1, 31), span(1, 12), span(0, 7), true, true}},
+		{"1,2,5-7 0 1,15 * *", Cron{bits(1, 2, 5, 6, 7), bits(0), bits(1, 15), span(1, 12), span(0, 7), false, true}},
+		{"0 0 * jan-mar,DEC mon-FRI", Cron{bits(0), bits(0), span(1, 31), bits(1, 2, 3, 12), span(1, 5), true, false}},
+		{"0 0 * * 7", Cron{bits(0), bits(0), span(1, 31), span(1, 12), bits(0, 7), true, false}},
+		{"0 0 * * sun", Cron{bits(0), bits(0), span(1, 31), span(1, 12), bits(0), true, false}},
+		{"0 0 13 * 5", Cron{bits(0), bits(0), bits(13), span(1, 12), bits(5), false, false}},
+		{"*/2 0 * * *", Cron{bits(0, 2, 4, 6, 8, 10, 12, 14, 16, 18, 20, 22, 24, 26, 28, 30, 32, 34, 36, 38, 40, 42, 44, 46, 48, 50, 52, 54, 56, 58), bits(0), span(1, 31), span(1, 12), span(0, 7), true, true}},
+		{"  @hourly ", Cron{bits(0), span(0, 23), span(1, 31), span(1, 12), span(0, 7), true, true}},
+		{"@DAILY", Cron{bits(0), bits(0), span(1, 31), span(1, 12), span(0, 7), true, true}},
+		{"@weekly", Cron{bits(0), bits(0), span(1, 31), span(1, 12), bits(0), true, false}},
+		{"@monthly", Cron{bits(0), bits(0), bits(1), span(1, 12), span(0, 7), false, true}},
+		{"@yearly", Cron{bits(0), bits(0), bits(1), bits(1), span(0, 7), false, true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			got, err := ParseCron(tt.spec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if *got != tt.want {
+				t.Fatalf("ParseCron(%q) = %+v, want %+v", tt.spec, *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseCronErrors(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"@every 5m",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * 32 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"-1 * * * *",
+		"*/0 * * * *",
+		"*/x * * * *",
+		"5-1 * * * *",
+		"1-x * * * *",
+		"a * * * *",
+		"* * * foo *",
+		"* * * * mon-",
+		"1,,2 * * * *",
+	} {
+		if c, err := ParseCron(spec); err == nil {
+			t.Errorf("ParseCron(%q) = %+v, want an error", spec, *c)
+		}
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	// A Saturday.
+	from := time.Date(2026, 3, 14, 10, 7, 30, 0, time.UTC)
+	at := func(month time.Month, day, hour, min int) time.Time {
+		return time.Date(2026, month, day, hour, min, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		spec string
+		from time.Time
+		want time.Time
+	}{
+		{"* * * * *", from, at(3, 14, 10, 8)},
+		{"*/15 * * * *", from, at(3, 14, 10, 15)},
+		{"10 10 * * *", from, at(3, 14, 10, 10)},
+		{"0 10 * * *", from, at(3, 15, 10, 0)},
+		{"@hourly", from, at(3, 14, 11, 0)},
+		{"@daily", from, at(3, 15, 0, 0)},
+		{"@weekly", from, at(3, 15, 0, 0)},
+		{"0 0 * * 7", from, at(3, 15, 0, 0)},
+		{"@monthly", from, at(4, 1, 0, 0)},
+		{"@yearly", from, time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 9-17/4 * * mon-fri", from, at(3, 16, 9, 0)},
+		{"0 12 * * 1", from, at(3, 16, 12, 0)},
+		{"0 12 20 * *", from, at(3, 20, 12, 0)},
+		// With both day fields restricted, either one matching will do.
+		{"0 12 13 * 5", from, at(3, 20, 12, 0)},
+		{"0 12 20 * 1", from, at(3, 16, 12, 0)},
+		// Months without the day are skipped.
+		{"0 0 31 * *", from, at(3, 31, 0, 0)},
+		{"0 0 31 * *", at(4, 1, 0, 0), at(5, 31, 0, 0)},
+		{"0 0 29 2 *", from, time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		// Strictly after: a time that matches gives the next match.
+		{"*/15 * * * *", at(3, 14, 10, 15), at(3, 14, 10, 30)},
+		{"59 23 31 12 *", time.Date(2026, 12, 31, 23, 59, 0, 0, time.UTC), time.Date(2027, 12, 31, 23, 59, 0, 0, time.UTC)},
+		// Never.
+		{"0 0 30 2 *", from, time.Time{}},
+		{"0 0 31 4,6,9,11 *", from, time.Time{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			c, err := ParseCron(tt.spec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := c.Next(tt.from); !got.Equal(tt.want) {
+				t.Fatalf("Next(%v) = %v, want %v", tt.from, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCronNextKeepsLocation(t *testing.T) {
+	loc := time.FixedZone("UTC+2", 2*60*60)
+	c, err := ParseCron("0 9 * * *")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := c.Next(time.Date(2026, 3, 14, 10, 0, 0, 0, loc))
+	want := time.Date(2026, 3, 15, 9, 0, 0, 0, loc)
+	if !got.Equal(want) || got.Location() != loc {
+		t.Fatalf("Next = %v, want %v", got, want)
+	}
+}
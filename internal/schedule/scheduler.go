@@ -0,0 +1,309 @@
+// Package schedule runs recurring maintenance tasks on cron schedules.
+// Every API replica schedules every task, but a Postgres advisory lock
+// lets only one of them run a task at a time, and each scheduled run is
+// recorded, and so done, once.
+package schedule
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jesusthecreator017/fswithgo/internal/logging"
+	"github.com/jesusthecreator017/fswithgo/internal/store/dbsqlc"
+)
+
+// Run statuses.
+const (
+	StatusRunning   = "running"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+)
+
+var ErrUnknownTask = errors.New("no such task")
+
+// historyRetention is how long run history is kept.
+const historyRetention = 30 * 24 * time.Hour
+
+// errShutdown cancels the tasks still running when Shutdown gives up
+// waiting for them.
+var errShutdown = errors.New("scheduler shutting down")
+
+// Run is one run of a task. ScheduledFor is the slot it ran for; a run
+// left running belonged to a replica that died.
+type Run struct {
+	ID           int64      `json:"id"`
+	Task         string     `json:"task"`
+	ScheduledFor time.Time  `json:"scheduled_for"`
+	Host         string     `json:"host"`
+	Status       string     `json:"status"`
+	Error        *string    `json:"error"`
+	StartedAt    time.Time  `json:"started_at"`
+	FinishedAt   *time.Time `json:"finished_at"`
+}
+
+// TaskStatus is a task's schedule with its next and most recent runs.
+type TaskStatus struct {
+	Name     string    `json:"name"`
+	Schedule string    `json:"schedule"`
+	NextRun  time.Time `json:"next_run"`
+	LastRun  *Run      `json:"last_run"`
+}
+
+type task struct {
+	name    string
+	spec    string
+	cron    *Cron
+	lockKey int64
+	run     func(context.Context) error
+}
+
+type Scheduler struct {
+	pool    *pgxpool.Pool
+	queries *dbsqlc.Queries
+	host    string
+	tasks   []*task
+
+	stopping chan struct{}
+	stopOnce sync.Once
+	cancel   context.CancelCauseFunc
+	wg       sync.WaitGroup
+}
+
+func New(pool *pgxpool.Pool) *Scheduler {
+	host, _ := os.Hostname()
+	return &Scheduler{
+		pool:     pool,
+		queries:  dbsqlc.New(pool),
+		host:     host,
+		stopping: make(chan struct{}),
+	}
+}
+
+// Add registers a task to run on the cron schedule spec, in UTC. Add every
+// task before calling Start.
+func (s *Scheduler) Add(name, spec string, run func(context.Context) error) error {
+	cron, err := ParseCron(spec)
+	if err != nil {
+		return fmt.Errorf("task %s: %w", name, err)
+	}
+
+	h := fnv.New64a()
+	h.Write([]byte("schedule:" + name))
+	s.tasks = append(s.tasks, &task{
+		name:    name,
+		spec:    spec,
+		cron:    cron,
+		lockKey: int64(h.Sum64()),
+		run:     run,
+	})
+	return nil
+}
+
+// Start schedules every task. Runs missed while no replica was up are
+// skipped, not caught up.
+func (s *Scheduler) Start() {
+	ctx, cancel := context.WithCancelCause(context.Background())
+	s.cancel = cancel
+
+	for _, t := range s.tasks {
+		s.wg.Go(func() { s.loop(ctx, t) })
+	}
+}
+
+// Shutdown stops scheduling and waits for running tasks to finish. If ctx
+// expires first, the running tasks are cancelled.
+func (s *Scheduler) Shutdown(ctx context.Context) error {
+	s.stopOnce.Do(func() { close(s.stopping) })
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		if s.cancel != nil {
+			s.cancel(errShutdown)
+		}
+		return ctx.Err()
+	}
+}
+
+func (s *Scheduler) loop(ctx context.Context, t *task) {
+	for {
+		next := t.cron.Next(time.Now().UTC())
+		if next.IsZero() {
+			slog.Warn("task never runs", "task", t.name, "schedule", t.spec)
+			return
+		}
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-s.stopping:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		if err := s.runOnce(ctx, t, next); err != nil && ctx.Err() == nil {
+			slog.Error("running task", "task", t.name, "error", err)
+		}
+	}
+}
+
+// runOnce runs t for the slot unless another replica is running it or
+// already has.
+func (s *Scheduler) runOnce(ctx context.Context, t *task, slot time.Time) error {
+	// A session-level advisory lock belongs to one connection, so hold one
+	// for the whole run.
+	conn, err := s.pool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("acquiring connection: %w", err)
+	}
+	defer conn.Release()
+
+	q := dbsqlc.New(conn)
+
+	locked, err := q.TryTaskLock(ctx, t.lockKey)
+	if err != nil {
+		return fmt.Errorf("locking: %w", err)
+	}
+	if !locked {
+		return nil
+	}
+	defer func() {
+		if err := q.UnlockTask(context.WithoutCancel(ctx), t.lockKey); err != nil {
+			// Closing the connection is the other way to let go.
+			conn.Conn().Close(context.WithoutCancel(ctx))
+		}
+	}()
+
+	runID, err := q.StartTaskRun(ctx, dbsqlc.StartTaskRunParams{
+		Task:         t.name,
+		ScheduledFor: pgtype.Timestamptz{Time: slot, Valid: true},
+		Host:         s.host,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("recording start: %w", err)
+	}
+
+	// Tasks log with their name and run.
+	runErr := call(logging.With(ctx, "task", t.name, "run_id", runID), t)
+
+	finish := dbsqlc.FinishTaskRunParams{ID: runID, Status: StatusSucceeded}
+	if runErr != nil {
+		finish.Status = StatusFailed
+		finish.Error = pgtype.Text{String: runErr.Error(), Valid: true}
+		slog.Error("task failed", "task", t.name, "run_id", runID, "error", runErr)
+	}
+
+	finishCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+	defer cancel()
+
+	if err := q.FinishTaskRun(finishCtx, finish); err != nil {
+		return fmt.Errorf("recording finish: %w", err)
+	}
+
+	if err := q.PruneTaskRuns(finishCtx, dbsqlc.PruneTaskRunsParams{
+		Task:          t.name,
+		RetentionSecs: historyRetention.Seconds(),
+	}); err != nil {
+		return fmt.Errorf("pruning history: %w", err)
+	}
+	return nil
+}
+
+// call runs the task, turning a panic into an error.
+func call(ctx context.Context, t *task) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return t.run(ctx)
+}
+
+// Status returns every task with its next run and its latest recorded run.
+func (s *Scheduler) Status(ctx context.Context) ([]TaskStatus, error) {
+	names := make([]string, len(s.tasks))
+	for i, t := range s.tasks {
+		names[i] = t.name
+	}
+
+	rows, err := s.queries.LastTaskRuns(ctx, names)
+	if err != nil {
+		return nil, fmt.Errorf("getting last runs: %w", err)
+	}
+
+	last := make(map[string]*Run, len(rows))
+	for _, row := range rows {
+		last[row.Task] = runFromRow(row)
+	}
+
+	now := time.Now().UTC()
+	statuses := make([]TaskStatus, len(s.tasks))
+	for i, t := range s.tasks {
+		statuses[i] = TaskStatus{
+			Name:     t.name,
+			Schedule: t.spec,
+			NextRun:  t.cron.Next(now),
+			LastRun:  last[t.name],
+		}
+	}
+	return statuses, nil
+}
+
+// Runs returns a task's most recent runs, newest first.
+func (s *Scheduler) Runs(ctx context.Context, name string, limit int) ([]*Run, error) {
+	known := false
+	for _, t := range s.tasks {
+		known = known || t.name == name
+	}
+	if !known {
+		return nil, ErrUnknownTask
+	}
+
+	rows, err := s.queries.ListTaskRuns(ctx, dbsqlc.ListTaskRunsParams{Task: name, MaxRuns: int32(limit)})
+	if err != nil {
+		return nil, fmt.Errorf("listing runs: %w", err)
+	}
+
+	runs := make([]*Run, len(rows))
+	for i, row := range rows {
+		runs[i] = runFromRow(row)
+	}
+	return runs, nil
+}
+
+func runFromRow(row dbsqlc.ScheduledTaskRun) *Run {
+	r := &Run{
+		ID:           row.ID,
+		Task:         row.Task,
+		ScheduledFor: row.ScheduledFor.Time,
+		Host:         row.Host,
+		Status:       row.Status,
+		StartedAt:    row.StartedAt.Time,
+	}
+	if row.Error.Valid {
+		r.Error = &row.Error.String
+	}
+	if row.FinishedAt.Valid {
+		r.FinishedAt = &row.FinishedAt.Time
+	}
+	return r
+}
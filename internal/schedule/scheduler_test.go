@@ -0,0 +1,182 @@
+package schedule
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// testPool returns a pool on the migrated database in TEST_DATABASE_URL.
+// The tests skip without one.
+func testPool(t *testing.T) *pgxpool.Pool {
+	t.Helper()
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+
+	ctx := context.Background()
+	pool, err := pgxpool.New(ctx, dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	deleteRuns := func() {
+		if _, err := pool.Exec(ctx, "DELETE FROM scheduled_task_runs WHERE task LIKE 'test.%'"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	deleteRuns()
+	t.Cleanup(func() {
+		deleteRuns()
+		pool.Close()
+	})
+	return pool
+}
+
+func TestRunOnceOncePerSlot(t *testing.T) {
+	pool := testPool(t)
+	ctx := context.Background()
+	slot := time.Now().UTC().Truncate(time.Minute)
+
+	// Replicas wake for the same slot at about the same time, or one a
+	// little late, once the other has finished.
+	var runs atomic.Int32
+	replicas := make([]*Scheduler, 4)
+	for i := range replicas {
+		replicas[i] = New(pool)
+		if err := replicas[i].Add("test.once", "* * * * *", func(context.Context) error {
+			runs.Add(1)
+			time.Sleep(50 * time.Millisecond)
+			return nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var wg sync.WaitGroup
+	for _, s := range replicas {
+		wg.Go(func() {
+			if err := s.runOnce(ctx, s.tasks[0], slot); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+	wg.Wait()
+	if err := replicas[0].runOnce(ctx, replicas[0].tasks[0], slot); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := runs.Load(); n != 1 {
+		t.Fatalf("task ran %d times for one slot, want once", n)
+	}
+
+	// The next slot runs again.
+	if err := replicas[1].runOnce(ctx, replicas[1].tasks[0], slot.Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if n := runs.Load(); n != 2 {
+		t.Fatalf("task ran %d times for two slots, want twice", n)
+	}
+
+	history, err := replicas[0].Runs(ctx, "test.once", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 2 || !history[0].ScheduledFor.Equal(slot.Add(time.Minute)) || !history[1].ScheduledFor.Equal(slot) {
+		t.Fatalf("history = %+v, want the two slots, newest first", history)
+	}
+	for _, r := range history {
+		if r.Status != StatusSucceeded || r.FinishedAt == nil || r.Error != nil {
+			t.Fatalf("run = %+v, want succeeded", r)
+		}
+	}
+}
+
+func TestRunOnceSkipsLockedTask(t *testing.T) {
+	pool := testPool(t)
+	ctx := context.Background()
+	slot := time.Now().UTC().Truncate(time.Minute)
+
+	var ran bool
+	s := New(pool)
+	if err := s.Add("test.locked", "* * * * *", func(context.Context) error {
+		ran = true
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	task := s.tasks[0]
+
+	// Another replica is running the task.
+	conn, err := pool.Acquire(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Release()
+	if _, err := conn.Exec(ctx, "SELECT pg_advisory_lock($1)", task.lockKey); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.runOnce(ctx, task, slot); err != nil {
+		t.Fatal(err)
+	}
+	if ran {
+		t.Fatal("task ran while another replica held its lock")
+	}
+
+	// The slot wasn't taken, so once the lock is free it still runs.
+	if _, err := conn.Exec(ctx, "SELECT pg_advisory_unlock($1)", task.lockKey); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.runOnce(ctx, task, slot); err != nil {
+		t.Fatal(err)
+	}
+	if !ran {
+		t.Fatal("task didn't run once its lock was free")
+	}
+}
+
+func TestRunOnceRecordsFailure(t *testing.T) {
+	pool := testPool(t)
+	ctx := context.Background()
+
+	s := New(pool)
+	if err := s.Add("test.failing", "@daily", func(context.Context) error {
+		return errors.New("disk full")
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Add("test.panicking", "@daily", func(context.Context) error {
+		panic("bug")
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	slot := time.Now().UTC().Truncate(time.Minute)
+	for _, task := range s.tasks {
+		if err := s.runOnce(ctx, task, slot); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	statuses, err := s.Status(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantErrs := map[string]string{"test.failing": "disk full", "test.panicking": "panic: bug"}
+	for _, st := range statuses {
+		r := st.LastRun
+		if r == nil || r.Status != StatusFailed || r.Error == nil || *r.Error != wantErrs[st.Name] {
+			t.Fatalf("%s last run = %+v, want failed with %q", st.Name, r, wantErrs[st.Name])
+		}
+		if !st.NextRun.After(time.Now()) {
+			t.Fatalf("%s next run %v is not in the future", st.Name, st.NextRun)
+		}
+	}
+}
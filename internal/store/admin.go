@@ -2,8 +2,14 @@ package store
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"slices"
 
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jesusthecreator017/fswithgo/internal/auth"
 	"github.com/jesusthecreator017/fswithgo/internal/store/dbsqlc"
 )
 
@@ -13,12 +19,50 @@ type IssueStatusCount struct {
 }
 
 type AdminStats struct {
-	TotalUsers    int64              `json:"total_users"`
-	TotalIssues   int64              `json:"total_issues"`
+	TotalUsers     int64              `json:"total_users"`
+	TotalIssues    int64              `json:"total_issues"`
 	IssuesByStatus []IssueStatusCount `json:"issues_by_status"`
 }
 
+// UserFilter narrows the admin user listing. Search matches email or name.
+type UserFilter struct {
+	Search   string
+	Page     int
+	PageSize int
+}
+
+// PageMetadata describes where a page sits in the full result set.
+type PageMetadata struct {
+	Page       int   `json:"page"`
+	PageSize   int   `json:"page_size"`
+	TotalCount int64 `json:"total_count"`
+}
+
+// UserDetail is a user as seen by an admin, including how many issues they own.
+type UserDetail struct {
+	User           *User              `json:"user"`
+	TotalIssues    int64              `json:"total_issues"`
+	IssuesByStatus []IssueStatusCount `json:"issues_by_status"`
+}
+
+// IssueDisposition decides what happens to a user's issues when the user is deleted.
+type IssueDisposition string
+
+const (
+	ReassignIssues IssueDisposition = "reassign"
+	CascadeIssues  IssueDisposition = "cascade"
+)
+
+type DeleteUserOptions struct {
+	Issues     IssueDisposition
+	ReassignTo uuid.UUID
+}
+
+// ErrLastAdmin is returned when a change would leave no active admin account.
+var ErrLastAdmin = errors.New("cannot remove the last active admin")
+
 type AdminStore struct {
+	db      *pgxpool.Pool
 	queries *dbsqlc.Queries
 }
 
@@ -49,3 +93,220 @@ func (a *AdminStore) GetStats(ctx context.Context) (*AdminStats, error) {
 		IssuesByStatus: issuesByStatus,
 	}, nil
 }
+
+func (a *AdminStore) ListUsers(ctx context.Context, filter UserFilter) ([]*User, PageMetadata, error) {
+	rows, err := a.queries.ListUsers(ctx, dbsqlc.ListUsersParams{
+		Search:     filter.Search,
+		PageLimit:  int32(filter.PageSize),
+		PageOffset: int32((filter.Page - 1) * filter.PageSize),
+	})
+	if err != nil {
+		return nil, PageMetadata{}, fmt.Errorf("listing users: %w", err)
+	}
+
+	meta := PageMetadata{Page: filter.Page, PageSize: filter.PageSize}
+	users := make([]*User, len(rows))
+	for i, row := range rows {
+		users[i] = &User{
+			ID:                    row.ID,
+			Email:                 row.Email,
+			Name:                  row.Name,
+			Permissions:           row.Permissions,
+			SuspendedAt:           timePtr(row.SuspendedAt),
+			PasswordResetRequired: row.PasswordResetRequired,
+			CreatedAt:             row.CreatedAt.Time,
+			UpdatedAt:             row.UpdatedAt.Time,
+		}
+		meta.TotalCount = row.TotalCount
+	}
+
+	return users, meta, nil
+}
+
+func (a *AdminStore) GetUserDetail(ctx context.Context, id uuid.UUID) (*UserDetail, error) {
+	row, err := a.queries.GetUserByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("getting user: %w", err)
+	}
+
+	counts, err := a.queries.CountIssuesByStatusForUser(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("counting user issues: %w", err)
+	}
+
+	detail := &UserDetail{
+		User: &User{
+			ID:                    row.ID,
+			Email:                 row.Email,
+			Name:                  row.Name,
+			Permissions:           row.Permissions,
+			SuspendedAt:           timePtr(row.SuspendedAt),
+			PasswordResetRequired: row.PasswordResetRequired,
+			CreatedAt:             row.CreatedAt.Time,
+			UpdatedAt:             row.UpdatedAt.Time,
+		},
+		IssuesByStatus: make([]IssueStatusCount, len(counts)),
+	}
+	for i, c := range counts {
+		detail.IssuesByStatus[i] = IssueStatusCount{Status: c.Status, Count: c.Count}
+		detail.TotalIssues += c.Count
+	}
+
+	return detail, nil
+}
+
+// UpdateUserPermissions replaces a user's permission bits. Dropping the admin
+// bit from the last active admin fails with ErrLastAdmin.
+func (a *AdminStore) UpdateUserPermissions(ctx context.Context, id uuid.UUID, permissions int32) (*User, error) {
+	var user *User
+	err := withTx(ctx, a.db, func(q *dbsqlc.Queries) error {
+		if !auth.HasPermission(auth.Permission(permissions), auth.PermAdmin) {
+			if err := ensureNotLastAdmin(ctx, q, id); err != nil {
+				return err
+			}
+		}
+
+		row, err := q.UpdateUserPermissions(ctx, dbsqlc.UpdateUserPermissionsParams{
+			ID:          id,
+			Permissions: permissions,
+		})
+		if err != nil {
+			return err
+		}
+		user = accountRowToDomain(dbsqlc.SuspendUserRow(row))
+		return nil
+	})
+	if err != nil {
+		return nil, wrapAccountErr("updating permissions", err)
+	}
+
+	return user, nil
+}
+
+// SuspendUser blocks the account from authenticating. Suspending the last
+// active admin fails with ErrLastAdmin.
+func (a *AdminStore) SuspendUser(ctx context.Context, id uuid.UUID) (*User, error) {
+	var user *User
+	err := withTx(ctx, a.db, func(q *dbsqlc.Queries) error {
+		if err := ensureNotLastAdmin(ctx, q, id); err != nil {
+			return err
+		}
+
+		row, err := q.SuspendUser(ctx, id)
+		if err != nil {
+			return err
+		}
+		user = accountRowToDomain(row)
+		return nil
+	})
+	if err != nil {
+		return nil, wrapAccountErr("suspending user", err)
+	}
+
+	return user, nil
+}
+
+func (a *AdminStore) ReactivateUser(ctx context.Context, id uuid.UUID) (*User, error) {
+	row, err := a.queries.ReactivateUser(ctx, id)
+	if err != nil {
+		return nil, wrapAccountErr("reactivating user", err)
+	}
+
+	return accountRowToDomain(dbsqlc.SuspendUserRow(row)), nil
+}
+
+// RequirePasswordReset flags the account so the user must choose a new
+// password before using any other protected route.
+func (a *AdminStore) RequirePasswordReset(ctx context.Context, id uuid.UUID) (*User, error) {
+	row, err := a.queries.RequireUserPasswordReset(ctx, id)
+	if err != nil {
+		return nil, wrapAccountErr("requiring password reset", err)
+	}
+
+	return accountRowToDomain(dbsqlc.SuspendUserRow(row)), nil
+}
+
+// DeleteUser removes the account and either hands its issues to another user
+// or deletes them, all in one transaction.
+func (a *AdminStore) DeleteUser(ctx context.Context, id uuid.UUID, opts DeleteUserOptions) error {
+	err := withTx(ctx, a.db, func(q *dbsqlc.Queries) error {
+		if err := ensureNotLastAdmin(ctx, q, id); err != nil {
+			return err
+		}
+
+		switch opts.Issues {
+		case ReassignIssues:
+			if err := q.ReassignUserIssues(ctx, dbsqlc.ReassignUserIssuesParams{
+				ToUserID:   opts.ReassignTo,
+				FromUserID: id,
+			}); err != nil {
+				return fmt.Errorf("reassigning issues: %w", err)
+			}
+		case CascadeIssues:
+			if err := q.DeleteIssuesByUserID(ctx, id); err != nil {
+				return fmt.Errorf("deleting issues: %w", err)
+			}
+		default:
+			return fmt.Errorf("unknown issue disposition %q", opts.Issues)
+		}
+
+		n, err := q.DeleteUser(ctx, id)
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return ErrNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		return wrapAccountErr("deleting user", err)
+	}
+
+	return nil
+}
+
+// ensureNotLastAdmin locks every active admin row and fails if id is the only
+// one left. Holding the locks until commit stops two admins from demoting each
+// other at the same time.
+func ensureNotLastAdmin(ctx context.Context, q *dbsqlc.Queries, id uuid.UUID) error {
+	admins, err := q.LockActiveAdmins(ctx)
+	if err != nil {
+		return fmt.Errorf("locking admins: %w", err)
+	}
+
+	if len(admins) == 1 && slices.Contains(admins, id) {
+		return ErrLastAdmin
+	}
+	return nil
+}
+
+// wrapAccountErr maps no-rows to ErrNotFound and passes sentinel errors through.
+func wrapAccountErr(action string, err error) error {
+	switch {
+	case errors.Is(err, pgx.ErrNoRows), errors.Is(err, ErrNotFound):
+		return ErrNotFound
+	case errors.Is(err, ErrLastAdmin):
+		return ErrLastAdmin
+	default:
+		return fmt.Errorf("%s: %w", action, err)
+	}
+}
+
+// accountRowToDomain converts the account columns returned by the admin
+// UPDATE queries. They all share SuspendUserRow's shape.
+func accountRowToDomain(row dbsqlc.SuspendUserRow) *User {
+	return &User{
+		ID:                    row.ID,
+		Email:                 row.Email,
+		Name:                  row.Name,
+		Permissions:           row.Permissions,
+		SuspendedAt:           timePtr(row.SuspendedAt),
+		PasswordResetRequired: row.PasswordResetRequired,
+		CreatedAt:             row.CreatedAt.Time,
+		UpdatedAt:             row.UpdatedAt.Time,
+	}
+}
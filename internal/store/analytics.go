@@ -0,0 +1,110 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jesusthecreator017/fswithgo/internal/store/dbsqlc"
+)
+
+// Granularity is the bucket size for analytics series. The values are valid
+// Postgres date_trunc fields.
+type Granularity string
+
+const (
+	GranularityDay   Granularity = "day"
+	GranularityWeek  Granularity = "week"
+	GranularityMonth Granularity = "month"
+)
+
+// AnalyticsRange selects [From, To) bucketed by Granularity. Buckets are
+// aligned to UTC, so the first one may start before From.
+type AnalyticsRange struct {
+	From        time.Time
+	To          time.Time
+	Granularity Granularity
+}
+
+type AnalyticsPoint struct {
+	Bucket           time.Time `json:"bucket"`
+	IssuesCreated    int64     `json:"issues_created"`
+	IssuesClosed     int64     `json:"issues_closed"`
+	Signups          int64     `json:"signups"`
+	MedianCycleHours *float64  `json:"median_cycle_hours"`
+}
+
+type AgingBucket struct {
+	Label string `json:"label"`
+	Count int64  `json:"count"`
+}
+
+// Analytics holds trend series over a range plus point-in-time open-issue aging.
+// Cycle time is measured from creation to reaching a done-category status.
+type Analytics struct {
+	From             time.Time        `json:"from"`
+	To               time.Time        `json:"to"`
+	Granularity      Granularity      `json:"granularity"`
+	Series           []AnalyticsPoint `json:"series"`
+	MedianCycleHours *float64         `json:"median_cycle_hours"`
+	OpenIssueAging   []AgingBucket    `json:"open_issue_aging"`
+}
+
+func (a *AdminStore) GetAnalytics(ctx context.Context, r AnalyticsRange) (*Analytics, error) {
+	start := pgtype.Timestamptz{Time: r.From, Valid: true}
+	end := pgtype.Timestamptz{Time: r.To, Valid: true}
+
+	rows, err := a.queries.AnalyticsSeries(ctx, dbsqlc.AnalyticsSeriesParams{
+		Granularity: string(r.Granularity),
+		RangeStart:  start,
+		RangeEnd:    end,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("querying analytics series: %w", err)
+	}
+
+	median, err := a.queries.MedianCycleTime(ctx, dbsqlc.MedianCycleTimeParams{
+		RangeStart: start,
+		RangeEnd:   end,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("querying cycle time: %w", err)
+	}
+
+	aging, err := a.queries.OpenIssueAging(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("querying issue aging: %w", err)
+	}
+
+	result := &Analytics{
+		From:             r.From,
+		To:               r.To,
+		Granularity:      r.Granularity,
+		Series:           make([]AnalyticsPoint, len(rows)),
+		MedianCycleHours: floatPtr(median),
+		OpenIssueAging:   make([]AgingBucket, len(aging)),
+	}
+	for i, row := range rows {
+		result.Series[i] = AnalyticsPoint{
+			Bucket:           row.Bucket.Time,
+			IssuesCreated:    row.IssuesCreated,
+			IssuesClosed:     row.IssuesClosed,
+			Signups:          row.Signups,
+			MedianCycleHours: floatPtr(row.MedianCycleHours),
+		}
+	}
+	for i, row := range aging {
+		result.OpenIssueAging[i] = AgingBucket{Label: row.Label, Count: row.Count}
+	}
+
+	return result, nil
+}
+
+func floatPtr(f pgtype.Float8) *float64 {
+	if !f.Valid {
+		return nil
+	}
+	v := f.Float64
+	return &v
+}
@@ -0,0 +1,134 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jesusthecreator017/fswithgo/internal/store/dbsqlc"
+)
+
+// Attachment is a file on an issue. Its bytes are in blob storage under
+// StorageKey; SHA256 is the hex digest of those bytes.
+type Attachment struct {
+	ID          int64      `json:"id"`
+	IssueID     *int64     `json:"issue_id"`
+	UserID      *uuid.UUID `json:"user_id"`
+	Filename    string     `json:"filename"`
+	ContentType string     `json:"content_type"`
+	SizeBytes   int64      `json:"size_bytes"`
+	SHA256      string     `json:"sha256"`
+	StorageKey  string     `json:"-"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+type AttachmentStore struct {
+	queries *dbsqlc.Queries
+}
+
+// Create records an attachment whose blob has already been stored. A
+// missing issue is ErrNotFound.
+func (s *AttachmentStore) Create(ctx context.Context, a *Attachment) error {
+	row, err := s.queries.CreateAttachment(ctx, dbsqlc.CreateAttachmentParams{
+		IssueID:     nullInt8(a.IssueID),
+		UserID:      nullUUID(a.UserID),
+		Filename:    a.Filename,
+		ContentType: a.ContentType,
+		SizeBytes:   a.SizeBytes,
+		Sha256:      a.SHA256,
+		StorageKey:  a.StorageKey,
+	})
+	if err != nil {
+		if isForeignKeyViolation(err) {
+			return ErrNotFound
+		}
+		return fmt.Errorf("creating attachment: %w", err)
+	}
+
+	*a = *attachmentToDomain(row)
+	return nil
+}
+
+func (s *AttachmentStore) ListByIssueID(ctx context.Context, issueID int64) ([]*Attachment, error) {
+	rows, err := s.queries.ListIssueAttachments(ctx, nullInt8(&issueID))
+	if err != nil {
+		return nil, fmt.Errorf("listing attachments: %w", err)
+	}
+
+	attachments := make([]*Attachment, len(rows))
+	for i, row := range rows {
+		attachments[i] = attachmentToDomain(row)
+	}
+	return attachments, nil
+}
+
+func (s *AttachmentStore) Get(ctx context.Context, issueID, id int64) (*Attachment, error) {
+	row, err := s.queries.GetAttachment(ctx, dbsqlc.GetAttachmentParams{
+		ID:      id,
+		IssueID: nullInt8(&issueID),
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("getting attachment: %w", err)
+	}
+
+	return attachmentToDomain(row), nil
+}
+
+// Detach removes an attachment from its issue. The row stays, as an
+// orphan, until its blob has been deleted.
+func (s *AttachmentStore) Detach(ctx context.Context, issueID, id int64) (*Attachment, error) {
+	row, err := s.queries.DetachAttachment(ctx, dbsqlc.DetachAttachmentParams{
+		ID:      id,
+		IssueID: nullInt8(&issueID),
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("detaching attachment: %w", err)
+	}
+
+	return attachmentToDomain(row), nil
+}
+
+// ListOrphaned returns up to limit attachments whose issue is gone.
+func (s *AttachmentStore) ListOrphaned(ctx context.Context, limit int) ([]*Attachment, error) {
+	rows, err := s.queries.ListOrphanedAttachments(ctx, int32(limit))
+	if err != nil {
+		return nil, fmt.Errorf("listing orphaned attachments: %w", err)
+	}
+
+	attachments := make([]*Attachment, len(rows))
+	for i, row := range rows {
+		attachments[i] = attachmentToDomain(row)
+	}
+	return attachments, nil
+}
+
+// Delete removes an attachment's row. Delete its blob first.
+func (s *AttachmentStore) Delete(ctx context.Context, id int64) error {
+	if err := s.queries.DeleteAttachment(ctx, id); err != nil {
+		return fmt.Errorf("deleting attachment: %w", err)
+	}
+	return nil
+}
+
+func attachmentToDomain(row dbsqlc.Attachment) *Attachment {
+	return &Attachment{
+		ID:          row.ID,
+		IssueID:     int64Ptr(row.IssueID),
+		UserID:      uuidPtr(row.UserID),
+		Filename:    row.Filename,
+		ContentType: row.ContentType,
+		SizeBytes:   row.SizeBytes,
+		SHA256:      row.Sha256,
+		StorageKey:  row.StorageKey,
+		CreatedAt:   row.CreatedAt.Time,
+	}
+}
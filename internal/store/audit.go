@@ -0,0 +1,200 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jesusthecreator017/fswithgo/internal/store/dbsqlc"
+)
+
+// Audit actions. Names are "<area>.<verb>" so they can be filtered by prefix.
+const (
+	AuditUserRegister       = "user.register"
+	AuditUserLogin          = "user.login"
+	AuditUserLoginFailed    = "user.login_failed"
+	AuditUserPasswordChange = "user.password_change"
+
+	AuditAdminPermissions   = "admin.user.permissions"
+	AuditAdminSuspend       = "admin.user.suspend"
+	AuditAdminReactivate    = "admin.user.reactivate"
+	AuditAdminResetPassword = "admin.user.reset_password"
+	AuditAdminDeleteUser    = "admin.user.delete"
+
+	AuditIssueDelete          = "issue.delete"
+	AuditIssueWIPOverride     = "issue.wip_override"
+	AuditIssueBlockerOverride = "issue.blocker_override"
+	AuditIssueLink            = "issue.link"
+	AuditIssueUnlink          = "issue.unlink"
+
+	AuditIssueAttachmentUpload = "issue.attachment_upload"
+	AuditIssueAttachmentDelete = "issue.attachment_delete"
+
+	AuditAdminWIPLimitSet    = "admin.wip_limit.set"
+	AuditAdminWIPLimitDelete = "admin.wip_limit.delete"
+
+	AuditAdminWebhookCreate    = "admin.webhook.create"
+	AuditAdminWebhookUpdate    = "admin.webhook.update"
+	AuditAdminWebhookDelete    = "admin.webhook.delete"
+	AuditAdminWebhookRedeliver = "admin.webhook.redeliver"
+
+	AuditAdminJobRetry = "admin.job.retry"
+
+	AuditImpersonationStart = "impersonation.start"
+	AuditImpersonatedWrite  = "impersonation.write"
+)
+
+// AuditEntry is one row of the append-only audit log.
+// ActorID is the identity the request ran as; ImpersonatorID is the admin
+// behind it when the request was made with an impersonation token.
+type AuditEntry struct {
+	ID             int64          `json:"id"`
+	OccurredAt     time.Time      `json:"occurred_at"`
+	ActorID        *uuid.UUID     `json:"actor_id"`
+	ImpersonatorID *uuid.UUID     `json:"impersonator_id"`
+	Action         string         `json:"action"`
+	TargetType     string         `json:"target_type"`
+	TargetID       string         `json:"target_id"`
+	IP             string         `json:"ip"`
+	RequestID      string         `json:"request_id"`
+	Metadata       map[string]any `json:"metadata"`
+}
+
+// AuditFilter narrows an audit query. Zero values mean "no filter".
+// Action matches by prefix, so "admin." returns every admin action.
+// BeforeID is the pagination cursor: only entries older than it are returned.
+type AuditFilter struct {
+	ActorID    *uuid.UUID
+	Action     string
+	TargetType string
+	TargetID   string
+	Since      time.Time
+	Until      time.Time
+	BeforeID   int64
+	Limit      int
+}
+
+type AuditStore struct {
+	queries *dbsqlc.Queries
+}
+
+func (a *AuditStore) Record(ctx context.Context, entry *AuditEntry) error {
+	metadata := entry.Metadata
+	if metadata == nil {
+		metadata = map[string]any{}
+	}
+
+	raw, err := json.Marshal(metadata)
+	if err != nil {
+		return fmt.Errorf("encoding audit metadata: %w", err)
+	}
+
+	err = a.queries.InsertAuditLog(ctx, dbsqlc.InsertAuditLogParams{
+		ActorID:        nullUUID(entry.ActorID),
+		ImpersonatorID: nullUUID(entry.ImpersonatorID),
+		Action:         entry.Action,
+		TargetType:     entry.TargetType,
+		TargetID:       entry.TargetID,
+		Ip:             entry.IP,
+		RequestID:      entry.RequestID,
+		Metadata:       raw,
+	})
+	if err != nil {
+		return fmt.Errorf("recording audit entry: %w", err)
+	}
+	return nil
+}
+
+// List returns matching entries, newest first.
+func (a *AuditStore) List(ctx context.Context, filter AuditFilter) ([]*AuditEntry, error) {
+	rows, err := a.queries.ListAuditLog(ctx, dbsqlc.ListAuditLogParams{
+		ActorID:    nullUUID(filter.ActorID),
+		Action:     nullText(filter.Action),
+		TargetType: nullText(filter.TargetType),
+		TargetID:   nullText(filter.TargetID),
+		Since:      nullTime(filter.Since),
+		Until:      nullTime(filter.Until),
+		BeforeID:   pgtype.Int8{Int64: filter.BeforeID, Valid: filter.BeforeID > 0},
+		PageLimit:  int32(filter.Limit),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("listing audit log: %w", err)
+	}
+
+	return auditRowsToDomain(rows)
+}
+
+// ListBefore returns up to limit entries older than cutoff with an id above
+// afterID, oldest first. The retention job pages through it to archive rows.
+func (a *AuditStore) ListBefore(ctx context.Context, cutoff time.Time, afterID int64, limit int) ([]*AuditEntry, error) {
+	rows, err := a.queries.ListAuditLogBefore(ctx, dbsqlc.ListAuditLogBeforeParams{
+		Cutoff:    pgtype.Timestamptz{Time: cutoff, Valid: true},
+		AfterID:   afterID,
+		BatchSize: int32(limit),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("listing audit log for archive: %w", err)
+	}
+
+	return auditRowsToDomain(rows)
+}
+
+// DeleteBefore removes entries older than cutoff up to and including maxID,
+// which must be the last id the caller has safely archived.
+func (a *AuditStore) DeleteBefore(ctx context.Context, cutoff time.Time, maxID int64) (int64, error) {
+	n, err := a.queries.DeleteAuditLogBefore(ctx, dbsqlc.DeleteAuditLogBeforeParams{
+		Cutoff: pgtype.Timestamptz{Time: cutoff, Valid: true},
+		MaxID:  maxID,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("deleting archived audit log: %w", err)
+	}
+	return n, nil
+}
+
+func auditRowsToDomain(rows []dbsqlc.AuditLog) ([]*AuditEntry, error) {
+	entries := make([]*AuditEntry, len(rows))
+	for i, row := range rows {
+		entry := &AuditEntry{
+			ID:             row.ID,
+			OccurredAt:     row.OccurredAt.Time,
+			ActorID:        uuidPtr(row.ActorID),
+			ImpersonatorID: uuidPtr(row.ImpersonatorID),
+			Action:         row.Action,
+			TargetType:     row.TargetType,
+			TargetID:       row.TargetID,
+			IP:             row.Ip,
+			RequestID:      row.RequestID,
+		}
+		if err := json.Unmarshal(row.Metadata, &entry.Metadata); err != nil {
+			return nil, fmt.Errorf("decoding audit metadata for entry %d: %w", row.ID, err)
+		}
+		entries[i] = entry
+	}
+	return entries, nil
+}
+
+func nullUUID(id *uuid.UUID) uuid.NullUUID {
+	if id == nil {
+		return uuid.NullUUID{}
+	}
+	return uuid.NullUUID{UUID: *id, Valid: true}
+}
+
+func uuidPtr(id uuid.NullUUID) *uuid.UUID {
+	if !id.Valid {
+		return nil
+	}
+	return &id.UUID
+}
+
+func nullText(s string) pgtype.Text {
+	return pgtype.Text{String: s, Valid: s != ""}
+}
+
+func nullTime(t time.Time) pgtype.Timestamptz {
+	return pgtype.Timestamptz{Time: t, Valid: !t.IsZero()}
+}
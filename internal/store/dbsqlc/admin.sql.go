@@ -7,6 +7,9 @@ package dbsqlc
 
 import (
 	"context"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 const countIssuesByStatus = `-- name: CountIssuesByStatus :many
@@ -38,6 +41,38 @@ func (q *Queries) CountIssuesByStatus(ctx context.Context) ([]CountIssuesByStatu
 	return items, nil
 }
 
+const countIssuesByStatusForUser = `-- name: CountIssuesByStatusForUser :many
+SELECT status, COUNT(*) AS count
+FROM issues
+WHERE user_id = $1
+GROUP BY status
+`
+
+type CountIssuesByStatusForUserRow struct {
+	Status string `json:"status"`
+	Count  int64  `json:"count"`
+}
+
+func (q *Queries) CountIssuesByStatusForUser(ctx context.Context, userID uuid.UUID) ([]CountIssuesByStatusForUserRow, error) {
+	rows, err := q.db.Query(ctx, countIssuesByStatusForUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []CountIssuesByStatusForUserRow{}
+	for rows.Next() {
+		var i CountIssuesByStatusForUserRow
+		if err := rows.Scan(&i.Status, &i.Count); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const countUsers = `-- name: CountUsers :one
 SELECT COUNT(*) AS total_users FROM users
 `
@@ -48,3 +83,269 @@ func (q *Queries) CountUsers(ctx context.Context) (int64, error) {
 	err := row.Scan(&total_users)
 	return total_users, err
 }
+
+const deleteIssuesByUserID = `-- name: DeleteIssuesByUserID :exec
+DELETE FROM issues
+WHERE user_id = $1
+`
+
+func (q *Queries) DeleteIssuesByUserID(ctx context.Context, userID uuid.UUID) error {
+	_, err := q.db.Exec(ctx, deleteIssuesByUserID, userID)
+	return err
+}
+
+const deleteUser = `-- name: DeleteUser :execrows
+DELETE FROM users
+WHERE id = $1
+`
+
+func (q *Queries) DeleteUser(ctx context.Context, id uuid.UUID) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteUser, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const listUsers = `-- name: ListUsers :many
+SELECT u.id, u.email, u.name, u.permissions, u.suspended_at, u.password_reset_required,
+       u.created_at, u.updated_at,
+       COUNT(*) OVER () AS total_count
+FROM users u
+WHERE $1::text = ''
+   OR u.email ILIKE '%' || $1::text || '%'
+   OR u.name ILIKE '%' || $1::text || '%'
+ORDER BY u.created_at DESC, u.id
+LIMIT $2 OFFSET $3
+`
+
+type ListUsersParams struct {
+	Search     string `json:"search"`
+	PageLimit  int32  `json:"page_limit"`
+	PageOffset int32  `json:"page_offset"`
+}
+
+type ListUsersRow struct {
+	ID                    uuid.UUID          `json:"id"`
+	Email                 string             `json:"email"`
+	Name                  string             `json:"name"`
+	Permissions           int32              `json:"permissions"`
+	SuspendedAt           pgtype.Timestamptz `json:"suspended_at"`
+	PasswordResetRequired bool               `json:"password_reset_required"`
+	CreatedAt             pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt             pgtype.Timestamptz `json:"updated_at"`
+	TotalCount            int64              `json:"total_count"`
+}
+
+func (q *Queries) ListUsers(ctx context.Context, arg ListUsersParams) ([]ListUsersRow, error) {
+	rows, err := q.db.Query(ctx, listUsers, arg.Search, arg.PageLimit, arg.PageOffset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListUsersRow{}
+	for rows.Next() {
+		var i ListUsersRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Email,
+			&i.Name,
+			&i.Permissions,
+			&i.SuspendedAt,
+			&i.PasswordResetRequired,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.TotalCount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const lockActiveAdmins = `-- name: LockActiveAdmins :many
+SELECT id FROM users
+WHERE permissions & 4 = 4 AND suspended_at IS NULL
+FOR UPDATE
+`
+
+func (q *Queries) LockActiveAdmins(ctx context.Context) ([]uuid.UUID, error) {
+	rows, err := q.db.Query(ctx, lockActiveAdmins)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []uuid.UUID{}
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const reactivateUser = `-- name: ReactivateUser :one
+UPDATE users
+SET suspended_at = NULL, updated_at = now()
+WHERE id = $1
+RETURNING id, email, name, permissions, suspended_at, password_reset_required, created_at, updated_at
+`
+
+type ReactivateUserRow struct {
+	ID                    uuid.UUID          `json:"id"`
+	Email                 string             `json:"email"`
+	Name                  string             `json:"name"`
+	Permissions           int32              `json:"permissions"`
+	SuspendedAt           pgtype.Timestamptz `json:"suspended_at"`
+	PasswordResetRequired bool               `json:"password_reset_required"`
+	CreatedAt             pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt             pgtype.Timestamptz `json:"updated_at"`
+}
+
+func (q *Queries) ReactivateUser(ctx context.Context, id uuid.UUID) (ReactivateUserRow, error) {
+	row := q.db.QueryRow(ctx, reactivateUser, id)
+	var i ReactivateUserRow
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.Name,
+		&i.Permissions,
+		&i.SuspendedAt,
+		&i.PasswordResetRequired,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const reassignUserIssues = `-- name: ReassignUserIssues :exec
+UPDATE issues
+SET user_id = $1, updated_at = now()
+WHERE user_id = $2
+`
+
+type ReassignUserIssuesParams struct {
+	ToUserID   uuid.UUID `json:"to_user_id"`
+	FromUserID uuid.UUID `json:"from_user_id"`
+}
+
+func (q *Queries) ReassignUserIssues(ctx context.Context, arg ReassignUserIssuesParams) error {
+	_, err := q.db.Exec(ctx, reassignUserIssues, arg.ToUserID, arg.FromUserID)
+	return err
+}
+
+const requireUserPasswordReset = `-- name: RequireUserPasswordReset :one
+UPDATE users
+SET password_reset_required = true, updated_at = now()
+WHERE id = $1
+RETURNING id, email, name, permissions, suspended_at, password_reset_required, created_at, updated_at
+`
+
+type RequireUserPasswordResetRow struct {
+	ID                    uuid.UUID          `json:"id"`
+	Email                 string             `json:"email"`
+	Name                  string             `json:"name"`
+	Permissions           int32              `json:"permissions"`
+	SuspendedAt           pgtype.Timestamptz `json:"suspended_at"`
+	PasswordResetRequired bool               `json:"password_reset_required"`
+	CreatedAt             pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt             pgtype.Timestamptz `json:"updated_at"`
+}
+
+func (q *Queries) RequireUserPasswordReset(ctx context.Context, id uuid.UUID) (RequireUserPasswordResetRow, error) {
+	row := q.db.QueryRow(ctx, requireUserPasswordReset, id)
+	var i RequireUserPasswordResetRow
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.Name,
+		&i.Permissions,
+		&i.SuspendedAt,
+		&i.PasswordResetRequired,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const suspendUser = `-- name: SuspendUser :one
+UPDATE users
+SET suspended_at = COALESCE(suspended_at, now()), updated_at = now()
+WHERE id = $1
+RETURNING id, email, name, permissions, suspended_at, password_reset_required, created_at, updated_at
+`
+
+type SuspendUserRow struct {
+	ID                    uuid.UUID          `json:"id"`
+	Email                 string             `json:"email"`
+	Name                  string             `json:"name"`
+	Permissions           int32              `json:"permissions"`
+	SuspendedAt           pgtype.Timestamptz `json:"suspended_at"`
+	PasswordResetRequired bool               `json:"password_reset_required"`
+	CreatedAt             pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt             pgtype.Timestamptz `json:"updated_at"`
+}
+
+func (q *Queries) SuspendUser(ctx context.Context, id uuid.UUID) (SuspendUserRow, error) {
+	row := q.db.QueryRow(ctx, suspendUser, id)
+	var i SuspendUserRow
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.Name,
+		&i.Permissions,
+		&i.SuspendedAt,
+		&i.PasswordResetRequired,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const updateUserPermissions = `-- name: UpdateUserPermissions :one
+UPDATE users
+SET permissions = $2, updated_at = now()
+WHERE id = $1
+RETURNING id, email, name, permissions, suspended_at, password_reset_required, created_at, updated_at
+`
+
+type UpdateUserPermissionsParams struct {
+	ID          uuid.UUID `json:"id"`
+	Permissions int32     `json:"permissions"`
+}
+
+type UpdateUserPermissionsRow struct {
+	ID                    uuid.UUID          `json:"id"`
+	Email                 string             `json:"email"`
+	Name                  string             `json:"name"`
+	Permissions           int32              `json:"permissions"`
+	SuspendedAt           pgtype.Timestamptz `json:"suspended_at"`
+	PasswordResetRequired bool               `json:"password_reset_required"`
+	CreatedAt             pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt             pgtype.Timestamptz `json:"updated_at"`
+}
+
+func (q *Queries) UpdateUserPermissions(ctx context.Context, arg UpdateUserPermissionsParams) (UpdateUserPermissionsRow, error) {
+	row := q.db.QueryRow(ctx, updateUserPermissions, arg.ID, arg.Permissions)
+	var i UpdateUserPermissionsRow
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.Name,
+		&i.Permissions,
+		&i.SuspendedAt,
+		&i.PasswordResetRequired,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
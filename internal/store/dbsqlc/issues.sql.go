@@ -12,17 +12,45 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const countIssueChildren = `-- name: CountIssueChildren :one
+SELECT COUNT(*) AS total,
+       COUNT(*) FILTER (WHERE ws.category = 'done') AS done
+FROM issues c
+LEFT JOIN workflow_statuses ws
+    ON ws.project_id IS NOT DISTINCT FROM c.project_id AND ws.name = c.status
+WHERE c.parent_id = $1
+`
+
+type CountIssueChildrenRow struct {
+	Total int64 `json:"total"`
+	Done  int64 `json:"done"`
+}
+
+// How many of an issue's direct children there are and how many are in a
+// done-category status.
+func (q *Queries) CountIssueChildren(ctx context.Context, parentID pgtype.Int8) (CountIssueChildrenRow, error) {
+	row := q.db.QueryRow(ctx, countIssueChildren, parentID)
+	var i CountIssueChildrenRow
+	err := row.Scan(&i.Total, &i.Done)
+	return i, err
+}
+
 const createIssue = `-- name: CreateIssue :one
-INSERT INTO issues (title, user_id, description, status)
-VALUES ($1, $2, $3, $4)
-RETURNING id, user_id, title, description, status, created_at, updated_at
+INSERT INTO issues (title, user_id, description, status, project_id, rank, parent_id, auto_complete)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+RETURNING id, user_id, title, description, status, created_at, updated_at, project_id, rank,
+          parent_id, auto_complete
 `
 
 type CreateIssueParams struct {
-	Title       string    `json:"title"`
-	UserID      uuid.UUID `json:"user_id"`
-	Description string    `json:"description"`
-	Status      string    `json:"status"`
+	Title        string      `json:"title"`
+	UserID       uuid.UUID   `json:"user_id"`
+	Description  string      `json:"description"`
+	Status       string      `json:"status"`
+	ProjectID    pgtype.Int8 `json:"project_id"`
+	Rank         string      `json:"rank"`
+	ParentID     pgtype.Int8 `json:"parent_id"`
+	AutoComplete bool        `json:"auto_complete"`
 }
 
 func (q *Queries) CreateIssue(ctx context.Context, arg CreateIssueParams) (Issue, error) {
@@ -31,6 +59,10 @@ func (q *Queries) CreateIssue(ctx context.Context, arg CreateIssueParams) (Issue
 		arg.UserID,
 		arg.Description,
 		arg.Status,
+		arg.ProjectID,
+		arg.Rank,
+		arg.ParentID,
+		arg.AutoComplete,
 	)
 	var i Issue
 	err := row.Scan(
@@ -41,37 +73,78 @@ func (q *Queries) CreateIssue(ctx context.Context, arg CreateIssueParams) (Issue
 		&i.Status,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.ProjectID,
+		&i.Rank,
+		&i.ParentID,
+		&i.AutoComplete,
 	)
 	return i, err
 }
 
-const deleteIssue = `-- name: DeleteIssue :exec
+const deleteIssue = `-- name: DeleteIssue :execrows
 DELETE FROM issues
 WHERE id = $1
 `
 
-func (q *Queries) DeleteIssue(ctx context.Context, id int64) error {
-	_, err := q.db.Exec(ctx, deleteIssue, id)
-	return err
+func (q *Queries) DeleteIssue(ctx context.Context, id int64) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteIssue, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteIssueTree = `-- name: DeleteIssueTree :execrows
+WITH RECURSIVE subtree AS (
+    SELECT id FROM issues WHERE id = $1
+    UNION ALL
+    SELECT c.id FROM issues c JOIN subtree s ON c.parent_id = s.id
+)
+DELETE FROM issues
+WHERE id IN (SELECT id FROM subtree)
+`
+
+// Deletes an issue together with all of its descendants.
+func (q *Queries) DeleteIssueTree(ctx context.Context, id int64) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteIssueTree, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
 }
 
 const getIssueByID = `-- name: GetIssueByID :one
 SELECT i.id, i.user_id, i.title, i.description, i.status, i.created_at, i.updated_at,
-       u.name AS user_name
+       i.project_id, i.rank, i.parent_id, i.auto_complete, u.name AS user_name,
+       progress.total AS children_total, progress.done AS children_done
 FROM issues i
 JOIN users u ON u.id = i.user_id
+CROSS JOIN LATERAL (
+    SELECT COUNT(*) AS total,
+           COUNT(*) FILTER (WHERE ws.category = 'done') AS done
+    FROM issues c
+    LEFT JOIN workflow_statuses ws
+        ON ws.project_id IS NOT DISTINCT FROM c.project_id AND ws.name = c.status
+    WHERE c.parent_id = i.id
+) progress
 WHERE i.id = $1
 `
 
 type GetIssueByIDRow struct {
-	ID          int64              `json:"id"`
-	UserID      uuid.UUID          `json:"user_id"`
-	Title       string             `json:"title"`
-	Description string             `json:"description"`
-	Status      string             `json:"status"`
-	CreatedAt   pgtype.Timestamptz `json:"created_at"`
-	UpdatedAt   pgtype.Timestamptz `json:"updated_at"`
-	UserName    string             `json:"user_name"`
+	ID            int64              `json:"id"`
+	UserID        uuid.UUID          `json:"user_id"`
+	Title         string             `json:"title"`
+	Description   string             `json:"description"`
+	Status        string             `json:"status"`
+	CreatedAt     pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt     pgtype.Timestamptz `json:"updated_at"`
+	ProjectID     pgtype.Int8        `json:"project_id"`
+	Rank          string             `json:"rank"`
+	ParentID      pgtype.Int8        `json:"parent_id"`
+	AutoComplete  bool               `json:"auto_complete"`
+	UserName      string             `json:"user_name"`
+	ChildrenTotal int64              `json:"children_total"`
+	ChildrenDone  int64              `json:"children_done"`
 }
 
 func (q *Queries) GetIssueByID(ctx context.Context, id int64) (GetIssueByIDRow, error) {
@@ -85,28 +158,277 @@ func (q *Queries) GetIssueByID(ctx context.Context, id int64) (GetIssueByIDRow,
 		&i.Status,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.ProjectID,
+		&i.Rank,
+		&i.ParentID,
+		&i.AutoComplete,
 		&i.UserName,
+		&i.ChildrenTotal,
+		&i.ChildrenDone,
+	)
+	return i, err
+}
+
+const getIssueForUpdate = `-- name: GetIssueForUpdate :one
+SELECT id, user_id, project_id, status, rank, parent_id, auto_complete
+FROM issues
+WHERE id = $1
+FOR UPDATE
+`
+
+type GetIssueForUpdateRow struct {
+	ID           int64       `json:"id"`
+	UserID       uuid.UUID   `json:"user_id"`
+	ProjectID    pgtype.Int8 `json:"project_id"`
+	Status       string      `json:"status"`
+	Rank         string      `json:"rank"`
+	ParentID     pgtype.Int8 `json:"parent_id"`
+	AutoComplete bool        `json:"auto_complete"`
+}
+
+// Locks the issue so a status change is checked against the status it
+// actually has when the update lands.
+func (q *Queries) GetIssueForUpdate(ctx context.Context, id int64) (GetIssueForUpdateRow, error) {
+	row := q.db.QueryRow(ctx, getIssueForUpdate, id)
+	var i GetIssueForUpdateRow
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.ProjectID,
+		&i.Status,
+		&i.Rank,
+		&i.ParentID,
+		&i.AutoComplete,
+	)
+	return i, err
+}
+
+const getIssuePosition = `-- name: GetIssuePosition :one
+SELECT id, user_id, project_id, status, rank
+FROM issues
+WHERE id = $1
+`
+
+type GetIssuePositionRow struct {
+	ID        int64       `json:"id"`
+	UserID    uuid.UUID   `json:"user_id"`
+	ProjectID pgtype.Int8 `json:"project_id"`
+	Status    string      `json:"status"`
+	Rank      string      `json:"rank"`
+}
+
+func (q *Queries) GetIssuePosition(ctx context.Context, id int64) (GetIssuePositionRow, error) {
+	row := q.db.QueryRow(ctx, getIssuePosition, id)
+	var i GetIssuePositionRow
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.ProjectID,
+		&i.Status,
+		&i.Rank,
 	)
 	return i, err
 }
 
+const issueSubtreeHeight = `-- name: IssueSubtreeHeight :one
+WITH RECURSIVE subtree AS (
+    SELECT id, 1 AS depth FROM issues WHERE id = $1
+    UNION ALL
+    SELECT c.id, s.depth + 1
+    FROM issues c
+    JOIN subtree s ON c.parent_id = s.id
+)
+SELECT COALESCE(MAX(depth), 0)::int AS height
+FROM subtree
+`
+
+// Levels in the subtree rooted at the issue: 1 for an issue with no children.
+func (q *Queries) IssueSubtreeHeight(ctx context.Context, id int64) (int32, error) {
+	row := q.db.QueryRow(ctx, issueSubtreeHeight, id)
+	var height int32
+	err := row.Scan(&height)
+	return height, err
+}
+
+const listColumnForRebalance = `-- name: ListColumnForRebalance :many
+SELECT id
+FROM issues
+WHERE project_id IS NOT DISTINCT FROM $1::bigint
+  AND ($1::bigint IS NOT NULL OR user_id = $2)
+  AND status = $3
+  AND id <> $4
+ORDER BY rank, id
+FOR UPDATE
+`
+
+type ListColumnForRebalanceParams struct {
+	ProjectID pgtype.Int8 `json:"project_id"`
+	UserID    uuid.UUID   `json:"user_id"`
+	Status    string      `json:"status"`
+	ExcludeID int64       `json:"exclude_id"`
+}
+
+func (q *Queries) ListColumnForRebalance(ctx context.Context, arg ListColumnForRebalanceParams) ([]int64, error) {
+	rows, err := q.db.Query(ctx, listColumnForRebalance,
+		arg.ProjectID,
+		arg.UserID,
+		arg.Status,
+		arg.ExcludeID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []int64{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listIssueAncestors = `-- name: ListIssueAncestors :many
+WITH RECURSIVE ancestors AS (
+    SELECT id, parent_id, 1 AS depth FROM issues WHERE id = $1
+    UNION ALL
+    SELECT i.id, i.parent_id, a.depth + 1
+    FROM issues i
+    JOIN ancestors a ON i.id = a.parent_id
+)
+SELECT id
+FROM ancestors
+ORDER BY depth
+`
+
+// The issue followed by its parent, grandparent and so on up to the root.
+func (q *Queries) ListIssueAncestors(ctx context.Context, id int64) ([]int64, error) {
+	rows, err := q.db.Query(ctx, listIssueAncestors, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []int64{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listIssueChildren = `-- name: ListIssueChildren :many
+SELECT i.id, i.user_id, i.title, i.description, i.status, i.created_at, i.updated_at,
+       i.project_id, i.rank, i.parent_id, i.auto_complete, u.name AS user_name,
+       progress.total AS children_total, progress.done AS children_done
+FROM issues i
+JOIN users u ON u.id = i.user_id
+CROSS JOIN LATERAL (
+    SELECT COUNT(*) AS total,
+           COUNT(*) FILTER (WHERE ws.category = 'done') AS done
+    FROM issues c
+    LEFT JOIN workflow_statuses ws
+        ON ws.project_id IS NOT DISTINCT FROM c.project_id AND ws.name = c.status
+    WHERE c.parent_id = i.id
+) progress
+WHERE i.parent_id = $1
+ORDER BY i.rank, i.id
+`
+
+type ListIssueChildrenRow struct {
+	ID            int64              `json:"id"`
+	UserID        uuid.UUID          `json:"user_id"`
+	Title         string             `json:"title"`
+	Description   string             `json:"description"`
+	Status        string             `json:"status"`
+	CreatedAt     pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt     pgtype.Timestamptz `json:"updated_at"`
+	ProjectID     pgtype.Int8        `json:"project_id"`
+	Rank          string             `json:"rank"`
+	ParentID      pgtype.Int8        `json:"parent_id"`
+	AutoComplete  bool               `json:"auto_complete"`
+	UserName      string             `json:"user_name"`
+	ChildrenTotal int64              `json:"children_total"`
+	ChildrenDone  int64              `json:"children_done"`
+}
+
+func (q *Queries) ListIssueChildren(ctx context.Context, parentID pgtype.Int8) ([]ListIssueChildrenRow, error) {
+	rows, err := q.db.Query(ctx, listIssueChildren, parentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListIssueChildrenRow{}
+	for rows.Next() {
+		var i ListIssueChildrenRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Title,
+			&i.Description,
+			&i.Status,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.ProjectID,
+			&i.Rank,
+			&i.ParentID,
+			&i.AutoComplete,
+			&i.UserName,
+			&i.ChildrenTotal,
+			&i.ChildrenDone,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listIssues = `-- name: ListIssues :many
 SELECT i.id, i.user_id, i.title, i.description, i.status, i.created_at, i.updated_at,
-       u.name AS user_name
+       i.project_id, i.rank, i.parent_id, i.auto_complete, u.name AS user_name,
+       progress.total AS children_total, progress.done AS children_done
 FROM issues i
 JOIN users u ON u.id = i.user_id
-ORDER BY i.created_at DESC
+CROSS JOIN LATERAL (
+    SELECT COUNT(*) AS total,
+           COUNT(*) FILTER (WHERE ws.category = 'done') AS done
+    FROM issues c
+    LEFT JOIN workflow_statuses ws
+        ON ws.project_id IS NOT DISTINCT FROM c.project_id AND ws.name = c.status
+    WHERE c.parent_id = i.id
+) progress
+ORDER BY i.rank, i.id
 `
 
 type ListIssuesRow struct {
-	ID          int64              `json:"id"`
-	UserID      uuid.UUID          `json:"user_id"`
-	Title       string             `json:"title"`
-	Description string             `json:"description"`
-	Status      string             `json:"status"`
-	CreatedAt   pgtype.Timestamptz `json:"created_at"`
-	UpdatedAt   pgtype.Timestamptz `json:"updated_at"`
-	UserName    string             `json:"user_name"`
+	ID            int64              `json:"id"`
+	UserID        uuid.UUID          `json:"user_id"`
+	Title         string             `json:"title"`
+	Description   string             `json:"description"`
+	Status        string             `json:"status"`
+	CreatedAt     pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt     pgtype.Timestamptz `json:"updated_at"`
+	ProjectID     pgtype.Int8        `json:"project_id"`
+	Rank          string             `json:"rank"`
+	ParentID      pgtype.Int8        `json:"parent_id"`
+	AutoComplete  bool               `json:"auto_complete"`
+	UserName      string             `json:"user_name"`
+	ChildrenTotal int64              `json:"children_total"`
+	ChildrenDone  int64              `json:"children_done"`
 }
 
 func (q *Queries) ListIssues(ctx context.Context) ([]ListIssuesRow, error) {
@@ -126,7 +448,83 @@ func (q *Queries) ListIssues(ctx context.Context) ([]ListIssuesRow, error) {
 			&i.Status,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.ProjectID,
+			&i.Rank,
+			&i.ParentID,
+			&i.AutoComplete,
 			&i.UserName,
+			&i.ChildrenTotal,
+			&i.ChildrenDone,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listIssuesByProjectID = `-- name: ListIssuesByProjectID :many
+SELECT i.id, i.user_id, i.title, i.description, i.status, i.created_at, i.updated_at,
+       i.project_id, i.rank, i.parent_id, i.auto_complete, u.name AS user_name,
+       progress.total AS children_total, progress.done AS children_done
+FROM issues i
+JOIN users u ON u.id = i.user_id
+CROSS JOIN LATERAL (
+    SELECT COUNT(*) AS total,
+           COUNT(*) FILTER (WHERE ws.category = 'done') AS done
+    FROM issues c
+    LEFT JOIN workflow_statuses ws
+        ON ws.project_id IS NOT DISTINCT FROM c.project_id AND ws.name = c.status
+    WHERE c.parent_id = i.id
+) progress
+WHERE i.project_id = $1
+ORDER BY i.rank, i.id
+`
+
+type ListIssuesByProjectIDRow struct {
+	ID            int64              `json:"id"`
+	UserID        uuid.UUID          `json:"user_id"`
+	Title         string             `json:"title"`
+	Description   string             `json:"description"`
+	Status        string             `json:"status"`
+	CreatedAt     pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt     pgtype.Timestamptz `json:"updated_at"`
+	ProjectID     pgtype.Int8        `json:"project_id"`
+	Rank          string             `json:"rank"`
+	ParentID      pgtype.Int8        `json:"parent_id"`
+	AutoComplete  bool               `json:"auto_complete"`
+	UserName      string             `json:"user_name"`
+	ChildrenTotal int64              `json:"children_total"`
+	ChildrenDone  int64              `json:"children_done"`
+}
+
+func (q *Queries) ListIssuesByProjectID(ctx context.Context, projectID pgtype.Int8) ([]ListIssuesByProjectIDRow, error) {
+	rows, err := q.db.Query(ctx, listIssuesByProjectID, projectID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListIssuesByProjectIDRow{}
+	for rows.Next() {
+		var i ListIssuesByProjectIDRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Title,
+			&i.Description,
+			&i.Status,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.ProjectID,
+			&i.Rank,
+			&i.ParentID,
+			&i.AutoComplete,
+			&i.UserName,
+			&i.ChildrenTotal,
+			&i.ChildrenDone,
 		); err != nil {
 			return nil, err
 		}
@@ -140,22 +538,37 @@ func (q *Queries) ListIssues(ctx context.Context) ([]ListIssuesRow, error) {
 
 const listIssuesByUserID = `-- name: ListIssuesByUserID :many
 SELECT i.id, i.user_id, i.title, i.description, i.status, i.created_at, i.updated_at,
-       u.name AS user_name
+       i.project_id, i.rank, i.parent_id, i.auto_complete, u.name AS user_name,
+       progress.total AS children_total, progress.done AS children_done
 FROM issues i
 JOIN users u ON u.id = i.user_id
+CROSS JOIN LATERAL (
+    SELECT COUNT(*) AS total,
+           COUNT(*) FILTER (WHERE ws.category = 'done') AS done
+    FROM issues c
+    LEFT JOIN workflow_statuses ws
+        ON ws.project_id IS NOT DISTINCT FROM c.project_id AND ws.name = c.status
+    WHERE c.parent_id = i.id
+) progress
 WHERE i.user_id = $1
-ORDER BY i.created_at DESC
+ORDER BY i.rank, i.id
 `
 
 type ListIssuesByUserIDRow struct {
-	ID          int64              `json:"id"`
-	UserID      uuid.UUID          `json:"user_id"`
-	Title       string             `json:"title"`
-	Description string             `json:"description"`
-	Status      string             `json:"status"`
-	CreatedAt   pgtype.Timestamptz `json:"created_at"`
-	UpdatedAt   pgtype.Timestamptz `json:"updated_at"`
-	UserName    string             `json:"user_name"`
+	ID            int64              `json:"id"`
+	UserID        uuid.UUID          `json:"user_id"`
+	Title         string             `json:"title"`
+	Description   string             `json:"description"`
+	Status        string             `json:"status"`
+	CreatedAt     pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt     pgtype.Timestamptz `json:"updated_at"`
+	ProjectID     pgtype.Int8        `json:"project_id"`
+	Rank          string             `json:"rank"`
+	ParentID      pgtype.Int8        `json:"parent_id"`
+	AutoComplete  bool               `json:"auto_complete"`
+	UserName      string             `json:"user_name"`
+	ChildrenTotal int64              `json:"children_total"`
+	ChildrenDone  int64              `json:"children_done"`
 }
 
 func (q *Queries) ListIssuesByUserID(ctx context.Context, userID uuid.UUID) ([]ListIssuesByUserIDRow, error) {
@@ -175,7 +588,13 @@ func (q *Queries) ListIssuesByUserID(ctx context.Context, userID uuid.UUID) ([]L
 			&i.Status,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.ProjectID,
+			&i.Rank,
+			&i.ParentID,
+			&i.AutoComplete,
 			&i.UserName,
+			&i.ChildrenTotal,
+			&i.ChildrenDone,
 		); err != nil {
 			return nil, err
 		}
@@ -187,20 +606,183 @@ func (q *Queries) ListIssuesByUserID(ctx context.Context, userID uuid.UUID) ([]L
 	return items, nil
 }
 
+const lockIssueHierarchy = `-- name: LockIssueHierarchy :exec
+SELECT pg_advisory_xact_lock(hashtext('issues.parent_id'))
+`
+
+// Serializes parent changes so two concurrent moves can't close a cycle
+// between them. Held until the transaction ends.
+func (q *Queries) LockIssueHierarchy(ctx context.Context) error {
+	_, err := q.db.Exec(ctx, lockIssueHierarchy)
+	return err
+}
+
+const nextRankInColumn = `-- name: NextRankInColumn :one
+SELECT rank
+FROM issues
+WHERE project_id IS NOT DISTINCT FROM $1::bigint
+  AND ($1::bigint IS NOT NULL OR user_id = $2)
+  AND status = $3
+  AND id <> $4
+  AND rank > $5::text
+ORDER BY rank, id
+LIMIT 1
+`
+
+type NextRankInColumnParams struct {
+	ProjectID pgtype.Int8 `json:"project_id"`
+	UserID    uuid.UUID   `json:"user_id"`
+	Status    string      `json:"status"`
+	ExcludeID int64       `json:"exclude_id"`
+	Above     string      `json:"above"`
+}
+
+// The first rank in a board column that sorts after @above.
+func (q *Queries) NextRankInColumn(ctx context.Context, arg NextRankInColumnParams) (string, error) {
+	row := q.db.QueryRow(ctx, nextRankInColumn,
+		arg.ProjectID,
+		arg.UserID,
+		arg.Status,
+		arg.ExcludeID,
+		arg.Above,
+	)
+	var rank string
+	err := row.Scan(&rank)
+	return rank, err
+}
+
+const prevRankInColumn = `-- name: PrevRankInColumn :one
+SELECT rank
+FROM issues
+WHERE project_id IS NOT DISTINCT FROM $1::bigint
+  AND ($1::bigint IS NOT NULL OR user_id = $2)
+  AND status = $3
+  AND id <> $4
+  AND ($5::text IS NULL OR rank < $5::text)
+ORDER BY rank DESC, id DESC
+LIMIT 1
+`
+
+type PrevRankInColumnParams struct {
+	ProjectID pgtype.Int8 `json:"project_id"`
+	UserID    uuid.UUID   `json:"user_id"`
+	Status    string      `json:"status"`
+	ExcludeID int64       `json:"exclude_id"`
+	Below     pgtype.Text `json:"below"`
+}
+
+// The last rank in a board column that sorts before @below, or the last
+// rank overall when below is NULL. A board is a project, or a user's issues
+// outside any project.
+func (q *Queries) PrevRankInColumn(ctx context.Context, arg PrevRankInColumnParams) (string, error) {
+	row := q.db.QueryRow(ctx, prevRankInColumn,
+		arg.ProjectID,
+		arg.UserID,
+		arg.Status,
+		arg.ExcludeID,
+		arg.Below,
+	)
+	var rank string
+	err := row.Scan(&rank)
+	return rank, err
+}
+
+const setIssueAutoComplete = `-- name: SetIssueAutoComplete :one
+UPDATE issues
+SET auto_complete = $2, updated_at = now()
+WHERE id = $1
+RETURNING id, user_id, title, description, status, created_at, updated_at, project_id, rank,
+          parent_id, auto_complete
+`
+
+type SetIssueAutoCompleteParams struct {
+	ID           int64 `json:"id"`
+	AutoComplete bool  `json:"auto_complete"`
+}
+
+func (q *Queries) SetIssueAutoComplete(ctx context.Context, arg SetIssueAutoCompleteParams) (Issue, error) {
+	row := q.db.QueryRow(ctx, setIssueAutoComplete, arg.ID, arg.AutoComplete)
+	var i Issue
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Title,
+		&i.Description,
+		&i.Status,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.ProjectID,
+		&i.Rank,
+		&i.ParentID,
+		&i.AutoComplete,
+	)
+	return i, err
+}
+
+const setIssueParent = `-- name: SetIssueParent :one
+UPDATE issues
+SET parent_id = $2, updated_at = now()
+WHERE id = $1
+RETURNING id, user_id, title, description, status, created_at, updated_at, project_id, rank,
+          parent_id, auto_complete
+`
+
+type SetIssueParentParams struct {
+	ID       int64       `json:"id"`
+	ParentID pgtype.Int8 `json:"parent_id"`
+}
+
+func (q *Queries) SetIssueParent(ctx context.Context, arg SetIssueParentParams) (Issue, error) {
+	row := q.db.QueryRow(ctx, setIssueParent, arg.ID, arg.ParentID)
+	var i Issue
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Title,
+		&i.Description,
+		&i.Status,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.ProjectID,
+		&i.Rank,
+		&i.ParentID,
+		&i.AutoComplete,
+	)
+	return i, err
+}
+
+const setIssueRank = `-- name: SetIssueRank :exec
+UPDATE issues
+SET rank = $2
+WHERE id = $1
+`
+
+type SetIssueRankParams struct {
+	ID   int64  `json:"id"`
+	Rank string `json:"rank"`
+}
+
+func (q *Queries) SetIssueRank(ctx context.Context, arg SetIssueRankParams) error {
+	_, err := q.db.Exec(ctx, setIssueRank, arg.ID, arg.Rank)
+	return err
+}
+
 const updateIssueStatus = `-- name: UpdateIssueStatus :one
 UPDATE issues
-SET status = $2, updated_at = now()
+SET status = $2, rank = $3, updated_at = now()
 WHERE id = $1
-RETURNING id, user_id, title, description, status, created_at, updated_at
+RETURNING id, user_id, title, description, status, created_at, updated_at, project_id, rank,
+          parent_id, auto_complete
 `
 
 type UpdateIssueStatusParams struct {
 	ID     int64  `json:"id"`
 	Status string `json:"status"`
+	Rank   string `json:"rank"`
 }
 
 func (q *Queries) UpdateIssueStatus(ctx context.Context, arg UpdateIssueStatusParams) (Issue, error) {
-	row := q.db.QueryRow(ctx, updateIssueStatus, arg.ID, arg.Status)
+	row := q.db.QueryRow(ctx, updateIssueStatus, arg.ID, arg.Status, arg.Rank)
 	var i Issue
 	err := row.Scan(
 		&i.ID,
@@ -210,6 +792,10 @@ func (q *Queries) UpdateIssueStatus(ctx context.Context, arg UpdateIssueStatusPa
 		&i.Status,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.ProjectID,
+		&i.Rank,
+		&i.ParentID,
+		&i.AutoComplete,
 	)
 	return i, err
 }
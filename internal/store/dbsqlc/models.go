@@ -5,72 +5,173 @@
 package dbsqlc
 
 import (
-	"database/sql/driver"
-	"fmt"
-
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
-type StatusType string
-
-const (
-	StatusTypeIncomplete StatusType = "Incomplete"
-	StatusTypeInProgress StatusType = "In-Progress"
-	StatusTypeComplete   StatusType = "Complete"
-)
+type Attachment struct {
+	ID          int64              `json:"id"`
+	IssueID     pgtype.Int8        `json:"issue_id"`
+	UserID      uuid.NullUUID      `json:"user_id"`
+	Filename    string             `json:"filename"`
+	ContentType string             `json:"content_type"`
+	SizeBytes   int64              `json:"size_bytes"`
+	Sha256      string             `json:"sha256"`
+	StorageKey  string             `json:"storage_key"`
+	CreatedAt   pgtype.Timestamptz `json:"created_at"`
+}
 
-func (e *StatusType) Scan(src interface{}) error {
-	switch s := src.(type) {
-	case []byte:
-		*e = StatusType(s)
-	case string:
-		*e = StatusType(s)
-	default:
-		return fmt.Errorf("unsupported scan type for StatusType: %T", src)
-	}
-	return nil
+type AuditLog struct {
+	ID             int64              `json:"id"`
+	OccurredAt     pgtype.Timestamptz `json:"occurred_at"`
+	ActorID        uuid.NullUUID      `json:"actor_id"`
+	ImpersonatorID uuid.NullUUID      `json:"impersonator_id"`
+	Action         string             `json:"action"`
+	TargetType     string             `json:"target_type"`
+	TargetID       string             `json:"target_id"`
+	Ip             string             `json:"ip"`
+	RequestID      string             `json:"request_id"`
+	Metadata       []byte             `json:"metadata"`
 }
 
-type NullStatusType struct {
-	StatusType StatusType `json:"status_type"`
-	Valid      bool       `json:"valid"` // Valid is true if StatusType is not NULL
+type Issue struct {
+	ID           int64              `json:"id"`
+	UserID       uuid.UUID          `json:"user_id"`
+	Title        string             `json:"title"`
+	Description  string             `json:"description"`
+	Status       string             `json:"status"`
+	CreatedAt    pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt    pgtype.Timestamptz `json:"updated_at"`
+	ProjectID    pgtype.Int8        `json:"project_id"`
+	Rank         string             `json:"rank"`
+	ParentID     pgtype.Int8        `json:"parent_id"`
+	AutoComplete bool               `json:"auto_complete"`
 }
 
-// Scan implements the Scanner interface.
-func (ns *NullStatusType) Scan(value interface{}) error {
-	if value == nil {
-		ns.StatusType, ns.Valid = "", false
-		return nil
-	}
-	ns.Valid = true
-	return ns.StatusType.Scan(value)
+type IssueLink struct {
+	ID        int64              `json:"id"`
+	SourceID  int64              `json:"source_id"`
+	TargetID  int64              `json:"target_id"`
+	LinkType  string             `json:"link_type"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
 }
 
-// Value implements the driver Valuer interface.
-func (ns NullStatusType) Value() (driver.Value, error) {
-	if !ns.Valid {
-		return nil, nil
-	}
-	return string(ns.StatusType), nil
+type IssueStatusEvent struct {
+	ID         int64              `json:"id"`
+	IssueID    int64              `json:"issue_id"`
+	FromStatus pgtype.Text        `json:"from_status"`
+	ToStatus   string             `json:"to_status"`
+	ChangedAt  pgtype.Timestamptz `json:"changed_at"`
 }
 
-type Issue struct {
+type Job struct {
 	ID          int64              `json:"id"`
-	UserID      uuid.UUID          `json:"user_id"`
-	Title       string             `json:"title"`
-	Description string             `json:"description"`
+	Kind        string             `json:"kind"`
+	Args        []byte             `json:"args"`
 	Status      string             `json:"status"`
+	Attempts    int32              `json:"attempts"`
+	MaxAttempts int32              `json:"max_attempts"`
+	RunAt       pgtype.Timestamptz `json:"run_at"`
+	UniqueKey   pgtype.Text        `json:"unique_key"`
+	LastError   pgtype.Text        `json:"last_error"`
+	LockedUntil pgtype.Timestamptz `json:"locked_until"`
 	CreatedAt   pgtype.Timestamptz `json:"created_at"`
 	UpdatedAt   pgtype.Timestamptz `json:"updated_at"`
+	FinishedAt  pgtype.Timestamptz `json:"finished_at"`
+}
+
+type Project struct {
+	ID        int64              `json:"id"`
+	Name      string             `json:"name"`
+	OwnerID   uuid.UUID          `json:"owner_id"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt pgtype.Timestamptz `json:"updated_at"`
+}
+
+type RateLimitBucket struct {
+	Key       string             `json:"key"`
+	Tokens    float64            `json:"tokens"`
+	UpdatedAt pgtype.Timestamptz `json:"updated_at"`
+}
+
+type ScheduledTaskRun struct {
+	ID           int64              `json:"id"`
+	Task         string             `json:"task"`
+	ScheduledFor pgtype.Timestamptz `json:"scheduled_for"`
+	Host         string             `json:"host"`
+	Status       string             `json:"status"`
+	Error        pgtype.Text        `json:"error"`
+	StartedAt    pgtype.Timestamptz `json:"started_at"`
+	FinishedAt   pgtype.Timestamptz `json:"finished_at"`
+}
+
+type SchemaMigration struct {
+	Version int64 `json:"version"`
+	Dirty   bool  `json:"dirty"`
 }
 
 type User struct {
-	ID           uuid.UUID          `json:"id"`
-	Email        string             `json:"email"`
-	PasswordHash string             `json:"password_hash"`
-	Name         string             `json:"name"`
-	CreatedAt    pgtype.Timestamptz `json:"created_at"`
-	UpdatedAt    pgtype.Timestamptz `json:"updated_at"`
-	Permissions  int32              `json:"permissions"`
+	ID                    uuid.UUID          `json:"id"`
+	Email                 string             `json:"email"`
+	PasswordHash          string             `json:"password_hash"`
+	Name                  string             `json:"name"`
+	CreatedAt             pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt             pgtype.Timestamptz `json:"updated_at"`
+	Permissions           int32              `json:"permissions"`
+	SuspendedAt           pgtype.Timestamptz `json:"suspended_at"`
+	PasswordResetRequired bool               `json:"password_reset_required"`
+}
+
+type Webhook struct {
+	ID                  int64              `json:"id"`
+	UserID              uuid.NullUUID      `json:"user_id"`
+	Url                 string             `json:"url"`
+	Secret              string             `json:"secret"`
+	EventTypes          []string           `json:"event_types"`
+	Active              bool               `json:"active"`
+	ConsecutiveFailures int32              `json:"consecutive_failures"`
+	DisabledAt          pgtype.Timestamptz `json:"disabled_at"`
+	CreatedAt           pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt           pgtype.Timestamptz `json:"updated_at"`
+}
+
+type WebhookDelivery struct {
+	ID             int64              `json:"id"`
+	WebhookID      int64              `json:"webhook_id"`
+	EventID        int64              `json:"event_id"`
+	EventType      string             `json:"event_type"`
+	Payload        []byte             `json:"payload"`
+	Status         string             `json:"status"`
+	Attempts       int32              `json:"attempts"`
+	NextAttemptAt  pgtype.Timestamptz `json:"next_attempt_at"`
+	ResponseStatus pgtype.Int4        `json:"response_status"`
+	ResponseBody   pgtype.Text        `json:"response_body"`
+	Error          pgtype.Text        `json:"error"`
+	CreatedAt      pgtype.Timestamptz `json:"created_at"`
+	CompletedAt    pgtype.Timestamptz `json:"completed_at"`
+	Traceparent    pgtype.Text        `json:"traceparent"`
+}
+
+type WipLimit struct {
+	ID        int64              `json:"id"`
+	ProjectID pgtype.Int8        `json:"project_id"`
+	UserID    uuid.NullUUID      `json:"user_id"`
+	Status    string             `json:"status"`
+	MaxIssues int32              `json:"max_issues"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt pgtype.Timestamptz `json:"updated_at"`
+}
+
+type WorkflowStatus struct {
+	ID        int64              `json:"id"`
+	ProjectID pgtype.Int8        `json:"project_id"`
+	Name      string             `json:"name"`
+	Category  string             `json:"category"`
+	Position  int32              `json:"position"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+}
+
+type WorkflowTransition struct {
+	FromStatusID int64 `json:"from_status_id"`
+	ToStatusID   int64 `json:"to_status_id"`
 }
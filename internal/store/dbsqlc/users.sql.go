@@ -50,19 +50,22 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (CreateU
 }
 
 const getUserByEmail = `-- name: GetUserByEmail :one
-SELECT u.id, u.email, u.password_hash, u.name, u.permissions, u.created_at, u.updated_at
+SELECT u.id, u.email, u.password_hash, u.name, u.permissions, u.suspended_at, u.password_reset_required,
+       u.created_at, u.updated_at
 FROM users u
 WHERE u.email = $1
 `
 
 type GetUserByEmailRow struct {
-	ID           uuid.UUID          `json:"id"`
-	Email        string             `json:"email"`
-	PasswordHash string             `json:"password_hash"`
-	Name         string             `json:"name"`
-	Permissions  int32              `json:"permissions"`
-	CreatedAt    pgtype.Timestamptz `json:"created_at"`
-	UpdatedAt    pgtype.Timestamptz `json:"updated_at"`
+	ID                    uuid.UUID          `json:"id"`
+	Email                 string             `json:"email"`
+	PasswordHash          string             `json:"password_hash"`
+	Name                  string             `json:"name"`
+	Permissions           int32              `json:"permissions"`
+	SuspendedAt           pgtype.Timestamptz `json:"suspended_at"`
+	PasswordResetRequired bool               `json:"password_reset_required"`
+	CreatedAt             pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt             pgtype.Timestamptz `json:"updated_at"`
 }
 
 func (q *Queries) GetUserByEmail(ctx context.Context, email string) (GetUserByEmailRow, error) {
@@ -74,6 +77,8 @@ func (q *Queries) GetUserByEmail(ctx context.Context, email string) (GetUserByEm
 		&i.PasswordHash,
 		&i.Name,
 		&i.Permissions,
+		&i.SuspendedAt,
+		&i.PasswordResetRequired,
 		&i.CreatedAt,
 		&i.UpdatedAt,
 	)
@@ -81,19 +86,22 @@ func (q *Queries) GetUserByEmail(ctx context.Context, email string) (GetUserByEm
 }
 
 const getUserByID = `-- name: GetUserByID :one
-SELECT u.id, u.email, u.password_hash, u.name, u.permissions, u.created_at, u.updated_at
+SELECT u.id, u.email, u.password_hash, u.name, u.permissions, u.suspended_at, u.password_reset_required,
+       u.created_at, u.updated_at
 FROM users u
 WHERE u.id = $1
 `
 
 type GetUserByIDRow struct {
-	ID           uuid.UUID          `json:"id"`
-	Email        string             `json:"email"`
-	PasswordHash string             `json:"password_hash"`
-	Name         string             `json:"name"`
-	Permissions  int32              `json:"permissions"`
-	CreatedAt    pgtype.Timestamptz `json:"created_at"`
-	UpdatedAt    pgtype.Timestamptz `json:"updated_at"`
+	ID                    uuid.UUID          `json:"id"`
+	Email                 string             `json:"email"`
+	PasswordHash          string             `json:"password_hash"`
+	Name                  string             `json:"name"`
+	Permissions           int32              `json:"permissions"`
+	SuspendedAt           pgtype.Timestamptz `json:"suspended_at"`
+	PasswordResetRequired bool               `json:"password_reset_required"`
+	CreatedAt             pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt             pgtype.Timestamptz `json:"updated_at"`
 }
 
 func (q *Queries) GetUserByID(ctx context.Context, id uuid.UUID) (GetUserByIDRow, error) {
@@ -105,8 +113,29 @@ func (q *Queries) GetUserByID(ctx context.Context, id uuid.UUID) (GetUserByIDRow
 		&i.PasswordHash,
 		&i.Name,
 		&i.Permissions,
+		&i.SuspendedAt,
+		&i.PasswordResetRequired,
 		&i.CreatedAt,
 		&i.UpdatedAt,
 	)
 	return i, err
 }
+
+const updateUserPassword = `-- name: UpdateUserPassword :execrows
+UPDATE users
+SET password_hash = $2, password_reset_required = false, updated_at = now()
+WHERE id = $1
+`
+
+type UpdateUserPasswordParams struct {
+	ID           uuid.UUID `json:"id"`
+	PasswordHash string    `json:"password_hash"`
+}
+
+func (q *Queries) UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) (int64, error) {
+	result, err := q.db.Exec(ctx, updateUserPassword, arg.ID, arg.PasswordHash)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
@@ -0,0 +1,278 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jesusthecreator017/fswithgo/internal/store/dbsqlc"
+)
+
+// MaxIssueDepth is how many levels a hierarchy may have, counting the root.
+const MaxIssueDepth = 5
+
+var (
+	ErrInvalidParent = errors.New("parent must be an existing issue on the same board")
+	ErrParentCycle   = errors.New("an issue cannot be its own ancestor")
+	ErrParentTooDeep = fmt.Errorf("sub-tasks may be nested at most %d levels deep", MaxIssueDepth)
+	ErrHasChildren   = errors.New("issue has sub-tasks")
+)
+
+// ChildPolicy says what happens to an issue's sub-tasks when it is deleted.
+type ChildPolicy string
+
+const (
+	ChildrenCascade ChildPolicy = "cascade" // delete the whole subtree
+	ChildrenOrphan  ChildPolicy = "orphan"  // children become top-level issues
+)
+
+func (p ChildPolicy) Valid() bool {
+	return p == ChildrenCascade || p == ChildrenOrphan
+}
+
+// IssueProgress is the roll-up of an issue's direct children: how many
+// there are and how many sit in a done-category status.
+type IssueProgress struct {
+	Completed int64 `json:"completed"`
+	Total     int64 `json:"total"`
+}
+
+// ListChildren returns an issue's direct sub-tasks in board order.
+func (s *IssueStore) ListChildren(ctx context.Context, id int64) ([]*Issue, error) {
+	rows, err := s.queries.ListIssueChildren(ctx, nullInt8(&id))
+	if err != nil {
+		return nil, fmt.Errorf("listing sub-tasks: %w", err)
+	}
+
+	issues := make([]*Issue, len(rows))
+	for i, row := range rows {
+		issues[i] = listRowToDomain(dbsqlc.ListIssuesRow(row))
+	}
+	return issues, nil
+}
+
+// SetParent makes the issue a sub-task of parentID, or a top-level issue
+// when parentID is nil. The parent must be on the same board
+// (ErrInvalidParent), must not be the issue or one of its descendants
+// (ErrParentCycle), and the result must fit in MaxIssueDepth
+// (ErrParentTooDeep).
+func (s *IssueStore) SetParent(ctx context.Context, id int64, parentID *int64) (*Issue, error) {
+	var issue *Issue
+
+	err := withTx(ctx, s.db, func(q *dbsqlc.Queries) error {
+		if err := q.LockIssueHierarchy(ctx); err != nil {
+			return fmt.Errorf("locking hierarchy: %w", err)
+		}
+
+		current, err := q.GetIssueForUpdate(ctx, id)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return ErrNotFound
+			}
+			return fmt.Errorf("locking issue: %w", err)
+		}
+
+		if parentID != nil {
+			height, err := q.IssueSubtreeHeight(ctx, id)
+			if err != nil {
+				return fmt.Errorf("measuring sub-tasks: %w", err)
+			}
+			err = checkParent(ctx, q, current.ProjectID, current.UserID, *parentID, id, height)
+			if err != nil {
+				return err
+			}
+		}
+
+		row, err := q.SetIssueParent(ctx, dbsqlc.SetIssueParentParams{
+			ID:       id,
+			ParentID: nullInt8(parentID),
+		})
+		if err != nil {
+			return fmt.Errorf("setting parent: %w", err)
+		}
+
+		// Either parent may now have every remaining child done.
+		if err := completeParents(ctx, q, current.ParentID); err != nil {
+			return err
+		}
+		if err := completeParents(ctx, q, row.ParentID); err != nil {
+			return err
+		}
+
+		issue = toDomainIssue(row)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return issue, nil
+}
+
+// SetAutoComplete turns auto-completion on or off for an issue. Turning it
+// on completes the issue straight away if all its children are done.
+func (s *IssueStore) SetAutoComplete(ctx context.Context, id int64, enabled bool) (*Issue, error) {
+	var issue *Issue
+
+	err := withTx(ctx, s.db, func(q *dbsqlc.Queries) error {
+		_, err := q.SetIssueAutoComplete(ctx, dbsqlc.SetIssueAutoCompleteParams{
+			ID:           id,
+			AutoComplete: enabled,
+		})
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return ErrNotFound
+			}
+			return fmt.Errorf("setting auto-complete: %w", err)
+		}
+
+		if enabled {
+			if err := completeParents(ctx, q, nullInt8(&id)); err != nil {
+				return err
+			}
+		}
+
+		// Re-read in case it was just completed.
+		latest, err := q.GetIssueByID(ctx, id)
+		if err != nil {
+			return fmt.Errorf("getting issue: %w", err)
+		}
+
+		issue = issueRowToDomain(latest)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return issue, nil
+}
+
+// checkParent validates parentID as the parent of an issue on the board
+// (projectID, or userID's personal board) whose subtree is height levels
+// tall. id is the issue itself, or 0 for one not created yet. The caller
+// must hold the hierarchy lock.
+func checkParent(ctx context.Context, q *dbsqlc.Queries, projectID pgtype.Int8, userID uuid.UUID, parentID, id int64, height int32) error {
+	parent, err := q.GetIssuePosition(ctx, parentID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrInvalidParent
+		}
+		return fmt.Errorf("getting parent: %w", err)
+	}
+
+	sameBoard := parent.ProjectID == projectID && (projectID.Valid || parent.UserID == userID)
+	if !sameBoard {
+		return ErrInvalidParent
+	}
+
+	ancestors, err := q.ListIssueAncestors(ctx, parentID)
+	if err != nil {
+		return fmt.Errorf("listing ancestors: %w", err)
+	}
+	if id != 0 && slices.Contains(ancestors, id) {
+		return ErrParentCycle
+	}
+	if len(ancestors)+int(height) > MaxIssueDepth {
+		return ErrParentTooDeep
+	}
+	return nil
+}
+
+// completeParents walks up from id, moving each auto-complete issue whose
+// children are all done to the top of its workflow's first done status. It
+// stops at the first issue that doesn't change. An issue whose workflow
+// doesn't allow that transition, or that has open blockers, is left where
+// it is; WIP limits don't apply. Rows are locked child first, the same order moves use.
+func completeParents(ctx context.Context, q *dbsqlc.Queries, id pgtype.Int8) error {
+	for id.Valid {
+		issue, err := q.GetIssueForUpdate(ctx, id.Int64)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return nil
+			}
+			return fmt.Errorf("locking parent: %w", err)
+		}
+		if !issue.AutoComplete {
+			return nil
+		}
+
+		children, err := q.CountIssueChildren(ctx, id)
+		if err != nil {
+			return fmt.Errorf("counting sub-tasks: %w", err)
+		}
+		if children.Total == 0 || children.Done < children.Total {
+			return nil
+		}
+
+		current, err := q.GetWorkflowStatusByName(ctx, dbsqlc.GetWorkflowStatusByNameParams{
+			ProjectID: issue.ProjectID,
+			Name:      issue.Status,
+		})
+		if err == nil && current.Category == string(CategoryDone) {
+			return nil
+		}
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("looking up status: %w", err)
+		}
+
+		target, err := q.FirstDoneWorkflowStatus(ctx, issue.ProjectID)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return nil
+			}
+			return fmt.Errorf("finding done status: %w", err)
+		}
+
+		_, err = checkTransition(ctx, q, issue.ProjectID, issue.Status, target.Name)
+		if errors.Is(err, ErrTransitionNotAllowed) || errors.Is(err, ErrUnknownStatus) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		// A parent with open blockers waits for them like a manual move would.
+		err = checkBlockers(ctx, q, issue.ID)
+		var blocked *BlockedError
+		if errors.As(err, &blocked) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		key, err := placeInColumn(ctx, q, boardColumn{
+			projectID: issue.ProjectID,
+			userID:    issue.UserID,
+			status:    target.Name,
+			exclude:   issue.ID,
+		}, placement{})
+		if err != nil {
+			return err
+		}
+
+		_, err = q.UpdateIssueStatus(ctx, dbsqlc.UpdateIssueStatusParams{
+			ID:     issue.ID,
+			Status: target.Name,
+			Rank:   key,
+		})
+		if err != nil {
+			return fmt.Errorf("completing parent: %w", err)
+		}
+
+		id = issue.ParentID
+	}
+	return nil
+}
+
+func progressToDomain(total, done int64) *IssueProgress {
+	if total == 0 {
+		return nil
+	}
+	return &IssueProgress{Completed: done, Total: total}
+}
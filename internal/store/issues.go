@@ -8,9 +8,13 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/jesusthecreator017/fswithgo/internal/store/dbsqlc"
 )
 
+// StatusType is a workflow status name. Projects define their own; these
+// are the statuses in the default workflow.
 type StatusType string
 
 const (
@@ -26,31 +30,93 @@ type Issue struct {
 	Title       string     `json:"title"`
 	Description string     `json:"description"`
 	Status      StatusType `json:"status"`
+	ProjectID   *int64     `json:"project_id"`
+	Rank        string     `json:"rank"`
 	CreatedAt   time.Time  `json:"created_at"`
 	UpdatedAt   time.Time  `json:"updated_at"`
+
+	// ParentID makes the issue a sub-task. AutoComplete completes it once
+	// all its children are done; Progress counts them and is nil for an
+	// issue without children.
+	ParentID     *int64         `json:"parent_id"`
+	AutoComplete bool           `json:"auto_complete"`
+	Progress     *IssueProgress `json:"progress,omitempty"`
 }
 
 type IssueStore struct {
+	db      *pgxpool.Pool
 	queries *dbsqlc.Queries
 }
 
+// IssueMove is a drag on the board: the target status plus the cards the
+// issue should land between. Either neighbor may be nil; with neither the
+// issue goes to the bottom of the column.
+type IssueMove struct {
+	Status    StatusType
+	AfterID   *int64
+	BeforeID  *int64
+	Overrides Overrides
+}
+
+// Overrides lets a status change skip checks that would otherwise reject it.
+type Overrides struct {
+	WIPLimits bool // go over a full column's WIP limit
+	Blockers  bool // complete an issue whose blockers are still open
+}
+
 var ErrNotFound = errors.New("resource not found")
 
-func (s *IssueStore) Create(ctx context.Context, issue *Issue) error {
-	row, err := s.queries.CreateIssue(ctx, dbsqlc.CreateIssueParams{
-		Title:       issue.Title,
-		UserID:      issue.UserID,
-		Description: issue.Description,
-		Status:      string(issue.Status),
-	})
-	if err != nil {
-		return fmt.Errorf("creating issue: %w", err)
-	}
+// Create inserts the issue at the top of its status column. Unless
+// overrideWIP is set it fails with a *WIPLimitError if that column is full.
+// A ParentID is checked the same way as in SetParent.
+func (s *IssueStore) Create(ctx context.Context, issue *Issue, overrideWIP bool) error {
+	return withTx(ctx, s.db, func(q *dbsqlc.Queries) error {
+		if issue.ParentID != nil {
+			if err := q.LockIssueHierarchy(ctx); err != nil {
+				return fmt.Errorf("locking hierarchy: %w", err)
+			}
+			err := checkParent(ctx, q, nullInt8(issue.ProjectID), issue.UserID, *issue.ParentID, 0, 1)
+			if err != nil {
+				return err
+			}
+		}
+
+		if !overrideWIP {
+			err := checkWIPLimits(ctx, q, nullInt8(issue.ProjectID), issue.UserID, string(issue.Status), 0)
+			if err != nil {
+				return err
+			}
+		}
+
+		key, err := placeInColumn(ctx, q, boardColumn{
+			projectID: nullInt8(issue.ProjectID),
+			userID:    issue.UserID,
+			status:    string(issue.Status),
+		}, placement{})
+		if err != nil {
+			return fmt.Errorf("ranking issue: %w", err)
+		}
+
+		row, err := q.CreateIssue(ctx, dbsqlc.CreateIssueParams{
+			Title:        issue.Title,
+			UserID:       issue.UserID,
+			Description:  issue.Description,
+			Status:       string(issue.Status),
+			ProjectID:    nullInt8(issue.ProjectID),
+			Rank:         key,
+			ParentID:     nullInt8(issue.ParentID),
+			AutoComplete: issue.AutoComplete,
+		})
+		if err != nil {
+			return fmt.Errorf("creating issue: %w", err)
+		}
 
-	issue.ID = row.ID
-	issue.CreatedAt = row.CreatedAt.Time
-	issue.UpdatedAt = row.UpdatedAt.Time
-	return nil
+		issue.ID = row.ID
+		issue.Rank = row.Rank
+		issue.CreatedAt = row.CreatedAt.Time
+		issue.UpdatedAt = row.UpdatedAt.Time
+		return nil
+	})
 }
 
 func (s *IssueStore) GetByID(ctx context.Context, id int64) (*Issue, error) {
@@ -86,84 +152,236 @@ func (s *IssueStore) ListByUserID(ctx context.Context, userID uuid.UUID) ([]*Iss
 
 	issues := make([]*Issue, len(rows))
 	for i, row := range rows {
-		issues[i] = &Issue{
-			ID:          row.ID,
-			UserID:      row.UserID,
-			UserName:    row.UserName,
-			Title:       row.Title,
-			Description: row.Description,
-			Status:      StatusType(row.Status),
-			CreatedAt:   row.CreatedAt.Time,
-			UpdatedAt:   row.UpdatedAt.Time,
-		}
+		issues[i] = listRowToDomain(dbsqlc.ListIssuesRow(row))
 	}
 	return issues, nil
 }
 
-func (s *IssueStore) UpdateStatus(ctx context.Context, id int64, newStatus StatusType) (*Issue, error) {
-	row, err := s.queries.UpdateIssueStatus(ctx, dbsqlc.UpdateIssueStatusParams{
-		ID:     id,
-		Status: string(newStatus),
-	})
+func (s *IssueStore) ListByProjectID(ctx context.Context, projectID int64) ([]*Issue, error) {
+	rows, err := s.queries.ListIssuesByProjectID(ctx, nullInt8(&projectID))
 	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			return nil, ErrNotFound
+		return nil, fmt.Errorf("listing issues by project: %w", err)
+	}
+
+	issues := make([]*Issue, len(rows))
+	for i, row := range rows {
+		issues[i] = listRowToDomain(dbsqlc.ListIssuesRow(row))
+	}
+	return issues, nil
+}
+
+// UpdateStatus moves an issue to newStatus, which must be in the issue's
+// workflow (ErrUnknownStatus) and reachable from its current status
+// (ErrTransitionNotAllowed), and must have room under its WIP limits
+// (*WIPLimitError). A done-category status also needs every blocker done
+// (*BlockedError). Overrides skips the last two. The issue goes to the top
+// of its new column. Setting the current status again is a no-op move and
+// always allowed.
+func (s *IssueStore) UpdateStatus(ctx context.Context, id int64, newStatus StatusType, overrides Overrides) (*Issue, error) {
+	return s.move(ctx, id, newStatus, placement{}, overrides)
+}
+
+// Move changes an issue's status and position in one step. Status rules
+// are the same as UpdateStatus; neighbors must already be in the target column
+// (ErrInvalidNeighbor).
+func (s *IssueStore) Move(ctx context.Context, id int64, move IssueMove) (*Issue, error) {
+	return s.move(ctx, id, move.Status, placement{
+		afterID:  move.AfterID,
+		beforeID: move.BeforeID,
+		bottom:   true,
+	}, move.Overrides)
+}
+
+func (s *IssueStore) move(ctx context.Context, id int64, newStatus StatusType, at placement, overrides Overrides) (*Issue, error) {
+	var issue *Issue
+
+	err := withTx(ctx, s.db, func(q *dbsqlc.Queries) error {
+		current, err := q.GetIssueForUpdate(ctx, id)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return ErrNotFound
+			}
+			return fmt.Errorf("locking issue: %w", err)
+		}
+
+		key := current.Rank
+		if current.Status != string(newStatus) {
+			target, err := checkTransition(ctx, q, current.ProjectID, current.Status, string(newStatus))
+			if err != nil {
+				return err
+			}
+			if target.Category == string(CategoryDone) && !overrides.Blockers {
+				if err := checkBlockers(ctx, q, current.ID); err != nil {
+					return err
+				}
+			}
+			if !overrides.WIPLimits {
+				err := checkWIPLimits(ctx, q, current.ProjectID, current.UserID, string(newStatus), current.ID)
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		// Keep the current rank when the issue stays in its column and no
+		// position was asked for.
+		if current.Status != string(newStatus) || at.afterID != nil || at.beforeID != nil || at.bottom {
+			key, err = placeInColumn(ctx, q, boardColumn{
+				projectID: current.ProjectID,
+				userID:    current.UserID,
+				status:    string(newStatus),
+				exclude:   current.ID,
+			}, at)
+			if err != nil {
+				return err
+			}
+		}
+
+		row, err := q.UpdateIssueStatus(ctx, dbsqlc.UpdateIssueStatusParams{
+			ID:     id,
+			Status: string(newStatus),
+			Rank:   key,
+		})
+		if err != nil {
+			return fmt.Errorf("updating issue status: %w", err)
 		}
-		return nil, fmt.Errorf("updating issue status: %w", err)
+
+		if current.Status != string(newStatus) {
+			if err := completeParents(ctx, q, current.ParentID); err != nil {
+				return err
+			}
+		}
+
+		issue = toDomainIssue(row)
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	return toDomainIssue(row), nil
+	return issue, nil
 }
 
-func (s *IssueStore) Delete(ctx context.Context, id int64) error {
-	err := s.queries.DeleteIssue(ctx, id)
+// checkTransition returns ErrUnknownStatus or ErrTransitionNotAllowed if an
+// issue in projectID's workflow may not move from one status to the other,
+// and otherwise the status it is moving to.
+func checkTransition(ctx context.Context, q *dbsqlc.Queries, projectID pgtype.Int8, from, to string) (dbsqlc.WorkflowStatus, error) {
+	target, err := q.GetWorkflowStatusByName(ctx, dbsqlc.GetWorkflowStatusByNameParams{
+		ProjectID: projectID,
+		Name:      to,
+	})
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			return ErrNotFound
+			return dbsqlc.WorkflowStatus{}, ErrUnknownStatus
 		}
-		return fmt.Errorf("deleting issue: %w", err)
+		return dbsqlc.WorkflowStatus{}, fmt.Errorf("looking up status: %w", err)
 	}
-	return nil
+
+	allowed, err := q.WorkflowTransitionExists(ctx, dbsqlc.WorkflowTransitionExistsParams{
+		ProjectID:  projectID,
+		FromStatus: from,
+		ToStatus:   to,
+	})
+	if err != nil {
+		return dbsqlc.WorkflowStatus{}, fmt.Errorf("checking transition: %w", err)
+	}
+	if !allowed {
+		return dbsqlc.WorkflowStatus{}, ErrTransitionNotAllowed
+	}
+	return target, nil
+}
+
+// Delete removes an issue. An issue with sub-tasks needs a ChildPolicy
+// saying whether they go too or become top-level issues; without one it
+// fails with ErrHasChildren.
+func (s *IssueStore) Delete(ctx context.Context, id int64, children ChildPolicy) error {
+	return withTx(ctx, s.db, func(q *dbsqlc.Queries) error {
+		if err := q.LockIssueHierarchy(ctx); err != nil {
+			return fmt.Errorf("locking hierarchy: %w", err)
+		}
+
+		issue, err := q.GetIssueForUpdate(ctx, id)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return ErrNotFound
+			}
+			return fmt.Errorf("locking issue: %w", err)
+		}
+
+		counts, err := q.CountIssueChildren(ctx, nullInt8(&id))
+		if err != nil {
+			return fmt.Errorf("counting sub-tasks: %w", err)
+		}
+
+		switch {
+		case counts.Total == 0 || children == ChildrenOrphan:
+			_, err = q.DeleteIssue(ctx, id)
+		case children == ChildrenCascade:
+			_, err = q.DeleteIssueTree(ctx, id)
+		default:
+			return ErrHasChildren
+		}
+		if err != nil {
+			return fmt.Errorf("deleting issue: %w", err)
+		}
+
+		// The parent may have just lost its last unfinished child.
+		return completeParents(ctx, q, issue.ParentID)
+	})
 }
 
 // toDomainIssue converts a sqlc-generated Issue (from CreateIssue/UpdateIssueStatus)
 // to the domain Issue. These queries don't JOIN users, so UserName is left empty.
 func toDomainIssue(row dbsqlc.Issue) *Issue {
 	return &Issue{
-		ID:          row.ID,
-		UserID:      row.UserID,
-		Title:       row.Title,
-		Description: row.Description,
-		Status:      StatusType(row.Status),
-		CreatedAt:   row.CreatedAt.Time,
-		UpdatedAt:   row.UpdatedAt.Time,
+		ID:           row.ID,
+		UserID:       row.UserID,
+		Title:        row.Title,
+		Description:  row.Description,
+		Status:       StatusType(row.Status),
+		ProjectID:    int64Ptr(row.ProjectID),
+		Rank:         row.Rank,
+		CreatedAt:    row.CreatedAt.Time,
+		UpdatedAt:    row.UpdatedAt.Time,
+		ParentID:     int64Ptr(row.ParentID),
+		AutoComplete: row.AutoComplete,
 	}
 }
 
 // issueRowToDomain converts a GetIssueByIDRow (which includes user_name via JOIN).
 func issueRowToDomain(row dbsqlc.GetIssueByIDRow) *Issue {
 	return &Issue{
-		ID:          row.ID,
-		UserID:      row.UserID,
-		UserName:    row.UserName,
-		Title:       row.Title,
-		Description: row.Description,
-		Status:      StatusType(row.Status),
-		CreatedAt:   row.CreatedAt.Time,
-		UpdatedAt:   row.UpdatedAt.Time,
+		ID:           row.ID,
+		UserID:       row.UserID,
+		UserName:     row.UserName,
+		Title:        row.Title,
+		Description:  row.Description,
+		Status:       StatusType(row.Status),
+		ProjectID:    int64Ptr(row.ProjectID),
+		Rank:         row.Rank,
+		CreatedAt:    row.CreatedAt.Time,
+		UpdatedAt:    row.UpdatedAt.Time,
+		ParentID:     int64Ptr(row.ParentID),
+		AutoComplete: row.AutoComplete,
+		Progress:     progressToDomain(row.ChildrenTotal, row.ChildrenDone),
 	}
 }
 
 // listRowToDomain converts a ListIssuesRow (which includes user_name via JOIN).
 func listRowToDomain(row dbsqlc.ListIssuesRow) *Issue {
 	return &Issue{
-		ID:          row.ID,
-		UserID:      row.UserID,
-		UserName:    row.UserName,
-		Title:       row.Title,
-		Description: row.Description,
-		Status:      StatusType(row.Status),
-		CreatedAt:   row.CreatedAt.Time,
-		UpdatedAt:   row.UpdatedAt.Time,
+		ID:           row.ID,
+		UserID:       row.UserID,
+		UserName:     row.UserName,
+		Title:        row.Title,
+		Description:  row.Description,
+		Status:       StatusType(row.Status),
+		ProjectID:    int64Ptr(row.ProjectID),
+		Rank:         row.Rank,
+		CreatedAt:    row.CreatedAt.Time,
+		UpdatedAt:    row.UpdatedAt.Time,
+		ParentID:     int64Ptr(row.ParentID),
+		AutoComplete: row.AutoComplete,
+		Progress:     progressToDomain(row.ChildrenTotal, row.ChildrenDone),
 	}
 }
@@ -0,0 +1,221 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jesusthecreator017/fswithgo/internal/store/dbsqlc"
+)
+
+// LinkType is how one issue relates to another, read from the issue the
+// link is listed on: "A blocks B" is listed on A as blocks and on B as
+// blocked_by.
+type LinkType string
+
+const (
+	LinkBlocks       LinkType = "blocks"
+	LinkBlockedBy    LinkType = "blocked_by"
+	LinkDuplicates   LinkType = "duplicates"
+	LinkDuplicatedBy LinkType = "duplicated_by"
+	LinkRelatesTo    LinkType = "relates_to"
+)
+
+var linkInverses = map[LinkType]LinkType{
+	LinkBlocks:       LinkBlockedBy,
+	LinkBlockedBy:    LinkBlocks,
+	LinkDuplicates:   LinkDuplicatedBy,
+	LinkDuplicatedBy: LinkDuplicates,
+	LinkRelatesTo:    LinkRelatesTo,
+}
+
+func (t LinkType) Valid() bool {
+	_, ok := linkInverses[t]
+	return ok
+}
+
+// Inverse is the type of the same link seen from the other issue.
+func (t LinkType) Inverse() LinkType {
+	return linkInverses[t]
+}
+
+var (
+	ErrDuplicateLink = errors.New("issues are already linked this way")
+	ErrLinkCycle     = errors.New("link would make a cycle of blocking issues")
+)
+
+// LinkedIssue is the issue at the other end of a link.
+type LinkedIssue struct {
+	ID     int64      `json:"id"`
+	Title  string     `json:"title"`
+	Status StatusType `json:"status"`
+	Done   bool       `json:"done"`
+}
+
+type IssueLink struct {
+	ID        int64       `json:"id"`
+	Type      LinkType    `json:"type"`
+	Issue     LinkedIssue `json:"issue"`
+	CreatedAt time.Time   `json:"created_at"`
+}
+
+// BlockedError is returned when an issue with open blockers is moved to a
+// done status.
+type BlockedError struct {
+	Blockers []LinkedIssue
+}
+
+func (e *BlockedError) Error() string {
+	ids := make([]string, len(e.Blockers))
+	for i, b := range e.Blockers {
+		ids[i] = fmt.Sprintf("#%d", b.ID)
+	}
+	return "issue is blocked by " + strings.Join(ids, ", ")
+}
+
+type LinkStore struct {
+	db      *pgxpool.Pool
+	queries *dbsqlc.Queries
+}
+
+// List returns the links on an issue, grouped by type.
+func (s *LinkStore) List(ctx context.Context, issueID int64) ([]*IssueLink, error) {
+	rows, err := s.queries.ListIssueLinks(ctx, issueID)
+	if err != nil {
+		return nil, fmt.Errorf("listing issue links: %w", err)
+	}
+
+	links := make([]*IssueLink, len(rows))
+	for i, row := range rows {
+		links[i] = linkRowToDomain(dbsqlc.GetIssueLinkRow(row))
+	}
+	return links, nil
+}
+
+// Create links issueID to targetID and stores the inverse on targetID. A
+// blocks or blocked_by link that would let an issue block itself through
+// a chain fails with ErrLinkCycle. A missing target is ErrNotFound.
+func (s *LinkStore) Create(ctx context.Context, issueID int64, linkType LinkType, targetID int64) (*IssueLink, error) {
+	var link *IssueLink
+
+	err := withTx(ctx, s.db, func(q *dbsqlc.Queries) error {
+		if blocker, blocked, ok := blockingPair(issueID, linkType, targetID); ok {
+			if err := q.LockIssueBlocks(ctx); err != nil {
+				return fmt.Errorf("locking blocks: %w", err)
+			}
+			cycle, err := q.BlocksPathExists(ctx, dbsqlc.BlocksPathExistsParams{
+				FromID: blocked,
+				ToID:   blocker,
+			})
+			if err != nil {
+				return fmt.Errorf("checking for cycles: %w", err)
+			}
+			if cycle {
+				return ErrLinkCycle
+			}
+		}
+
+		n, err := q.CreateIssueLinkPair(ctx, dbsqlc.CreateIssueLinkPairParams{
+			SourceID:    issueID,
+			TargetID:    targetID,
+			LinkType:    string(linkType),
+			InverseType: string(linkType.Inverse()),
+		})
+		if err != nil {
+			if isForeignKeyViolation(err) {
+				return ErrNotFound
+			}
+			return fmt.Errorf("creating issue link: %w", err)
+		}
+		if n == 0 {
+			return ErrDuplicateLink
+		}
+
+		row, err := q.GetIssueLink(ctx, dbsqlc.GetIssueLinkParams{
+			SourceID: issueID,
+			TargetID: targetID,
+			LinkType: string(linkType),
+		})
+		if err != nil {
+			return fmt.Errorf("getting issue link: %w", err)
+		}
+
+		link = linkRowToDomain(row)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return link, nil
+}
+
+// Delete removes a link and its inverse.
+func (s *LinkStore) Delete(ctx context.Context, issueID int64, linkType LinkType, targetID int64) error {
+	n, err := s.queries.DeleteIssueLinkPair(ctx, dbsqlc.DeleteIssueLinkPairParams{
+		SourceID:    issueID,
+		TargetID:    targetID,
+		LinkType:    string(linkType),
+		InverseType: string(linkType.Inverse()),
+	})
+	if err != nil {
+		return fmt.Errorf("deleting issue link: %w", err)
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// checkBlockers returns a *BlockedError if issueID has blockers that
+// aren't done.
+func checkBlockers(ctx context.Context, q *dbsqlc.Queries, issueID int64) error {
+	rows, err := q.ListOpenBlockers(ctx, issueID)
+	if err != nil {
+		return fmt.Errorf("listing blockers: %w", err)
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+
+	blockers := make([]LinkedIssue, len(rows))
+	for i, row := range rows {
+		blockers[i] = linkRowToDomain(dbsqlc.GetIssueLinkRow(row)).Issue
+	}
+	return &BlockedError{Blockers: blockers}
+}
+
+// blockingPair puts a link in blocker/blocked order if it is a blocks link
+// in either direction.
+func blockingPair(issueID int64, linkType LinkType, targetID int64) (blocker, blocked int64, ok bool) {
+	switch linkType {
+	case LinkBlocks:
+		return issueID, targetID, true
+	case LinkBlockedBy:
+		return targetID, issueID, true
+	}
+	return 0, 0, false
+}
+
+func isForeignKeyViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23503"
+}
+
+func linkRowToDomain(row dbsqlc.GetIssueLinkRow) *IssueLink {
+	return &IssueLink{
+		ID:   row.ID,
+		Type: LinkType(row.LinkType),
+		Issue: LinkedIssue{
+			ID:     row.IssueID,
+			Title:  row.IssueTitle,
+			Status: StatusType(row.IssueStatus),
+			Done:   row.IssueDone,
+		},
+		CreatedAt: row.CreatedAt.Time,
+	}
+}
@@ -0,0 +1,88 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jesusthecreator017/fswithgo/internal/store/dbsqlc"
+)
+
+// Project groups issues under one board with its own workflow. Projects are
+// shared by every user and are not an access boundary: anyone signed in can
+// see a project and create or move issues in it. Only the owner and admins
+// may change the workflow.
+type Project struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	OwnerID   uuid.UUID `json:"owner_id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type ProjectStore struct {
+	db      *pgxpool.Pool
+	queries *dbsqlc.Queries
+}
+
+// Create inserts the project and gives it a copy of the default workflow.
+func (s *ProjectStore) Create(ctx context.Context, project *Project) error {
+	return withTx(ctx, s.db, func(q *dbsqlc.Queries) error {
+		row, err := q.CreateProject(ctx, dbsqlc.CreateProjectParams{
+			Name:    project.Name,
+			OwnerID: project.OwnerID,
+		})
+		if err != nil {
+			return fmt.Errorf("creating project: %w", err)
+		}
+
+		if err := q.CopyDefaultWorkflowStatuses(ctx, row.ID); err != nil {
+			return fmt.Errorf("copying workflow statuses: %w", err)
+		}
+		if err := q.CopyDefaultWorkflowTransitions(ctx, row.ID); err != nil {
+			return fmt.Errorf("copying workflow transitions: %w", err)
+		}
+
+		*project = *projectToDomain(row)
+		return nil
+	})
+}
+
+func (s *ProjectStore) GetByID(ctx context.Context, id int64) (*Project, error) {
+	row, err := s.queries.GetProjectByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("getting project: %w", err)
+	}
+
+	return projectToDomain(row), nil
+}
+
+func (s *ProjectStore) List(ctx context.Context) ([]*Project, error) {
+	rows, err := s.queries.ListProjects(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("listing projects: %w", err)
+	}
+
+	projects := make([]*Project, len(rows))
+	for i, row := range rows {
+		projects[i] = projectToDomain(row)
+	}
+	return projects, nil
+}
+
+func projectToDomain(row dbsqlc.Project) *Project {
+	return &Project{
+		ID:        row.ID,
+		Name:      row.Name,
+		OwnerID:   row.OwnerID,
+		CreatedAt: row.CreatedAt.Time,
+		UpdatedAt: row.UpdatedAt.Time,
+	}
+}
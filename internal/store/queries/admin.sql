@@ -3,3 +3,62 @@ SELECT COUNT(*) AS total_users FROM users;
 
 -- name: CountIssuesByStatus :many
 SELECT status, COUNT(*) AS count FROM issues GROUP BY status;
+
+-- name: ListUsers :many
+SELECT u.id, u.email, u.name, u.permissions, u.suspended_at, u.password_reset_required,
+       u.created_at, u.updated_at,
+       COUNT(*) OVER () AS total_count
+FROM users u
+WHERE @search::text = ''
+   OR u.email ILIKE '%' || @search::text || '%'
+   OR u.name ILIKE '%' || @search::text || '%'
+ORDER BY u.created_at DESC, u.id
+LIMIT @page_limit OFFSET @page_offset;
+
+-- name: CountIssuesByStatusForUser :many
+SELECT status, COUNT(*) AS count
+FROM issues
+WHERE user_id = $1
+GROUP BY status;
+
+-- name: LockActiveAdmins :many
+SELECT id FROM users
+WHERE permissions & 4 = 4 AND suspended_at IS NULL
+FOR UPDATE;
+
+-- name: UpdateUserPermissions :one
+UPDATE users
+SET permissions = $2, updated_at = now()
+WHERE id = $1
+RETURNING id, email, name, permissions, suspended_at, password_reset_required, created_at, updated_at;
+
+-- name: SuspendUser :one
+UPDATE users
+SET suspended_at = COALESCE(suspended_at, now()), updated_at = now()
+WHERE id = $1
+RETURNING id, email, name, permissions, suspended_at, password_reset_required, created_at, updated_at;
+
+-- name: ReactivateUser :one
+UPDATE users
+SET suspended_at = NULL, updated_at = now()
+WHERE id = $1
+RETURNING id, email, name, permissions, suspended_at, password_reset_required, created_at, updated_at;
+
+-- name: RequireUserPasswordReset :one
+UPDATE users
+SET password_reset_required = true, updated_at = now()
+WHERE id = $1
+RETURNING id, email, name, permissions, suspended_at, password_reset_required, created_at, updated_at;
+
+-- name: ReassignUserIssues :exec
+UPDATE issues
+SET user_id = @to_user_id, updated_at = now()
+WHERE user_id = @from_user_id;
+
+-- name: DeleteIssuesByUserID :exec
+DELETE FROM issues
+WHERE user_id = $1;
+
+-- name: DeleteUser :execrows
+DELETE FROM users
+WHERE id = $1;
@@ -0,0 +1,87 @@
+-- name: AnalyticsSeries :many
+-- One row per bucket between range_start and range_end, including empty
+-- buckets. Each series is aggregated before joining so the range filters
+-- can use the created_at / changed_at indexes.
+WITH buckets AS (
+    SELECT generate_series(
+        date_trunc(@granularity::text, @range_start::timestamptz, 'UTC'),
+        @range_end::timestamptz - interval '1 microsecond',
+        ('1 ' || @granularity::text)::interval
+    ) AS bucket
+),
+created AS (
+    SELECT date_trunc(@granularity::text, created_at, 'UTC') AS bucket, COUNT(*) AS count
+    FROM issues
+    WHERE created_at >= @range_start::timestamptz AND created_at < @range_end::timestamptz
+    GROUP BY 1
+),
+closed AS (
+    SELECT date_trunc(@granularity::text, e.changed_at, 'UTC') AS bucket, COUNT(*) AS count
+    FROM issue_status_events e
+    JOIN issues i ON i.id = e.issue_id
+    JOIN workflow_statuses ws
+        ON ws.project_id IS NOT DISTINCT FROM i.project_id AND ws.name = e.to_status
+    WHERE ws.category = 'done'
+      AND e.changed_at >= @range_start::timestamptz AND e.changed_at < @range_end::timestamptz
+    GROUP BY 1
+),
+signups AS (
+    SELECT date_trunc(@granularity::text, created_at, 'UTC') AS bucket, COUNT(*) AS count
+    FROM users
+    WHERE created_at >= @range_start::timestamptz AND created_at < @range_end::timestamptz
+    GROUP BY 1
+),
+cycle AS (
+    SELECT date_trunc(@granularity::text, e.changed_at, 'UTC') AS bucket,
+           percentile_cont(0.5) WITHIN GROUP (ORDER BY EXTRACT(EPOCH FROM e.changed_at - i.created_at)) / 3600 AS median_hours
+    FROM issue_status_events e
+    JOIN issues i ON i.id = e.issue_id
+    JOIN workflow_statuses ws
+        ON ws.project_id IS NOT DISTINCT FROM i.project_id AND ws.name = e.to_status
+    WHERE ws.category = 'done'
+      AND e.changed_at >= @range_start::timestamptz AND e.changed_at < @range_end::timestamptz
+    GROUP BY 1
+)
+SELECT b.bucket::timestamptz AS bucket,
+       COALESCE(c.count, 0)::bigint AS issues_created,
+       COALESCE(d.count, 0)::bigint AS issues_closed,
+       COALESCE(s.count, 0)::bigint AS signups,
+       ct.median_hours::float8 AS median_cycle_hours
+FROM buckets b
+LEFT JOIN created c ON c.bucket = b.bucket
+LEFT JOIN closed d ON d.bucket = b.bucket
+LEFT JOIN signups s ON s.bucket = b.bucket
+LEFT JOIN cycle ct ON ct.bucket = b.bucket
+ORDER BY b.bucket;
+
+-- name: MedianCycleTime :one
+-- Median hours from creation to reaching a done-category status, over the
+-- whole range.
+SELECT (percentile_cont(0.5) WITHIN GROUP (ORDER BY EXTRACT(EPOCH FROM e.changed_at - i.created_at)) / 3600)::float8 AS median_hours
+FROM issue_status_events e
+JOIN issues i ON i.id = e.issue_id
+JOIN workflow_statuses ws
+    ON ws.project_id IS NOT DISTINCT FROM i.project_id AND ws.name = e.to_status
+WHERE ws.category = 'done'
+  AND e.changed_at >= @range_start::timestamptz AND e.changed_at < @range_end::timestamptz;
+
+-- name: OpenIssueAging :many
+SELECT b.label::text AS label, COUNT(i.id) AS count
+FROM (VALUES
+    (1, '0-1d',   interval '0',       interval '1 day'),
+    (2, '1-7d',   interval '1 day',   interval '7 days'),
+    (3, '7-30d',  interval '7 days',  interval '30 days'),
+    (4, '30-90d', interval '30 days', interval '90 days'),
+    (5, '90d+',   interval '90 days', NULL::interval)
+) AS b(ord, label, min_age, max_age)
+LEFT JOIN issues i
+    ON now() - i.created_at >= b.min_age
+   AND (b.max_age IS NULL OR now() - i.created_at < b.max_age)
+   AND NOT EXISTS (
+       SELECT 1 FROM workflow_statuses ws
+       WHERE ws.project_id IS NOT DISTINCT FROM i.project_id
+         AND ws.name = i.status
+         AND ws.category = 'done'
+   )
+GROUP BY b.ord, b.label
+ORDER BY b.ord;
@@ -0,0 +1,33 @@
+-- name: CreateAttachment :one
+INSERT INTO attachments (issue_id, user_id, filename, content_type, size_bytes, sha256, storage_key)
+VALUES (@issue_id, @user_id, @filename, @content_type, @size_bytes, @sha256, @storage_key)
+RETURNING id, issue_id, user_id, filename, content_type, size_bytes, sha256, storage_key, created_at;
+
+-- name: ListIssueAttachments :many
+SELECT id, issue_id, user_id, filename, content_type, size_bytes, sha256, storage_key, created_at
+FROM attachments
+WHERE issue_id = $1
+ORDER BY created_at, id;
+
+-- name: GetAttachment :one
+SELECT id, issue_id, user_id, filename, content_type, size_bytes, sha256, storage_key, created_at
+FROM attachments
+WHERE id = @id AND issue_id = @issue_id;
+
+-- name: DetachAttachment :one
+-- Unhooks an attachment from its issue, leaving the row for the sweeper.
+UPDATE attachments
+SET issue_id = NULL
+WHERE id = @id AND issue_id = @issue_id
+RETURNING id, issue_id, user_id, filename, content_type, size_bytes, sha256, storage_key, created_at;
+
+-- name: ListOrphanedAttachments :many
+SELECT id, issue_id, user_id, filename, content_type, size_bytes, sha256, storage_key, created_at
+FROM attachments
+WHERE issue_id IS NULL
+ORDER BY id
+LIMIT $1;
+
+-- name: DeleteAttachment :exec
+DELETE FROM attachments
+WHERE id = $1;
@@ -0,0 +1,27 @@
+-- name: InsertAuditLog :exec
+INSERT INTO audit_log (actor_id, impersonator_id, action, target_type, target_id, ip, request_id, metadata)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8);
+
+-- name: ListAuditLog :many
+SELECT id, occurred_at, actor_id, impersonator_id, action, target_type, target_id, ip, request_id, metadata
+FROM audit_log
+WHERE (sqlc.narg('actor_id')::uuid IS NULL OR actor_id = sqlc.narg('actor_id')::uuid)
+  AND (sqlc.narg('action')::text IS NULL OR starts_with(action, sqlc.narg('action')::text))
+  AND (sqlc.narg('target_type')::text IS NULL OR target_type = sqlc.narg('target_type')::text)
+  AND (sqlc.narg('target_id')::text IS NULL OR target_id = sqlc.narg('target_id')::text)
+  AND (sqlc.narg('since')::timestamptz IS NULL OR occurred_at >= sqlc.narg('since')::timestamptz)
+  AND (sqlc.narg('until')::timestamptz IS NULL OR occurred_at < sqlc.narg('until')::timestamptz)
+  AND (sqlc.narg('before_id')::bigint IS NULL OR id < sqlc.narg('before_id')::bigint)
+ORDER BY id DESC
+LIMIT sqlc.arg('page_limit');
+
+-- name: ListAuditLogBefore :many
+SELECT id, occurred_at, actor_id, impersonator_id, action, target_type, target_id, ip, request_id, metadata
+FROM audit_log
+WHERE occurred_at < @cutoff AND id > @after_id
+ORDER BY id
+LIMIT @batch_size;
+
+-- name: DeleteAuditLogBefore :execrows
+DELETE FROM audit_log
+WHERE occurred_at < @cutoff AND id <= @max_id;
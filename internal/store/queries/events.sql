@@ -0,0 +1,7 @@
+-- name: NextEventID :one
+SELECT nextval('event_id_seq')::bigint AS id;
+
+-- name: Notify :exec
+-- Sends payload on a LISTEN/NOTIFY channel. The notification goes out when
+-- the surrounding transaction commits.
+SELECT pg_notify(@channel::text, @payload::text);
@@ -0,0 +1,6 @@
+-- name: GetSchemaVersion :one
+-- golang-migrate keeps a single row: the last migration applied, and
+-- whether it failed partway.
+SELECT version, dirty
+FROM schema_migrations
+LIMIT 1;
@@ -1,36 +1,217 @@
 -- name: CreateIssue :one
-INSERT INTO issues (title, user_id, description, status)
-VALUES ($1, $2, $3, $4)
-RETURNING id, user_id, title, description, status, created_at, updated_at;
+INSERT INTO issues (title, user_id, description, status, project_id, rank, parent_id, auto_complete)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+RETURNING id, user_id, title, description, status, created_at, updated_at, project_id, rank,
+          parent_id, auto_complete;
 
 -- name: GetIssueByID :one
 SELECT i.id, i.user_id, i.title, i.description, i.status, i.created_at, i.updated_at,
-       u.name AS user_name
+       i.project_id, i.rank, i.parent_id, i.auto_complete, u.name AS user_name,
+       progress.total AS children_total, progress.done AS children_done
 FROM issues i
 JOIN users u ON u.id = i.user_id
+CROSS JOIN LATERAL (
+    SELECT COUNT(*) AS total,
+           COUNT(*) FILTER (WHERE ws.category = 'done') AS done
+    FROM issues c
+    LEFT JOIN workflow_statuses ws
+        ON ws.project_id IS NOT DISTINCT FROM c.project_id AND ws.name = c.status
+    WHERE c.parent_id = i.id
+) progress
 WHERE i.id = $1;
 
+-- name: GetIssueForUpdate :one
+-- Locks the issue so a status change is checked against the status it
+-- actually has when the update lands.
+SELECT id, user_id, project_id, status, rank, parent_id, auto_complete
+FROM issues
+WHERE id = $1
+FOR UPDATE;
+
+-- name: GetIssuePosition :one
+SELECT id, user_id, project_id, status, rank
+FROM issues
+WHERE id = $1;
+
 -- name: ListIssues :many
 SELECT i.id, i.user_id, i.title, i.description, i.status, i.created_at, i.updated_at,
-       u.name AS user_name
+       i.project_id, i.rank, i.parent_id, i.auto_complete, u.name AS user_name,
+       progress.total AS children_total, progress.done AS children_done
 FROM issues i
 JOIN users u ON u.id = i.user_id
-ORDER BY i.created_at DESC;
+CROSS JOIN LATERAL (
+    SELECT COUNT(*) AS total,
+           COUNT(*) FILTER (WHERE ws.category = 'done') AS done
+    FROM issues c
+    LEFT JOIN workflow_statuses ws
+        ON ws.project_id IS NOT DISTINCT FROM c.project_id AND ws.name = c.status
+    WHERE c.parent_id = i.id
+) progress
+ORDER BY i.rank, i.id;
 
 -- name: ListIssuesByUserID :many
 SELECT i.id, i.user_id, i.title, i.description, i.status, i.created_at, i.updated_at,
-       u.name AS user_name
+       i.project_id, i.rank, i.parent_id, i.auto_complete, u.name AS user_name,
+       progress.total AS children_total, progress.done AS children_done
 FROM issues i
 JOIN users u ON u.id = i.user_id
+CROSS JOIN LATERAL (
+    SELECT COUNT(*) AS total,
+           COUNT(*) FILTER (WHERE ws.category = 'done') AS done
+    FROM issues c
+    LEFT JOIN workflow_statuses ws
+        ON ws.project_id IS NOT DISTINCT FROM c.project_id AND ws.name = c.status
+    WHERE c.parent_id = i.id
+) progress
 WHERE i.user_id = $1
-ORDER BY i.created_at DESC;
+ORDER BY i.rank, i.id;
+
+-- name: ListIssuesByProjectID :many
+SELECT i.id, i.user_id, i.title, i.description, i.status, i.created_at, i.updated_at,
+       i.project_id, i.rank, i.parent_id, i.auto_complete, u.name AS user_name,
+       progress.total AS children_total, progress.done AS children_done
+FROM issues i
+JOIN users u ON u.id = i.user_id
+CROSS JOIN LATERAL (
+    SELECT COUNT(*) AS total,
+           COUNT(*) FILTER (WHERE ws.category = 'done') AS done
+    FROM issues c
+    LEFT JOIN workflow_statuses ws
+        ON ws.project_id IS NOT DISTINCT FROM c.project_id AND ws.name = c.status
+    WHERE c.parent_id = i.id
+) progress
+WHERE i.project_id = $1
+ORDER BY i.rank, i.id;
+
+-- name: ListIssueChildren :many
+SELECT i.id, i.user_id, i.title, i.description, i.status, i.created_at, i.updated_at,
+       i.project_id, i.rank, i.parent_id, i.auto_complete, u.name AS user_name,
+       progress.total AS children_total, progress.done AS children_done
+FROM issues i
+JOIN users u ON u.id = i.user_id
+CROSS JOIN LATERAL (
+    SELECT COUNT(*) AS total,
+           COUNT(*) FILTER (WHERE ws.category = 'done') AS done
+    FROM issues c
+    LEFT JOIN workflow_statuses ws
+        ON ws.project_id IS NOT DISTINCT FROM c.project_id AND ws.name = c.status
+    WHERE c.parent_id = i.id
+) progress
+WHERE i.parent_id = $1
+ORDER BY i.rank, i.id;
 
 -- name: UpdateIssueStatus :one
 UPDATE issues
-SET status = $2, updated_at = now()
+SET status = $2, rank = $3, updated_at = now()
 WHERE id = $1
-RETURNING id, user_id, title, description, status, created_at, updated_at;
+RETURNING id, user_id, title, description, status, created_at, updated_at, project_id, rank,
+          parent_id, auto_complete;
+
+-- name: DeleteIssue :execrows
+DELETE FROM issues
+WHERE id = $1;
 
--- name: DeleteIssue :exec
+-- name: DeleteIssueTree :execrows
+-- Deletes an issue together with all of its descendants.
+WITH RECURSIVE subtree AS (
+    SELECT id FROM issues WHERE id = $1
+    UNION ALL
+    SELECT c.id FROM issues c JOIN subtree s ON c.parent_id = s.id
+)
 DELETE FROM issues
+WHERE id IN (SELECT id FROM subtree);
+
+-- name: PrevRankInColumn :one
+-- The last rank in a board column that sorts before @below, or the last
+-- rank overall when below is NULL. A board is a project, or a user's issues
+-- outside any project.
+SELECT rank
+FROM issues
+WHERE project_id IS NOT DISTINCT FROM sqlc.narg('project_id')::bigint
+  AND (sqlc.narg('project_id')::bigint IS NOT NULL OR user_id = @user_id)
+  AND status = @status
+  AND id <> @exclude_id
+  AND (sqlc.narg('below')::text IS NULL OR rank < sqlc.narg('below')::text)
+ORDER BY rank DESC, id DESC
+LIMIT 1;
+
+-- name: NextRankInColumn :one
+-- The first rank in a board column that sorts after @above.
+SELECT rank
+FROM issues
+WHERE project_id IS NOT DISTINCT FROM sqlc.narg('project_id')::bigint
+  AND (sqlc.narg('project_id')::bigint IS NOT NULL OR user_id = @user_id)
+  AND status = @status
+  AND id <> @exclude_id
+  AND rank > @above::text
+ORDER BY rank, id
+LIMIT 1;
+
+-- name: ListColumnForRebalance :many
+SELECT id
+FROM issues
+WHERE project_id IS NOT DISTINCT FROM sqlc.narg('project_id')::bigint
+  AND (sqlc.narg('project_id')::bigint IS NOT NULL OR user_id = @user_id)
+  AND status = @status
+  AND id <> @exclude_id
+ORDER BY rank, id
+FOR UPDATE;
+
+-- name: SetIssueRank :exec
+UPDATE issues
+SET rank = $2
 WHERE id = $1;
+
+-- name: LockIssueHierarchy :exec
+-- Serializes parent changes so two concurrent moves can't close a cycle
+-- between them. Held until the transaction ends.
+SELECT pg_advisory_xact_lock(hashtext('issues.parent_id'));
+
+-- name: ListIssueAncestors :many
+-- The issue followed by its parent, grandparent and so on up to the root.
+WITH RECURSIVE ancestors AS (
+    SELECT id, parent_id, 1 AS depth FROM issues WHERE id = $1
+    UNION ALL
+    SELECT i.id, i.parent_id, a.depth + 1
+    FROM issues i
+    JOIN ancestors a ON i.id = a.parent_id
+)
+SELECT id
+FROM ancestors
+ORDER BY depth;
+
+-- name: IssueSubtreeHeight :one
+-- Levels in the subtree rooted at the issue: 1 for an issue with no children.
+WITH RECURSIVE subtree AS (
+    SELECT id, 1 AS depth FROM issues WHERE id = $1
+    UNION ALL
+    SELECT c.id, s.depth + 1
+    FROM issues c
+    JOIN subtree s ON c.parent_id = s.id
+)
+SELECT COALESCE(MAX(depth), 0)::int AS height
+FROM subtree;
+
+-- name: SetIssueParent :one
+UPDATE issues
+SET parent_id = $2, updated_at = now()
+WHERE id = $1
+RETURNING id, user_id, title, description, status, created_at, updated_at, project_id, rank,
+          parent_id, auto_complete;
+
+-- name: SetIssueAutoComplete :one
+UPDATE issues
+SET auto_complete = $2, updated_at = now()
+WHERE id = $1
+RETURNING id, user_id, title, description, status, created_at, updated_at, project_id, rank,
+          parent_id, auto_complete;
+
+-- name: CountIssueChildren :one
+-- How many of an issue's direct children there are and how many are in a
+-- done-category status.
+SELECT COUNT(*) AS total,
+       COUNT(*) FILTER (WHERE ws.category = 'done') AS done
+FROM issues c
+LEFT JOIN workflow_statuses ws
+    ON ws.project_id IS NOT DISTINCT FROM c.project_id AND ws.name = c.status
+WHERE c.parent_id = $1;
@@ -0,0 +1,95 @@
+-- name: EnqueueJob :one
+-- Returns no row if a job of the same kind and unique key is already
+-- pending or running.
+INSERT INTO jobs (kind, args, max_attempts, run_at, unique_key)
+VALUES (@kind, @args, @max_attempts, @run_at, sqlc.narg('unique_key'))
+ON CONFLICT (kind, unique_key)
+    WHERE unique_key IS NOT NULL AND status IN ('pending', 'running')
+    DO NOTHING
+RETURNING id, kind, args, status, attempts, max_attempts, run_at, unique_key, last_error, locked_until, created_at, updated_at, finished_at;
+
+-- name: GetQueuedJob :one
+-- Finds the pending or running job holding a unique key.
+SELECT id, kind, args, status, attempts, max_attempts, run_at, unique_key, last_error, locked_until, created_at, updated_at, finished_at
+FROM jobs
+WHERE kind = @kind AND unique_key = @unique_key AND status IN ('pending', 'running');
+
+-- name: ListJobs :many
+-- Empty status and kind, and a zero before_id, match every job.
+SELECT id, kind, args, status, attempts, max_attempts, run_at, unique_key, last_error, locked_until, created_at, updated_at, finished_at
+FROM jobs
+WHERE (@status::text = '' OR status = @status::text)
+  AND (@kind::text = '' OR kind = @kind::text)
+  AND (@before_id::bigint = 0 OR id < @before_id::bigint)
+ORDER BY id DESC
+LIMIT @max_jobs;
+
+-- name: GetJob :one
+SELECT id, kind, args, status, attempts, max_attempts, run_at, unique_key, last_error, locked_until, created_at, updated_at, finished_at
+FROM jobs
+WHERE id = $1;
+
+-- name: RetryJob :one
+-- Runs a dead or pending job now. A dead job gets a fresh set of attempts.
+UPDATE jobs
+SET status      = 'pending',
+    attempts    = CASE WHEN status = 'dead' THEN 0 ELSE attempts END,
+    run_at      = now(),
+    finished_at = NULL,
+    updated_at  = now()
+WHERE id = $1 AND status IN ('pending', 'dead')
+RETURNING id, kind, args, status, attempts, max_attempts, run_at, unique_key, last_error, locked_until, created_at, updated_at, finished_at;
+
+-- name: ClaimJob :one
+-- Locks the next due job of one of the given kinds until lock_secs from
+-- now. A running job whose lock has lapsed belonged to a worker that died
+-- and is claimed again. SKIP LOCKED lets workers claim side by side
+-- without waiting on each other.
+UPDATE jobs
+SET status       = 'running',
+    attempts     = attempts + 1,
+    locked_until = now() + make_interval(secs => @lock_secs::float8),
+    updated_at   = now()
+WHERE id = (
+    SELECT id FROM jobs
+    WHERE kind = ANY (@kinds::text[])
+      AND ((status = 'pending' AND run_at <= now())
+        OR (status = 'running' AND locked_until < now()))
+    ORDER BY run_at, id
+    LIMIT 1
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING id, kind, args, status, attempts, max_attempts, run_at, unique_key, last_error, locked_until, created_at, updated_at, finished_at;
+
+-- name: CompleteJob :exec
+-- The attempt count in this and the other finishing queries makes them a
+-- no-op if the job's lock lapsed and another worker has since claimed it.
+UPDATE jobs
+SET status = 'succeeded', last_error = NULL, locked_until = NULL,
+    finished_at = now(), updated_at = now()
+WHERE id = @id AND attempts = @attempts AND status = 'running';
+
+-- name: ReleaseJob :exec
+-- Puts back a job that was interrupted rather than failed, so the attempt
+-- doesn't count.
+UPDATE jobs
+SET status = 'pending', attempts = attempts - 1, run_at = now(),
+    locked_until = NULL, updated_at = now()
+WHERE id = @id AND attempts = @attempts AND status = 'running';
+
+-- name: KillJob :exec
+UPDATE jobs
+SET status = 'dead', last_error = @last_error, locked_until = NULL,
+    finished_at = now(), updated_at = now()
+WHERE id = @id AND attempts = @attempts AND status = 'running';
+
+-- name: RescheduleJob :exec
+UPDATE jobs
+SET status = 'pending', last_error = @last_error, run_at = @run_at,
+    locked_until = NULL, updated_at = now()
+WHERE id = @id AND attempts = @attempts AND status = 'running';
+
+-- name: PruneJobs :execrows
+-- Deletes succeeded jobs that finished more than retention_secs ago.
+DELETE FROM jobs
+WHERE status = 'succeeded' AND finished_at < now() - make_interval(secs => @retention_secs::float8);
@@ -0,0 +1,65 @@
+-- name: CreateIssueLinkPair :execrows
+-- Inserts a link and its inverse. Zero rows means the link already exists.
+INSERT INTO issue_links (source_id, target_id, link_type)
+VALUES (@source_id, @target_id, @link_type),
+       (@target_id, @source_id, @inverse_type)
+ON CONFLICT DO NOTHING;
+
+-- name: DeleteIssueLinkPair :execrows
+DELETE FROM issue_links
+WHERE (source_id = @source_id AND target_id = @target_id AND link_type = @link_type)
+   OR (source_id = @target_id AND target_id = @source_id AND link_type = @inverse_type);
+
+-- name: GetIssueLink :one
+SELECT l.id, l.link_type, l.created_at,
+       i.id AS issue_id, i.title AS issue_title, i.status AS issue_status,
+       COALESCE(ws.category = 'done', false)::boolean AS issue_done
+FROM issue_links l
+JOIN issues i ON i.id = l.target_id
+LEFT JOIN workflow_statuses ws
+    ON ws.project_id IS NOT DISTINCT FROM i.project_id AND ws.name = i.status
+WHERE l.source_id = @source_id AND l.target_id = @target_id AND l.link_type = @link_type;
+
+-- name: ListIssueLinks :many
+SELECT l.id, l.link_type, l.created_at,
+       i.id AS issue_id, i.title AS issue_title, i.status AS issue_status,
+       COALESCE(ws.category = 'done', false)::boolean AS issue_done
+FROM issue_links l
+JOIN issues i ON i.id = l.target_id
+LEFT JOIN workflow_statuses ws
+    ON ws.project_id IS NOT DISTINCT FROM i.project_id AND ws.name = i.status
+WHERE l.source_id = $1
+ORDER BY l.link_type, l.id;
+
+-- name: ListOpenBlockers :many
+-- Issues blocking $1 that aren't in a done-category status yet.
+SELECT l.id, l.link_type, l.created_at,
+       i.id AS issue_id, i.title AS issue_title, i.status AS issue_status,
+       false::boolean AS issue_done
+FROM issue_links l
+JOIN issues i ON i.id = l.target_id
+LEFT JOIN workflow_statuses ws
+    ON ws.project_id IS NOT DISTINCT FROM i.project_id AND ws.name = i.status
+WHERE l.source_id = $1
+  AND l.link_type = 'blocked_by'
+  AND ws.category IS DISTINCT FROM 'done'
+ORDER BY i.id;
+
+-- name: LockIssueBlocks :exec
+-- Serializes new "blocks" links so two concurrent ones can't close a cycle
+-- between them. Held until the transaction ends.
+SELECT pg_advisory_xact_lock(hashtext('issue_links.blocks'));
+
+-- name: BlocksPathExists :one
+-- Whether @from_id blocks @to_id directly or through a chain of blocks links.
+WITH RECURSIVE blocked AS (
+    SELECT target_id AS id
+    FROM issue_links
+    WHERE source_id = @from_id AND link_type = 'blocks'
+    UNION
+    SELECT l.target_id
+    FROM issue_links l
+    JOIN blocked b ON l.source_id = b.id
+    WHERE l.link_type = 'blocks'
+)
+SELECT EXISTS (SELECT 1 FROM blocked WHERE id = @to_id) AS found;
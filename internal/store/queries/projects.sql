@@ -0,0 +1,14 @@
+-- name: CreateProject :one
+INSERT INTO projects (name, owner_id)
+VALUES ($1, $2)
+RETURNING id, name, owner_id, created_at, updated_at;
+
+-- name: GetProjectByID :one
+SELECT id, name, owner_id, created_at, updated_at
+FROM projects
+WHERE id = $1;
+
+-- name: ListProjects :many
+SELECT id, name, owner_id, created_at, updated_at
+FROM projects
+ORDER BY name, id;
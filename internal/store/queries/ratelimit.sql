@@ -0,0 +1,25 @@
+-- name: TakeRateLimitToken :one
+-- Takes a token from the bucket, creating it full if it is new. The bucket
+-- holds what it had plus what has been added since, up to burst, at rate
+-- tokens per second. A bucket without a token is left as it was, and no
+-- row comes back. It is one statement, so concurrent requests can't both
+-- spend the last token.
+INSERT INTO rate_limit_buckets AS b (key, tokens, updated_at)
+VALUES (@key, @burst::float8 - 1, now())
+ON CONFLICT (key) DO UPDATE
+SET tokens = LEAST(@burst::float8, b.tokens + extract(epoch FROM now() - b.updated_at)::float8 * @rate::float8) - 1,
+    updated_at = now()
+WHERE LEAST(@burst::float8, b.tokens + extract(epoch FROM now() - b.updated_at)::float8 * @rate::float8) >= 1
+RETURNING tokens;
+
+-- name: GetRateLimitTokens :one
+-- Returns the tokens the bucket holds now, refilled as in
+-- TakeRateLimitToken.
+SELECT LEAST(@burst::float8, b.tokens + extract(epoch FROM now() - b.updated_at)::float8 * @rate::float8)::float8 AS tokens
+FROM rate_limit_buckets b
+WHERE key = @key;
+
+-- name: PruneRateLimitBuckets :execrows
+-- Deletes buckets untouched for idle_secs.
+DELETE FROM rate_limit_buckets
+WHERE updated_at < now() - make_interval(secs => @idle_secs::float8);
@@ -0,0 +1,22 @@
+-- name: DailyStatusCounts :many
+-- For every day in the range, how many issues on the board were in each
+-- status at the end of that day (UTC), replayed from issue_status_events.
+-- With a project_id the board is that project; without one it is the
+-- user's issues that aren't in a project.
+-- Issues that didn't exist yet on a day are not counted for it.
+WITH days AS (
+    SELECT generate_series(@range_start::date, @range_end::date, interval '1 day')::date AS day
+)
+SELECT d.day, s.to_status AS status, COUNT(*) AS count
+FROM days d
+JOIN LATERAL (
+    SELECT DISTINCT ON (e.issue_id) e.issue_id, e.to_status
+    FROM issue_status_events e
+    JOIN issues i ON i.id = e.issue_id
+    WHERE i.project_id IS NOT DISTINCT FROM sqlc.narg('project_id')::bigint
+      AND (sqlc.narg('project_id')::bigint IS NOT NULL OR i.user_id = @user_id)
+      AND e.changed_at < ((d.day + 1)::timestamp AT TIME ZONE 'UTC')
+    ORDER BY e.issue_id, e.changed_at DESC, e.id DESC
+) s ON true
+GROUP BY d.day, s.to_status
+ORDER BY d.day, s.to_status;
@@ -0,0 +1,39 @@
+-- name: TryTaskLock :one
+-- Takes a task's session-level advisory lock if no other replica holds
+-- it. It belongs to the connection, so unlock on the same one.
+SELECT pg_try_advisory_lock(@lock_key::bigint) AS locked;
+
+-- name: UnlockTask :exec
+SELECT pg_advisory_unlock(@lock_key::bigint);
+
+-- name: StartTaskRun :one
+-- Records a run for its scheduled slot. Returns no row if the slot has
+-- already been run, by this replica or another.
+INSERT INTO scheduled_task_runs (task, scheduled_for, host)
+VALUES (@task, @scheduled_for, @host)
+ON CONFLICT (task, scheduled_for) DO NOTHING
+RETURNING id;
+
+-- name: FinishTaskRun :exec
+UPDATE scheduled_task_runs
+SET status = @status, error = @error, finished_at = now()
+WHERE id = @id;
+
+-- name: PruneTaskRuns :exec
+-- Deletes a task's runs that started more than retention_secs ago.
+DELETE FROM scheduled_task_runs
+WHERE task = @task AND started_at < now() - make_interval(secs => @retention_secs::float8);
+
+-- name: LastTaskRuns :many
+-- Returns the most recent run of each of the given tasks that has one.
+SELECT DISTINCT ON (task) id, task, scheduled_for, host, status, error, started_at, finished_at
+FROM scheduled_task_runs
+WHERE task = ANY (@tasks::text[])
+ORDER BY task, id DESC;
+
+-- name: ListTaskRuns :many
+SELECT id, task, scheduled_for, host, status, error, started_at, finished_at
+FROM scheduled_task_runs
+WHERE task = @task
+ORDER BY id DESC
+LIMIT @max_runs;
@@ -4,11 +4,18 @@ VALUES ($1, $2, $3)
 RETURNING id, email, name, password_hash, permissions, created_at, updated_at;
 
 -- name: GetUserByEmail :one
-SELECT u.id, u.email, u.password_hash, u.name, u.permissions, u.created_at, u.updated_at
+SELECT u.id, u.email, u.password_hash, u.name, u.permissions, u.suspended_at, u.password_reset_required,
+       u.created_at, u.updated_at
 FROM users u
 WHERE u.email = $1;
 
 -- name: GetUserByID :one
-SELECT u.id, u.email, u.password_hash, u.name, u.permissions, u.created_at, u.updated_at
+SELECT u.id, u.email, u.password_hash, u.name, u.permissions, u.suspended_at, u.password_reset_required,
+       u.created_at, u.updated_at
 FROM users u
 WHERE u.id = $1;
+
+-- name: UpdateUserPassword :execrows
+UPDATE users
+SET password_hash = $2, password_reset_required = false, updated_at = now()
+WHERE id = $1;
@@ -0,0 +1,114 @@
+-- name: CreateWebhook :one
+INSERT INTO webhooks (user_id, url, secret, event_types)
+VALUES (@user_id, @url, @secret, @event_types)
+RETURNING id, user_id, url, secret, event_types, active, consecutive_failures, disabled_at, created_at, updated_at;
+
+-- name: ListWebhooks :many
+SELECT id, user_id, url, secret, event_types, active, consecutive_failures, disabled_at, created_at, updated_at
+FROM webhooks
+ORDER BY id;
+
+-- name: GetWebhook :one
+SELECT id, user_id, url, secret, event_types, active, consecutive_failures, disabled_at, created_at, updated_at
+FROM webhooks
+WHERE id = $1;
+
+-- name: UpdateWebhook :one
+-- Changes whichever fields are non-NULL. Turning a webhook back on clears
+-- its failure count.
+UPDATE webhooks
+SET url                  = COALESCE(sqlc.narg('url'), url),
+    secret               = COALESCE(sqlc.narg('secret'), secret),
+    event_types          = COALESCE(sqlc.narg('event_types'), event_types),
+    active               = COALESCE(sqlc.narg('active'), active),
+    consecutive_failures = CASE WHEN sqlc.narg('active') THEN 0 ELSE consecutive_failures END,
+    disabled_at          = CASE WHEN sqlc.narg('active') THEN NULL ELSE disabled_at END,
+    updated_at           = now()
+WHERE id = @id
+RETURNING id, user_id, url, secret, event_types, active, consecutive_failures, disabled_at, created_at, updated_at;
+
+-- name: DeleteWebhook :execrows
+DELETE FROM webhooks
+WHERE id = $1;
+
+-- name: EnqueueWebhookDeliveries :many
+-- Queues an event for every active webhook subscribed to its type.
+INSERT INTO webhook_deliveries (webhook_id, event_id, event_type, payload, traceparent)
+SELECT id, @event_id::bigint, @event_type::text, @payload::jsonb, sqlc.narg('traceparent')::text
+FROM webhooks
+WHERE active AND @event_type::text = ANY (event_types)
+RETURNING id;
+
+-- name: CreateWebhookPing :one
+-- Queues a ping for one webhook, whatever its event types.
+INSERT INTO webhook_deliveries (webhook_id, event_id, event_type, payload, traceparent)
+VALUES (@webhook_id, nextval('event_id_seq'), 'ping', @payload, sqlc.narg('traceparent'))
+RETURNING id, webhook_id, event_id, event_type, payload, status, attempts, next_attempt_at, response_status, response_body, error, created_at, completed_at, traceparent;
+
+-- name: RedeliverWebhookDelivery :one
+-- Queues a fresh copy of a past delivery, traced as part of the request
+-- that asked for it.
+INSERT INTO webhook_deliveries (webhook_id, event_id, event_type, payload, traceparent)
+SELECT webhook_id, event_id, event_type, payload, sqlc.narg('traceparent')::text
+FROM webhook_deliveries
+WHERE webhook_deliveries.id = @id AND webhook_deliveries.webhook_id = @webhook_id
+RETURNING id, webhook_id, event_id, event_type, payload, status, attempts, next_attempt_at, response_status, response_body, error, created_at, completed_at, traceparent;
+
+-- name: ListWebhookDeliveries :many
+SELECT id, webhook_id, event_id, event_type, payload, status, attempts, next_attempt_at, response_status, response_body, error, created_at, completed_at, traceparent
+FROM webhook_deliveries
+WHERE webhook_id = @webhook_id
+ORDER BY id DESC
+LIMIT @max_deliveries;
+
+-- name: GetWebhookDeliveryToSend :one
+-- Returns an unfinished delivery with its webhook's URL and secret. A
+-- failed delivery counts as unfinished, so retrying its dead job sends it
+-- again.
+SELECT d.id, d.webhook_id, d.event_id, d.event_type, d.payload, d.attempts, d.traceparent, w.url, w.secret, w.active
+FROM webhook_deliveries d
+JOIN webhooks w ON w.id = d.webhook_id
+WHERE d.id = $1 AND d.status <> 'succeeded';
+
+-- name: ListPendingWebhookDeliveryIDs :many
+-- Lists the deliveries a disabled webhook held back, to queue them again
+-- when it is turned back on.
+SELECT id
+FROM webhook_deliveries
+WHERE webhook_id = $1 AND status = 'pending'
+ORDER BY id;
+
+-- name: RecordWebhookAttempt :exec
+UPDATE webhook_deliveries
+SET status          = @status,
+    attempts        = attempts + 1,
+    next_attempt_at = @next_attempt_at,
+    response_status = @response_status,
+    response_body   = @response_body,
+    error           = @error,
+    completed_at    = CASE WHEN @status = 'pending' THEN NULL ELSE now() END
+WHERE id = @id;
+
+-- name: ResetWebhookFailures :exec
+UPDATE webhooks
+SET consecutive_failures = 0
+WHERE id = $1;
+
+-- name: CountWebhookFailure :one
+-- Adds a failed attempt and disables the webhook once it reaches
+-- disable_after in a row. Returns whether the webhook is still active.
+UPDATE webhooks
+SET consecutive_failures = consecutive_failures + 1,
+    active               = active AND consecutive_failures + 1 < @disable_after,
+    disabled_at          = CASE
+                               WHEN active AND consecutive_failures + 1 >= @disable_after THEN now()
+                               ELSE disabled_at
+                           END,
+    updated_at           = now()
+WHERE id = @id
+RETURNING active;
+
+-- name: PruneWebhookDeliveries :execrows
+-- Deletes finished deliveries created before the cutoff.
+DELETE FROM webhook_deliveries
+WHERE status <> 'pending' AND created_at < @cutoff;
@@ -0,0 +1,47 @@
+-- name: ListWipLimits :many
+SELECT id, project_id, user_id, status, max_issues, created_at, updated_at
+FROM wip_limits
+ORDER BY status, project_id NULLS FIRST, user_id NULLS FIRST, id;
+
+-- name: ListBoardWipLimits :many
+-- Limits that can affect @user_id's issues on a board: the project's, or
+-- the user's personal board when project_id is NULL.
+SELECT id, project_id, user_id, status, max_issues, created_at, updated_at
+FROM wip_limits
+WHERE (project_id IS NULL OR project_id = sqlc.narg('project_id')::bigint)
+  AND (user_id IS NULL OR user_id = @user_id::uuid)
+ORDER BY status, id;
+
+-- name: LockApplicableWipLimits :many
+-- The limits an issue entering @status would count against. Locking them
+-- makes concurrent moves into a capped status take turns, so two of them
+-- can't both see room for one more.
+SELECT id, project_id, user_id, status, max_issues, created_at, updated_at
+FROM wip_limits
+WHERE status = @status
+  AND (project_id IS NULL OR project_id = sqlc.narg('project_id')::bigint)
+  AND (user_id IS NULL OR user_id = @user_id::uuid)
+ORDER BY id
+FOR UPDATE;
+
+-- name: CountIssuesForWipLimit :one
+-- NULL filters match everything; personal_only restricts the count to
+-- issues outside any project.
+SELECT COUNT(*)
+FROM issues
+WHERE status = @status
+  AND id <> @exclude_id
+  AND (sqlc.narg('project_id')::bigint IS NULL OR project_id = sqlc.narg('project_id')::bigint)
+  AND (sqlc.narg('user_id')::uuid IS NULL OR user_id = sqlc.narg('user_id')::uuid)
+  AND (NOT @personal_only::boolean OR project_id IS NULL);
+
+-- name: UpsertWipLimit :one
+INSERT INTO wip_limits (project_id, user_id, status, max_issues)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (project_id, user_id, status)
+DO UPDATE SET max_issues = EXCLUDED.max_issues, updated_at = now()
+RETURNING id, project_id, user_id, status, max_issues, created_at, updated_at;
+
+-- name: DeleteWipLimit :execrows
+DELETE FROM wip_limits
+WHERE id = $1;
@@ -0,0 +1,88 @@
+-- name: ListWorkflowStatuses :many
+-- A NULL project_id selects the default workflow.
+SELECT id, project_id, name, category, position, created_at
+FROM workflow_statuses
+WHERE project_id IS NOT DISTINCT FROM sqlc.narg('project_id')::bigint
+ORDER BY position, id;
+
+-- name: ListWorkflowTransitions :many
+SELECT f.name AS from_status, t.name AS to_status
+FROM workflow_transitions wt
+JOIN workflow_statuses f ON f.id = wt.from_status_id
+JOIN workflow_statuses t ON t.id = wt.to_status_id
+WHERE f.project_id IS NOT DISTINCT FROM sqlc.narg('project_id')::bigint
+ORDER BY f.position, f.id, t.position, t.id;
+
+-- name: GetWorkflowStatusByName :one
+-- FOR SHARE keeps the status from being deleted while an issue moves into it.
+SELECT id, project_id, name, category, position, created_at
+FROM workflow_statuses
+WHERE project_id IS NOT DISTINCT FROM sqlc.narg('project_id')::bigint
+  AND name = @name
+FOR SHARE;
+
+-- name: FirstDoneWorkflowStatus :one
+-- The leftmost done-category status, where auto-completed parents go.
+SELECT id, project_id, name, category, position, created_at
+FROM workflow_statuses
+WHERE project_id IS NOT DISTINCT FROM sqlc.narg('project_id')::bigint
+  AND category = 'done'
+ORDER BY position, id
+LIMIT 1;
+
+-- name: WorkflowTransitionExists :one
+SELECT EXISTS (
+    SELECT 1
+    FROM workflow_transitions wt
+    JOIN workflow_statuses f ON f.id = wt.from_status_id
+    JOIN workflow_statuses t ON t.id = wt.to_status_id
+    WHERE f.project_id IS NOT DISTINCT FROM sqlc.narg('project_id')::bigint
+      AND f.name = @from_status
+      AND t.name = @to_status
+) AS allowed;
+
+-- name: CreateWorkflowStatus :one
+INSERT INTO workflow_statuses (project_id, name, category, position)
+VALUES ($1, $2, $3, $4)
+RETURNING id, project_id, name, category, position, created_at;
+
+-- name: UpdateWorkflowStatus :one
+UPDATE workflow_statuses
+SET category = @category, position = @position
+WHERE id = @id AND project_id = @project_id
+RETURNING id, project_id, name, category, position, created_at;
+
+-- name: CountIssuesInStatus :one
+SELECT COUNT(*)
+FROM issues
+WHERE project_id = $1 AND status = $2;
+
+-- name: DeleteWorkflowStatus :one
+DELETE FROM workflow_statuses
+WHERE id = $1 AND project_id = $2
+RETURNING id, project_id, name, category, position, created_at;
+
+-- name: DeleteWorkflowTransitions :exec
+DELETE FROM workflow_transitions
+WHERE from_status_id IN (SELECT id FROM workflow_statuses WHERE project_id = $1);
+
+-- name: CreateWorkflowTransition :exec
+INSERT INTO workflow_transitions (from_status_id, to_status_id)
+VALUES ($1, $2);
+
+-- name: CopyDefaultWorkflowStatuses :exec
+-- New projects start from a copy of the default workflow.
+INSERT INTO workflow_statuses (project_id, name, category, position)
+SELECT @project_id::bigint, name, category, position
+FROM workflow_statuses
+WHERE project_id IS NULL;
+
+-- name: CopyDefaultWorkflowTransitions :exec
+-- Run after CopyDefaultWorkflowStatuses; statuses are matched by name.
+INSERT INTO workflow_transitions (from_status_id, to_status_id)
+SELECT pf.id, pt.id
+FROM workflow_transitions wt
+JOIN workflow_statuses df ON df.id = wt.from_status_id AND df.project_id IS NULL
+JOIN workflow_statuses dt ON dt.id = wt.to_status_id
+JOIN workflow_statuses pf ON pf.project_id = @project_id::bigint AND pf.name = df.name
+JOIN workflow_statuses pt ON pt.project_id = @project_id::bigint AND pt.name = dt.name;
@@ -0,0 +1,160 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jesusthecreator017/fswithgo/internal/rank"
+	"github.com/jesusthecreator017/fswithgo/internal/store/dbsqlc"
+)
+
+var ErrInvalidNeighbor = errors.New("neighbor must be another issue in the target column")
+
+// boardColumn is one status column on a board: a project, or a user's
+// issues outside any project. exclude is the issue being placed, which
+// never counts as its own neighbor.
+type boardColumn struct {
+	projectID pgtype.Int8
+	userID    uuid.UUID
+	status    string
+	exclude   int64
+}
+
+// placement says where in a column an issue goes. With neither neighbor
+// set it goes to the top, or the bottom if bottom is true.
+type placement struct {
+	afterID  *int64
+	beforeID *int64
+	bottom   bool
+}
+
+// placeInColumn returns a rank for a new position in col. If the keys
+// around that position are too dense (or tied) the column is respread
+// once and the position worked out again.
+func placeInColumn(ctx context.Context, q *dbsqlc.Queries, col boardColumn, at placement) (string, error) {
+	for attempt := 0; ; attempt++ {
+		lo, hi, err := columnBounds(ctx, q, col, at)
+		if err != nil {
+			return "", err
+		}
+
+		key, err := rank.Between(lo, hi)
+		if err == nil && len(key) <= rank.MaxLen {
+			return key, nil
+		}
+		if attempt > 0 {
+			if err != nil {
+				// Still out of order after a respread: the caller named
+				// the neighbors the wrong way round.
+				return "", ErrInvalidNeighbor
+			}
+			return key, nil
+		}
+
+		if err := rebalanceColumn(ctx, q, col); err != nil {
+			return "", err
+		}
+	}
+}
+
+// columnBounds finds the ranks either side of the target position; ""
+// means the start or end of the column.
+func columnBounds(ctx context.Context, q *dbsqlc.Queries, col boardColumn, at placement) (lo, hi string, err error) {
+	switch {
+	case at.afterID != nil:
+		if lo, err = neighborRank(ctx, q, col, *at.afterID); err != nil {
+			return "", "", err
+		}
+		if at.beforeID != nil {
+			hi, err = neighborRank(ctx, q, col, *at.beforeID)
+		} else {
+			hi, err = nextRank(ctx, q, col, lo)
+		}
+	case at.beforeID != nil:
+		if hi, err = neighborRank(ctx, q, col, *at.beforeID); err != nil {
+			return "", "", err
+		}
+		lo, err = prevRank(ctx, q, col, pgtype.Text{String: hi, Valid: true})
+	case at.bottom:
+		lo, err = prevRank(ctx, q, col, pgtype.Text{})
+	default:
+		hi, err = nextRank(ctx, q, col, "")
+	}
+	return lo, hi, err
+}
+
+func neighborRank(ctx context.Context, q *dbsqlc.Queries, col boardColumn, id int64) (string, error) {
+	row, err := q.GetIssuePosition(ctx, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", ErrInvalidNeighbor
+		}
+		return "", fmt.Errorf("getting neighbor: %w", err)
+	}
+
+	sameBoard := row.ProjectID == col.projectID && (col.projectID.Valid || row.UserID == col.userID)
+	if !sameBoard || row.Status != col.status || row.ID == col.exclude {
+		return "", ErrInvalidNeighbor
+	}
+	return row.Rank, nil
+}
+
+func prevRank(ctx context.Context, q *dbsqlc.Queries, col boardColumn, below pgtype.Text) (string, error) {
+	key, err := q.PrevRankInColumn(ctx, dbsqlc.PrevRankInColumnParams{
+		ProjectID: col.projectID,
+		UserID:    col.userID,
+		Status:    col.status,
+		ExcludeID: col.exclude,
+		Below:     below,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("finding previous rank: %w", err)
+	}
+	return key, nil
+}
+
+func nextRank(ctx context.Context, q *dbsqlc.Queries, col boardColumn, above string) (string, error) {
+	key, err := q.NextRankInColumn(ctx, dbsqlc.NextRankInColumnParams{
+		ProjectID: col.projectID,
+		UserID:    col.userID,
+		Status:    col.status,
+		ExcludeID: col.exclude,
+		Above:     above,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("finding next rank: %w", err)
+	}
+	return key, nil
+}
+
+// rebalanceColumn rewrites every rank in col with evenly spaced short keys,
+// keeping the current order. The rows stay locked until the caller's
+// transaction ends.
+func rebalanceColumn(ctx context.Context, q *dbsqlc.Queries, col boardColumn) error {
+	ids, err := q.ListColumnForRebalance(ctx, dbsqlc.ListColumnForRebalanceParams{
+		ProjectID: col.projectID,
+		UserID:    col.userID,
+		Status:    col.status,
+		ExcludeID: col.exclude,
+	})
+	if err != nil {
+		return fmt.Errorf("locking column: %w", err)
+	}
+
+	for i, key := range rank.Spread(len(ids)) {
+		if err := q.SetIssueRank(ctx, dbsqlc.SetIssueRankParams{ID: ids[i], Rank: key}); err != nil {
+			return fmt.Errorf("rebalancing column: %w", err)
+		}
+	}
+	return nil
+}
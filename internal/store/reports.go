@@ -0,0 +1,56 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jesusthecreator017/fswithgo/internal/store/dbsqlc"
+)
+
+// ReportFilter selects the board to report on and the inclusive day range.
+// With ProjectID set the board is that project; otherwise it is UserID's
+// issues outside any project. From and To are truncated to UTC dates.
+type ReportFilter struct {
+	UserID    uuid.UUID
+	ProjectID *int64
+	From      time.Time
+	To        time.Time
+}
+
+// DailyStatusCount is how many issues sat in Status at the end of Day.
+type DailyStatusCount struct {
+	Day    time.Time
+	Status StatusType
+	Count  int64
+}
+
+type ReportStore struct {
+	queries *dbsqlc.Queries
+}
+
+// DailyStatusCounts replays recorded status transitions to rebuild each day's
+// board. Statuses with no issues on a day are omitted.
+func (r *ReportStore) DailyStatusCounts(ctx context.Context, filter ReportFilter) ([]DailyStatusCount, error) {
+	rows, err := r.queries.DailyStatusCounts(ctx, dbsqlc.DailyStatusCountsParams{
+		RangeStart: pgtype.Date{Time: filter.From, Valid: true},
+		RangeEnd:   pgtype.Date{Time: filter.To, Valid: true},
+		ProjectID:  nullInt8(filter.ProjectID),
+		UserID:     filter.UserID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("counting daily statuses: %w", err)
+	}
+
+	counts := make([]DailyStatusCount, len(rows))
+	for i, row := range rows {
+		counts[i] = DailyStatusCount{
+			Day:    row.Day.Time,
+			Status: StatusType(row.Status),
+			Count:  row.Count,
+		}
+	}
+	return counts, nil
+}
@@ -0,0 +1,8 @@
+-- schema_migrations is created and kept by golang-migrate, not by a
+-- migration of ours. It is declared here only so sqlc can check queries
+-- that read it; nothing runs this file.
+
+CREATE TABLE IF NOT EXISTS schema_migrations (
+    version BIGINT NOT NULL PRIMARY KEY,
+    dirty   BOOLEAN NOT NULL
+);
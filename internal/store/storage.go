@@ -2,8 +2,11 @@ package store
 
 import (
 	"context"
+	"fmt"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/jesusthecreator017/fswithgo/internal/store/dbsqlc"
 )
@@ -18,20 +21,88 @@ import (
 // interfaces, not on the database directly.
 type Storage struct {
 	Issues interface {
-		Create(context.Context, *Issue) error
+		Create(context.Context, *Issue, bool) error
 		GetByID(context.Context, int64) (*Issue, error)
 		List(context.Context) ([]*Issue, error)
 		ListByUserID(context.Context, uuid.UUID) ([]*Issue, error)
-		UpdateStatus(context.Context, int64, StatusType) (*Issue, error)
+		ListByProjectID(context.Context, int64) ([]*Issue, error)
+		UpdateStatus(context.Context, int64, StatusType, Overrides) (*Issue, error)
+		Move(context.Context, int64, IssueMove) (*Issue, error)
+		ListChildren(context.Context, int64) ([]*Issue, error)
+		SetParent(context.Context, int64, *int64) (*Issue, error)
+		SetAutoComplete(context.Context, int64, bool) (*Issue, error)
+		Delete(context.Context, int64, ChildPolicy) error
+	}
+	Attachments interface {
+		Create(context.Context, *Attachment) error
+		ListByIssueID(context.Context, int64) ([]*Attachment, error)
+		Get(context.Context, int64, int64) (*Attachment, error)
+		Detach(context.Context, int64, int64) (*Attachment, error)
+		ListOrphaned(context.Context, int) ([]*Attachment, error)
+		Delete(context.Context, int64) error
+	}
+	Links interface {
+		List(context.Context, int64) ([]*IssueLink, error)
+		Create(context.Context, int64, LinkType, int64) (*IssueLink, error)
+		Delete(context.Context, int64, LinkType, int64) error
+	}
+	Projects interface {
+		Create(context.Context, *Project) error
+		GetByID(context.Context, int64) (*Project, error)
+		List(context.Context) ([]*Project, error)
+	}
+	Workflows interface {
+		Get(context.Context, *int64) (*Workflow, error)
+		CreateStatus(context.Context, *WorkflowStatus) error
+		UpdateStatus(context.Context, *WorkflowStatus) error
+		DeleteStatus(context.Context, int64, int64) error
+		SetTransitions(context.Context, int64, []WorkflowTransition) error
+	}
+	WIPLimits interface {
+		List(context.Context) ([]*WIPLimit, error)
+		Usage(context.Context, *int64, uuid.UUID) ([]*WIPUsage, error)
+		Set(context.Context, *WIPLimit) error
 		Delete(context.Context, int64) error
 	}
 	Users interface {
 		Create(context.Context, *User) error
 		GetByEmail(context.Context, string) (*User, error)
 		GetByID(context.Context, uuid.UUID) (*User, error)
+		UpdatePassword(context.Context, uuid.UUID, string) error
 	}
 	Admin interface {
 		GetStats(context.Context) (*AdminStats, error)
+		GetAnalytics(context.Context, AnalyticsRange) (*Analytics, error)
+		ListUsers(context.Context, UserFilter) ([]*User, PageMetadata, error)
+		GetUserDetail(context.Context, uuid.UUID) (*UserDetail, error)
+		UpdateUserPermissions(context.Context, uuid.UUID, int32) (*User, error)
+		SuspendUser(context.Context, uuid.UUID) (*User, error)
+		ReactivateUser(context.Context, uuid.UUID) (*User, error)
+		RequirePasswordReset(context.Context, uuid.UUID) (*User, error)
+		DeleteUser(context.Context, uuid.UUID, DeleteUserOptions) error
+	}
+	Reports interface {
+		DailyStatusCounts(context.Context, ReportFilter) ([]DailyStatusCount, error)
+	}
+	Webhooks interface {
+		Create(context.Context, *Webhook) error
+		List(context.Context) ([]*Webhook, error)
+		Get(context.Context, int64) (*Webhook, error)
+		Update(context.Context, int64, WebhookUpdate, QueueDelivery) (*Webhook, error)
+		Delete(context.Context, int64) error
+		Enqueue(context.Context, int64, string, []byte, string, QueueDelivery) (int64, error)
+		Ping(context.Context, int64, []byte, string, QueueDelivery) (*WebhookDelivery, error)
+		ListDeliveries(context.Context, int64, int) ([]*WebhookDelivery, error)
+		Redeliver(context.Context, int64, int64, string, QueueDelivery) (*WebhookDelivery, error)
+		GetDeliveryToSend(context.Context, int64) (*OutgoingDelivery, error)
+		RecordAttempt(context.Context, WebhookAttempt, int) (bool, error)
+		PruneDeliveries(context.Context, time.Time) (int64, error)
+	}
+	Audit interface {
+		Record(context.Context, *AuditEntry) error
+		List(context.Context, AuditFilter) ([]*AuditEntry, error)
+		ListBefore(context.Context, time.Time, int64, int) ([]*AuditEntry, error)
+		DeleteBefore(context.Context, time.Time, int64) (int64, error)
 	}
 }
 
@@ -45,8 +116,41 @@ func NewStorage(pool *pgxpool.Pool) Storage {
 	queries := dbsqlc.New(pool)
 
 	return Storage{
-		Issues: &IssueStore{queries: queries},
-		Users:  &UserStore{queries: queries},
-		Admin:  &AdminStore{queries: queries},
+		Issues:      &IssueStore{db: pool, queries: queries},
+		Links:       &LinkStore{db: pool, queries: queries},
+		Attachments: &AttachmentStore{queries: queries},
+		Projects:    &ProjectStore{db: pool, queries: queries},
+		Workflows:   &WorkflowStore{db: pool, queries: queries},
+		WIPLimits:   &WIPStore{queries: queries},
+		Users:       &UserStore{queries: queries},
+		Admin:       &AdminStore{db: pool, queries: queries},
+		Audit:       &AuditStore{queries: queries},
+		Reports:     &ReportStore{queries: queries},
+		Webhooks:    &WebhookStore{db: pool, queries: queries},
 	}
 }
+
+// withTx runs fn inside a transaction using a Queries bound to it.
+// The transaction commits if fn returns nil and rolls back otherwise.
+func withTx(ctx context.Context, pool *pgxpool.Pool, fn func(*dbsqlc.Queries) error) error {
+	return inTx(ctx, pool, func(tx pgx.Tx) error {
+		return fn(dbsqlc.New(tx))
+	})
+}
+
+// inTx is withTx for work that needs the transaction itself, such as
+// queueing a job alongside the rows it is about.
+func inTx(ctx context.Context, pool *pgxpool.Pool, fn func(pgx.Tx) error) error {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	// Rollback after a successful Commit is a no-op.
+	defer tx.Rollback(ctx)
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
@@ -8,17 +8,25 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jesusthecreator017/fswithgo/internal/store/dbsqlc"
 )
 
 type User struct {
-	ID           uuid.UUID `json:"id"`
-	Email        string    `json:"email"`
-	PasswordHash string    `json:"-"`
-	Name         string    `json:"name"`
-	Permissions  int32     `json:"permissions"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
+	ID                    uuid.UUID  `json:"id"`
+	Email                 string     `json:"email"`
+	PasswordHash          string     `json:"-"`
+	Name                  string     `json:"name"`
+	Permissions           int32      `json:"permissions"`
+	SuspendedAt           *time.Time `json:"suspended_at"`
+	PasswordResetRequired bool       `json:"password_reset_required"`
+	CreatedAt             time.Time  `json:"created_at"`
+	UpdatedAt             time.Time  `json:"updated_at"`
+}
+
+// IsSuspended reports whether an admin has suspended the account.
+func (u *User) IsSuspended() bool {
+	return u.SuspendedAt != nil
 }
 
 type UserStore struct {
@@ -53,13 +61,15 @@ func (u *UserStore) GetByEmail(ctx context.Context, email string) (*User, error)
 	}
 
 	return &User{
-		ID:           row.ID,
-		Email:        row.Email,
-		PasswordHash: row.PasswordHash,
-		Name:         row.Name,
-		Permissions:  row.Permissions,
-		CreatedAt:    row.CreatedAt.Time,
-		UpdatedAt:    row.UpdatedAt.Time,
+		ID:                    row.ID,
+		Email:                 row.Email,
+		PasswordHash:          row.PasswordHash,
+		Name:                  row.Name,
+		Permissions:           row.Permissions,
+		SuspendedAt:           timePtr(row.SuspendedAt),
+		PasswordResetRequired: row.PasswordResetRequired,
+		CreatedAt:             row.CreatedAt.Time,
+		UpdatedAt:             row.UpdatedAt.Time,
 	}, nil
 }
 
@@ -73,12 +83,40 @@ func (u *UserStore) GetByID(ctx context.Context, id uuid.UUID) (*User, error) {
 	}
 
 	return &User{
-		ID:           row.ID,
-		Email:        row.Email,
-		PasswordHash: row.PasswordHash,
-		Name:         row.Name,
-		Permissions:  row.Permissions,
-		CreatedAt:    row.CreatedAt.Time,
-		UpdatedAt:    row.UpdatedAt.Time,
+		ID:                    row.ID,
+		Email:                 row.Email,
+		PasswordHash:          row.PasswordHash,
+		Name:                  row.Name,
+		Permissions:           row.Permissions,
+		SuspendedAt:           timePtr(row.SuspendedAt),
+		PasswordResetRequired: row.PasswordResetRequired,
+		CreatedAt:             row.CreatedAt.Time,
+		UpdatedAt:             row.UpdatedAt.Time,
 	}, nil
 }
+
+// UpdatePassword stores a new password hash and clears any pending
+// admin-forced reset.
+func (u *UserStore) UpdatePassword(ctx context.Context, id uuid.UUID, passwordHash string) error {
+	n, err := u.queries.UpdateUserPassword(ctx, dbsqlc.UpdateUserPasswordParams{
+		ID:           id,
+		PasswordHash: passwordHash,
+	})
+	if err != nil {
+		return fmt.Errorf("updating password: %w", err)
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// timePtr converts a nullable timestamp into a *time.Time so that NULL
+// serializes as JSON null instead of the zero time.
+func timePtr(ts pgtype.Timestamptz) *time.Time {
+	if !ts.Valid {
+		return nil
+	}
+	t := ts.Time
+	return &t
+}
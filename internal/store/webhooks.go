@@ -0,0 +1,430 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jesusthecreator017/fswithgo/internal/store/dbsqlc"
+)
+
+// Webhook delivery statuses.
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliverySucceeded = "succeeded"
+	WebhookDeliveryFailed    = "failed"
+)
+
+// Webhook is a URL that is sent the events in EventTypes. Deliveries are
+// signed with Secret, which is only shown when it is set.
+type Webhook struct {
+	ID                  int64      `json:"id"`
+	UserID              *uuid.UUID `json:"user_id"`
+	URL                 string     `json:"url"`
+	Secret              string     `json:"secret,omitempty"`
+	EventTypes          []string   `json:"event_types"`
+	Active              bool       `json:"active"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	DisabledAt          *time.Time `json:"disabled_at"`
+	CreatedAt           time.Time  `json:"created_at"`
+	UpdatedAt           time.Time  `json:"updated_at"`
+}
+
+// WebhookUpdate holds the fields to change; nil fields are left alone.
+type WebhookUpdate struct {
+	URL        *string
+	Secret     *string
+	EventTypes []string
+	Active     *bool
+}
+
+// WebhookDelivery is one event queued for, or sent to, a webhook.
+// NextAttemptAt is only set while it is pending.
+type WebhookDelivery struct {
+	ID             int64           `json:"id"`
+	WebhookID      int64           `json:"webhook_id"`
+	EventID        int64           `json:"event_id"`
+	EventType      string          `json:"event_type"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	NextAttemptAt  *time.Time      `json:"next_attempt_at"`
+	ResponseStatus *int            `json:"response_status"`
+	ResponseBody   *string         `json:"response_body"`
+	Error          *string         `json:"error"`
+	CreatedAt      time.Time       `json:"created_at"`
+	CompletedAt    *time.Time      `json:"completed_at"`
+}
+
+// OutgoingDelivery is an unfinished delivery with what sending it needs.
+type OutgoingDelivery struct {
+	ID        int64
+	WebhookID int64
+	EventID   int64
+	EventType string
+	Payload   []byte
+	Attempts  int // made before this one
+	// TraceParent is the W3C traceparent of the request that queued the
+	// delivery, if it was traced.
+	TraceParent string
+	URL         string
+	Secret      string
+	// Active is false while the webhook is disabled; its deliveries wait
+	// until it is turned back on.
+	Active bool
+}
+
+// QueueDelivery arranges for a delivery to be sent. It runs in the
+// transaction that creates the delivery, so the two happen together or
+// not at all.
+type QueueDelivery func(ctx context.Context, tx pgx.Tx, deliveryID int64) error
+
+// WebhookAttempt is the outcome of sending a delivery. A failed
+// attempt with a zero RetryAt is the last one.
+type WebhookAttempt struct {
+	DeliveryID     int64
+	WebhookID      int64
+	Succeeded      bool
+	RetryAt        time.Time
+	ResponseStatus int // 0 if no response arrived
+	ResponseBody   string
+	Error          string
+}
+
+type WebhookStore struct {
+	db      *pgxpool.Pool
+	queries *dbsqlc.Queries
+}
+
+func (s *WebhookStore) Create(ctx context.Context, w *Webhook) error {
+	row, err := s.queries.CreateWebhook(ctx, dbsqlc.CreateWebhookParams{
+		UserID:     nullUUID(w.UserID),
+		Url:        w.URL,
+		Secret:     w.Secret,
+		EventTypes: w.EventTypes,
+	})
+	if err != nil {
+		return fmt.Errorf("creating webhook: %w", err)
+	}
+
+	*w = *webhookToDomain(row)
+	return nil
+}
+
+func (s *WebhookStore) List(ctx context.Context) ([]*Webhook, error) {
+	rows, err := s.queries.ListWebhooks(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("listing webhooks: %w", err)
+	}
+
+	webhooks := make([]*Webhook, len(rows))
+	for i, row := range rows {
+		webhooks[i] = webhookToDomain(row)
+	}
+	return webhooks, nil
+}
+
+func (s *WebhookStore) Get(ctx context.Context, id int64) (*Webhook, error) {
+	row, err := s.queries.GetWebhook(ctx, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("getting webhook: %w", err)
+	}
+
+	return webhookToDomain(row), nil
+}
+
+// Update changes a webhook. Turning it back on resets its failure count
+// and queues the deliveries it held back.
+func (s *WebhookStore) Update(ctx context.Context, id int64, u WebhookUpdate, queue QueueDelivery) (*Webhook, error) {
+	params := dbsqlc.UpdateWebhookParams{ID: id, EventTypes: u.EventTypes}
+	if u.URL != nil {
+		params.Url = pgtype.Text{String: *u.URL, Valid: true}
+	}
+	if u.Secret != nil {
+		params.Secret = pgtype.Text{String: *u.Secret, Valid: true}
+	}
+	if u.Active != nil {
+		params.Active = pgtype.Bool{Bool: *u.Active, Valid: true}
+	}
+
+	var hook *Webhook
+	err := inTx(ctx, s.db, func(tx pgx.Tx) error {
+		q := dbsqlc.New(tx)
+		row, err := q.UpdateWebhook(ctx, params)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return ErrNotFound
+			}
+			return fmt.Errorf("updating webhook: %w", err)
+		}
+		hook = webhookToDomain(row)
+
+		if u.Active == nil || !*u.Active {
+			return nil
+		}
+		ids, err := q.ListPendingWebhookDeliveryIDs(ctx, id)
+		if err != nil {
+			return fmt.Errorf("listing pending webhook deliveries: %w", err)
+		}
+		return queueDeliveries(ctx, tx, queue, ids)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return hook, nil
+}
+
+// Delete removes a webhook along with its delivery log.
+func (s *WebhookStore) Delete(ctx context.Context, id int64) error {
+	n, err := s.queries.DeleteWebhook(ctx, id)
+	if err != nil {
+		return fmt.Errorf("deleting webhook: %w", err)
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Enqueue queues an event for every active webhook subscribed to
+// eventType and returns how many deliveries it queued. traceParent, if not
+// empty, is the trace the deliveries continue.
+func (s *WebhookStore) Enqueue(ctx context.Context, eventID int64, eventType string, payload []byte, traceParent string, queue QueueDelivery) (int64, error) {
+	var n int64
+	err := inTx(ctx, s.db, func(tx pgx.Tx) error {
+		ids, err := dbsqlc.New(tx).EnqueueWebhookDeliveries(ctx, dbsqlc.EnqueueWebhookDeliveriesParams{
+			EventID:     eventID,
+			EventType:   eventType,
+			Payload:     payload,
+			Traceparent: nullText(traceParent),
+		})
+		if err != nil {
+			return fmt.Errorf("queueing webhook deliveries: %w", err)
+		}
+		n = int64(len(ids))
+		return queueDeliveries(ctx, tx, queue, ids)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// Ping queues a ping event for one webhook, in the trace traceParent.
+func (s *WebhookStore) Ping(ctx context.Context, webhookID int64, payload []byte, traceParent string, queue QueueDelivery) (*WebhookDelivery, error) {
+	var delivery *WebhookDelivery
+	err := inTx(ctx, s.db, func(tx pgx.Tx) error {
+		row, err := dbsqlc.New(tx).CreateWebhookPing(ctx, dbsqlc.CreateWebhookPingParams{
+			WebhookID:   webhookID,
+			Payload:     payload,
+			Traceparent: nullText(traceParent),
+		})
+		if err != nil {
+			if isForeignKeyViolation(err) {
+				return ErrNotFound
+			}
+			return fmt.Errorf("queueing webhook ping: %w", err)
+		}
+		delivery = webhookDeliveryToDomain(row)
+		return queueDeliveries(ctx, tx, queue, []int64{row.ID})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return delivery, nil
+}
+
+// ListDeliveries returns a webhook's most recent deliveries, newest first.
+func (s *WebhookStore) ListDeliveries(ctx context.Context, webhookID int64, limit int) ([]*WebhookDelivery, error) {
+	rows, err := s.queries.ListWebhookDeliveries(ctx, dbsqlc.ListWebhookDeliveriesParams{
+		WebhookID:     webhookID,
+		MaxDeliveries: int32(limit),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("listing webhook deliveries: %w", err)
+	}
+
+	deliveries := make([]*WebhookDelivery, len(rows))
+	for i, row := range rows {
+		deliveries[i] = webhookDeliveryToDomain(row)
+	}
+	return deliveries, nil
+}
+
+// Redeliver queues a new delivery of the same event as an earlier one, in
+// the trace traceParent.
+func (s *WebhookStore) Redeliver(ctx context.Context, webhookID, deliveryID int64, traceParent string, queue QueueDelivery) (*WebhookDelivery, error) {
+	var delivery *WebhookDelivery
+	err := inTx(ctx, s.db, func(tx pgx.Tx) error {
+		row, err := dbsqlc.New(tx).RedeliverWebhookDelivery(ctx, dbsqlc.RedeliverWebhookDeliveryParams{
+			Traceparent: nullText(traceParent),
+			ID:          deliveryID,
+			WebhookID:   webhookID,
+		})
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return ErrNotFound
+			}
+			return fmt.Errorf("redelivering webhook delivery: %w", err)
+		}
+		delivery = webhookDeliveryToDomain(row)
+		return queueDeliveries(ctx, tx, queue, []int64{row.ID})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return delivery, nil
+}
+
+// GetDeliveryToSend returns an unfinished delivery, or ErrNotFound if it
+// has succeeded or no longer exists.
+func (s *WebhookStore) GetDeliveryToSend(ctx context.Context, id int64) (*OutgoingDelivery, error) {
+	row, err := s.queries.GetWebhookDeliveryToSend(ctx, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("getting webhook delivery: %w", err)
+	}
+
+	return &OutgoingDelivery{
+		ID:          row.ID,
+		WebhookID:   row.WebhookID,
+		EventID:     row.EventID,
+		EventType:   row.EventType,
+		Payload:     row.Payload,
+		Attempts:    int(row.Attempts),
+		TraceParent: row.Traceparent.String,
+		URL:         row.Url,
+		Secret:      row.Secret,
+		Active:      row.Active,
+	}, nil
+}
+
+// RecordAttempt logs an attempt on its delivery and updates the webhook's
+// failure count, disabling it after disableAfter failures in a row.
+// disabled reports whether the webhook is now off.
+func (s *WebhookStore) RecordAttempt(ctx context.Context, a WebhookAttempt, disableAfter int) (disabled bool, err error) {
+	params := dbsqlc.RecordWebhookAttemptParams{
+		ID:           a.DeliveryID,
+		Status:       WebhookDeliveryPending,
+		ResponseBody: nullText(a.ResponseBody),
+		Error:        nullText(a.Error),
+	}
+	switch {
+	case a.Succeeded:
+		params.Status = WebhookDeliverySucceeded
+	case a.RetryAt.IsZero():
+		params.Status = WebhookDeliveryFailed
+	default:
+		params.NextAttemptAt = pgtype.Timestamptz{Time: a.RetryAt, Valid: true}
+	}
+	if a.ResponseStatus != 0 {
+		params.ResponseStatus = pgtype.Int4{Int32: int32(a.ResponseStatus), Valid: true}
+	}
+	// A finished delivery's next_attempt_at no longer matters, but the
+	// column is NOT NULL.
+	if !params.NextAttemptAt.Valid {
+		params.NextAttemptAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+	}
+
+	err = withTx(ctx, s.db, func(q *dbsqlc.Queries) error {
+		if err := q.RecordWebhookAttempt(ctx, params); err != nil {
+			return fmt.Errorf("recording webhook attempt: %w", err)
+		}
+
+		if a.Succeeded {
+			if err := q.ResetWebhookFailures(ctx, a.WebhookID); err != nil {
+				return fmt.Errorf("resetting webhook failures: %w", err)
+			}
+			return nil
+		}
+
+		active, err := q.CountWebhookFailure(ctx, dbsqlc.CountWebhookFailureParams{
+			ID:           a.WebhookID,
+			DisableAfter: int32(disableAfter),
+		})
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("counting webhook failure: %w", err)
+		}
+		disabled = err == nil && !active
+		return nil
+	})
+	return disabled, err
+}
+
+// PruneDeliveries deletes finished deliveries created before cutoff and
+// returns how many it deleted.
+func (s *WebhookStore) PruneDeliveries(ctx context.Context, cutoff time.Time) (int64, error) {
+	n, err := s.queries.PruneWebhookDeliveries(ctx, pgtype.Timestamptz{Time: cutoff, Valid: true})
+	if err != nil {
+		return 0, fmt.Errorf("pruning webhook deliveries: %w", err)
+	}
+	return n, nil
+}
+
+func queueDeliveries(ctx context.Context, tx pgx.Tx, queue QueueDelivery, ids []int64) error {
+	for _, id := range ids {
+		if err := queue(ctx, tx, id); err != nil {
+			return fmt.Errorf("queueing webhook delivery %d: %w", id, err)
+		}
+	}
+	return nil
+}
+
+func webhookToDomain(row dbsqlc.Webhook) *Webhook {
+	w := &Webhook{
+		ID:                  row.ID,
+		UserID:              uuidPtr(row.UserID),
+		URL:                 row.Url,
+		Secret:              row.Secret,
+		EventTypes:          row.EventTypes,
+		Active:              row.Active,
+		ConsecutiveFailures: int(row.ConsecutiveFailures),
+		CreatedAt:           row.CreatedAt.Time,
+		UpdatedAt:           row.UpdatedAt.Time,
+	}
+	if row.DisabledAt.Valid {
+		w.DisabledAt = &row.DisabledAt.Time
+	}
+	return w
+}
+
+func webhookDeliveryToDomain(row dbsqlc.WebhookDelivery) *WebhookDelivery {
+	d := &WebhookDelivery{
+		ID:        row.ID,
+		WebhookID: row.WebhookID,
+		EventID:   row.EventID,
+		EventType: row.EventType,
+		Payload:   row.Payload,
+		Status:    row.Status,
+		Attempts:  int(row.Attempts),
+		CreatedAt: row.CreatedAt.Time,
+	}
+	if row.Status == WebhookDeliveryPending {
+		d.NextAttemptAt = &row.NextAttemptAt.Time
+	}
+	if row.ResponseStatus.Valid {
+		status := int(row.ResponseStatus.Int32)
+		d.ResponseStatus = &status
+	}
+	if row.ResponseBody.Valid {
+		d.ResponseBody = &row.ResponseBody.String
+	}
+	if row.Error.Valid {
+		d.Error = &row.Error.String
+	}
+	if row.CompletedAt.Valid {
+		d.CompletedAt = &row.CompletedAt.Time
+	}
+	return d
+}
@@ -0,0 +1,191 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jesusthecreator017/fswithgo/internal/store/dbsqlc"
+)
+
+// WIPLimit caps how many issues may sit in Status. With neither ProjectID
+// nor UserID set it is the default for every board's column; a project
+// limit on the same status replaces it for that project. A UserID limit
+// counts that user's issues (within ProjectID, if set).
+type WIPLimit struct {
+	ID        int64      `json:"id"`
+	ProjectID *int64     `json:"project_id"`
+	UserID    *uuid.UUID `json:"user_id"`
+	Status    StatusType `json:"status"`
+	MaxIssues int32      `json:"max_issues"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// WIPUsage is a limit together with how much of it one board is using, so
+// clients can show "3/5".
+type WIPUsage struct {
+	WIPLimit
+	Current int64 `json:"current"`
+}
+
+// WIPLimitError is returned when a write would put more issues in a status
+// than one of its limits allows.
+type WIPLimitError struct {
+	Limit   WIPLimit
+	Current int64
+}
+
+func (e *WIPLimitError) Error() string {
+	return fmt.Sprintf("WIP limit for %s reached (%d/%d)", e.Limit.Status, e.Current, e.Limit.MaxIssues)
+}
+
+type WIPStore struct {
+	queries *dbsqlc.Queries
+}
+
+func (s *WIPStore) List(ctx context.Context) ([]*WIPLimit, error) {
+	rows, err := s.queries.ListWipLimits(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("listing wip limits: %w", err)
+	}
+
+	limits := make([]*WIPLimit, len(rows))
+	for i, row := range rows {
+		limits[i] = wipLimitToDomain(row)
+	}
+	return limits, nil
+}
+
+// Usage returns the limits that apply to userID's issues on a board (a
+// project, or the personal board when projectID is nil) with current counts.
+func (s *WIPStore) Usage(ctx context.Context, projectID *int64, userID uuid.UUID) ([]*WIPUsage, error) {
+	board := nullInt8(projectID)
+
+	rows, err := s.queries.ListBoardWipLimits(ctx, dbsqlc.ListBoardWipLimitsParams{
+		ProjectID: board,
+		UserID:    userID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("listing wip limits: %w", err)
+	}
+
+	usage := make([]*WIPUsage, 0, len(rows))
+	for _, row := range effectiveWIPLimits(rows) {
+		current, err := s.queries.CountIssuesForWipLimit(ctx, wipCountParams(row, board, userID, 0))
+		if err != nil {
+			return nil, fmt.Errorf("counting issues for wip limit: %w", err)
+		}
+		usage = append(usage, &WIPUsage{WIPLimit: *wipLimitToDomain(row), Current: current})
+	}
+	return usage, nil
+}
+
+// Set creates the limit for its scope and status, or changes MaxIssues if
+// one already exists.
+func (s *WIPStore) Set(ctx context.Context, limit *WIPLimit) error {
+	row, err := s.queries.UpsertWipLimit(ctx, dbsqlc.UpsertWipLimitParams{
+		ProjectID: nullInt8(limit.ProjectID),
+		UserID:    nullUUID(limit.UserID),
+		Status:    string(limit.Status),
+		MaxIssues: limit.MaxIssues,
+	})
+	if err != nil {
+		return fmt.Errorf("setting wip limit: %w", err)
+	}
+
+	*limit = *wipLimitToDomain(row)
+	return nil
+}
+
+func (s *WIPStore) Delete(ctx context.Context, id int64) error {
+	n, err := s.queries.DeleteWipLimit(ctx, id)
+	if err != nil {
+		return fmt.Errorf("deleting wip limit: %w", err)
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// checkWIPLimits returns a *WIPLimitError if moving an issue owned by userID
+// on the given board into status would break a limit. exclude is the issue
+// itself, which may already be counted. It locks the limits it checks, so
+// it must run in the same transaction as the write.
+func checkWIPLimits(ctx context.Context, q *dbsqlc.Queries, board pgtype.Int8, userID uuid.UUID, status string, exclude int64) error {
+	rows, err := q.LockApplicableWipLimits(ctx, dbsqlc.LockApplicableWipLimitsParams{
+		Status:    status,
+		ProjectID: board,
+		UserID:    userID,
+	})
+	if err != nil {
+		return fmt.Errorf("locking wip limits: %w", err)
+	}
+
+	for _, row := range effectiveWIPLimits(rows) {
+		current, err := q.CountIssuesForWipLimit(ctx, wipCountParams(row, board, userID, exclude))
+		if err != nil {
+			return fmt.Errorf("counting issues for wip limit: %w", err)
+		}
+		if current >= int64(row.MaxIssues) {
+			return &WIPLimitError{Limit: *wipLimitToDomain(row), Current: current}
+		}
+	}
+	return nil
+}
+
+// effectiveWIPLimits drops default limits on statuses that also have a
+// project-wide limit, since the project's replaces the default.
+func effectiveWIPLimits(rows []dbsqlc.WipLimit) []dbsqlc.WipLimit {
+	projectWide := make(map[string]bool)
+	for _, row := range rows {
+		if row.ProjectID.Valid && !row.UserID.Valid {
+			projectWide[row.Status] = true
+		}
+	}
+
+	effective := rows[:0:0]
+	for _, row := range rows {
+		if !row.ProjectID.Valid && !row.UserID.Valid && projectWide[row.Status] {
+			continue
+		}
+		effective = append(effective, row)
+	}
+	return effective
+}
+
+// wipCountParams scopes the issue count for a limit. A default limit counts
+// the board the issue is on; any other limit counts its own scope.
+func wipCountParams(row dbsqlc.WipLimit, board pgtype.Int8, userID uuid.UUID, exclude int64) dbsqlc.CountIssuesForWipLimitParams {
+	params := dbsqlc.CountIssuesForWipLimitParams{
+		Status:    row.Status,
+		ExcludeID: exclude,
+		ProjectID: row.ProjectID,
+		UserID:    row.UserID,
+	}
+
+	if !row.ProjectID.Valid && !row.UserID.Valid {
+		if board.Valid {
+			params.ProjectID = board
+		} else {
+			params.UserID = uuid.NullUUID{UUID: userID, Valid: true}
+			params.PersonalOnly = true
+		}
+	}
+	return params
+}
+
+func wipLimitToDomain(row dbsqlc.WipLimit) *WIPLimit {
+	return &WIPLimit{
+		ID:        row.ID,
+		ProjectID: int64Ptr(row.ProjectID),
+		UserID:    uuidPtr(row.UserID),
+		Status:    StatusType(row.Status),
+		MaxIssues: row.MaxIssues,
+		CreatedAt: row.CreatedAt.Time,
+		UpdatedAt: row.UpdatedAt.Time,
+	}
+}
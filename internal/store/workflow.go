@@ -0,0 +1,268 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jesusthecreator017/fswithgo/internal/store/dbsqlc"
+)
+
+// StatusCategory groups statuses across workflows. Reports treat every
+// done status as closed, whatever the project calls it.
+type StatusCategory string
+
+const (
+	CategoryTodo       StatusCategory = "todo"
+	CategoryInProgress StatusCategory = "in_progress"
+	CategoryDone       StatusCategory = "done"
+)
+
+func (c StatusCategory) Valid() bool {
+	switch c {
+	case CategoryTodo, CategoryInProgress, CategoryDone:
+		return true
+	}
+	return false
+}
+
+var (
+	ErrUnknownStatus        = errors.New("status is not part of the workflow")
+	ErrTransitionNotAllowed = errors.New("status transition is not allowed")
+	ErrDuplicateStatus      = errors.New("status already exists in the workflow")
+	ErrStatusInUse          = errors.New("status still has issues")
+	ErrLastStatus           = errors.New("workflow must keep at least one status")
+)
+
+// WorkflowStatus is one board column. ProjectID is nil for the default
+// workflow.
+type WorkflowStatus struct {
+	ID        int64          `json:"id"`
+	ProjectID *int64         `json:"project_id"`
+	Name      StatusType     `json:"name"`
+	Category  StatusCategory `json:"category"`
+	Position  int32          `json:"position"`
+}
+
+type WorkflowTransition struct {
+	From StatusType `json:"from"`
+	To   StatusType `json:"to"`
+}
+
+// Workflow is a project's statuses in board order and the moves allowed
+// between them.
+type Workflow struct {
+	ProjectID   *int64               `json:"project_id"`
+	Statuses    []*WorkflowStatus    `json:"statuses"`
+	Transitions []WorkflowTransition `json:"transitions"`
+}
+
+// Status returns the named status, or nil if the workflow doesn't have it.
+func (w *Workflow) Status(name StatusType) *WorkflowStatus {
+	for _, s := range w.Statuses {
+		if s.Name == name {
+			return s
+		}
+	}
+	return nil
+}
+
+// Initial is the status new issues start in: the first column on the board.
+func (w *Workflow) Initial() *WorkflowStatus {
+	if len(w.Statuses) == 0 {
+		return nil
+	}
+	return w.Statuses[0]
+}
+
+type WorkflowStore struct {
+	db      *pgxpool.Pool
+	queries *dbsqlc.Queries
+}
+
+// Get loads a project's workflow, or the default one when projectID is nil.
+func (s *WorkflowStore) Get(ctx context.Context, projectID *int64) (*Workflow, error) {
+	statuses, err := s.queries.ListWorkflowStatuses(ctx, nullInt8(projectID))
+	if err != nil {
+		return nil, fmt.Errorf("listing workflow statuses: %w", err)
+	}
+
+	transitions, err := s.queries.ListWorkflowTransitions(ctx, nullInt8(projectID))
+	if err != nil {
+		return nil, fmt.Errorf("listing workflow transitions: %w", err)
+	}
+
+	workflow := &Workflow{
+		ProjectID:   projectID,
+		Statuses:    make([]*WorkflowStatus, len(statuses)),
+		Transitions: make([]WorkflowTransition, len(transitions)),
+	}
+	for i, row := range statuses {
+		workflow.Statuses[i] = workflowStatusToDomain(row)
+	}
+	for i, row := range transitions {
+		workflow.Transitions[i] = WorkflowTransition{
+			From: StatusType(row.FromStatus),
+			To:   StatusType(row.ToStatus),
+		}
+	}
+	return workflow, nil
+}
+
+// CreateStatus adds a status to a project's workflow. It starts with no
+// transitions in or out; those are set with SetTransitions.
+func (s *WorkflowStore) CreateStatus(ctx context.Context, status *WorkflowStatus) error {
+	row, err := s.queries.CreateWorkflowStatus(ctx, dbsqlc.CreateWorkflowStatusParams{
+		ProjectID: nullInt8(status.ProjectID),
+		Name:      string(status.Name),
+		Category:  string(status.Category),
+		Position:  status.Position,
+	})
+	if err != nil {
+		if isUniqueViolation(err) {
+			return ErrDuplicateStatus
+		}
+		return fmt.Errorf("creating workflow status: %w", err)
+	}
+
+	*status = *workflowStatusToDomain(row)
+	return nil
+}
+
+// UpdateStatus changes a status's category and position. Names are fixed
+// because issues and their history refer to statuses by name.
+func (s *WorkflowStore) UpdateStatus(ctx context.Context, status *WorkflowStatus) error {
+	row, err := s.queries.UpdateWorkflowStatus(ctx, dbsqlc.UpdateWorkflowStatusParams{
+		Category:  string(status.Category),
+		Position:  status.Position,
+		ID:        status.ID,
+		ProjectID: nullInt8(status.ProjectID),
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrNotFound
+		}
+		return fmt.Errorf("updating workflow status: %w", err)
+	}
+
+	*status = *workflowStatusToDomain(row)
+	return nil
+}
+
+// DeleteStatus removes a status and its transitions. It fails with
+// ErrStatusInUse while any issue in the project is in that status.
+func (s *WorkflowStore) DeleteStatus(ctx context.Context, projectID, statusID int64) error {
+	project := pgtype.Int8{Int64: projectID, Valid: true}
+
+	return withTx(ctx, s.db, func(q *dbsqlc.Queries) error {
+		// Delete first so the row lock makes concurrent moves into this
+		// status finish before the count below.
+		deleted, err := q.DeleteWorkflowStatus(ctx, dbsqlc.DeleteWorkflowStatusParams{
+			ID:        statusID,
+			ProjectID: project,
+		})
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return ErrNotFound
+			}
+			return fmt.Errorf("deleting workflow status: %w", err)
+		}
+
+		inUse, err := q.CountIssuesInStatus(ctx, dbsqlc.CountIssuesInStatusParams{
+			ProjectID: project,
+			Status:    deleted.Name,
+		})
+		if err != nil {
+			return fmt.Errorf("counting issues in status: %w", err)
+		}
+		if inUse > 0 {
+			return ErrStatusInUse
+		}
+
+		remaining, err := q.ListWorkflowStatuses(ctx, project)
+		if err != nil {
+			return fmt.Errorf("listing workflow statuses: %w", err)
+		}
+		if len(remaining) == 0 {
+			return ErrLastStatus
+		}
+		return nil
+	})
+}
+
+// SetTransitions replaces a project's whole transition graph. Every status
+// named must already be in the project's workflow.
+func (s *WorkflowStore) SetTransitions(ctx context.Context, projectID int64, transitions []WorkflowTransition) error {
+	project := pgtype.Int8{Int64: projectID, Valid: true}
+
+	return withTx(ctx, s.db, func(q *dbsqlc.Queries) error {
+		statuses, err := q.ListWorkflowStatuses(ctx, project)
+		if err != nil {
+			return fmt.Errorf("listing workflow statuses: %w", err)
+		}
+
+		ids := make(map[StatusType]int64, len(statuses))
+		for _, row := range statuses {
+			ids[StatusType(row.Name)] = row.ID
+		}
+
+		if err := q.DeleteWorkflowTransitions(ctx, project); err != nil {
+			return fmt.Errorf("clearing workflow transitions: %w", err)
+		}
+
+		seen := make(map[WorkflowTransition]bool, len(transitions))
+		for _, t := range transitions {
+			from, okFrom := ids[t.From]
+			to, okTo := ids[t.To]
+			if !okFrom || !okTo {
+				return ErrUnknownStatus
+			}
+			if from == to || seen[t] {
+				continue
+			}
+			seen[t] = true
+
+			err := q.CreateWorkflowTransition(ctx, dbsqlc.CreateWorkflowTransitionParams{
+				FromStatusID: from,
+				ToStatusID:   to,
+			})
+			if err != nil {
+				return fmt.Errorf("creating workflow transition: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+func workflowStatusToDomain(row dbsqlc.WorkflowStatus) *WorkflowStatus {
+	return &WorkflowStatus{
+		ID:        row.ID,
+		ProjectID: int64Ptr(row.ProjectID),
+		Name:      StatusType(row.Name),
+		Category:  StatusCategory(row.Category),
+		Position:  row.Position,
+	}
+}
+
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
+func nullInt8(v *int64) pgtype.Int8 {
+	if v == nil {
+		return pgtype.Int8{}
+	}
+	return pgtype.Int8{Int64: *v, Valid: true}
+}
+
+func int64Ptr(v pgtype.Int8) *int64 {
+	if !v.Valid {
+		return nil
+	}
+	return &v.Int64
+}
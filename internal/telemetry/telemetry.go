@@ -0,0 +1,114 @@
+// Package telemetry sets up OpenTelemetry tracing. Traces continue from an
+// inbound W3C traceparent header and are exported over OTLP, or written as
+// JSON to stdout or a file for local use.
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.39.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentation = "github.com/jesusthecreator017/fswithgo"
+
+// Config picks where spans go.
+type Config struct {
+	// Exporter is "otlp", "console" (stdout), "file" or "none". The OTLP
+	// exporter reads the standard OTEL_EXPORTER_OTLP_* variables.
+	Exporter string
+	File     string // for the file exporter
+	// ServiceName is used unless OTEL_SERVICE_NAME is set.
+	ServiceName string
+}
+
+// Setup installs the global tracer provider and propagator and returns a
+// function that flushes and stops the exporter. With Exporter "none" no
+// spans are recorded, but traceparent headers are still passed along.
+func Setup(ctx context.Context, cfg Config) (shutdown func(context.Context) error, err error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	if cfg.Exporter == "" || cfg.Exporter == "none" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	// Attributes from the environment, OTEL_SERVICE_NAME among them, win.
+	res, err := resource.New(ctx,
+		resource.WithAttributes(semconv.ServiceName(cfg.ServiceName)),
+		resource.WithFromEnv(),
+		resource.WithHost(),
+		resource.WithProcessRuntimeVersion(),
+	)
+	if err != nil && !errors.Is(err, resource.ErrPartialResource) {
+		return nil, fmt.Errorf("describing service: %w", err)
+	}
+
+	var exporter sdktrace.SpanExporter
+	var file *os.File
+	switch cfg.Exporter {
+	case "otlp":
+		exporter, err = otlptracehttp.New(ctx)
+	case "console":
+		exporter, err = stdouttrace.New()
+	case "file":
+		if file, err = os.OpenFile(cfg.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644); err == nil {
+			exporter, err = stdouttrace.New(stdouttrace.WithWriter(file))
+		}
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q", cfg.Exporter)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("creating %s exporter: %w", cfg.Exporter, err)
+	}
+
+	// The sampler comes from OTEL_TRACES_SAMPLER, and defaults to
+	// sampling everything that isn't part of an unsampled trace.
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+
+	return func(ctx context.Context) error {
+		err := provider.Shutdown(ctx)
+		if file != nil {
+			err = errors.Join(err, file.Close())
+		}
+		return err
+	}, nil
+}
+
+// Tracer returns the tracer for the application's own spans.
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentation)
+}
+
+// TraceParent returns the W3C traceparent for the span in ctx, or "" if
+// there is none, so work done later can continue the trace.
+func TraceParent(ctx context.Context) string {
+	carrier := propagation.MapCarrier{}
+	propagation.TraceContext{}.Inject(ctx, carrier)
+	return carrier.Get("traceparent")
+}
+
+// WithTraceParent returns ctx carrying the remote span that traceParent
+// names, so spans started from it join that trace.
+func WithTraceParent(ctx context.Context, traceParent string) context.Context {
+	if traceParent == "" {
+		return ctx
+	}
+	carrier := propagation.MapCarrier{"traceparent": traceParent}
+	return propagation.TraceContext{}.Extract(ctx, carrier)
+}
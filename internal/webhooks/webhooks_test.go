@@ -0,0 +1,135 @@
+package webhooks
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSendSigned(t *testing.T) {
+	const secret = "whsec_test"
+	payload := []byte(`{"type":"issue.created","id":7}`)
+
+	type received struct {
+		header http.Header
+		body   []byte
+	}
+	got := make(chan received, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		got <- received{req.Header.Clone(), body}
+		w.WriteHeader(http.StatusAccepted)
+		io.WriteString(w, "thanks")
+	}))
+	defer srv.Close()
+
+	res := Send(context.Background(), NewClient(time.Second), Delivery{
+		ID:        42,
+		EventType: "issue.created",
+		URL:       srv.URL,
+		Secret:    secret,
+		Payload:   payload,
+	})
+	if !res.OK() || res.Status != http.StatusAccepted || res.Body != "thanks" {
+		t.Fatalf("result = %+v, want 202 thanks", res)
+	}
+
+	r := <-got
+	if string(r.body) != string(payload) {
+		t.Fatalf("body = %s, want %s", r.body, payload)
+	}
+	if r.header.Get(HeaderDelivery) != "42" || r.header.Get(HeaderEvent) != "issue.created" {
+		t.Fatalf("delivery headers = %q, %q", r.header.Get(HeaderDelivery), r.header.Get(HeaderEvent))
+	}
+
+	timestamp, err := strconv.ParseInt(r.header.Get(HeaderTimestamp), 10, 64)
+	if err != nil {
+		t.Fatalf("timestamp header: %v", err)
+	}
+	sig := r.header.Get(HeaderSignature)
+	if !Verify(secret, timestamp, r.body, sig) {
+		t.Fatalf("signature %q does not verify", sig)
+	}
+	if Verify("whsec_other", timestamp, r.body, sig) {
+		t.Fatal("signature verifies with the wrong secret")
+	}
+	if Verify(secret, timestamp+1, r.body, sig) {
+		t.Fatal("signature verifies with a different timestamp")
+	}
+	if Verify(secret, timestamp, append(r.body, ' '), sig) {
+		t.Fatal("signature verifies with a changed body")
+	}
+}
+
+func TestSendFailures(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		http.Error(w, "nope", http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	res := Send(context.Background(), NewClient(time.Second), Delivery{URL: srv.URL})
+	if res.OK() || res.Status != http.StatusInternalServerError || res.Err != nil {
+		t.Fatalf("result = %+v, want a 500 that isn't OK", res)
+	}
+
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		<-req.Context().Done()
+	}))
+	defer slow.Close()
+
+	res = Send(context.Background(), NewClient(50*time.Millisecond), Delivery{URL: slow.URL})
+	if res.OK() || res.Err == nil || res.Status != 0 {
+		t.Fatalf("result = %+v, want a timeout error", res)
+	}
+}
+
+func TestSendDoesNotFollowRedirects(t *testing.T) {
+	var followed atomic.Bool
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		followed.Store(true)
+	}))
+	defer target.Close()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		http.Redirect(w, req, target.URL, http.StatusTemporaryRedirect)
+	}))
+	defer srv.Close()
+
+	res := Send(context.Background(), NewClient(time.Second), Delivery{URL: srv.URL, Payload: []byte("{}")})
+	if res.OK() || res.Status != http.StatusTemporaryRedirect {
+		t.Fatalf("result = %+v, want an unfollowed 307", res)
+	}
+	if followed.Load() {
+		t.Fatal("redirect was followed")
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	const (
+		base  = 30 * time.Second
+		limit = time.Hour
+	)
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{0, 0},
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{3, 2 * time.Minute},
+		{4, 4 * time.Minute},
+		{7, 32 * time.Minute},
+		{8, time.Hour},
+		{100, time.Hour},
+	}
+	for _, tt := range tests {
+		if got := Backoff(tt.failures, base, limit); got != tt.want {
+			t.Errorf("Backoff(%d) = %v, want %v", tt.failures, got, tt.want)
+		}
+	}
+}
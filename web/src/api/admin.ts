@@ -11,6 +11,68 @@ export interface AdminStats {
 	issues_by_status: IssueStatusCount[];
 }
 
+export type JobStatus = "pending" | "running" | "succeeded" | "dead";
+
+export interface Job {
+	id: number;
+	kind: string;
+	args: unknown;
+	status: JobStatus;
+	attempts: number;
+	max_attempts: number;
+	run_at: string;
+	unique_key: string | null;
+	last_error: string | null;
+	locked_until: string | null;
+	created_at: string;
+	updated_at: string;
+	finished_at: string | null;
+}
+
+export interface TaskRun {
+	id: number;
+	task: string;
+	scheduled_for: string;
+	host: string;
+	status: "running" | "succeeded" | "failed";
+	error: string | null;
+	started_at: string;
+	finished_at: string | null;
+}
+
+export interface TaskStatus {
+	name: string;
+	schedule: string;
+	next_run: string;
+	last_run: TaskRun | null;
+}
+
 export const adminApi = {
 	getStats: () => apiFetch<{ stats: AdminStats }>("/v1/admin/stats"),
+
+	listJobs: (
+		params: { status?: JobStatus; kind?: string; cursor?: string } = {},
+	) => {
+		const qs = new URLSearchParams(
+			Object.entries(params).filter(([, v]) => v) as [string, string][],
+		);
+		return apiFetch<{ jobs: Job[]; next_cursor: string }>(
+			`/v1/admin/jobs?${qs}`,
+		);
+	},
+
+	retryJob: (id: number) =>
+		apiFetch<{ job: Job }>(`/v1/admin/jobs/${id}/retry`, {
+			method: "POST",
+		}).then((r) => r.job),
+
+	schedule: () =>
+		apiFetch<{ tasks: TaskStatus[] }>("/v1/admin/schedule").then(
+			(r) => r.tasks,
+		),
+
+	taskRuns: (task: string, limit = 50) =>
+		apiFetch<{ runs: TaskRun[] }>(
+			`/v1/admin/schedule/${encodeURIComponent(task)}/runs?limit=${limit}`,
+		).then((r) => r.runs),
 };
@@ -4,11 +4,35 @@ export async function apiFetch<T>(
 	path: string,
 	init?: RequestInit,
 ): Promise<T> {
+	const res = await send(path, init);
+	return res.json();
+}
+
+// apiFetchBlob is apiFetch for endpoints that return a file.
+export async function apiFetchBlob(
+	path: string,
+	init?: RequestInit,
+): Promise<Blob> {
+	const res = await send(path, init);
+	return res.blob();
+}
+
+// apiStream is apiFetch for streaming endpoints; the caller reads the body.
+export function apiStream(
+	path: string,
+	init?: RequestInit,
+): Promise<Response> {
+	return send(path, init);
+}
+
+async function send(path: string, init?: RequestInit): Promise<Response> {
 	const token = getToken();
+	// Let the browser set the multipart boundary for FormData bodies.
+	const isForm = init?.body instanceof FormData;
 	const res = await fetch(`${API_BASE}${path}`, {
 		...init,
 		headers: {
-			"Content-Type": "application/json",
+			...(isForm ? {} : { "Content-Type": "application/json" }),
 			...(token ? { Authorization: `Bearer ${token}` } : {}),
 			...init?.headers,
 		},
@@ -17,7 +41,7 @@ export async function apiFetch<T>(
 		const body = await res.json().catch(() => ({}));
 		throw new Error(body.error ?? `HTTP ${res.status}`);
 	}
-	return res.json();
+	return res;
 }
 
 function getToken(): string | null {
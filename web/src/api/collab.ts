@@ -0,0 +1,46 @@
+import type { IssueEvent } from "../schemas/issue";
+
+const API_BASE = process.env.NEXT_PUBLIC_API_URL ?? "http://localhost:8080";
+
+export type CollabUser = { id: string; name: string };
+
+export type CollabMessage =
+	| { type: "subscribed" | "unsubscribed"; topic: string }
+	| { type: "event"; event: IssueEvent }
+	| { type: "presence"; issue_id: number; viewers: CollabUser[] }
+	| { type: "typing"; issue_id: number; user: CollabUser }
+	| { type: "reset" }
+	| { type: "error"; error: string };
+
+// Topics are "board", "project:<id>" or "issue:<id>".
+export type CollabCommand =
+	| { type: "subscribe" | "unsubscribe"; topic: string }
+	| { type: "typing"; issue_id: number };
+
+// openCollab connects to the collaboration WebSocket. The token goes in the
+// subprotocol list because browsers can't set headers on a WebSocket.
+export function openCollab(
+	token: string,
+	onMessage: (msg: CollabMessage) => void,
+): { send: (cmd: CollabCommand) => void; close: () => void } {
+	const ws = new WebSocket(`${API_BASE.replace(/^http/, "ws")}/v1/ws`, [
+		"bearer",
+		token,
+	]);
+	ws.onmessage = (e) => onMessage(JSON.parse(e.data));
+
+	// Commands sent before the socket opens wait for it.
+	const pending: string[] = [];
+	ws.onopen = () => {
+		for (const data of pending.splice(0)) ws.send(data);
+	};
+
+	return {
+		send: (cmd) => {
+			const data = JSON.stringify(cmd);
+			if (ws.readyState === WebSocket.OPEN) ws.send(data);
+			else pending.push(data);
+		},
+		close: () => ws.close(),
+	};
+}
@@ -0,0 +1,59 @@
+import { apiStream } from "./client";
+import { IssueEventSchema, type IssueEvent } from "../schemas/issue";
+
+export type StreamMessage = IssueEvent | { type: "reset" };
+
+const RECONNECT_MS = 3000;
+
+// streamEvents follows /v1/events/stream until signal aborts, reconnecting
+// with Last-Event-ID. EventSource can't send the Authorization header, so
+// the stream is read with fetch.
+export async function streamEvents(
+	onMessage: (msg: StreamMessage) => void,
+	signal: AbortSignal,
+): Promise<void> {
+	let lastId: string | undefined;
+
+	while (!signal.aborted) {
+		try {
+			const res = await apiStream("/v1/events/stream", {
+				signal,
+				headers: lastId ? { "Last-Event-ID": lastId } : {},
+			});
+			if (!res.body) return;
+
+			const reader = res.body.pipeThrough(new TextDecoderStream()).getReader();
+			let buf = "";
+			for (;;) {
+				const { value, done } = await reader.read();
+				if (done) break;
+				buf += value;
+
+				let end: number;
+				while ((end = buf.indexOf("\n\n")) >= 0) {
+					const block = buf.slice(0, end);
+					buf = buf.slice(end + 2);
+
+					let id: string | undefined;
+					let type: string | undefined;
+					let data = "";
+					for (const line of block.split("\n")) {
+						if (line.startsWith("id: ")) id = line.slice(4);
+						else if (line.startsWith("event: ")) type = line.slice(7);
+						else if (line.startsWith("data: ")) data += line.slice(6);
+					}
+
+					if (id) lastId = id;
+					if (type === "reset") {
+						onMessage({ type: "reset" });
+					} else if (type && data) {
+						onMessage(IssueEventSchema.parse(JSON.parse(data)));
+					}
+				}
+			}
+		} catch {
+			if (signal.aborted) return;
+		}
+		await new Promise((r) => setTimeout(r, RECONNECT_MS));
+	}
+}
@@ -1,5 +1,14 @@
-import { apiFetch } from "./client";
-import type { Issue, CreateIssueInput, StatusType } from "../schemas/issue";
+import { apiFetch, apiFetchBlob } from "./client";
+import type {
+	Attachment,
+	ChildPolicy,
+	Issue,
+	IssueLink,
+	LinkType,
+	CreateIssueInput,
+	MoveIssueInput,
+	StatusType,
+} from "../schemas/issue";
 
 export const issuesApi = {
 	list: () => apiFetch<{ issues: Issue[] }>("/v1/issues").then((r) => r.issues),
@@ -19,8 +28,66 @@ export const issuesApi = {
 			body: JSON.stringify({ status }),
 		}).then((r) => r.issue),
 
-	delete: (id: number) =>
-		apiFetch<{ message: string }>(`/v1/issues/${id}`, {
-			method: "DELETE",
-		}),
+	move: (id: number, data: MoveIssueInput) =>
+		apiFetch<{ issue: Issue }>(`/v1/issues/${id}/move`, {
+			method: "POST",
+			body: JSON.stringify(data),
+		}).then((r) => r.issue),
+
+	children: (id: number) =>
+		apiFetch<{ issues: Issue[] }>(`/v1/issues/${id}/children`).then(
+			(r) => r.issues,
+		),
+
+	setParent: (id: number, parentId: number | null) =>
+		apiFetch<{ issue: Issue }>(`/v1/issues/${id}/parent`, {
+			method: "PATCH",
+			body: JSON.stringify({ parent_id: parentId }),
+		}).then((r) => r.issue),
+
+	links: (id: number) =>
+		apiFetch<{ links: IssueLink[] }>(`/v1/issues/${id}/links`).then(
+			(r) => r.links,
+		),
+
+	link: (id: number, type: LinkType, issueId: number) =>
+		apiFetch<{ link: IssueLink }>(`/v1/issues/${id}/links`, {
+			method: "POST",
+			body: JSON.stringify({ type, issue_id: issueId }),
+		}).then((r) => r.link),
+
+	unlink: (id: number, type: LinkType, issueId: number) =>
+		apiFetch<{ message: string }>(
+			`/v1/issues/${id}/links?type=${type}&issue_id=${issueId}`,
+			{ method: "DELETE" },
+		),
+
+	attachments: (id: number) =>
+		apiFetch<{ attachments: Attachment[] }>(
+			`/v1/issues/${id}/attachments`,
+		).then((r) => r.attachments),
+
+	uploadAttachment: (id: number, file: File) => {
+		const form = new FormData();
+		form.append("file", file);
+		return apiFetch<{ attachment: Attachment }>(
+			`/v1/issues/${id}/attachments`,
+			{ method: "POST", body: form },
+		).then((r) => r.attachment);
+	},
+
+	downloadAttachment: (id: number, attachmentId: number) =>
+		apiFetchBlob(`/v1/issues/${id}/attachments/${attachmentId}`),
+
+	deleteAttachment: (id: number, attachmentId: number) =>
+		apiFetch<{ message: string }>(
+			`/v1/issues/${id}/attachments/${attachmentId}`,
+			{ method: "DELETE" },
+		),
+
+	delete: (id: number, children?: ChildPolicy) =>
+		apiFetch<{ message: string }>(
+			children ? `/v1/issues/${id}?children=${children}` : `/v1/issues/${id}`,
+			{ method: "DELETE" },
+		),
 };
@@ -0,0 +1,79 @@
+import { apiFetch } from "./client";
+
+export type WebhookEventType =
+	| "issue.created"
+	| "issue.updated"
+	| "issue.deleted";
+
+export interface Webhook {
+	id: number;
+	user_id: string | null;
+	url: string;
+	// Only present when the webhook is created or its secret rotated.
+	secret?: string;
+	event_types: WebhookEventType[];
+	active: boolean;
+	consecutive_failures: number;
+	disabled_at: string | null;
+	created_at: string;
+	updated_at: string;
+}
+
+export interface WebhookDelivery {
+	id: number;
+	webhook_id: number;
+	event_id: number;
+	event_type: string;
+	payload: unknown;
+	status: "pending" | "succeeded" | "failed";
+	attempts: number;
+	next_attempt_at: string | null;
+	response_status: number | null;
+	response_body: string | null;
+	error: string | null;
+	created_at: string;
+	completed_at: string | null;
+}
+
+export interface UpdateWebhookInput {
+	url?: string;
+	event_types?: WebhookEventType[];
+	active?: boolean;
+	rotate_secret?: boolean;
+}
+
+export const webhooksApi = {
+	list: () =>
+		apiFetch<{ webhooks: Webhook[] }>("/v1/webhooks").then((r) => r.webhooks),
+
+	create: (url: string, event_types: WebhookEventType[]) =>
+		apiFetch<{ webhook: Webhook }>("/v1/webhooks", {
+			method: "POST",
+			body: JSON.stringify({ url, event_types }),
+		}).then((r) => r.webhook),
+
+	update: (id: number, data: UpdateWebhookInput) =>
+		apiFetch<{ webhook: Webhook }>(`/v1/webhooks/${id}`, {
+			method: "PATCH",
+			body: JSON.stringify(data),
+		}).then((r) => r.webhook),
+
+	remove: (id: number) =>
+		apiFetch<{ message: string }>(`/v1/webhooks/${id}`, { method: "DELETE" }),
+
+	ping: (id: number) =>
+		apiFetch<{ delivery: WebhookDelivery }>(`/v1/webhooks/${id}/ping`, {
+			method: "POST",
+		}).then((r) => r.delivery),
+
+	deliveries: (id: number) =>
+		apiFetch<{ deliveries: WebhookDelivery[] }>(
+			`/v1/webhooks/${id}/deliveries`,
+		).then((r) => r.deliveries),
+
+	redeliver: (id: number, deliveryId: number) =>
+		apiFetch<{ delivery: WebhookDelivery }>(
+			`/v1/webhooks/${id}/deliveries/${deliveryId}/redeliver`,
+			{ method: "POST" },
+		).then((r) => r.delivery),
+};
@@ -3,7 +3,7 @@
 import { useState } from "react";
 import { Plus } from "lucide-react";
 import { Button } from "@/components/ui/button";
-import { useIssues } from "@/hooks/useIssues";
+import { useIssueEvents, useIssues } from "@/hooks/useIssues";
 import { KanbanColumn } from "./KanbanColumn";
 import { CreateIssueDialog } from "@/components/issues/CreateIssueDialog";
 import type { StatusType } from "@/schemas/issue";
@@ -17,6 +17,7 @@ const columns: { status: StatusType; label: string }[] = [
 export function KanbanBoard() {
 	const [createOpen, setCreateOpen] = useState(false);
 	const { data: issues, isLoading, error } = useIssues();
+	useIssueEvents();
 
 	if (isLoading) {
 		return (
@@ -1,5 +1,6 @@
 import { useQuery } from "@tanstack/react-query";
 import { adminApi } from "@/api/admin";
+import { webhooksApi } from "@/api/webhooks";
 
 export function useAdminStats() {
 	return useQuery({
@@ -7,3 +8,17 @@ export function useAdminStats() {
 		queryFn: () => adminApi.getStats(),
 	});
 }
+
+export function useWebhooks() {
+	return useQuery({
+		queryKey: ["admin", "webhooks"],
+		queryFn: () => webhooksApi.list(),
+	});
+}
+
+export function useWebhookDeliveries(id: number) {
+	return useQuery({
+		queryKey: ["admin", "webhooks", id, "deliveries"],
+		queryFn: () => webhooksApi.deliveries(id),
+	});
+}
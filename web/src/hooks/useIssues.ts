@@ -1,8 +1,14 @@
 "use client";
 
+import { useEffect } from "react";
 import { useQuery, useMutation, useQueryClient } from "@tanstack/react-query";
 import { issuesApi } from "@/api/issues";
-import type { CreateIssueInput, StatusType } from "@/schemas/issue";
+import { streamEvents } from "@/api/events";
+import type {
+	CreateIssueInput,
+	MoveIssueInput,
+	StatusType,
+} from "@/schemas/issue";
 
 export function useIssues() {
 	return useQuery({
@@ -28,6 +34,15 @@ export function useUpdateIssueStatus() {
 	});
 }
 
+export function useMoveIssue() {
+	const qc = useQueryClient();
+	return useMutation({
+		mutationFn: ({ id, ...data }: { id: number } & MoveIssueInput) =>
+			issuesApi.move(id, data),
+		onSuccess: () => qc.invalidateQueries({ queryKey: ["issues"] }),
+	});
+}
+
 export function useDeleteIssue() {
 	const qc = useQueryClient();
 	return useMutation({
@@ -35,3 +50,16 @@ export function useDeleteIssue() {
 		onSuccess: () => qc.invalidateQueries({ queryKey: ["issues"] }),
 	});
 }
+
+// useIssueEvents refetches issues whenever the server reports a change.
+export function useIssueEvents() {
+	const qc = useQueryClient();
+	useEffect(() => {
+		const controller = new AbortController();
+		streamEvents(
+			() => qc.invalidateQueries({ queryKey: ["issues"] }),
+			controller.signal,
+		);
+		return () => controller.abort();
+	}, [qc]);
+}
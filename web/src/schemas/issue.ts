@@ -10,14 +10,23 @@ export const IssueSchema = z.object({
 	title: z.string().min(1, "Title is required").max(255, "Title too long"),
 	description: z.string(),
 	status: StatusType,
+	rank: z.string(),
 	created_at: z.string(),
 	updated_at: z.string(),
+	parent_id: z.number().nullable(),
+	auto_complete: z.boolean(),
+	// Only present on issues with sub-tasks.
+	progress: z
+		.object({ completed: z.number(), total: z.number() })
+		.optional(),
 });
 export type Issue = z.infer<typeof IssueSchema>;
 
 export const CreateIssueSchema = z.object({
 	title: z.string().min(1, "Title is required").max(255, "Title too long"),
 	description: z.string().default(""),
+	parent_id: z.number().optional(),
+	auto_complete: z.boolean().optional(),
 });
 export type CreateIssueInput = z.infer<typeof CreateIssueSchema>;
 
@@ -25,3 +34,71 @@ export const UpdateStatusSchema = z.object({
 	status: StatusType,
 });
 export type UpdateStatusInput = z.infer<typeof UpdateStatusSchema>;
+
+// after_id / before_id are the cards directly above / below the drop target.
+export const MoveIssueSchema = z.object({
+	status: StatusType.optional(),
+	after_id: z.number().optional(),
+	before_id: z.number().optional(),
+});
+export type MoveIssueInput = z.infer<typeof MoveIssueSchema>;
+
+// Required when deleting an issue that has sub-tasks.
+export const ChildPolicy = z.enum(["cascade", "orphan"]);
+export type ChildPolicy = z.infer<typeof ChildPolicy>;
+
+// Links are listed from the issue they're fetched for: "A blocks B" shows
+// up on A as blocks and on B as blocked_by.
+export const LinkType = z.enum([
+	"blocks",
+	"blocked_by",
+	"duplicates",
+	"duplicated_by",
+	"relates_to",
+]);
+export type LinkType = z.infer<typeof LinkType>;
+
+export const IssueLinkSchema = z.object({
+	id: z.number(),
+	type: LinkType,
+	issue: z.object({
+		id: z.number(),
+		title: z.string(),
+		status: StatusType,
+		done: z.boolean(),
+	}),
+	created_at: z.string(),
+});
+export type IssueLink = z.infer<typeof IssueLinkSchema>;
+
+export const AttachmentSchema = z.object({
+	id: z.number(),
+	issue_id: z.number().nullable(),
+	user_id: z.uuid().nullable(),
+	filename: z.string(),
+	content_type: z.string(),
+	size_bytes: z.number(),
+	sha256: z.string(),
+	created_at: z.string(),
+});
+export type Attachment = z.infer<typeof AttachmentSchema>;
+
+export const IssueEventType = z.enum([
+	"issue.created",
+	"issue.updated",
+	"issue.deleted",
+]);
+export type IssueEventType = z.infer<typeof IssueEventType>;
+
+export const IssueEventSchema = z.object({
+	id: z.number(),
+	type: IssueEventType,
+	issue_id: z.number(),
+	project_id: z.number().nullable(),
+	owner_id: z.uuid(),
+	actor_id: z.uuid().optional(),
+	// Missing on deletes and on events too large to carry the issue.
+	data: IssueSchema.optional(),
+	created_at: z.string(),
+});
+export type IssueEvent = z.infer<typeof IssueEventSchema>;